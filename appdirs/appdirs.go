@@ -0,0 +1,129 @@
+// Package appdirs resolves the per-user directories where the application keeps its configuration,
+// cache, and state, following the XDG Base Directory Specification on Linux and other Unix systems
+// and the platform conventions on macOS and Windows.
+//
+// The directories are only resolved, not created; Ensure creates one when a feature first writes to it,
+// so that runs which persist nothing leave no empty directories behind.
+//
+// # Example Usage
+//
+//	dirs, err := appdirs.Resolve(os.Getenv)
+//	if err != nil {
+//		return err
+//	}
+//	if err := appdirs.Ensure(filesystem.RealFileSystem{}, dirs.State); err != nil {
+//		return err
+//	}
+//	path := filepath.Join(dirs.State, "state.json")
+package appdirs
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// EnvHome is the environment variable that places all directories below a single directory, for
+// example a volume of a container. It takes precedence over the platform conventions.
+const EnvHome = "EXPORTER_HOME"
+
+// appName is the name of the directory of the application below each base directory.
+const appName = "ChatGPT-Next-Web-Session-Exporter"
+
+// dirPerm is the permission of the directories created by Ensure. Their files are derived from
+// private conversations, so only the owner may read them.
+const dirPerm = 0o700
+
+// Dirs holds the resolved directories of the application.
+type Dirs struct {
+	Config string // Config holds files the user edits, such as settings.
+	Cache  string // Cache holds data that can be recreated, such as downloads.
+	State  string // State holds data the application keeps between runs, such as history.
+}
+
+// Resolve returns the directories of the application for the current platform, reading the
+// environment with getenv.
+//
+// When EnvHome is set, the directories are its config, cache, and state subdirectories. Otherwise
+// XDG_CONFIG_HOME, XDG_CACHE_HOME, and XDG_STATE_HOME are honored on every platform when they hold
+// absolute paths, as the specification requires, and the platform defaults are used for the others:
+//
+//   - Linux and other Unix systems: ~/.config, ~/.cache, and ~/.local/state
+//   - macOS: ~/Library/Application Support for configuration and state, ~/Library/Caches for the cache
+//   - Windows: %APPDATA% for configuration, %LOCALAPPDATA% for the cache and state
+//
+// It returns an error if a directory cannot be resolved because the home directory is unknown.
+func Resolve(getenv func(string) string) (Dirs, error) {
+	return resolve(getenv, runtime.GOOS)
+}
+
+// resolve implements Resolve for the operating system goos, so that every platform can be tested.
+func resolve(getenv func(string) string, goos string) (Dirs, error) {
+	if home := getenv(EnvHome); home != "" {
+		return Dirs{
+			Config: filepath.Join(home, "config"),
+			Cache:  filepath.Join(home, "cache"),
+			State:  filepath.Join(home, "state"),
+		}, nil
+	}
+
+	home := getenv("HOME")
+	if goos == "windows" {
+		home = getenv("USERPROFILE")
+	}
+	// under joins elem below the directory in the environment variable env, or below the home
+	// directory with the path fallback if env is empty. It returns "" if neither is known.
+	under := func(env string, fallback []string, elem ...string) string {
+		dir := getenv(env)
+		if dir == "" {
+			if home == "" {
+				return ""
+			}
+			dir = filepath.Join(append([]string{home}, fallback...)...)
+		}
+		return filepath.Join(append([]string{dir}, elem...)...)
+	}
+
+	var dirs Dirs
+	switch goos {
+	case "windows":
+		dirs.Config = under("APPDATA", []string{"AppData", "Roaming"}, appName)
+		dirs.Cache = under("LOCALAPPDATA", []string{"AppData", "Local"}, appName, "cache")
+		dirs.State = under("LOCALAPPDATA", []string{"AppData", "Local"}, appName, "state")
+	case "darwin":
+		dirs.Config = under("", []string{"Library", "Application Support"}, appName)
+		dirs.Cache = under("", []string{"Library", "Caches"}, appName)
+		dirs.State = under("", []string{"Library", "Application Support"}, appName, "state")
+	default:
+		dirs.Config = under("", []string{".config"}, appName)
+		dirs.Cache = under("", []string{".cache"}, appName)
+		dirs.State = under("", []string{".local", "state"}, appName)
+	}
+
+	// The XDG variables override the platform defaults; relative paths are invalid and ignored.
+	for _, xdg := range []struct {
+		env string
+		dir *string
+	}{
+		{"XDG_CONFIG_HOME", &dirs.Config},
+		{"XDG_CACHE_HOME", &dirs.Cache},
+		{"XDG_STATE_HOME", &dirs.State},
+	} {
+		if dir := getenv(xdg.env); filepath.IsAbs(dir) {
+			*xdg.dir = filepath.Join(dir, appName)
+		}
+	}
+
+	if dirs.Config == "" || dirs.Cache == "" || dirs.State == "" {
+		return Dirs{}, errors.New("cannot resolve the application directories because the home directory is not set; set " + EnvHome + " instead")
+	}
+	return dirs, nil
+}
+
+// Ensure creates the directory dir with its missing parents, readable only by the owner, unless it
+// already exists. Features call it before they first write to one of the directories of Dirs.
+func Ensure(fsys filesystem.FileSystem, dir string) error {
+	return filesystem.MkdirAll(fsys, dir, dirPerm)
+}
@@ -0,0 +1,110 @@
+package appdirs
+
+import (
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestResolve verifies the directories of every platform, the XDG overrides, and EnvHome.
+func TestResolve(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the expected paths use forward slashes")
+	}
+	app := "/" + appName
+	tests := []struct {
+		name string
+		goos string
+		env  map[string]string
+		want Dirs
+	}{
+		{
+			name: "linux defaults",
+			goos: "linux",
+			env:  map[string]string{"HOME": "/home/u"},
+			want: Dirs{Config: "/home/u/.config" + app, Cache: "/home/u/.cache" + app, State: "/home/u/.local/state" + app},
+		},
+		{
+			name: "xdg overrides",
+			goos: "linux",
+			env:  map[string]string{"HOME": "/home/u", "XDG_CONFIG_HOME": "/xdg/config", "XDG_CACHE_HOME": "relative", "XDG_STATE_HOME": "/xdg/state"},
+			want: Dirs{Config: "/xdg/config" + app, Cache: "/home/u/.cache" + app, State: "/xdg/state" + app},
+		},
+		{
+			name: "macOS defaults",
+			goos: "darwin",
+			env:  map[string]string{"HOME": "/Users/u"},
+			want: Dirs{
+				Config: "/Users/u/Library/Application Support" + app,
+				Cache:  "/Users/u/Library/Caches" + app,
+				State:  "/Users/u/Library/Application Support" + app + "/state",
+			},
+		},
+		{
+			name: "windows variables",
+			goos: "windows",
+			env:  map[string]string{"APPDATA": "/roaming", "LOCALAPPDATA": "/local"},
+			want: Dirs{Config: "/roaming" + app, Cache: "/local" + app + "/cache", State: "/local" + app + "/state"},
+		},
+		{
+			name: "windows profile",
+			goos: "windows",
+			env:  map[string]string{"USERPROFILE": "/users/u", "HOME": "/ignored"},
+			want: Dirs{
+				Config: "/users/u/AppData/Roaming" + app,
+				Cache:  "/users/u/AppData/Local" + app + "/cache",
+				State:  "/users/u/AppData/Local" + app + "/state",
+			},
+		},
+		{
+			name: "exporter home",
+			goos: "linux",
+			env:  map[string]string{EnvHome: "/data", "HOME": "/home/u", "XDG_CONFIG_HOME": "/xdg/config"},
+			want: Dirs{Config: "/data/config", Cache: "/data/cache", State: "/data/state"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolve(func(key string) string { return tt.env[key] }, tt.goos)
+			if err != nil {
+				t.Fatalf("resolve() returned an error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := resolve(func(string) string { return "" }, "linux"); err == nil {
+		t.Error("resolve() without a home directory returned no error")
+	}
+}
+
+// TestEnsure verifies that Ensure creates a directory readable only by the owner, and that it
+// succeeds when the directory already exists.
+func TestEnsure(t *testing.T) {
+	mockFS := filesystem.NewMockFileSystem()
+	if err := Ensure(mockFS, "/state/app"); err != nil || !mockFS.Dirs["/state/app"] {
+		t.Errorf("Ensure() on the mock file system = %v with directories %v", err, mockFS.Dirs)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	dir := filepath.Join(t.TempDir(), "state", appName)
+	for i := 0; i < 2; i++ {
+		if err := Ensure(filesystem.RealFileSystem{}, dir); err != nil {
+			t.Fatalf("Ensure() returned an error: %v", err)
+		}
+	}
+	info, err := filesystem.RealFileSystem{}.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != fs.FileMode(dirPerm) {
+		t.Errorf("the directory has mode %v, want %v", perm, fs.FileMode(dirPerm))
+	}
+}
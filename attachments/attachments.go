@@ -0,0 +1,244 @@
+// Package attachments moves images and files embedded in or linked from chat messages
+// into a sidecar directory next to the export.
+//
+// Base64 data URIs (for example "data:image/png;base64,iVBOR...") are decoded and written
+// to the directory, and the inline blob is replaced by a relative path reference.
+// Links to remote attachments can optionally be downloaded in the same way.
+// Files are named after a hash of their content, so identical attachments are stored once.
+package attachments
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// MaxDownloadSize is the largest remote attachment that will be downloaded, in bytes.
+const MaxDownloadSize = 32 << 20
+
+// DownloadTimeout bounds each download of the default client, so that a stalled server cannot hang the export.
+const DownloadTimeout = 30 * time.Second
+
+// defaultClient performs the downloads of an Extractor without a Client.
+var defaultClient = &http.Client{Timeout: DownloadTimeout}
+
+var (
+	// dataURIPattern matches base64 encoded data URIs and captures the media type and the payload.
+	dataURIPattern = regexp.MustCompile(`data:([a-zA-Z0-9.+-]+/[a-zA-Z0-9.+-]+)(?:;[a-zA-Z0-9=._-]+)*;base64,([A-Za-z0-9+/]+={0,2})`)
+	// remoteURLPattern matches links to files that look like attachments, judged by their extension.
+	remoteURLPattern = regexp.MustCompile(`(?i)https?://[^\s()<>"']+\.(?:png|jpe?g|gif|webp|bmp|svg|pdf|txt|csv|json|zip)(?:\?[^\s()<>"']*)?`)
+)
+
+// extensions maps the common media types to file extensions; others are resolved through the mime package.
+var extensions = map[string]string{
+	"image/png":        ".png",
+	"image/jpeg":       ".jpg",
+	"image/gif":        ".gif",
+	"image/webp":       ".webp",
+	"image/bmp":        ".bmp",
+	"image/svg+xml":    ".svg",
+	"application/pdf":  ".pdf",
+	"text/plain":       ".txt",
+	"text/csv":         ".csv",
+	"application/json": ".json",
+	"application/zip":  ".zip",
+}
+
+// Extractor writes the attachments found in message content to Dir through FS.
+type Extractor struct {
+	FS       filesystem.FileSystem // FS receives the attachment files.
+	Dir      string                // Dir is the sidecar directory the attachment files are written to.
+	Base     string                // Base is the directory of the output file the path references are relative to; the working directory when empty.
+	Download bool                  // Download fetches remote attachment links in addition to decoding data URIs.
+	Client   *http.Client          // Client performs the downloads; a client with DownloadTimeout is used when nil.
+}
+
+// Report summarizes the work done by Extract.
+type Report struct {
+	Files    []string // Files lists the written attachment paths in the order they were first seen.
+	Replaced int      // Replaced counts the inline blobs and links replaced by a path reference.
+	Failed   int      // Failed counts the attachments that could not be decoded or downloaded and were left untouched.
+	Bytes    int      // Bytes is the total size of the written files.
+}
+
+// extraction holds the state of a single Extract call.
+type extraction struct {
+	*Extractor
+	report     Report
+	written    map[string]bool   // written holds the attachment files written so far.
+	downloaded map[string]string // downloaded maps the downloaded links to their path references.
+}
+
+// Extract returns a copy of sessions in which every attachment is replaced by a relative path
+// reference to the file written into the sidecar directory. The input sessions are not modified.
+//
+// Attachments that cannot be decoded or downloaded are left untouched and counted in the report.
+// It returns an error if the context is cancelled or an attachment file cannot be written.
+func (e *Extractor) Extract(ctx context.Context, sessions []exporter.Session) ([]exporter.Session, Report, error) {
+	x := &extraction{Extractor: e, written: make(map[string]bool), downloaded: make(map[string]string)}
+	result := make([]exporter.Session, len(sessions))
+
+	for i, session := range sessions {
+		if err := ctx.Err(); err != nil {
+			return nil, x.report, err
+		}
+		result[i] = session
+		if session.Messages == nil {
+			continue
+		}
+		result[i].Messages = make([]exporter.Message, len(session.Messages))
+		for j, message := range session.Messages {
+			content, err := x.extractContent(ctx, message.Content)
+			if err != nil {
+				return nil, x.report, err
+			}
+			message.Content = content
+			result[i].Messages[j] = message
+		}
+	}
+	return result, x.report, nil
+}
+
+// extractContent replaces the attachments of a single message content.
+func (x *extraction) extractContent(ctx context.Context, content string) (string, error) {
+	var writeErr error
+
+	content = dataURIPattern.ReplaceAllStringFunc(content, func(match string) string {
+		if writeErr != nil {
+			return match
+		}
+		groups := dataURIPattern.FindStringSubmatch(match)
+		data, err := base64.StdEncoding.DecodeString(groups[2])
+		if err != nil {
+			x.report.Failed++
+			return match
+		}
+		reference, err := x.store(data, extensionForType(groups[1]))
+		if err != nil {
+			writeErr = err
+			return match
+		}
+		x.report.Replaced++
+		return reference
+	})
+	if writeErr != nil || !x.Download {
+		return content, writeErr
+	}
+
+	content = remoteURLPattern.ReplaceAllStringFunc(content, func(link string) string {
+		if writeErr != nil {
+			return link
+		}
+		if reference, ok := x.downloaded[link]; ok {
+			x.report.Replaced++
+			return reference
+		}
+		data, mediaType, err := x.download(ctx, link)
+		if err != nil {
+			x.report.Failed++
+			return link
+		}
+		extension := extensionForType(mediaType)
+		if urlExtension := path.Ext(strings.SplitN(link, "?", 2)[0]); urlExtension != "" {
+			extension = strings.ToLower(urlExtension)
+		}
+		reference, err := x.store(data, extension)
+		if err != nil {
+			writeErr = err
+			return link
+		}
+		x.downloaded[link] = reference
+		x.report.Replaced++
+		return reference
+	})
+	return content, writeErr
+}
+
+// store writes data into the sidecar directory unless an identical file was already written,
+// and returns the relative path reference to it.
+func (x *extraction) store(data []byte, extension string) (string, error) {
+	sum := sha256.Sum256(data)
+	name := filepath.Join(x.Dir, hex.EncodeToString(sum[:8])+extension)
+	if !x.written[name] {
+		if err := x.FS.WriteFile(name, data, 0644); err != nil {
+			return "", fmt.Errorf("failed to write attachment %s: %w", name, err)
+		}
+		x.written[name] = true
+		x.report.Files = append(x.report.Files, name)
+		x.report.Bytes += len(data)
+	}
+	return x.reference(name)
+}
+
+// reference returns the path of the attachment file name relative to the directory of the output file.
+func (x *extraction) reference(name string) (string, error) {
+	base, err := filepath.Abs(x.Base)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the output directory %s: %w", x.Base, err)
+	}
+	target, err := filepath.Abs(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve attachment %s: %w", name, err)
+	}
+	reference, err := filepath.Rel(base, target)
+	if err != nil {
+		return "", fmt.Errorf("failed to reference attachment %s from %s: %w", name, x.Base, err)
+	}
+	return filepath.ToSlash(reference), nil
+}
+
+// download fetches a remote attachment and returns its content and media type.
+func (e *Extractor) download(ctx context.Context, link string) ([]byte, string, error) {
+	client := e.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxDownloadSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > MaxDownloadSize {
+		return nil, "", fmt.Errorf("attachment exceeds %d bytes", MaxDownloadSize)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return data, mediaType, nil
+}
+
+// extensionForType returns the file extension for a media type, or ".bin" if it is unknown.
+func extensionForType(mediaType string) string {
+	mediaType = strings.ToLower(mediaType)
+	if extension, ok := extensions[mediaType]; ok {
+		return extension
+	}
+	if candidates, err := mime.ExtensionsByType(mediaType); err == nil && len(candidates) > 0 {
+		return candidates[0]
+	}
+	return ".bin"
+}
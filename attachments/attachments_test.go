@@ -0,0 +1,107 @@
+package attachments_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/attachments"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/testsupport"
+)
+
+// TestExtract verifies that data URIs are decoded, identical blobs are stored once,
+// and remote links are only downloaded when requested.
+func TestExtract(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cat.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("remote"))
+	}))
+	defer server.Close()
+
+	// "aGVsbG8=" is "hello".
+	content := "look ![a](data:image/png;base64,aGVsbG8=) and data:image/png;base64,aGVsbG8= " +
+		server.URL + "/cat.png " + server.URL + "/missing.png"
+	sessions := []exporter.Session{testsupport.NewSession("s1", testsupport.WithMessages(
+		testsupport.NewMessage("m1", "user", content),
+	))}
+
+	for _, download := range []bool{false, true} {
+		mockFS := filesystem.NewMockFileSystem()
+		extractor := &attachments.Extractor{FS: mockFS, Dir: "files", Download: download}
+		extracted, report, err := extractor.Extract(context.Background(), sessions)
+		if err != nil {
+			t.Fatalf("Extract() returned an error: %v", err)
+		}
+		got := extracted[0].Messages[0].Content
+
+		if strings.Contains(got, "base64") || !strings.Contains(got, "![a](files/") {
+			t.Errorf("download=%v: data URIs were not replaced: %q", download, got)
+		}
+		if sessions[0].Messages[0].Content != content {
+			t.Errorf("download=%v: the input sessions were modified", download)
+		}
+
+		wantFiles, wantReplaced, wantFailed := 1, 2, 0
+		if download {
+			wantFiles, wantReplaced, wantFailed = 2, 3, 1
+		}
+		if len(report.Files) != wantFiles || report.Replaced != wantReplaced || report.Failed != wantFailed {
+			t.Errorf("download=%v: unexpected report: %+v", download, report)
+		}
+		if strings.Contains(got, "/cat.png") == download {
+			t.Errorf("download=%v: unexpected handling of remote link: %q", download, got)
+		}
+		if !strings.Contains(got, server.URL+"/missing.png") {
+			t.Errorf("download=%v: failed link should be left untouched: %q", download, got)
+		}
+		for _, name := range report.Files {
+			if data, ok := mockFS.Files[name]; !ok || (string(data) != "hello" && string(data) != "remote") {
+				t.Errorf("download=%v: unexpected attachment %s: %q", download, name, data)
+			}
+		}
+	}
+}
+
+// TestExtractReferences verifies that the path references are relative to the directory of the output
+// file, even when the sidecar directory is given as an absolute path.
+func TestExtractReferences(t *testing.T) {
+	root := t.TempDir()
+	sessions := []exporter.Session{testsupport.NewSession("s1", testsupport.WithMessages(
+		testsupport.NewMessage("m1", "user", "![a](data:image/png;base64,aGVsbG8=)"),
+	))}
+	tests := []struct {
+		name string
+		dir  string
+		base string
+		want string
+	}{
+		{"WorkingDirectory", "files", "", "![a](files/"},
+		{"AbsoluteDir", filepath.Join(root, "out", "files"), filepath.Join(root, "out"), "![a](files/"},
+		{"SiblingDir", filepath.Join(root, "files"), filepath.Join(root, "out"), "![a](../files/"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockFS := filesystem.NewMockFileSystem()
+			extractor := &attachments.Extractor{FS: mockFS, Dir: tc.dir, Base: tc.base}
+			extracted, report, err := extractor.Extract(context.Background(), sessions)
+			if err != nil {
+				t.Fatalf("Extract() returned an error: %v", err)
+			}
+			if got := extracted[0].Messages[0].Content; !strings.HasPrefix(got, tc.want) {
+				t.Errorf("Extract() replaced the attachment with %q, want a reference starting with %q", got, tc.want)
+			}
+			if len(report.Files) != 1 || filepath.Dir(report.Files[0]) != tc.dir {
+				t.Errorf("Extract() wrote %v, want a file in %s", report.Files, tc.dir)
+			}
+		})
+	}
+}
@@ -12,6 +12,9 @@
 //		// Optionally, display an typing animated banner
 //		bannercli.PrintAnimatedBanner("ChatGPT Session Exporter", 3, 200*time.Millisecond)
 //
+//		// Or let the configuration choose the style
+//		bannercli.Print(bannercli.DefaultConfig(), "ChatGPT Session Exporter", os.Stdout)
+//
 //		// ... rest of your main function ...
 //	}
 //
@@ -19,20 +22,104 @@
 package bannercli
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 )
 
+// after is time.After, replaced in tests to control the delay between the characters of PrintTypingBannerCtx.
+var after = time.After
+
+// Style selects how Print shows a banner.
+type Style int
+
+const (
+	// StyleTyping types the banner character by character, like PrintTypingBanner.
+	StyleTyping Style = iota
+
+	// StyleAnimated scrolls the banner across the line, like PrintAnimatedBanner.
+	StyleAnimated
+
+	// StyleBinary prints the banner in binary at once, like PrintBinaryBanner.
+	StyleBinary
+
+	// StyleNone prints nothing.
+	StyleNone
+)
+
+// ParseStyle parses the name of a Style: "typing", "animated", "binary", or "none".
+func ParseStyle(name string) (Style, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "typing":
+		return StyleTyping, nil
+	case "animated":
+		return StyleAnimated, nil
+	case "binary":
+		return StyleBinary, nil
+	case "none":
+		return StyleNone, nil
+	default:
+		return StyleTyping, fmt.Errorf("unknown banner style %q, expected typing, animated, binary, or none", name)
+	}
+}
+
+// String returns the name of the style as accepted by ParseStyle.
+func (s Style) String() string {
+	switch s {
+	case StyleAnimated:
+		return "animated"
+	case StyleBinary:
+		return "binary"
+	case StyleNone:
+		return "none"
+	default:
+		return "typing"
+	}
+}
+
+// Config determines how Print shows a banner.
+type Config struct {
+	Style  Style         // Style selects the kind of banner.
+	Delay  time.Duration // Delay is the pause after each character or frame of the typing and animated styles.
+	Repeat int           // Repeat is the number of times the animated style scrolls the banner.
+}
+
+// DefaultConfig returns the configuration of the banner the exporter shows at startup: typed with a
+// delay of 100 milliseconds per character.
+func DefaultConfig() Config {
+	return Config{Style: StyleTyping, Delay: 100 * time.Millisecond, Repeat: 3}
+}
+
+// Print writes the message to w as a banner in the style of cfg, so that callers can choose the style
+// at run time instead of calling one of the Print*Banner functions.
+func Print(cfg Config, message string, w io.Writer) {
+	switch cfg.Style {
+	case StyleTyping:
+		typeBanner(context.Background(), w, message, cfg.Delay)
+	case StyleAnimated:
+		PrintAnimatedBannerTo(w, message, cfg.Repeat, cfg.Delay)
+	case StyleBinary:
+		binaryBanner(w, message)
+	}
+}
+
 // PrintBinaryBanner prints a binary representation of a banner.
 // Each character of the message is converted into its binary form.
 // Spaces between words are widened to enhance readability.
 func PrintBinaryBanner(message string) {
+	binaryBanner(os.Stdout, message)
+}
+
+// binaryBanner writes the banner of PrintBinaryBanner to w.
+func binaryBanner(w io.Writer, message string) {
 	banner := strings.ReplaceAll(message, " ", "   ")
 	for _, char := range banner {
-		fmt.Printf(" %08b", char)
+		fmt.Fprintf(w, " %08b", char)
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 }
 
 // PrintAnimatedBanner prints a simple animated banner by scrolling the message
@@ -40,13 +127,19 @@ func PrintBinaryBanner(message string) {
 // specified by the `repeat` parameter with a delay between each frame as
 // specified by the `delay` parameter.
 func PrintAnimatedBanner(message string, repeat int, delay time.Duration) {
+	PrintAnimatedBannerTo(os.Stdout, message, repeat, delay)
+}
+
+// PrintAnimatedBannerTo is like PrintAnimatedBanner, but writes the animation to w, such as standard
+// error or a buffer, instead of standard output.
+func PrintAnimatedBannerTo(w io.Writer, message string, repeat int, delay time.Duration) {
 	for r := 0; r < repeat; r++ {
 		for i := 0; i < len(message); i++ {
-			fmt.Print("\r" + strings.Repeat(" ", i) + message)
+			fmt.Fprint(w, "\r"+strings.Repeat(" ", i)+message)
 			time.Sleep(delay)
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 }
 
 // PrintTypingBanner prints the message with a typing animation effect.
@@ -55,9 +148,36 @@ func PrintAnimatedBanner(message string, repeat int, delay time.Duration) {
 //
 // Note: This simulation typing just like a human would type.
 func PrintTypingBanner(message string, delay time.Duration) {
+	PrintTypingBannerCtx(context.Background(), message, delay)
+}
+
+// PrintTypingBannerTo is like PrintTypingBanner, but writes the message to w, such as standard error or
+// a buffer, instead of standard output.
+func PrintTypingBannerTo(w io.Writer, message string, delay time.Duration) {
+	typeBanner(context.Background(), w, message, delay)
+}
+
+// PrintTypingBannerCtx is like PrintTypingBanner, but stops typing when ctx is cancelled. The line is
+// still ended, so that the next output starts on a new line, and ctx.Err() is returned. Nothing is
+// printed if ctx is already cancelled.
+func PrintTypingBannerCtx(ctx context.Context, message string, delay time.Duration) error {
+	return typeBanner(ctx, os.Stdout, message, delay)
+}
+
+// typeBanner writes the typing animation of PrintTypingBannerCtx to w.
+func typeBanner(ctx context.Context, w io.Writer, message string, delay time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	for _, char := range message {
-		fmt.Printf("%c", char)
-		time.Sleep(delay)
+		fmt.Fprintf(w, "%c", char)
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(w)
+			return ctx.Err()
+		case <-after(delay):
+		}
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
+	return nil
 }
@@ -0,0 +1,97 @@
+package bannercli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPrintTypingBannerCtx verifies that cancelling the context stops the typing after the characters
+// printed so far, ends the line, and returns the context error.
+func TestPrintTypingBannerCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The delay after the fifth character never ends, but cancels the context instead.
+	calls := 0
+	defer func(original func(time.Duration) <-chan time.Time) { after = original }(after)
+	after = func(time.Duration) <-chan time.Time {
+		calls++
+		if calls == 5 {
+			cancel()
+			return nil
+		}
+		elapsed := make(chan time.Time)
+		close(elapsed)
+		return elapsed
+	}
+
+	oldStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	err = PrintTypingBannerCtx(ctx, "Hello, Gopher", time.Second)
+	w.Close()
+	os.Stdout = oldStdout
+	output, _ := io.ReadAll(r)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("PrintTypingBannerCtx() returned %v, want context.Canceled", err)
+	}
+	if string(output) != "Hello\n" {
+		t.Errorf("PrintTypingBannerCtx() printed %q, want %q", output, "Hello\n")
+	}
+
+	if err := PrintTypingBannerCtx(ctx, "Hello", 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("PrintTypingBannerCtx() with a cancelled context returned %v, want context.Canceled", err)
+	}
+}
+
+// TestPrintAnimatedBannerTo verifies that the animation is written to the given writer, one frame per
+// position of the message and repetition, followed by a newline.
+func TestPrintAnimatedBannerTo(t *testing.T) {
+	var output bytes.Buffer
+	PrintAnimatedBannerTo(&output, "Gopher", 3, 0)
+	got := output.String()
+	if frames := strings.Count(got, "\r"); frames != 18 {
+		t.Errorf("PrintAnimatedBannerTo() wrote %d frames, want 18", frames)
+	}
+	if !strings.HasPrefix(got, "\rGopher\r Gopher") || !strings.HasSuffix(got, "\r"+strings.Repeat(" ", 5)+"Gopher\n") {
+		t.Errorf("PrintAnimatedBannerTo() wrote %q", got)
+	}
+}
+
+// TestPrint verifies the output of every banner style.
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		style Style
+		want  string
+	}{
+		{StyleTyping, "Go!\n"},
+		{StyleAnimated, "\rGo!\r Go!\r  Go!\rGo!\r Go!\r  Go!\n"},
+		{StyleBinary, " 01000111 01101111 00100001\n"},
+		{StyleNone, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.style.String(), func(t *testing.T) {
+			var output bytes.Buffer
+			Print(Config{Style: tc.style, Repeat: 2}, "Go!", &output)
+			if output.String() != tc.want {
+				t.Errorf("Print() wrote %q, want %q", output.String(), tc.want)
+			}
+			if parsed, err := ParseStyle(tc.style.String()); err != nil || parsed != tc.style {
+				t.Errorf("ParseStyle(%q) = %v, %v, want %v", tc.style.String(), parsed, err, tc.style)
+			}
+		})
+	}
+	if _, err := ParseStyle("blink"); err == nil {
+		t.Error("ParseStyle() accepted an unknown style")
+	}
+}
@@ -0,0 +1,80 @@
+// @batch.go:
+// This file implements the batch mode of the CLI tool: when the input path names a directory or is a glob
+// pattern, every matching JSON file is exported in turn, either stopping at the first file that fails
+// (-fail-fast) or exporting all of them and reporting the failures at the end (-keep-going, the default).
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// expandInputPaths returns the input files named by path: the JSON files directly inside it if it is a
+// directory, the files matching it if it is a glob pattern, and path itself otherwise. The files are
+// sorted by name, so that a batch always runs in the same order. It returns an error if a directory or
+// pattern matches no file.
+func expandInputPaths(rfs filesystem.FileSystem, path string) ([]string, error) {
+	pattern := path
+	if info, err := rfs.Stat(path); err == nil && info.IsDir() {
+		pattern = filepath.Join(path, "*.json")
+	} else if !strings.ContainsAny(path, "*?[") {
+		return []string{path}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input pattern %q: %w", path, err)
+	}
+	var paths []string
+	for _, match := range matches {
+		if info, err := rfs.Stat(match); err == nil && !info.IsDir() {
+			paths = append(paths, match)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no input files match %s", pattern)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// exportBatch calls export for every path in turn. A single path is exported as is. With several, a
+// failed file stops the batch with its error when failFast is set; otherwise the remaining files are still
+// exported, and the failures are reported with printError at the end, followed by an error counting them.
+// A cancellation or the end of input always stops the batch, since no later file could be exported either.
+func exportBatch(run *runState, paths []string, failFast bool, export func(path string) error) error {
+	if len(paths) == 1 {
+		return export(paths[0])
+	}
+
+	var failures []error
+	for i, path := range paths {
+		run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("Exporting %s (%d of %d).", path, i+1, len(paths)),
+			"file", path, "index", i+1, "files", len(paths))
+		err := export(path)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("%s: %w", path, err)
+		if failFast || errors.Is(err, context.Canceled) || errors.Is(err, io.EOF) {
+			return err
+		}
+		failures = append(failures, err)
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	for _, failure := range failures {
+		run.printError(fmt.Sprintf("\n[GopherHelper] Error: %s\n", failure))
+	}
+	return fmt.Errorf("%d of %d input files failed", len(failures), len(paths))
+}
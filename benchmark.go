@@ -0,0 +1,172 @@
+// @benchmark.go:
+// This file implements the hidden -benchmark flag, which measures the conversion throughput on the
+// user's hardware by running the selected conversion repeatedly into an in-memory file system.
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// benchmarkConversion is a conversion measured by the -benchmark flag.
+// It writes its output for the sessions into the given file system.
+type benchmarkConversion struct {
+	name    string
+	convert func(ctx context.Context, fsys filesystem.FileSystem, sessions []exporter.Session) error
+}
+
+// benchmarkResult holds the measurements of a benchmarked conversion.
+type benchmarkResult struct {
+	Name     string        // Name identifies the conversion, e.g. "csv/inline".
+	Runs     int           // Runs is the number of times the conversion was run.
+	Sessions int           // Sessions is the number of sessions converted by each run.
+	Bytes    int64         // Bytes is the output size of a single run.
+	Elapsed  time.Duration // Elapsed is the total time of all runs.
+}
+
+// SessionsPerSecond returns the number of sessions converted per second.
+func (r benchmarkResult) SessionsPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Sessions*r.Runs) / r.Elapsed.Seconds()
+}
+
+// MBPerSecond returns the output throughput in megabytes (10^6 bytes) per second.
+func (r benchmarkResult) MBPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) * float64(r.Runs) / 1e6 / r.Elapsed.Seconds()
+}
+
+// csvBenchmarkConversion returns the benchmark of a single-file CSV format option.
+func csvBenchmarkConversion(name string, formatOption int, opts cliOptions) benchmarkConversion {
+	return benchmarkConversion{name: name, convert: func(ctx context.Context, fsys filesystem.FileSystem, sessions []exporter.Session) error {
+		var csvOutput bytes.Buffer
+		if err := exporter.WriteSessionsCSV(ctx, &csvOutput, sessions, formatOption, opts.csvOptions()); err != nil {
+			return err
+		}
+		return fsys.WriteFile("output.csv", csvOutput.Bytes(), 0644)
+	}}
+}
+
+// benchmarkConversions returns the conversions measured for an output format name.
+// The CSV format is measured in each of its layouts. Formats that write no files yield none.
+func benchmarkConversions(format string, opts cliOptions) []benchmarkConversion {
+	switch format {
+	case "csv":
+		return []benchmarkConversion{
+			csvBenchmarkConversion("csv/inline", exporter.FormatOptionInline, opts),
+			csvBenchmarkConversion("csv/perline", exporter.FormatOptionPerLine, opts),
+			csvBenchmarkConversion("csv/json", exporter.FormatOptionJSON, opts),
+			{name: "csv/separate", convert: func(ctx context.Context, fsys filesystem.FileSystem, sessions []exporter.Session) error {
+				return exporter.CreateSeparateCSVFiles(ctx, sessions, "sessions.csv", "messages.csv", fsys, opts.csvOptions())
+			}},
+		}
+	case "dataset":
+		return []benchmarkConversion{{name: "dataset", convert: func(ctx context.Context, fsys filesystem.FileSystem, sessions []exporter.Session) error {
+			output, err := exporter.ExtractToDatasetWithOptions(sessions, opts.exportOptions().Export)
+			if err != nil {
+				return err
+			}
+			return fsys.WriteFile("output.json", []byte(output), 0644)
+		}}}
+	case "orgmode":
+		return []benchmarkConversion{{name: "orgmode", convert: func(ctx context.Context, fsys filesystem.FileSystem, sessions []exporter.Session) error {
+			var orgOutput bytes.Buffer
+			if err := exporter.WriteOrgMode(ctx, &orgOutput, sessions, opts.exportOptions().Export); err != nil {
+				return err
+			}
+			return fsys.WriteFile("output.org", orgOutput.Bytes(), 0644)
+		}}}
+	case "finetune":
+		return []benchmarkConversion{{name: "finetune", convert: func(ctx context.Context, fsys filesystem.FileSystem, sessions []exporter.Session) error {
+			exportOpts := opts.exportOptions().Export
+			output, err := fineTuningOutput(sessions, exportOpts)
+			if err != nil {
+				return err
+			}
+			return fsys.WriteFile("output"+fileExtension(fineTuningFileType(exportOpts)), []byte(output), 0644)
+		}}}
+	case "epub":
+		return []benchmarkConversion{{name: "epub", convert: func(ctx context.Context, fsys filesystem.FileSystem, sessions []exporter.Session) error {
+			var epubOutput bytes.Buffer
+			if err := exporter.WriteEPUB(ctx, &epubOutput, sessions, exporter.EPUBOptions{}); err != nil {
+				return err
+			}
+			return fsys.WriteFile("output.epub", epubOutput.Bytes(), 0644)
+		}}}
+	case "summaries":
+		return []benchmarkConversion{
+			{name: "summaries/csv", convert: func(ctx context.Context, fsys filesystem.FileSystem, sessions []exporter.Session) error {
+				var csvOutput bytes.Buffer
+				if err := exporter.WriteSummariesCSV(ctx, &csvOutput, sessions, opts.csvOptions()); err != nil {
+					return err
+				}
+				return fsys.WriteFile("summaries.csv", csvOutput.Bytes(), 0644)
+			}},
+			{name: "summaries/md", convert: func(ctx context.Context, fsys filesystem.FileSystem, sessions []exporter.Session) error {
+				var mdOutput bytes.Buffer
+				if err := exporter.WriteSummariesMarkdown(ctx, &mdOutput, sessions, opts.csvOptions()); err != nil {
+					return err
+				}
+				return fsys.WriteFile("summaries.md", mdOutput.Bytes(), 0644)
+			}},
+		}
+	case "text", "qa-jsonl", "turns-json", "atom":
+		return []benchmarkConversion{{name: format, convert: func(ctx context.Context, fsys filesystem.FileSystem, sessions []exporter.Session) error {
+			var output bytes.Buffer
+			if err := exporter.ConvertSessions(ctx, &output, sessions, format, opts.exportOptions()); err != nil {
+				return err
+			}
+			return fsys.WriteFile("output"+fileExtension(format), output.Bytes(), 0644)
+		}}}
+	default:
+		return nil
+	}
+}
+
+// runBenchmark runs a conversion the given number of times into a fresh in-memory file system
+// and measures it. The output size is taken from the files written by the last run.
+func runBenchmark(ctx context.Context, conversion benchmarkConversion, sessions []exporter.Session, runs int) (benchmarkResult, error) {
+	result := benchmarkResult{Name: conversion.name, Runs: runs, Sessions: len(sessions)}
+	memFS := filesystem.NewMockFileSystem()
+	start := time.Now()
+	for i := 0; i < runs; i++ {
+		if err := conversion.convert(ctx, memFS, sessions); err != nil {
+			return result, err
+		}
+	}
+	result.Elapsed = time.Since(start)
+	for _, data := range memFS.Files {
+		result.Bytes += int64(len(data))
+	}
+	return result, nil
+}
+
+// runBenchmarks measures every conversion of the output format and prints one line per conversion to w.
+// It returns an error if the format writes no files or a conversion fails.
+func runBenchmarks(ctx context.Context, w io.Writer, format string, sessions []exporter.Session, runs int, opts cliOptions) error {
+	conversions := benchmarkConversions(format, opts)
+	if len(conversions) == 0 {
+		return fmt.Errorf("the %s format cannot be benchmarked", format)
+	}
+	fmt.Fprintf(w, "[GopherHelper] Benchmarking %d session(s), %d run(s) per conversion, in memory:\n", len(sessions), runs)
+	for _, conversion := range conversions {
+		result, err := runBenchmark(ctx, conversion, sessions, runs)
+		if err != nil {
+			return fmt.Errorf("%s: %w", conversion.name, err)
+		}
+		fmt.Fprintf(w, "  %-13s %10.0f sessions/sec %10.2f MB/sec  (%s per run, output %s)\n",
+			result.Name, result.SessionsPerSecond(), result.MBPerSecond(),
+			(result.Elapsed / time.Duration(runs)).Round(time.Microsecond), formatSize(result.Bytes))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// @diff.go:
+// This file implements the -diff mode, which compares the input file with an older export and prints
+// the sessions that were added, removed, or modified since then instead of exporting anything.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// runDiff loads the older export at oldPath and the newer export at newPath, both decoded as format, and
+// prints their differences to w.
+func runDiff(ctx context.Context, rfs filesystem.FileSystem, w io.Writer, oldPath, newPath string, format exporter.InputFormat, policy filesystem.RetryPolicy) error {
+	older, err := loadStore(ctx, rfs, oldPath, format, policy)
+	if err != nil {
+		return fmt.Errorf("%s: %w", oldPath, err)
+	}
+	newer, err := loadStore(ctx, rfs, newPath, format, policy)
+	if err != nil {
+		return fmt.Errorf("%s: %w", newPath, err)
+	}
+	printDiff(w, exporter.DiffStores(&older, &newer), oldPath)
+	return nil
+}
+
+// printDiff prints a one-line summary of the diff followed by one line per added (+), removed (-),
+// and modified (~) session with its ID, topic, and message count.
+func printDiff(w io.Writer, diff exporter.Diff, oldPath string) {
+	fmt.Fprintf(w, "Compared with %s: %d added, %d removed, %d modified, %d unchanged.\n",
+		oldPath, len(diff.Added), len(diff.Removed), len(diff.Modified), diff.Unchanged)
+	for _, session := range diff.Added {
+		fmt.Fprintf(w, "+ %s  %s (%d messages)\n", session.ID, diffTopic(session), len(session.Messages))
+	}
+	for _, session := range diff.Removed {
+		fmt.Fprintf(w, "- %s  %s (%d messages)\n", session.ID, diffTopic(session), len(session.Messages))
+	}
+	for _, modified := range diff.Modified {
+		fmt.Fprintf(w, "~ %s  %s (%d -> %d messages)\n", modified.After.ID, diffTopic(modified.After),
+			len(modified.Before.Messages), len(modified.After.Messages))
+	}
+}
+
+// diffTopic returns the topic of the session on a single line, or "(untitled)" when it has none.
+func diffTopic(session exporter.Session) string {
+	topic := strings.Join(strings.Fields(session.Topic), " ")
+	if topic == "" {
+		return "(untitled)"
+	}
+	return topic
+}
@@ -0,0 +1,112 @@
+// @diskspace.go:
+// This file estimates the size of an export before it is written and checks it against the free
+// space of the destination, so that a full disk does not leave a truncated file behind.
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// PromptContinueLowDiskSpace asks whether to continue when the export may not fit on the disk.
+const PromptContinueLowDiskSpace = "Continue anyway? (yes/no): "
+
+// diskSpaceThreshold is the fraction of the free space an export may be estimated to use without a warning.
+const diskSpaceThreshold = 0.9
+
+// formatSizeMultipliers relates the size of an export to the size of the session text for each output format.
+// They cover quoting and separators, repeated columns, JSON keys and indentation, and Org-mode headings.
+var formatSizeMultipliers = map[string]float64{
+	"csv":        1.5,
+	"dataset":    2.5,
+	"orgmode":    1.2,
+	"finetune":   1.3,
+	"epub":       0.8,
+	"text":       1.1,
+	"qa-jsonl":   1.3,
+	"turns-json": 2.0,
+	"atom":       1.6,
+}
+
+// summarySizeMultiplier relates the size of the summaries export to the size of the session IDs, topics, and summaries.
+const summarySizeMultiplier = 1.5
+
+// estimateOutputSize estimates the size in bytes of exporting sessions in the named output format,
+// from the byte totals of the session and message text, or of the session text alone for summaries.
+// It returns 0 for formats that write no files.
+func estimateOutputSize(sessions []exporter.Session, format string) int64 {
+	if format == "summaries" {
+		// The summaries export leaves out the messages, which make up most of the text.
+		var total int64
+		for _, session := range sessions {
+			total += int64(len(session.ID) + len(session.Topic) + len(session.MemoryPrompt))
+		}
+		return int64(float64(total) * summarySizeMultiplier)
+	}
+	multiplier, ok := formatSizeMultipliers[format]
+	if !ok {
+		return 0
+	}
+	var total int64
+	for _, session := range sessions {
+		total += int64(len(session.ID) + len(session.Topic) + len(session.MemoryPrompt))
+		for _, message := range session.Messages {
+			total += int64(len(message.ID) + len(message.Date) + len(message.Role) + len(message.Content))
+		}
+	}
+	return int64(float64(total) * multiplier)
+}
+
+// confirmDiskSpace checks the estimated export size against the space available in dir.
+// When the estimate exceeds diskSpaceThreshold of the free space, it warns and asks the user
+// whether to continue. If the free space cannot be determined, the export is allowed.
+func confirmDiskSpace(ctx context.Context, run *runState, reader *bufio.Reader, ds filesystem.DiskSpace, dir string, estimate int64) (bool, error) {
+	if estimate <= 0 {
+		return true, nil
+	}
+	free, err := ds.Available(dir)
+	if err != nil {
+		return true, nil
+	}
+	if float64(estimate) <= float64(free)*diskSpaceThreshold {
+		return true, nil
+	}
+
+	run.logDiagnostic(slog.LevelWarn, fmt.Sprintf("Warning: the export is estimated at %s, but only %s is free in %s.",
+		formatSize(estimate), formatSize(int64(free)), dir), "estimate_bytes", estimate, "free_bytes", free, "dir", dir)
+	answer, err := promptForInput(ctx, run, reader, PromptContinueLowDiskSpace)
+	if err != nil {
+		return false, err
+	}
+	return strings.ToLower(answer) == "yes", nil
+}
+
+// printSizeEstimates shows the estimated export size of the sessions for every output format that writes files.
+func printSizeEstimates(w io.Writer, sessions []exporter.Session) {
+	var estimates []string
+	for _, format := range []string{"csv", "dataset", "orgmode", "finetune", "summaries"} {
+		estimates = append(estimates, fmt.Sprintf("%s ~%s", format, formatSize(estimateOutputSize(sessions, format))))
+	}
+	fmt.Fprintf(w, "Estimated export size: %s\n", strings.Join(estimates, ", "))
+}
+
+// formatSize formats a number of bytes using binary units, e.g. 1536 becomes "1.5 KiB".
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// AnonymizeSessionIDs replaces the ID of every session with a sequential anonymous ID, s0001, s0002,
+// and so on in the order of the sessions, so that exports can be shared without revealing the IDs the
+// web app assigned. The IDs get more digits if there are more than 9999 sessions, so that they always
+// sort in order.
+//
+// It returns the sessions with their new IDs and the mapping from each original ID to its anonymous
+// ID, which can be kept aside with WriteIDMapping to reverse the anonymization. Sessions sharing an ID
+// get the same anonymous ID; use ResolveDuplicateIDs first to tell them apart. Only session IDs are
+// replaced. The input sessions are not modified.
+func AnonymizeSessionIDs(sessions []Session) (mapped []Session, mapping map[string]string) {
+	width := max(4, len(strconv.Itoa(len(sessions))))
+	mapped = make([]Session, len(sessions))
+	mapping = make(map[string]string, len(sessions))
+	for i, session := range sessions {
+		anonymized, ok := mapping[session.ID]
+		if !ok {
+			anonymized = fmt.Sprintf("s%0*d", width, len(mapping)+1)
+			mapping[session.ID] = anonymized
+		}
+		session.ID = anonymized
+		mapped[i] = session
+	}
+	return mapped, mapping
+}
+
+// WriteIDMapping writes the mapping returned by AnonymizeSessionIDs to w as CSV with the columns
+// anonymized_id and original_id, ordered by anonymized ID.
+//
+// It returns an error if writing the CSV fails.
+func WriteIDMapping(w io.Writer, mapping map[string]string) error {
+	rows := make([][]string, 0, len(mapping))
+	for original, anonymized := range mapping {
+		rows = append(rows, []string{anonymized, original})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
+
+	csvWriter := csv.NewWriter(w)
+	if err := WriteHeaders(csvWriter, []string{"anonymized_id", "original_id"}); err != nil {
+		return err
+	}
+	return csvWriter.WriteAll(rows)
+}
@@ -0,0 +1,165 @@
+package exporter
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// DefaultAtomEntries is the number of sessions in the Atom feed of the registered "atom" format.
+const DefaultAtomEntries = 20
+
+// FeedMeta describes the Atom feed written by ConvertSessionsToAtom.
+type FeedMeta struct {
+	ID      string // ID is the permanent IRI of the feed; empty derives a stable URN from Title.
+	Title   string // Title is the title of the feed; empty uses "ChatGPT-Next-Web Sessions".
+	Author  string // Author is the name of the author of the feed, which Atom requires; empty uses "ChatGPT-Next-Web".
+	Link    string // Link is the URL of the site publishing the feed; omitted if empty.
+	BaseURL string // BaseURL links each entry back to its session, as described at SessionURL; omitted if empty.
+}
+
+// atomFeed is the feed element of an Atom document (RFC 4287).
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Link    *atomLink   `xml:"link,omitempty"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomPerson is a person construct of Atom, such as the author of a feed.
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+// atomLink is a link element of Atom.
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+// atomEntry is an entry element of Atom, one per session.
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Link      *atomLink   `xml:"link,omitempty"`
+	Content   atomContent `xml:"content"`
+}
+
+// atomContent is the content element of an entry, holding escaped HTML.
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// ConvertSessionsToAtom converts the n most recently updated sessions into an Atom feed (RFC 4287),
+// newest first, so that conversations can be published on a site with a static pipeline. If n is not
+// positive, every session is included.
+//
+// Each session becomes an entry titled with its topic, updated at its last update, and holding the
+// conversation of its active branch as HTML. Entry IDs are URNs derived from the session IDs alone, so
+// feed readers recognize the same session across exports and never show it twice. The feed is updated
+// at the newest entry, which keeps the output identical for the same sessions.
+//
+// It returns an error if encoding the feed as XML fails.
+func ConvertSessionsToAtom(sessions []Session, meta FeedMeta, n int) (string, error) {
+	recent := append([]Session(nil), sessions...)
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].Timestamp(DateFieldUpdated) > recent[j].Timestamp(DateFieldUpdated)
+	})
+	if n > 0 && n < len(recent) {
+		recent = recent[:n]
+	}
+
+	feed := atomFeed{
+		ID:      meta.ID,
+		Title:   meta.Title,
+		Updated: atomTime(0),
+		Author:  atomPerson{Name: meta.Author},
+	}
+	if feed.Title == "" {
+		feed.Title = "ChatGPT-Next-Web Sessions"
+	}
+	if feed.ID == "" {
+		feed.ID = nameURN("feed", feed.Title)
+	}
+	if feed.Author.Name == "" {
+		feed.Author.Name = "ChatGPT-Next-Web"
+	}
+	if meta.Link != "" {
+		feed.Link = &atomLink{Rel: "alternate", Href: meta.Link}
+	}
+	if len(recent) > 0 {
+		feed.Updated = atomTime(recent[0].Timestamp(DateFieldUpdated))
+	}
+
+	for _, session := range recent {
+		entry := atomEntry{
+			ID:      nameURN("session", session.ID),
+			Title:   session.Topic,
+			Updated: atomTime(session.Timestamp(DateFieldUpdated)),
+			Content: atomContent{Type: "html", Body: renderMessagesHTML(ActiveBranch(session.Messages))},
+		}
+		if entry.Title == "" {
+			entry.Title = "Untitled Session"
+		}
+		if session.Mask.CreatedAt > 0 {
+			entry.Published = atomTime(session.Mask.CreatedAt)
+		}
+		if link := SessionURL(meta.BaseURL, session.ID); link != "" {
+			entry.Link = &atomLink{Rel: "alternate", Href: link}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data) + "\n", nil
+}
+
+// AtomOptions holds the settings of the feed written by WriteAtom.
+type AtomOptions struct {
+	Feed       FeedMeta // Feed describes the feed itself.
+	MaxEntries int      // MaxEntries limits the feed to the most recently updated sessions; 0 includes all of them.
+}
+
+// WriteAtom writes the Atom feed of ConvertSessionsToAtom to w, with the opts.MaxEntries most recent
+// sessions. The feed is encoded before anything is written, since its entries are sorted by date.
+//
+// It returns an error if the context is cancelled, encoding the feed as XML fails, or writing fails.
+func WriteAtom(ctx context.Context, w io.Writer, sessions []Session, opts AtomOptions) error {
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	feed, err := ConvertSessionsToAtom(sessions, opts.Feed, opts.MaxEntries)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, feed)
+	return err
+}
+
+// atomTime formats a Unix millisecond timestamp as an RFC 3339 date in UTC, as Atom requires.
+// A zero timestamp yields the Unix epoch, since every entry must have an update date.
+func atomTime(millis int64) string {
+	return time.UnixMilli(max(millis, 0)).UTC().Format(time.RFC3339)
+}
+
+// nameURN derives a stable urn:uuid IRI from a kind and a name, formatted as a name-based (version 5) UUID,
+// so that the same name always yields the same ID.
+func nameURN(kind, name string) string {
+	sum := sha1.Sum([]byte("chatgpt-next-web-" + kind + ":" + name))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
@@ -0,0 +1,77 @@
+package exporter
+
+// HasBranches reports whether the session records message branching, that is, whether any of its
+// messages names a parent. Sessions from stores without branching support never do.
+func (s Session) HasBranches() bool {
+	for _, message := range s.Messages {
+		if message.ParentID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveBranch returns the messages of the branch the conversation currently shows, in their original order.
+//
+// When an answer is regenerated, newer stores keep the earlier answer as a sibling: both name the same
+// parent message. The active branch is the chain of parents leading to the last message of the session.
+// A message without a parent ID, or whose parent is not in the session, follows the message before it,
+// so stores that record parents only for newer messages, or lost the parent of a message, are handled too. Messages without any parent IDs are returned unchanged.
+func ActiveBranch(messages []Message) []Message {
+	branched := false
+	for _, message := range messages {
+		if message.ParentID != "" {
+			branched = true
+			break
+		}
+	}
+	if !branched || len(messages) == 0 {
+		return messages
+	}
+
+	index := make(map[string]int, len(messages))
+	for i, message := range messages {
+		if message.ID != "" {
+			index[message.ID] = i
+		}
+	}
+
+	active := make([]bool, len(messages))
+	for i := len(messages) - 1; i >= 0 && !active[i]; {
+		active[i] = true
+		if parent, ok := index[messages[i].ParentID]; ok && messages[i].ParentID != "" {
+			i = parent
+		} else {
+			i--
+		}
+	}
+
+	result := make([]Message, 0, len(messages))
+	for i, message := range messages {
+		if active[i] {
+			result = append(result, message)
+		}
+	}
+	return result
+}
+
+// ApplyBranchPolicy restricts every session to its active branch, unless includeAll is set, in which
+// case all branches are kept and the sessions are returned as they are. It reports the number of
+// messages of abandoned branches that were removed.
+//
+// Like ApplyRolePolicy, it is meant to be applied once right after loading, so that every export format
+// sees the same messages. The input sessions are not modified.
+func ApplyBranchPolicy(sessions []Session, includeAll bool) ([]Session, int) {
+	if includeAll {
+		return sessions, 0
+	}
+	removed := 0
+	result := make([]Session, len(sessions))
+	for i, session := range sessions {
+		active := ActiveBranch(session.Messages)
+		removed += len(session.Messages) - len(active)
+		session.Messages = active
+		result[i] = session
+	}
+	return result, removed
+}
@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Options holds the settings of every export format for ConvertSessions and Format.Write.
+// Each format uses only the part that applies to it; the Atom and EPUB formats have none.
+type Options struct {
+	CSV    CSVOptions    // CSV configures the CSV formats and the summaries digests.
+	Export ExportOptions // Export configures the dataset, JSON, and text formats.
+}
+
+// formatAliases maps the short names accepted by ConvertSessions to the names of the registered formats.
+var formatAliases = map[string]string{
+	"csv":         "csv-inline",
+	"csv-perline": "csv-per-line",
+	"org":         "orgmode",
+	"jsonl":       "finetune",
+	"summaries":   "summaries-csv",
+	"markdown":    "summaries-markdown",
+}
+
+// ConvertSessions writes the sessions to w in the registered format with the given name, configured by
+// opts, so that callers can select a format by name instead of calling its writer directly.
+//
+// The name is one of the formats listed by Formats, such as "csv-inline", "csv-per-line", "dataset",
+// or "summaries-markdown", or one of the short names "csv", "csv-perline", "org", "jsonl",
+// "summaries", and "markdown". Names are case-insensitive.
+//
+// It returns an error if the name is unknown, the context is cancelled, or writing fails, and an error
+// wrapping ErrMultipleFiles for a format that writes several files, such as "csv-separate".
+func ConvertSessions(ctx context.Context, w io.Writer, sessions []Session, format string, opts Options) error {
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+
+	name := strings.ToLower(strings.TrimSpace(format))
+	if alias, ok := formatAliases[name]; ok {
+		name = alias
+	}
+	for _, registered := range Formats() {
+		if registered.Describe().Name == name {
+			return registered.Write(ctx, w, sessions, opts)
+		}
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
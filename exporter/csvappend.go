@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// AppendSummary reports how AppendNewSessionsCSV treated the sessions.
+type AppendSummary struct {
+	Appended int // Appended is the number of sessions whose rows were appended.
+	Skipped  int // Skipped is the number of sessions left out because their ID was already present.
+}
+
+// ReadCSVSessionIDs reads a CSV export written by WriteSessionsCSV and returns its header together with
+// the set of session IDs it contains, taken from the "id" or, for one message per line, "session_id" column.
+//
+// The CSV is read one record at a time and only the ID column is kept, so large files are not held in memory.
+// An empty input yields a nil header and an empty set. It returns an error if the CSV is malformed or
+// has no ID column.
+func ReadCSVSessionIDs(r io.Reader) ([]string, map[string]bool, error) {
+	ids := make(map[string]bool)
+	csvReader := csv.NewReader(r)
+	csvReader.ReuseRecord = true
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ids, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	header = slices.Clone(header)
+	column := slices.Index(header, "id")
+	if column < 0 {
+		column = slices.Index(header, "session_id")
+	}
+	if column < 0 {
+		return nil, nil, fmt.Errorf("CSV has no id or session_id column")
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return header, ids, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		ids[record[column]] = true
+	}
+}
+
+// AppendNewSessionsCSV writes to w the CSV rows of the sessions whose IDs do not occur in the existing CSV
+// export, so that appending the output to the existing file adds every session at most once. This allows
+// repeated exports, such as a daily job, into one growing CSV file.
+//
+// The existing CSV must have been written with the same format option and CSV options, since rows of
+// different columns cannot be mixed; see ReadCSVSessionIDs for how it is read. Headers are only written
+// when the existing CSV is empty. Sessions occurring more than once in sessions are appended once.
+//
+// It returns an error if the existing CSV cannot be read or has different columns, the context is
+// cancelled, or writing to the CSV fails.
+func AppendNewSessionsCSV(ctx context.Context, existing io.Reader, w io.Writer, sessions []Session, formatOption int, opts CSVOptions) (AppendSummary, error) {
+	headers, err := sessionsCSVHeaders(formatOption, opts)
+	if err != nil {
+		return AppendSummary{}, err
+	}
+	existingHeaders, ids, err := ReadCSVSessionIDs(existing)
+	if err != nil {
+		return AppendSummary{}, err
+	}
+
+	csvWriter := newCSVWriter(w, opts)
+	if existingHeaders == nil {
+		if err := WriteHeaders(csvWriter, headers); err != nil {
+			return AppendSummary{}, err
+		}
+	} else if !slices.Equal(existingHeaders, headers) {
+		return AppendSummary{}, fmt.Errorf("existing CSV has the columns %s, but the selected format writes %s",
+			strings.Join(existingHeaders, ","), strings.Join(headers, ","))
+	}
+
+	var summary AppendSummary
+	newSessions := make([]Session, 0, len(sessions))
+	for _, session := range sessions {
+		if ids[session.ID] {
+			summary.Skipped++
+			continue
+		}
+		ids[session.ID] = true
+		newSessions = append(newSessions, session)
+	}
+	summary.Appended = len(newSessions)
+
+	if err := writeSessionRows(ctx, csvWriter, newSessions, formatOption, opts); err != nil {
+		return AppendSummary{}, err
+	}
+	return summary, nil
+}
@@ -0,0 +1,117 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// Dataset is a Hugging Face dataset of sessions that can be streamed to a writer.
+//
+// It implements io.WriterTo, so large datasets can be written directly to a file or a network
+// connection without first building the whole document in memory, as ExtractToDataset does.
+// The bytes written are identical to the output of ExtractToDatasetWithOptions.
+type Dataset struct {
+	Sessions []Session     // Sessions are the sessions in the dataset.
+	Options  ExportOptions // Options adds optional metadata to every session, or a header record before it.
+}
+
+// WriteTo writes the dataset as indented JSON to w, encoding one session at a time.
+// It returns the number of bytes written and the first error encountered.
+func (d Dataset) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	switch {
+	case d.Sessions == nil:
+		cw.WriteString("{\n  \"dataset\": null\n}")
+	case len(d.Sessions) == 0:
+		cw.WriteString("{\n  \"dataset\": []\n}")
+	default:
+		cw.WriteString("{\n  \"dataset\": [\n    ")
+		for i, session := range d.Sessions {
+			if i > 0 {
+				cw.WriteString(",\n    ")
+			}
+			if d.Options.IncludeSessionMetadata {
+				data, err := json.MarshalIndent(newSessionHeader(session), "    ", "  ")
+				if err != nil {
+					return cw.n, err
+				}
+				cw.Write(data)
+				cw.WriteString(",\n    ")
+			}
+			var v interface{} = session
+			if d.Options.BaseURL != "" {
+				v = linkedSession{Session: session, URL: SessionURL(d.Options.BaseURL, session.ID)}
+			}
+			// The prefix lines the session up with the surrounding array, as json.MarshalIndent
+			// would when encoding the whole dataset at once.
+			data, err := json.MarshalIndent(v, "    ", "  ")
+			if err != nil {
+				return cw.n, err
+			}
+			cw.Write(data)
+			if cw.err != nil {
+				return cw.n, cw.err
+			}
+		}
+		cw.WriteString("\n  ]\n}")
+	}
+	return cw.n, cw.err
+}
+
+// WriteDataset writes the sessions as the dataset of Dataset to w, stopping at the next session once
+// ctx is cancelled.
+//
+// It returns an error if the context is cancelled, encoding a session fails, or writing fails.
+func WriteDataset(ctx context.Context, w io.Writer, sessions []Session, opts ExportOptions) error {
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	_, err := Dataset{Sessions: sessions, Options: opts}.WriteTo(contextWriter{ctx: ctx, w: w})
+	return err
+}
+
+// contextWriter fails every write once its context is done, so that a format written in many small
+// writes stops soon after a cancellation instead of running to the end.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+// Write writes p to the underlying writer unless the context is done.
+func (cw contextWriter) Write(p []byte) (int, error) {
+	if err := checkContextCancellation(cw.ctx); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
+
+// countingWriter counts the bytes written to the underlying writer and remembers the first error,
+// after which further writes are skipped. This lets a sequence of writes be checked once at the end.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// Write writes p to the underlying writer unless an earlier write failed.
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+// WriteString writes s to the underlying writer unless an earlier write failed.
+func (cw *countingWriter) WriteString(s string) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := io.WriteString(cw.w, s)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
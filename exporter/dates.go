@@ -0,0 +1,143 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateField selects which timestamp of a session represents its date.
+type DateField int
+
+const (
+	// DateFieldUpdated uses the time of the session's last update.
+	DateFieldUpdated DateField = iota
+
+	// DateFieldCreated uses the time the session was created, as recorded by its mask.
+	DateFieldCreated
+)
+
+// ParseDateField parses the name of a DateField: "updated" or "created".
+func ParseDateField(name string) (DateField, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "updated":
+		return DateFieldUpdated, nil
+	case "created":
+		return DateFieldCreated, nil
+	default:
+		return DateFieldUpdated, fmt.Errorf("unknown date field %q, expected created or updated", name)
+	}
+}
+
+// Timestamp returns the Unix millisecond timestamp of the session selected by field.
+// When the store does not record the chosen timestamp, the other one is used instead,
+// and 0 is returned only when neither is known.
+func (s Session) Timestamp(field DateField) int64 {
+	created, updated := s.Mask.CreatedAt, s.LastUpdate
+	if field == DateFieldCreated {
+		if created > 0 {
+			return created
+		}
+		return max(updated, 0)
+	}
+	if updated > 0 {
+		return updated
+	}
+	return max(created, 0)
+}
+
+// MessageDatePolicy determines what EstimateMessageDates does with messages without a date of their own,
+// as found in older stores that only record the dates of their sessions.
+type MessageDatePolicy int
+
+const (
+	// MessageDatesEstimate gives every message without a date an estimated one; see EstimateMessageDates.
+	MessageDatesEstimate MessageDatePolicy = iota
+
+	// MessageDatesBlank leaves the date of such messages empty.
+	MessageDatesBlank
+)
+
+// ParseMessageDatePolicy parses the name of a MessageDatePolicy: "estimate" or "blank".
+func ParseMessageDatePolicy(name string) (MessageDatePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "estimate":
+		return MessageDatesEstimate, nil
+	case "blank":
+		return MessageDatesBlank, nil
+	default:
+		return MessageDatesEstimate, fmt.Errorf("unknown message date policy %q, expected estimate or blank", name)
+	}
+}
+
+// EstimateMessageDates gives the messages without a parsable date an estimated one according to the
+// policy, so that exports can be sorted by message date even for older stores. It returns the sessions
+// and the number of messages whose date was estimated.
+//
+// With MessageDatesEstimate, the dates are interpolated in proportion to the index of the messages:
+// between the dates of the nearest messages before and after that have one, and otherwise between the
+// creation of the session for its first message and its last update for its last message. Estimated
+// dates are written in the first of messageDateLayouts, in local time like the web app, and the
+// messages are marked as Estimated. Sessions without a known creation or update time are left as they
+// are, as is every session with MessageDatesBlank. The input sessions are not modified.
+func EstimateMessageDates(sessions []Session, policy MessageDatePolicy) ([]Session, int) {
+	if policy != MessageDatesEstimate {
+		return sessions, 0
+	}
+
+	estimated := 0
+	result := make([]Session, len(sessions))
+	for i, session := range sessions {
+		result[i] = session
+		created, updated := session.Timestamp(DateFieldCreated), session.Timestamp(DateFieldUpdated)
+		if created <= 0 || len(session.Messages) == 0 {
+			continue
+		}
+
+		// Known dates anchor the interpolation; the ends of the session fall back to its own dates.
+		n := len(session.Messages)
+		anchors := make([]int64, n)
+		known := make([]bool, n)
+		missing := 0
+		for j, message := range session.Messages {
+			if t, ok := parseMessageDate(message.Date); ok {
+				anchors[j], known[j] = t.UnixMilli(), true
+			} else {
+				missing++
+			}
+		}
+		if missing == 0 {
+			continue
+		}
+		if !known[0] {
+			anchors[0], known[0] = created, true
+		}
+		if !known[n-1] {
+			anchors[n-1], known[n-1] = max(updated, anchors[0]), true
+		}
+
+		messages := session.DeepCopy().Messages
+		for j := range messages {
+			if _, ok := parseMessageDate(messages[j].Date); ok {
+				continue
+			}
+			// The first and last messages are anchors, so both searches stop within the session.
+			before, after := j, j
+			for !known[before] {
+				before--
+			}
+			for !known[after] {
+				after++
+			}
+			at := anchors[before]
+			if after > before {
+				at += max(anchors[after]-anchors[before], 0) * int64(j-before) / int64(after-before)
+			}
+			messages[j].Date = time.UnixMilli(at).In(time.Local).Format(messageDateLayouts[0])
+			messages[j].Estimated = true
+		}
+		estimated += missing
+		result[i].Messages = messages
+	}
+	return result, estimated
+}
@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Diff describes how the sessions of one store differ from those of an earlier store, as computed by DiffStores.
+type Diff struct {
+	Added     []Session         // Added holds the sessions only found in the newer store, in its order.
+	Removed   []Session         // Removed holds the sessions only found in the older store, in its order.
+	Modified  []ModifiedSession // Modified holds the sessions found in both stores whose messages differ.
+	Unchanged int               // Unchanged is the number of sessions found in both stores with the same messages.
+}
+
+// ModifiedSession pairs the two versions of a session whose messages changed between two stores.
+type ModifiedSession struct {
+	Before Session // Before is the session as found in the older store.
+	After  Session // After is the session as found in the newer store.
+}
+
+// Empty reports whether the two stores hold the same sessions with the same messages.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// MessagesHash returns a hex-encoded SHA-256 hash of the roles and contents of the session's messages.
+// Unlike SessionHash, it ignores metadata such as the topic, the mask, or message dates, so it only
+// changes when the conversation itself does.
+func MessagesHash(session Session) string {
+	hash := sha256.New()
+	for _, message := range session.Messages {
+		// The NUL separators keep the boundaries of roles and contents unambiguous.
+		hash.Write([]byte(message.Role))
+		hash.Write([]byte{0})
+		hash.Write([]byte(message.Content))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// DiffStores compares the sessions of an older store a with those of a newer store b, matching them by ID.
+// Sessions found in both stores are reported as modified when their MessagesHash differs.
+// A nil store is treated as empty.
+func DiffStores(a, b *ChatNextWebStore) Diff {
+	var before, after []Session
+	if a != nil {
+		before = a.ChatNextWebStore.Sessions
+	}
+	if b != nil {
+		after = b.ChatNextWebStore.Sessions
+	}
+
+	older := make(map[string]Session, len(before))
+	for _, session := range before {
+		older[session.ID] = session
+	}
+
+	var diff Diff
+	newer := make(map[string]bool, len(after))
+	for _, session := range after {
+		newer[session.ID] = true
+		previous, ok := older[session.ID]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, session)
+		case MessagesHash(previous) != MessagesHash(session):
+			diff.Modified = append(diff.Modified, ModifiedSession{Before: previous, After: session})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for _, session := range before {
+		if !newer[session.ID] {
+			diff.Removed = append(diff.Removed, session)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,146 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DuplicateIDPolicy determines how sessions sharing an ID are resolved by ResolveDuplicateIDs.
+type DuplicateIDPolicy int
+
+const (
+	// DuplicateIDKeepBoth keeps every session, giving each later one a new ID with a numeric suffix.
+	DuplicateIDKeepBoth DuplicateIDPolicy = iota
+
+	// DuplicateIDKeepNewest keeps only the most recently updated session of each ID.
+	DuplicateIDKeepNewest
+
+	// DuplicateIDAbort refuses to export a store with duplicate IDs.
+	DuplicateIDAbort
+)
+
+// ParseDuplicateIDPolicy parses the name of a DuplicateIDPolicy: "keep-both", "newest", or "abort".
+func ParseDuplicateIDPolicy(name string) (DuplicateIDPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "keep-both":
+		return DuplicateIDKeepBoth, nil
+	case "newest":
+		return DuplicateIDKeepNewest, nil
+	case "abort":
+		return DuplicateIDAbort, nil
+	default:
+		return DuplicateIDKeepBoth, fmt.Errorf("unknown duplicate ID policy %q, expected keep-both, newest, or abort", name)
+	}
+}
+
+// DuplicateID describes a session ID shared by several sessions of a store.
+type DuplicateID struct {
+	ID    string // ID is the shared session ID.
+	Count int    // Count is the number of sessions with this ID.
+}
+
+// DuplicateReport describes the duplicate session IDs found by ResolveDuplicateIDs and what was done with them.
+type DuplicateReport struct {
+	Duplicates []DuplicateID     // Duplicates lists the shared IDs in the order they first occur.
+	Renamed    map[string]string // Renamed maps every new ID given by DuplicateIDKeepBoth to the original ID.
+	Dropped    int               // Dropped is the number of sessions removed by DuplicateIDKeepNewest.
+}
+
+// FindDuplicateIDs returns the session IDs that occur more than once, in the order they first occur.
+func FindDuplicateIDs(sessions []Session) []DuplicateID {
+	counts := make(map[string]int, len(sessions))
+	var order []string
+	for _, session := range sessions {
+		if counts[session.ID] == 1 {
+			order = append(order, session.ID)
+		}
+		counts[session.ID]++
+	}
+	duplicates := make([]DuplicateID, 0, len(order))
+	for _, id := range order {
+		duplicates = append(duplicates, DuplicateID{ID: id, Count: counts[id]})
+	}
+	return duplicates
+}
+
+// ResolveDuplicateIDs makes the session IDs unique according to the policy, so that formats keyed by
+// session ID, such as per-session files or incremental state, never mix up different conversations.
+// It should run right after loading, before any other processing, so every format sees the same IDs.
+//
+// DuplicateIDKeepBoth keeps the first session of an ID unchanged and renames each later one to
+// "<id>-2", "<id>-3", and so on, skipping suffixes already taken by other sessions.
+// DuplicateIDKeepNewest keeps the session with the latest update time, at the position of the first
+// session of its ID; of sessions updated at the same time, the later one in the store is kept.
+//
+// The input slice is not modified. It returns an error listing the shared IDs if the policy is
+// DuplicateIDAbort and duplicates were found.
+func ResolveDuplicateIDs(sessions []Session, policy DuplicateIDPolicy) ([]Session, DuplicateReport, error) {
+	report := DuplicateReport{Duplicates: FindDuplicateIDs(sessions)}
+	if len(report.Duplicates) == 0 {
+		return sessions, report, nil
+	}
+
+	switch policy {
+	case DuplicateIDAbort:
+		ids := make([]string, len(report.Duplicates))
+		for i, duplicate := range report.Duplicates {
+			ids[i] = strconv.Quote(duplicate.ID)
+		}
+		return nil, report, fmt.Errorf("%d session ID(s) are shared by several sessions: %s", len(ids), strings.Join(ids, ", "))
+	case DuplicateIDKeepNewest:
+		return keepNewestSessions(sessions, &report), report, nil
+	default:
+		return renameDuplicateSessions(sessions, &report), report, nil
+	}
+}
+
+// renameDuplicateSessions gives every session whose ID occurred before a new ID with a numeric suffix.
+func renameDuplicateSessions(sessions []Session, report *DuplicateReport) []Session {
+	taken := make(map[string]bool, len(sessions))
+	for _, session := range sessions {
+		taken[session.ID] = true
+	}
+	report.Renamed = make(map[string]string)
+
+	result := make([]Session, len(sessions))
+	seen := make(map[string]int, len(sessions))
+	for i, session := range sessions {
+		seen[session.ID]++
+		if seen[session.ID] > 1 {
+			suffix := seen[session.ID]
+			newID := session.ID + "-" + strconv.Itoa(suffix)
+			for taken[newID] {
+				suffix++
+				newID = session.ID + "-" + strconv.Itoa(suffix)
+			}
+			taken[newID] = true
+			report.Renamed[newID] = session.ID
+			session.ID = newID
+		}
+		result[i] = session
+	}
+	return result
+}
+
+// keepNewestSessions keeps the most recently updated session of each ID.
+func keepNewestSessions(sessions []Session, report *DuplicateReport) []Session {
+	newest := make(map[string]int, len(sessions))
+	for i, session := range sessions {
+		if j, ok := newest[session.ID]; !ok || session.Timestamp(DateFieldUpdated) >= sessions[j].Timestamp(DateFieldUpdated) {
+			newest[session.ID] = i
+		}
+	}
+
+	result := make([]Session, 0, len(newest))
+	added := make(map[string]bool, len(newest))
+	for _, session := range sessions {
+		if added[session.ID] {
+			report.Dropped++
+			continue
+		}
+		added[session.ID] = true
+		result = append(result, sessions[newest[session.ID]])
+	}
+	return result
+}
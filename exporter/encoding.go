@@ -0,0 +1,153 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// ErrUnencodable is returned by an EncodingWriter with the UnencodableError policy when the output holds
+// a character the target encoding cannot represent.
+var ErrUnencodable = errors.New("character cannot be encoded")
+
+// Encoding is a character encoding for CSV output, for legacy tools that cannot read UTF-8; see
+// ParseEncoding. The zero Encoding is UTF-8, which writes the output unchanged.
+type Encoding struct {
+	name    string           // name is the canonical name of the encoding; empty for UTF-8.
+	charmap *charmap.Charmap // charmap converts UTF-8 into the encoding; nil for UTF-8.
+}
+
+// Name returns the canonical name of the encoding, such as "windows-1252".
+func (e Encoding) Name() string {
+	if e.name == "" {
+		return "utf-8"
+	}
+	return e.name
+}
+
+// IsUTF8 reports whether the encoding is UTF-8, which needs no conversion.
+func (e Encoding) IsUTF8() bool {
+	return e.name == ""
+}
+
+// Single-byte encodings supported by ParseEncoding.
+var (
+	encodingISO88591    = Encoding{name: "iso-8859-1", charmap: charmap.ISO8859_1}
+	encodingWindows1252 = Encoding{name: "windows-1252", charmap: charmap.Windows1252}
+	encodingISO885915   = Encoding{name: "iso-8859-15", charmap: charmap.ISO8859_15}
+)
+
+// ParseEncoding parses the name of an encoding: "utf-8", "windows-1252" (or "cp1252"), "iso-8859-1"
+// (or "latin1"), or "iso-8859-15" (or "latin9"). Names are case-insensitive; an empty name is UTF-8.
+func ParseEncoding(name string) (Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "utf-8", "utf8":
+		return Encoding{}, nil
+	case "windows-1252", "cp1252":
+		return encodingWindows1252, nil
+	case "iso-8859-1", "latin1", "latin-1":
+		return encodingISO88591, nil
+	case "iso-8859-15", "latin9", "latin-9":
+		return encodingISO885915, nil
+	default:
+		return Encoding{}, fmt.Errorf("unknown encoding %q, expected utf-8, windows-1252, iso-8859-1, or iso-8859-15", name)
+	}
+}
+
+// UnencodablePolicy determines what an EncodingWriter does with characters the encoding cannot represent.
+type UnencodablePolicy int
+
+const (
+	// UnencodableReplace writes the substitute character of the encoding, the ASCII control character
+	// SUB (0x1A), instead of the character.
+	UnencodableReplace UnencodablePolicy = iota
+
+	// UnencodableError fails the write with ErrUnencodable.
+	UnencodableError
+)
+
+// ParseUnencodablePolicy parses the name of an UnencodablePolicy: "replace" or "error".
+func ParseUnencodablePolicy(name string) (UnencodablePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "replace":
+		return UnencodableReplace, nil
+	case "error":
+		return UnencodableError, nil
+	default:
+		return UnencodableReplace, fmt.Errorf("unknown unencodable character policy %q, expected replace or error", name)
+	}
+}
+
+// EncodingWriter converts UTF-8 text written to it into another encoding before writing it to the
+// underlying writer. Bytes that are not valid UTF-8 are handled like characters the encoding cannot
+// represent.
+type EncodingWriter struct {
+	w      io.Writer         // w is the underlying writer, written to directly in UTF-8.
+	encode *transform.Writer // encode converts the text into the encoding; nil for UTF-8.
+}
+
+// NewEncodingWriter returns an EncodingWriter writing to w in the encoding, handling characters it
+// cannot represent according to the policy. Call Close after the last write.
+func NewEncodingWriter(w io.Writer, enc Encoding, policy UnencodablePolicy) *EncodingWriter {
+	if enc.IsUTF8() {
+		return &EncodingWriter{w: w}
+	}
+	var encoder transform.Transformer = encoding.ReplaceUnsupported(enc.charmap.NewEncoder())
+	if policy == UnencodableError {
+		encoder = strictEncoder{Transformer: enc.charmap.NewEncoder(), name: enc.Name()}
+	}
+	return &EncodingWriter{w: w, encode: transform.NewWriter(w, encoder)}
+}
+
+// Write converts p and writes it to the underlying writer. A character split across two writes is
+// converted by the second.
+func (e *EncodingWriter) Write(p []byte) (int, error) {
+	if e.encode == nil {
+		return e.w.Write(p)
+	}
+	return e.encode.Write(p)
+}
+
+// Close handles the start of a character left by the last write, which is invalid UTF-8 since the
+// character was never completed. It does not close the underlying writer.
+func (e *EncodingWriter) Close() error {
+	if e.encode == nil {
+		return nil
+	}
+	return e.encode.Close()
+}
+
+// strictEncoder is an encoder that reports the characters it cannot represent as ErrUnencodable.
+type strictEncoder struct {
+	transform.Transformer
+	name string // name is the name of the encoding, for the error message.
+}
+
+// Transform converts src like the wrapped encoder, naming the character it stopped at in the error.
+func (s strictEncoder) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	nDst, nSrc, err := s.Transformer.Transform(dst, src, atEOF)
+	if err == nil || err == transform.ErrShortDst || err == transform.ErrShortSrc {
+		return nDst, nSrc, err
+	}
+	if r, size := utf8.DecodeRune(src[nSrc:]); r != utf8.RuneError || size > 1 {
+		return nDst, nSrc, fmt.Errorf("%w: %q (U+%04X) in %s", ErrUnencodable, r, r, s.name)
+	}
+	return nDst, nSrc, fmt.Errorf("%w: invalid UTF-8 byte 0x%02x in %s", ErrUnencodable, src[nSrc], s.name)
+}
+
+// newCSVWriter returns a csv.Writer writing to w in the encoding selected by opts. The EncodingWriter
+// never needs closing there: every CSV record ends with a newline, which completes or invalidates any
+// character split across writes, and its errors are returned by the Error method of the csv.Writer.
+func newCSVWriter(w io.Writer, opts CSVOptions) *csv.Writer {
+	if opts.Encoding.IsUTF8() {
+		return csv.NewWriter(w)
+	}
+	return csv.NewWriter(NewEncodingWriter(w, opts.Encoding, opts.Unencodable))
+}
@@ -0,0 +1,235 @@
+package exporter
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// EPUBOptions holds the metadata of the e-book written by WriteEPUB.
+type EPUBOptions struct {
+	Title    string // Title is the title of the book; empty uses "ChatGPT-Next-Web Sessions".
+	Author   string // Author is the creator of the book; empty uses "ChatGPT-Next-Web".
+	Language string // Language is the BCP 47 language tag of the book; empty uses "en".
+}
+
+// epubMimetype is the content of the mimetype file that identifies a ZIP archive as an EPUB.
+const epubMimetype = "application/epub+zip"
+
+// epubContainer is the META-INF/container.xml pointing reading systems to the package document.
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// epubStylesheet is the basic CSS shared by the chapters.
+const epubStylesheet = `body { font-family: serif; line-height: 1.4; margin: 0 1em; }
+h1 { font-size: 1.4em; margin-bottom: 0.2em; }
+p.date { color: #666; font-size: 0.9em; margin-top: 0; }
+p strong { font-family: sans-serif; font-size: 0.85em; text-transform: uppercase; color: #444; }
+pre { font-family: monospace; font-size: 0.8em; white-space: pre-wrap; background: #f4f4f4; padding: 0.5em; }
+`
+
+// imagePattern matches the images of multimodal messages: Markdown images, whose alternative text is
+// captured, and bare data URIs of images.
+var imagePattern = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)|data:image/[a-zA-Z0-9.+-]+(?:;[a-zA-Z0-9=._-]+)*;base64,[A-Za-z0-9+/]+={0,2}`)
+
+// WriteEPUB writes the sessions to w as an EPUB 3 e-book, with one chapter per session in store order,
+// a table of contents linking the chapters, and a basic stylesheet, for reading long conversations on
+// an e-reader.
+//
+// Each chapter is titled with the session topic and holds the messages of its active branch, rendered
+// like the entries of ConvertSessionsToAtom. Images of multimodal messages are not embedded; they are
+// replaced by a placeholder naming their alternative text. The book is dated at the newest update of
+// its sessions, and its identifier is derived from the session IDs, so the same sessions always yield
+// the same file.
+//
+// It returns an error if the context is cancelled or writing the archive fails.
+func WriteEPUB(ctx context.Context, w io.Writer, sessions []Session, opts EPUBOptions) error {
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	if opts.Title == "" {
+		opts.Title = "ChatGPT-Next-Web Sessions"
+	}
+	if opts.Author == "" {
+		opts.Author = "ChatGPT-Next-Web"
+	}
+	if opts.Language == "" {
+		opts.Language = "en"
+	}
+
+	archive := zip.NewWriter(w)
+	// The mimetype file must come first, stored uncompressed and without extra fields, so that its
+	// content can be found at a fixed offset; CreateRaw also avoids a data descriptor.
+	mimetype, err := archive.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE([]byte(epubMimetype)),
+		CompressedSize64:   uint64(len(epubMimetype)),
+		UncompressedSize64: uint64(len(epubMimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mimetype, epubMimetype); err != nil {
+		return err
+	}
+
+	files := []struct{ name, content string }{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/content.opf", epubPackage(sessions, opts)},
+		{"OEBPS/nav.xhtml", epubNavigation(sessions, opts)},
+		{"OEBPS/style.css", epubStylesheet},
+	}
+	for i, session := range sessions {
+		files = append(files, struct{ name, content string }{"OEBPS/" + epubChapterName(i), epubChapter(session, opts)})
+	}
+	for _, file := range files {
+		if err := checkContextCancellation(ctx); err != nil {
+			return err
+		}
+		writer, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(writer, file.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// epubChapterName returns the file name of the chapter of the i-th session, relative to the package document.
+func epubChapterName(i int) string {
+	return fmt.Sprintf("session-%d.xhtml", i+1)
+}
+
+// epubTopic returns the chapter title of a session.
+func epubTopic(session Session) string {
+	if session.Topic == "" {
+		return "Untitled Session"
+	}
+	return session.Topic
+}
+
+// epubPackage returns the package document listing the metadata, the files, and the reading order of the book.
+func epubPackage(sessions []Session, opts EPUBOptions) string {
+	var newest int64
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		newest = max(newest, session.Timestamp(DateFieldUpdated))
+		ids[i] = session.ID
+	}
+
+	var manifest, spine strings.Builder
+	for i := range sessions {
+		fmt.Fprintf(&manifest, "    <item id=\"session-%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, epubChapterName(i))
+		fmt.Fprintf(&spine, "    <itemref idref=\"session-%d\"/>\n", i+1)
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="` + xmlText(opts.Language) + `">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">` + nameURN("epub", strings.Join(ids, "\n")) + `</dc:identifier>
+    <dc:title>` + xmlText(opts.Title) + `</dc:title>
+    <dc:creator>` + xmlText(opts.Author) + `</dc:creator>
+    <dc:language>` + xmlText(opts.Language) + `</dc:language>
+    <meta property="dcterms:modified">` + time.UnixMilli(newest).UTC().Format("2006-01-02T15:04:05Z") + `</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="css" href="style.css" media-type="text/css"/>
+` + manifest.String() + `  </manifest>
+  <spine>
+` + spine.String() + `  </spine>
+</package>
+`
+}
+
+// epubNavigation returns the navigation document holding the table of contents.
+func epubNavigation(sessions []Session, opts EPUBOptions) string {
+	var items strings.Builder
+	for i, session := range sessions {
+		fmt.Fprintf(&items, "      <li><a href=\"%s\">%s</a></li>\n", epubChapterName(i), xmlText(epubTopic(session)))
+	}
+	if len(sessions) == 0 {
+		// A list must not be empty, so a book without sessions lists the contents page itself.
+		items.WriteString("      <li><a href=\"nav.xhtml\">Contents</a></li>\n")
+	}
+	return epubDocument(opts, "Contents", `  <nav epub:type="toc" id="toc">
+    <h1>Contents</h1>
+    <ol>
+`+items.String()+`    </ol>
+  </nav>
+`)
+}
+
+// epubChapter returns the chapter of a session.
+func epubChapter(session Session, opts EPUBOptions) string {
+	messages := ActiveBranch(session.Messages)
+	cleaned := make([]Message, len(messages))
+	for i, message := range messages {
+		message.Role = xmlSafe(message.Role)
+		message.Content = xmlSafe(imagePattern.ReplaceAllStringFunc(message.Content, imagePlaceholder))
+		cleaned[i] = message
+	}
+
+	body := "  <h1>" + xmlText(epubTopic(session)) + "</h1>\n"
+	if date := summaryDate(session.Timestamp(DateFieldUpdated)); date != "" {
+		body += "  <p class=\"date\">" + date + "</p>\n"
+	}
+	return epubDocument(opts, epubTopic(session), body+renderMessagesHTML(cleaned))
+}
+
+// epubDocument wraps the body of a content document into an XHTML page using the stylesheet.
+func epubDocument(opts EPUBOptions, title, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="` + xmlText(opts.Language) + `" lang="` + xmlText(opts.Language) + `">
+<head>
+  <meta charset="UTF-8"/>
+  <title>` + xmlText(title) + `</title>
+  <link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+` + body + `</body>
+</html>
+`
+}
+
+// imagePlaceholder replaces an image matched by imagePattern with a note naming its alternative text.
+func imagePlaceholder(image string) string {
+	if match := imagePattern.FindStringSubmatch(image); match[1] != "" {
+		return "[image omitted: " + match[1] + "]"
+	}
+	return "[image omitted]"
+}
+
+// xmlText escapes s for use in XML text and attribute values, dropping characters XML cannot hold.
+func xmlText(s string) string {
+	return html.EscapeString(xmlSafe(s))
+}
+
+// xmlSafe replaces bytes that are not valid UTF-8 with U+FFFD and drops the control characters that
+// XML 1.0 does not allow, which would make a document malformed.
+func xmlSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == utf8.RuneError, r == '\t', r == '\n', r == '\r':
+			return r
+		case r < 0x20, r == 0xFFFE, r == 0xFFFF, r >= 0xD800 && r <= 0xDFFF:
+			return -1
+		}
+		return r
+	}, s)
+}
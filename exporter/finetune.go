@@ -0,0 +1,112 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// fineTuningExample is a single training example of the OpenAI chat fine-tuning format.
+type fineTuningExample struct {
+	Messages []fineTuningMessage `json:"messages"`
+}
+
+// fineTuningMessage is a message of a fine-tuning example.
+type fineTuningMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Weight  *float64 `json:"weight,omitempty"`
+}
+
+// DefaultWeight is the WeightFunction used when ExportOptions.IncludeWeight is set without one:
+// every assistant message is trained on.
+func DefaultWeight(Message) float64 {
+	return 1.0
+}
+
+// ExtractToFineTuningJSONL converts a slice of Session objects into the JSONL format of OpenAI's chat
+// fine-tuning jobs, with one {"messages": [...]} example per line and one line per session.
+//
+// Only the role and content of each message are kept. When opts.IncludeWeight is set, every assistant
+// message carries a "weight" computed by opts.WeightFunction (DefaultWeight if nil); the fine-tuning
+// API only accepts weights on assistant messages, where 0 excludes a reply from training. Sessions
+// without messages are skipped, since the API rejects empty examples. Sessions with branches always
+// contribute their active branch only, even when all branches were kept; see ActiveBranch.
+//
+// It returns an error if marshaling an example into JSON fails.
+//
+// To write the examples to a writer instead of building them in memory, use WriteFineTuningJSONL.
+func ExtractToFineTuningJSONL(sessions []Session, opts ExportOptions) (string, error) {
+	var builder strings.Builder
+	if err := WriteFineTuningJSONL(context.Background(), &builder, sessions, opts); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// WriteFineTuningJSONL writes the examples of ExtractToFineTuningJSONL to w one session at a time,
+// stopping at the next session once ctx is cancelled.
+//
+// It returns an error if the context is cancelled, marshaling an example into JSON fails, or writing fails.
+func WriteFineTuningJSONL(ctx context.Context, w io.Writer, sessions []Session, opts ExportOptions) error {
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	weight := opts.WeightFunction
+	if weight == nil {
+		weight = DefaultWeight
+	}
+
+	cw := &countingWriter{w: contextWriter{ctx: ctx, w: w}}
+	for _, session := range sessions {
+		// Training examples must never pair a prompt with a reply from an abandoned branch.
+		messages := ActiveBranch(session.Messages)
+		if len(messages) == 0 {
+			continue
+		}
+		example := fineTuningExample{Messages: make([]fineTuningMessage, 0, len(messages))}
+		for _, message := range messages {
+			m := fineTuningMessage{Role: message.Role, Content: message.Content}
+			if opts.IncludeWeight && message.Role == RoleAssistant {
+				value := weight(message)
+				m.Weight = &value
+			}
+			example.Messages = append(example.Messages, m)
+		}
+		line, err := json.Marshal(example)
+		if err != nil {
+			return err
+		}
+		cw.Write(line)
+		cw.WriteString("\n")
+		if cw.err != nil {
+			return cw.err
+		}
+	}
+	return nil
+}
+
+// ExtractToFineTuningJSONArray converts sessions into the same examples as ExtractToFineTuningJSONL,
+// but wraps them in a single JSON array, one example per line separated by commas, for tools that
+// require a file to hold exactly one JSON value. Without examples it returns an empty array.
+//
+// It returns an error if marshaling an example into JSON fails.
+func ExtractToFineTuningJSONArray(sessions []Session, opts ExportOptions) (string, error) {
+	var builder strings.Builder
+	if err := WriteFineTuningJSONArray(context.Background(), &builder, sessions, opts); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// WriteFineTuningJSONArray writes the JSON array of ExtractToFineTuningJSONArray to w one session at a
+// time, stopping at the next session once ctx is cancelled. It is WriteFineTuningJSONL wrapped with
+// WriteJSONLAsArray.
+//
+// It returns an error if the context is cancelled, marshaling an example into JSON fails, or writing fails.
+func WriteFineTuningJSONArray(ctx context.Context, w io.Writer, sessions []Session, opts ExportOptions) error {
+	return WriteJSONLAsArray(w, func(w io.Writer) error {
+		return WriteFineTuningJSONL(ctx, w, sessions, opts)
+	})
+}
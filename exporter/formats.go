@@ -0,0 +1,450 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// FieldDoc describes a column of a CSV format or a field of a structured format.
+type FieldDoc struct {
+	Name        string // Name is the column header or field name.
+	Type        string // Type is the kind of value, such as "string" or "array".
+	Description string // Description is a one-line explanation of the value.
+}
+
+// FormatDoc documents an export format for users, for example in the formats command of the CLI tool.
+type FormatDoc struct {
+	Name        string     // Name identifies the format; it is unique among the registered formats.
+	Extension   string     // Extension is the file extension of the output, including the dot.
+	Description string     // Description is a one-line summary of the layout.
+	Fields      []FieldDoc // Fields lists the columns or fields of each record, in output order.
+	Binary      bool       // Binary reports that the output is not text, such as a ZIP archive.
+}
+
+// Format is an export format. Every format documents itself, so that its layout can be explained to
+// users without reading the code; see Formats.
+type Format interface {
+	// Describe returns the documentation of the format.
+	Describe() FormatDoc
+
+	// Write writes the sessions in the format to w, configured by the parts of opts that apply to it;
+	// the zero Options selects the defaults.
+	Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error
+}
+
+// ErrMultipleFiles is returned by the Write method of a MultiFileFormat, whose output does not fit a single writer.
+var ErrMultipleFiles = errors.New("the format writes several files")
+
+// MultiFileFormat is a Format whose output is several files, such as the sessions and messages files
+// of csv-separate. Its Write fails with ErrMultipleFiles; WriteFiles writes the files instead.
+type MultiFileFormat interface {
+	Format
+
+	// WriteFiles writes every file of the format to the writer create returns for its name.
+	WriteFiles(ctx context.Context, create func(name string) io.Writer, sessions []Session, opts Options) error
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   []Format
+)
+
+// RegisterFormat adds a format to the registry returned by Formats.
+// It panics if the format has no name or a format with the same name is already registered.
+func RegisterFormat(format Format) {
+	name := format.Describe().Name
+	if name == "" {
+		panic("exporter: RegisterFormat of a format without a name")
+	}
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for _, registered := range formats {
+		if registered.Describe().Name == name {
+			panic("exporter: RegisterFormat called twice for format " + name)
+		}
+	}
+	formats = append(formats, format)
+}
+
+// Formats returns the registered formats in registration order, starting with the built-in formats.
+func Formats() []Format {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	return append([]Format(nil), formats...)
+}
+
+func init() {
+	RegisterFormat(csvFormat{option: FormatOptionInline, doc: FormatDoc{
+		Name: "csv-inline", Extension: ".csv",
+		Description: "One row per session, with all messages joined into a single cell.",
+		Fields: []FieldDoc{
+			{"id", "string", "Session ID."},
+			{"topic", "string", "Session topic."},
+			{"memoryPrompt", "string", "Rolling summary of the conversation, empty if none."},
+			{"messages", "string", `Messages as [role, date] "content", separated by "; ".`},
+		},
+	}})
+	RegisterFormat(csvFormat{option: FormatOptionPerLine, doc: FormatDoc{
+		Name: "csv-per-line", Extension: ".csv",
+		Description: "One row per message, repeating the session it belongs to.",
+		Fields:      messageFieldDocs,
+	}})
+	RegisterFormat(csvFormat{option: FormatOptionJSON, doc: FormatDoc{
+		Name: "csv-json", Extension: ".csv",
+		Description: "One row per session, with the messages encoded as a JSON array in a single cell.",
+		Fields: []FieldDoc{
+			{"id", "string", "Session ID."},
+			{"topic", "string", "Session topic."},
+			{"memoryPrompt", "string", "Rolling summary of the conversation, empty if none."},
+			{"messages", "JSON array", "Messages as objects with id, date, role, and content."},
+		},
+	}})
+	RegisterFormat(csvFormat{option: FormatOptionSessionOnly, doc: FormatDoc{
+		Name: "csv-sessions", Extension: ".csv",
+		Description: "One row per session with its metadata, without messages, for a quick inventory.",
+		Fields: []FieldDoc{
+			{"session_id", "string", "Session ID."},
+			{"title", "string", "Session topic."},
+			{"model", "string", "Model of the session, empty if not recorded."},
+			{"message_count", "number", "Number of messages."},
+			{"first_user_message", "string", "First 200 characters of the first user message."},
+			{"created_at", "date", "Creation time in RFC 3339 format, empty if unknown."},
+		},
+	}})
+	RegisterFormat(csvFormat{option: FormatOptionTurns, doc: FormatDoc{
+		Name: "csv-turns", Extension: ".csv",
+		Description: "One row per message with the index of its conversational turn.",
+		Fields: []FieldDoc{
+			{"session_id", "string", "ID of the session the message belongs to."},
+			{"turn_index", "number", "Turn of the message, from 1; 0 for messages before the first user message."},
+			{"message_id", "string", "Message ID."},
+			{"date", "string", "Date the message was sent, as recorded by the store."},
+			{"role", "string", "Sender of the message: user, assistant, system, or tool."},
+			{"content", "string", "Message text."},
+		},
+	}})
+	RegisterFormat(separateCSVFormat{})
+	RegisterFormat(datasetFormat{})
+	RegisterFormat(orgModeFormat{})
+	RegisterFormat(plainTextFormat{})
+	RegisterFormat(jsonlFormat{fineTuningFormat{}})
+	RegisterFormat(jsonlFormat{qaFormat{}})
+	RegisterFormat(turnsJSONFormat{})
+	RegisterFormat(epubFormat{})
+	RegisterFormat(atomFormat{})
+	RegisterFormat(summariesCSVFormat{})
+	RegisterFormat(summariesMarkdownFormat{})
+}
+
+// messageFieldDocs documents the columns of the message-level CSV formats.
+var messageFieldDocs = []FieldDoc{
+	{"session_id", "string", "ID of the session the message belongs to."},
+	{"message_id", "string", "Message ID."},
+	{"date", "string", "Date the message was sent, as recorded by the store."},
+	{"role", "string", "Sender of the message: user, assistant, system, or tool."},
+	{"content", "string", "Message text."},
+	{"memoryPrompt", "string", "Rolling summary of the session, empty if none."},
+}
+
+// csvFormat is a single-file CSV format of WriteSessionsCSV.
+type csvFormat struct {
+	option int
+	doc    FormatDoc
+}
+
+// Describe returns the documentation of the CSV layout.
+func (f csvFormat) Describe() FormatDoc { return f.doc }
+
+// Write writes the sessions to w as rows of the CSV layout.
+func (f csvFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WriteSessionsCSV(ctx, w, sessions, f.option, opts.CSV)
+}
+
+// separateCSVFormat is the pair of sessions and messages CSV files of WriteSeparateCSV.
+type separateCSVFormat struct{}
+
+// Describe returns the documentation of the separate sessions and messages CSV files.
+func (separateCSVFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "csv-separate", Extension: ".csv",
+		Description: "Two files: sessions (id, topic, memoryPrompt) and one row per message with the columns below.",
+		Fields:      messageFieldDocs,
+	}
+}
+
+// Write fails with ErrMultipleFiles, since the two files cannot share a single writer; use WriteFiles,
+// WriteSeparateCSV, or CreateSeparateCSVFiles instead.
+func (separateCSVFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return fmt.Errorf("csv-separate: %w; write them with WriteSeparateCSV or CreateSeparateCSVFiles", ErrMultipleFiles)
+}
+
+// WriteFiles writes the sessions file as sessions.csv and the messages file as messages.csv.
+func (separateCSVFormat) WriteFiles(ctx context.Context, create func(name string) io.Writer, sessions []Session, opts Options) error {
+	return WriteSeparateCSV(ctx, create("sessions.csv"), create("messages.csv"), sessions, opts.CSV)
+}
+
+// datasetFormat is the Hugging Face dataset of Dataset.
+type datasetFormat struct{}
+
+// Describe returns the documentation of the Hugging Face dataset.
+func (datasetFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "dataset", Extension: ".json",
+		Description: `A JSON object whose "dataset" array holds every session with all of its fields.`,
+		Fields: []FieldDoc{
+			{"id", "string", "Session ID."},
+			{"topic", "string", "Session topic."},
+			{"memoryPrompt", "string", "Rolling summary of the conversation."},
+			{"stat", "object", "Token, word, and character counts."},
+			{"lastUpdate", "number", "Time of the last update in Unix milliseconds."},
+			{"lastSummarizeIndex", "number", "Index of the last summarized message."},
+			{"mask", "object", "Mask of the session, including its model configuration."},
+			{"messages", "array", "Messages with id, date, role, and content."},
+			{"tags", "array", "Tags of the session from a tags file, omitted if none."},
+		},
+	}
+}
+
+// Write writes the sessions to w as a Hugging Face dataset.
+func (datasetFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WriteDataset(ctx, w, sessions, opts.Export)
+}
+
+// orgModeFormat is the Emacs Org-mode document of OrgModeDocument.
+type orgModeFormat struct{}
+
+// Describe returns the documentation of the Org-mode document.
+func (orgModeFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "orgmode", Extension: ".org",
+		Description: "An Org-mode document with a heading per session and a subheading per message.",
+		Fields: []FieldDoc{
+			{"* heading", "text", "Session topic, or Untitled Session."},
+			{":ID:", "property", "Session ID."},
+			{":MODEL:", "property", "Model of the session, if recorded."},
+			{"** heading", "text", "Role of the sender, followed by the message content."},
+		},
+	}
+}
+
+// Write writes the sessions to w as an Org-mode document.
+func (orgModeFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WriteOrgMode(ctx, w, sessions, opts.Export)
+}
+
+// plainTextFormat is the plain-text transcript of ExtractToPlainText.
+type plainTextFormat struct{}
+
+// Describe returns the documentation of the plain-text transcript.
+func (plainTextFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "text", Extension: ".txt",
+		Description: "A plain-text transcript with a heading per session and the role of each message in brackets.",
+		Fields: []FieldDoc{
+			{"heading", "text", "Session topic, or Untitled Session, underlined with equals signs."},
+			{"details", "text", "Session ID, model, and date of the last update."},
+			{"[Role]", "text", "Role of the sender, followed by the message content with code blocks indented."},
+		},
+	}
+}
+
+// Write writes the sessions to w as a plain-text transcript.
+func (plainTextFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WritePlainText(ctx, w, sessions, opts.Export)
+}
+
+// jsonlFormat is a JSON Lines format that is written as a single JSON array with opts.Export.JSONArray.
+type jsonlFormat struct {
+	Format
+}
+
+// Write writes the sessions to w in the wrapped format, as a single JSON array with opts.Export.JSONArray.
+func (f jsonlFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	if !opts.Export.JSONArray {
+		return f.Format.Write(ctx, w, sessions, opts)
+	}
+	return WriteJSONLAsArray(w, func(w io.Writer) error {
+		return f.Format.Write(ctx, w, sessions, opts)
+	})
+}
+
+// fineTuningFormat is the OpenAI fine-tuning JSONL of ExtractToFineTuningJSONL.
+type fineTuningFormat struct{}
+
+// Describe returns the documentation of the fine-tuning JSONL.
+func (fineTuningFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "finetune", Extension: ".jsonl",
+		Description: "One OpenAI chat fine-tuning example per line and session, from the active branch.",
+		Fields: []FieldDoc{
+			{"messages", "array", "Messages of the session as objects with role and content."},
+			{"messages[].weight", "number", "With -fine-tune-weights, whether an assistant message is trained on."},
+		},
+	}
+}
+
+// Write writes the sessions to w as fine-tuning examples, one per line.
+func (fineTuningFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WriteFineTuningJSONL(ctx, w, sessions, opts.Export)
+}
+
+// qaFormat is the question and answer JSONL of ConvertSessionsToQAJSONL.
+type qaFormat struct{}
+
+// Describe returns the documentation of the question and answer JSONL.
+func (qaFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "qa-jsonl", Extension: ".jsonl",
+		Description: "One question and answer record per line and user message answered by the assistant, for RAG evaluation sets.",
+		Fields: []FieldDoc{
+			{"question", "string", "Content of the user message."},
+			{"answer", "string", "Content of the assistant message that follows it."},
+			{"metadata.session_id", "string", "Session ID."},
+			{"metadata.title", "string", "Session topic."},
+		},
+	}
+}
+
+// Write writes the sessions to w as question and answer records, one per line.
+func (qaFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WriteQAJSONL(ctx, w, sessions, opts.Export)
+}
+
+// atomFormat is the Atom feed of ConvertSessionsToAtom.
+type atomFormat struct{}
+
+// Describe returns the documentation of the Atom feed.
+func (atomFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "atom", Extension: ".xml",
+		Description: fmt.Sprintf("An Atom feed of the %d most recently updated sessions, with the conversations as HTML.", DefaultAtomEntries),
+		Fields: []FieldDoc{
+			{"entry/id", "string", "Stable urn:uuid derived from the session ID."},
+			{"entry/title", "string", "Session topic."},
+			{"entry/updated", "date", "Last update of the session in RFC 3339 format."},
+			{"entry/published", "date", "Creation time of the session, omitted if unknown."},
+			{"entry/content", "HTML", "Messages of the active branch with their roles."},
+		},
+	}
+}
+
+// Write writes the sessions to w as an Atom feed of the DefaultAtomEntries most recently updated sessions.
+func (atomFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WriteAtom(ctx, w, sessions, AtomOptions{MaxEntries: DefaultAtomEntries})
+}
+
+// turnsJSONFormat is the JSON of the sessions grouped into turns by WriteTurnsJSON.
+type turnsJSONFormat struct{}
+
+// Describe returns the documentation of the turns JSON.
+func (turnsJSONFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "turns-json", Extension: ".json",
+		Description: "A JSON array of sessions with their messages grouped into conversational turns.",
+		Fields: []FieldDoc{
+			{"session_id", "string", "Session ID."},
+			{"topic", "string", "Session topic."},
+			{"turns[].turn_index", "number", "Turn index, from 1; 0 for messages before the first user message."},
+			{"turns[].messages", "array", "Messages of the turn as objects with id, date, role, and content."},
+		},
+	}
+}
+
+// Write writes the sessions to w as a JSON array of sessions grouped into turns.
+func (turnsJSONFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WriteTurnsJSON(ctx, w, sessions, opts.Export)
+}
+
+// epubFormat is the e-book of WriteEPUB.
+type epubFormat struct{}
+
+// Describe returns the documentation of the EPUB e-book.
+func (epubFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "epub", Extension: ".epub",
+		Description: "An EPUB 3 e-book with a chapter per session and a table of contents, for e-readers.",
+		Fields: []FieldDoc{
+			{"OEBPS/nav.xhtml", "XHTML", "Table of contents linking the chapters."},
+			{"OEBPS/session-N.xhtml", "XHTML", "Chapter titled with the session topic, with the messages of its active branch."},
+			{"OEBPS/content.opf", "XML", "Package document with the title, author, language, and reading order."},
+		},
+		Binary: true,
+	}
+}
+
+// Write writes the sessions to w as an EPUB e-book.
+func (epubFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WriteEPUB(ctx, w, sessions, EPUBOptions{})
+}
+
+// summariesCSVFormat is the summaries digest of WriteSummariesCSV.
+type summariesCSVFormat struct{}
+
+// Describe returns the documentation of the CSV summaries digest.
+func (summariesCSVFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "summaries-csv", Extension: ".csv",
+		Description: "One row per session with its summary, without messages.",
+		Fields: []FieldDoc{
+			{"id", "string", "Session ID."},
+			{"topic", "string", "Session topic."},
+			{"date", "date", "Day of the session in UTC (YYYY-MM-DD), empty if unknown."},
+			{"memoryPrompt", "string", "Rolling summary of the conversation, empty if none."},
+		},
+	}
+}
+
+// Write writes the sessions to w as a CSV digest of their summaries.
+func (summariesCSVFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WriteSummariesCSV(ctx, w, sessions, opts.CSV)
+}
+
+// summariesMarkdownFormat is the summaries digest of ExtractToSummariesMarkdown.
+type summariesMarkdownFormat struct{}
+
+// Describe returns the documentation of the Markdown summaries digest.
+func (summariesMarkdownFormat) Describe() FormatDoc {
+	return FormatDoc{
+		Name: "summaries-markdown", Extension: ".md",
+		Description: "A Markdown section per session with its summary, without messages.",
+		Fields: []FieldDoc{
+			{"## heading", "text", "Session topic."},
+			{"ID and date", "text", "Session ID and day of the session."},
+			{"paragraph", "text", "Rolling summary of the conversation, omitted if none."},
+		},
+	}
+}
+
+// Write writes the sessions to w as a Markdown digest of their summaries.
+func (summariesMarkdownFormat) Write(ctx context.Context, w io.Writer, sessions []Session, opts Options) error {
+	return WriteSummariesMarkdown(ctx, w, sessions, opts.CSV)
+}
+
+// ExampleSessions returns a tiny store of three short sessions, used to render examples of the formats.
+func ExampleSessions() []Session {
+	exchanges := [][3]string{
+		{"Go generics", "How do I write a generic Max function?", "Use a type parameter constrained by cmp.Ordered."},
+		{"Trip ideas", "Suggest a weekend trip from Istanbul.", "Try Bursa for its old town and Uludağ."},
+		{"Unit tests", "What is a table-driven test?", "A test that loops over a slice of cases."},
+	}
+	sessions := make([]Session, 0, len(exchanges))
+	for i, exchange := range exchanges {
+		id := fmt.Sprintf("s%d", i+1)
+		sessions = append(sessions, Session{
+			ID:           id,
+			Topic:        exchange[0],
+			MemoryPrompt: "",
+			LastUpdate:   1701166585000 + int64(i)*86400000,
+			Mask:         Mask{ModelConfig: &ModelConfig{Model: "gpt-4"}},
+			Messages: []Message{
+				{ID: id + "-m1", Date: "11/28/2023, 10:16:25 AM", Role: RoleUser, Content: exchange[1]},
+				{ID: id + "-m2", Date: "11/28/2023, 10:16:31 AM", Role: RoleAssistant, Content: exchange[2]},
+			},
+		})
+	}
+	sessions[0].MemoryPrompt = "The user is learning Go generics."
+	return sessions
+}
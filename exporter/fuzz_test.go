@@ -0,0 +1,93 @@
+package exporter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+// maxSeedSize is the size of the largest input that seeds the fuzzer. The fuzzer mutates small inputs
+// far more effectively, and the larger ones are checked by TestReadJSONFromReaderInputs instead.
+const maxSeedSize = 16 << 10
+
+// readJSONInputs returns the fixture stores and the broken files of the repairdata corpus, the inputs
+// ReadJSONFromReader is checked with.
+func readJSONInputs(tb testing.TB) [][]byte {
+	tb.Helper()
+	var inputs [][]byte
+	for _, fixture := range fixtures {
+		data, err := json.Marshal(fixture.store)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		inputs = append(inputs, data)
+	}
+	paths, err := filepath.Glob(filepath.Join("..", "repairdata", "testdata", "corpus", "*.json"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		inputs = append(inputs, data)
+	}
+	return inputs
+}
+
+// checkReadJSONFromReader verifies that a store read from data without an error holds a sessions
+// slice, and that a JSON syntax or type error is returned as a *ParseError with a position inside data.
+func checkReadJSONFromReader(t *testing.T, data []byte) {
+	store, err := exporter.ReadJSONFromReader(bytes.NewReader(data))
+	if err == nil {
+		if store.ChatNextWebStore.Sessions == nil {
+			t.Errorf("ReadJSONFromReader(%q) returned nil sessions without an error", data)
+		}
+		return
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+		return
+	}
+	var parseErr *exporter.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("ReadJSONFromReader(%q) returned %T %v, want a *ParseError", data, err, err)
+	}
+	if lines := bytes.Count(data, []byte("\n")) + 1; parseErr.Line < 1 || parseErr.Line > lines || parseErr.Column < 1 {
+		t.Errorf("ReadJSONFromReader(%q) reported line %d, column %d for input of %d lines", data, parseErr.Line, parseErr.Column, lines)
+	}
+}
+
+// TestReadJSONFromReaderInputs runs the checks of FuzzReadJSONFromReader on every input, including
+// those too large to seed the fuzzer.
+func TestReadJSONFromReaderInputs(t *testing.T) {
+	for _, data := range readJSONInputs(t) {
+		checkReadJSONFromReader(t, data)
+	}
+}
+
+// FuzzReadJSONFromReader verifies that ReadJSONFromReader never panics, that a store read without
+// an error holds a sessions slice, and that every JSON syntax or type error is returned as a
+// *ParseError with a position inside the input. The inputs of readJSONInputs up to maxSeedSize seed
+// the fuzzer; run it with
+//
+//	go test ./exporter -run '^$' -fuzz FuzzReadJSONFromReader
+func FuzzReadJSONFromReader(f *testing.F) {
+	for _, data := range readJSONInputs(f) {
+		if len(data) <= maxSeedSize {
+			f.Add(data)
+		}
+	}
+	f.Add([]byte(`{"chat-next-web-store":{"sessions":[{"id":1,"mask":{"id":"2","createdAt":"3"}}]}}`))
+	f.Add([]byte("{\n\"chat-next-web-store\": [\n"))
+
+	f.Fuzz(checkReadJSONFromReader)
+}
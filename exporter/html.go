@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"html"
+	"strings"
+)
+
+// renderMessagesHTML renders messages as an HTML fragment. Each message starts with a paragraph holding
+// its role in bold, followed by its content: fenced code blocks become pre elements with a language
+// class for syntax highlighters, and the remaining text becomes paragraphs split at blank lines, with
+// line breaks kept. All text is escaped, so content can never inject markup, and the fragment is also
+// well-formed XHTML.
+func renderMessagesHTML(messages []Message) string {
+	var builder strings.Builder
+	for _, message := range messages {
+		builder.WriteString("<p><strong>" + html.EscapeString(message.Role) + "</strong></p>\n")
+		renderContentHTML(&builder, message.Content)
+	}
+	return builder.String()
+}
+
+// renderContentHTML writes the content of a message as HTML; see renderMessagesHTML.
+func renderContentHTML(builder *strings.Builder, content string) {
+	lines := strings.Split(content, "\n")
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			builder.WriteString("<p>" + strings.Join(paragraph, "<br />\n") + "</p>\n")
+			paragraph = nil
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		fence := codeFence(lines[i])
+		if fence == "" {
+			if strings.TrimSpace(lines[i]) == "" {
+				flush()
+			} else {
+				paragraph = append(paragraph, html.EscapeString(lines[i]))
+			}
+			continue
+		}
+
+		flush()
+		builder.WriteString("<pre><code")
+		if info := strings.Fields(strings.TrimLeft(strings.TrimSpace(lines[i]), fence[:1])); len(info) > 0 {
+			builder.WriteString(` class="language-` + html.EscapeString(info[0]) + `"`)
+		}
+		builder.WriteString(">")
+		var code []string
+		for i++; i < len(lines) && !isClosingFence(lines[i], fence); i++ {
+			code = append(code, html.EscapeString(lines[i]))
+		}
+		builder.WriteString(strings.Join(code, "\n") + "</code></pre>\n")
+	}
+	flush()
+}
@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// ExportState records the content hash of every session seen by an export, keyed by session ID.
+// Saving it after an export and passing it to FilterChangedSessions on the next run allows
+// incremental exports that only contain new and changed sessions.
+type ExportState struct {
+	Sessions map[string]string `json:"sessions"` // Sessions maps each session ID to its SessionHash.
+}
+
+// IncrementalSummary reports how FilterChangedSessions classified the sessions.
+type IncrementalSummary struct {
+	New       int `json:"new"`       // New is the number of sessions missing from the previous state.
+	Changed   int `json:"changed"`   // Changed is the number of sessions whose hash differs from the previous state.
+	Unchanged int `json:"unchanged"` // Unchanged is the number of sessions whose hash matches the previous state.
+}
+
+// SessionHash returns a hex-encoded SHA-256 hash of the session's JSON encoding.
+// Any change to the session, including its messages and mask, results in a different hash.
+func SessionHash(session Session) string {
+	// A Session only holds strings and numbers, so encoding it cannot fail.
+	data, _ := json.Marshal(session)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewExportState builds the export state describing the given sessions.
+func NewExportState(sessions []Session) ExportState {
+	state := ExportState{Sessions: make(map[string]string, len(sessions))}
+	for _, session := range sessions {
+		state.Sessions[session.ID] = SessionHash(session)
+	}
+	return state
+}
+
+// ParseExportState parses an export state previously encoded as JSON.
+//
+// It returns an error if the data is not valid JSON or has no "sessions" object.
+func ParseExportState(data []byte) (ExportState, error) {
+	var state ExportState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return ExportState{}, err
+	}
+	if state.Sessions == nil {
+		return ExportState{}, fmt.Errorf("JSON does not match the expected export state format")
+	}
+	return state, nil
+}
+
+// FilterChangedSessions returns the sessions that are new or whose content changed since the
+// previous export state, in their original order, together with a summary of the classification.
+// A zero ExportState treats every session as new.
+func FilterChangedSessions(sessions []Session, previous ExportState) ([]Session, IncrementalSummary) {
+	var summary IncrementalSummary
+	changed := make([]Session, 0, len(sessions))
+	for _, session := range sessions {
+		hash, ok := previous.Sessions[session.ID]
+		switch {
+		case !ok:
+			summary.New++
+		case hash != SessionHash(session):
+			summary.Changed++
+		default:
+			summary.Unchanged++
+			continue
+		}
+		changed = append(changed, session)
+	}
+	return changed, summary
+}
@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InputFormat selects how ReadJSONFromReaderAs decodes a store.
+type InputFormat int
+
+const (
+	// InputFormatAuto detects the layout of the sessions, trying an array first and an object keyed
+	// by session ID second. It is what ReadJSONFromReader does.
+	InputFormatAuto InputFormat = iota
+
+	// InputFormatNextWeb only accepts the sessions of the web app itself, stored as an array.
+	InputFormatNextWeb
+
+	// InputFormatNextWebObject only accepts the sessions of the forks that store them in an object
+	// keyed by session ID; see SessionsShapeObject.
+	InputFormatNextWebObject
+)
+
+// ParseInputFormat parses the name of an InputFormat: "auto", "nextweb", or "nextweb-object".
+func ParseInputFormat(name string) (InputFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "auto":
+		return InputFormatAuto, nil
+	case "nextweb":
+		return InputFormatNextWeb, nil
+	case "nextweb-object":
+		return InputFormatNextWebObject, nil
+	default:
+		return InputFormatAuto, fmt.Errorf("unknown input format %q, expected auto, nextweb, or nextweb-object", name)
+	}
+}
+
+// String returns the name of the input format as accepted by ParseInputFormat.
+func (f InputFormat) String() string {
+	switch f {
+	case InputFormatNextWeb:
+		return "nextweb"
+	case InputFormatNextWebObject:
+		return "nextweb-object"
+	default:
+		return "auto"
+	}
+}
@@ -0,0 +1,204 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// InspectOptions controls the output of InspectStore.
+type InspectOptions struct {
+	// MaxSessions limits the output to the first MaxSessions sessions; 0 includes all of them.
+	MaxSessions int
+
+	// SessionID limits the output to the session with this ID; empty includes all sessions.
+	SessionID string
+
+	// Indent is the indentation of one nesting level; it defaults to two spaces.
+	Indent string
+}
+
+// InspectStore re-emits the JSON store read from r pretty-printed to w, to make the structure of a store
+// readable that an editor cannot open, such as a store of hundreds of megabytes on a single line.
+//
+// The input is streamed through a json.Decoder token by token, so memory use is bounded by the largest
+// single session rather than the size of the store. Any JSON document is accepted; the sessions array
+// of a ChatGPT-Next-Web store is filtered according to opts, while everything else is printed as it is.
+// Strings are re-encoded, so escape sequences may differ from the input, but their values do not.
+//
+// It returns an error if the context is cancelled, the input is not valid JSON, or writing to w fails.
+func InspectStore(ctx context.Context, r io.Reader, w io.Writer, opts InspectOptions) error {
+	indent := opts.Indent
+	if indent == "" {
+		indent = "  "
+	}
+	input := &offsetReader{r: r}
+	decoder := json.NewDecoder(input)
+	decoder.UseNumber()
+	out := bufio.NewWriter(w)
+	printer := &prettyPrinter{ctx: ctx, decoder: decoder, out: out, indent: indent, opts: opts}
+
+	if err := printer.value(0, nil); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return input.wrapParseError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after the JSON document")
+	}
+	out.WriteString("\n")
+	return out.Flush()
+}
+
+// prettyPrinter writes the tokens of a json.Decoder as indented JSON.
+type prettyPrinter struct {
+	ctx     context.Context
+	decoder *json.Decoder
+	out     *bufio.Writer
+	indent  string
+	opts    InspectOptions
+}
+
+// value prints the next JSON value. The path holds the object keys leading to it.
+func (p *prettyPrinter) value(depth int, path []string) error {
+	token, err := p.decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		return p.object(depth, path)
+	case json.Delim('['):
+		if len(path) == 2 && path[0] == "chat-next-web-store" && path[1] == "sessions" {
+			return p.sessions(depth)
+		}
+		return p.array(depth, path)
+	}
+	return p.scalar(token)
+}
+
+// object prints the members of an object whose opening brace has been read.
+func (p *prettyPrinter) object(depth int, path []string) error {
+	p.out.WriteString("{")
+	members := 0
+	for p.decoder.More() {
+		token, err := p.decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		p.separator(members, depth+1)
+		if err := p.scalar(key); err != nil {
+			return err
+		}
+		p.out.WriteString(": ")
+		if err := p.value(depth+1, append(path, key)); err != nil {
+			return err
+		}
+		members++
+	}
+	return p.close(members, depth, "}")
+}
+
+// array prints the elements of an array whose opening bracket has been read.
+func (p *prettyPrinter) array(depth int, path []string) error {
+	p.out.WriteString("[")
+	elements := 0
+	for p.decoder.More() {
+		if err := p.ctx.Err(); err != nil {
+			return err
+		}
+		p.separator(elements, depth+1)
+		if err := p.value(depth+1, path); err != nil {
+			return err
+		}
+		elements++
+	}
+	return p.close(elements, depth, "]")
+}
+
+// sessions prints the sessions array of a store, one session at a time, leaving out the sessions
+// excluded by the options. The sessions that are left out are still read to reach the rest of the store.
+func (p *prettyPrinter) sessions(depth int) error {
+	p.out.WriteString("[")
+	written := 0
+	var indented bytes.Buffer
+	for p.decoder.More() {
+		if err := p.ctx.Err(); err != nil {
+			return err
+		}
+		var raw json.RawMessage
+		if err := p.decoder.Decode(&raw); err != nil {
+			return err
+		}
+		if p.opts.MaxSessions > 0 && written >= p.opts.MaxSessions {
+			continue
+		}
+		if p.opts.SessionID != "" {
+			var session struct {
+				ID string `json:"id"`
+			}
+			if json.Unmarshal(raw, &session) != nil || session.ID != p.opts.SessionID {
+				continue
+			}
+		}
+
+		indented.Reset()
+		if err := json.Indent(&indented, raw, strings.Repeat(p.indent, depth+1), p.indent); err != nil {
+			return err
+		}
+		p.separator(written, depth+1)
+		p.out.Write(indented.Bytes())
+		written++
+	}
+	return p.close(written, depth, "]")
+}
+
+// scalar prints a string, number, boolean, or null token.
+func (p *prettyPrinter) scalar(token json.Token) error {
+	if token == nil {
+		p.out.WriteString("null")
+		return nil
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(token); err != nil {
+		return err
+	}
+	p.out.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return nil
+}
+
+// separator starts the next member or element on a new line, after a comma unless it is the first.
+func (p *prettyPrinter) separator(index, depth int) {
+	if index > 0 {
+		p.out.WriteString(",")
+	}
+	p.newline(depth)
+}
+
+// close reads the closing delimiter of an object or array and prints it, on its own line unless it is empty.
+func (p *prettyPrinter) close(count, depth int, delim string) error {
+	if _, err := p.decoder.Token(); err != nil {
+		return err
+	}
+	if count > 0 {
+		p.newline(depth)
+	}
+	p.out.WriteString(delim)
+	return p.ctx.Err()
+}
+
+// newline starts a new line indented for the given depth.
+func (p *prettyPrinter) newline(depth int) {
+	p.out.WriteString("\n")
+	for i := 0; i < depth; i++ {
+		p.out.WriteString(p.indent)
+	}
+}
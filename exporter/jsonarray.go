@@ -0,0 +1,77 @@
+package exporter
+
+import (
+	"bytes"
+	"io"
+)
+
+// WriteJSONLAsArray calls write with a writer that takes JSON Lines and writes them to w as a single JSON
+// array instead, one record per line separated by commas, for tools that require a file to hold exactly
+// one JSON value. Blank lines are dropped, and without records an empty array is written. The closing
+// bracket is only written when write succeeds, so a failed export never looks like a complete one.
+//
+// Every JSON Lines format, such as finetune and qa-jsonl, is written this way by ConvertSessions when
+// ExportOptions.JSONArray is set.
+//
+// It returns the error of write, or an error if writing to w fails.
+func WriteJSONLAsArray(w io.Writer, write func(w io.Writer) error) error {
+	array := &jsonArrayWriter{w: &countingWriter{w: w}}
+	if err := write(array); err != nil {
+		return err
+	}
+	return array.Close()
+}
+
+// jsonArrayWriter turns the JSON Lines written to it into the elements of a JSON array.
+type jsonArrayWriter struct {
+	w       *countingWriter
+	line    []byte // line holds the incomplete last line written so far.
+	records int
+}
+
+// Write writes every complete line of p as an element of the array and keeps the rest for the next write.
+func (a *jsonArrayWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			a.line = append(a.line, p...)
+			return n, a.w.err
+		}
+		a.line = append(a.line, p[:i]...)
+		p = p[i+1:]
+		if err := a.writeRecord(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// writeRecord writes the buffered line as the next element of the array, unless it is blank.
+func (a *jsonArrayWriter) writeRecord() error {
+	record := bytes.TrimSpace(a.line)
+	a.line = a.line[:0]
+	if len(record) == 0 {
+		return a.w.err
+	}
+	if a.records == 0 {
+		a.w.WriteString("[\n")
+	} else {
+		a.w.WriteString(",\n")
+	}
+	a.w.Write(record)
+	a.records++
+	return a.w.err
+}
+
+// Close writes a final line without a line break and closes the array.
+func (a *jsonArrayWriter) Close() error {
+	if err := a.writeRecord(); err != nil {
+		return err
+	}
+	if a.records == 0 {
+		a.w.WriteString("[]\n")
+	} else {
+		a.w.WriteString("\n]\n")
+	}
+	return a.w.err
+}
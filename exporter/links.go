@@ -0,0 +1,34 @@
+package exporter
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SessionURLPlaceholder is replaced by the escaped session ID when it appears in a base URL.
+const SessionURLPlaceholder = "{id}"
+
+// SessionURL builds a link back to a session in a ChatGPT-Next-Web deployment.
+//
+// If baseURL contains SessionURLPlaceholder, the placeholder is replaced by the escaped session ID,
+// which allows deployments with a custom routing scheme. Otherwise the default ChatGPT-Next-Web
+// chat route "/#/chat/<id>" is appended to baseURL. An empty baseURL yields an empty link.
+func SessionURL(baseURL, id string) string {
+	if baseURL == "" {
+		return ""
+	}
+	escaped := url.PathEscape(id)
+	if strings.Contains(baseURL, SessionURLPlaceholder) {
+		return strings.ReplaceAll(baseURL, SessionURLPlaceholder, escaped)
+	}
+	return strings.TrimRight(baseURL, "/") + "/#/chat/" + escaped
+}
+
+// withURLColumn appends the session link to a CSV record when a base URL is configured,
+// so that the column is omitted entirely rather than left blank otherwise.
+func withURLColumn(record []string, baseURL, id string) []string {
+	if baseURL == "" {
+		return record
+	}
+	return append(record, SessionURL(baseURL, id))
+}
@@ -0,0 +1,384 @@
+package exporter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MergeOrder determines how MergeSessions orders the messages of the merged session.
+type MergeOrder int
+
+const (
+	// MergeOrderTimestamp interleaves the messages of all sessions by the time they were sent.
+	MergeOrderTimestamp MergeOrder = iota
+
+	// MergeOrderManual keeps the messages of each session together, one session after the other,
+	// in the order the sessions are given.
+	MergeOrderManual
+)
+
+// ParseMergeOrder parses the name of a MergeOrder: "timestamp" or "manual".
+func ParseMergeOrder(name string) (MergeOrder, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "timestamp":
+		return MergeOrderTimestamp, nil
+	case "manual":
+		return MergeOrderManual, nil
+	default:
+		return MergeOrderTimestamp, fmt.Errorf("unknown merge order %q, expected timestamp or manual", name)
+	}
+}
+
+// MergeOptions controls how MergeSessions combines sessions.
+type MergeOptions struct {
+	Order MergeOrder // Order determines the order of the merged messages.
+	ID    string     // ID is the ID of the merged session; empty derives a new one from the IDs of the sessions.
+	Topic string     // Topic is the topic of the merged session; empty keeps the topic of the first session.
+}
+
+// messageDateLayouts lists the layouts of message dates MergeOrderTimestamp understands. The web app
+// stores the date formatted for the locale of the browser, so only the common ones are recognized.
+var messageDateLayouts = []string{
+	"1/2/2006, 3:04:05 PM",
+	"1/2/2006, 15:04:05",
+	"2006/1/2 15:04:05",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+// MergeSessions combines two or more sessions, such as a conversation continued in a new chat, into
+// a single session with a new ID and the messages of all of them.
+//
+// The merged session takes its mask from the first session, with the earliest known creation time,
+// the latest update time, the sum of the statistics, and the tags of all sessions. The memory prompt
+// summarizing the conversation is cleared, as it no longer covers all of it.
+//
+// With MergeOrderTimestamp, a message whose date cannot be parsed keeps its place after the previous
+// message of its session, and messages sent at the same time keep the order of their sessions, so the
+// result is the same on every run. The input slice is not modified.
+//
+// It returns an error if fewer than two sessions are given.
+func MergeSessions(sessions []Session, opts MergeOptions) (Session, error) {
+	if len(sessions) < 2 {
+		return Session{}, fmt.Errorf("merging needs at least 2 sessions, got %d", len(sessions))
+	}
+
+	merged := sessions[0].DeepCopy()
+	merged.ID = opts.ID
+	if merged.ID == "" {
+		merged.ID = mergedSessionID(sessions)
+	}
+	if opts.Topic != "" {
+		merged.Topic = opts.Topic
+	}
+	merged.MemoryPrompt = ""
+	merged.LastSummarizeIndex = 0
+	merged.Stat = Stat{}
+	var tags []string
+	for _, session := range sessions {
+		merged.Stat.TokenCount += session.Stat.TokenCount
+		merged.Stat.WordCount += session.Stat.WordCount
+		merged.Stat.CharCount += session.Stat.CharCount
+		merged.LastUpdate = max(merged.LastUpdate, session.LastUpdate)
+		if created := session.Mask.CreatedAt; created > 0 && (merged.Mask.CreatedAt <= 0 || created < merged.Mask.CreatedAt) {
+			merged.Mask.CreatedAt = created
+		}
+		tags = append(tags, session.Tags...)
+	}
+	merged.Tags = ParseTags(strings.Join(tags, ","))
+	if len(merged.Tags) == 0 {
+		merged.Tags = nil
+	}
+	merged.Messages = mergeMessages(sessions, opts.Order)
+	return merged, nil
+}
+
+// mergedSessionID derives the ID of a merged session from the IDs of the sessions, so that merging
+// the same sessions again yields the same ID.
+func mergedSessionID(sessions []Session) string {
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	sum := sha256.Sum256([]byte(strings.Join(ids, "\x00")))
+	return "merged-" + hex.EncodeToString(sum[:8])
+}
+
+// mergeMessages returns the messages of all sessions in the given order.
+func mergeMessages(sessions []Session, order MergeOrder) []Message {
+	type timedMessage struct {
+		message Message
+		time    time.Time
+	}
+	var timed []timedMessage
+	for _, session := range sessions {
+		// A message without a parsable date inherits the time of the message before it, and the
+		// first messages of a session that has none fall back to the time the session was created.
+		last := time.UnixMilli(session.Timestamp(DateFieldCreated))
+		for _, message := range session.Messages {
+			if t, ok := parseMessageDate(message.Date); ok {
+				last = t
+			}
+			timed = append(timed, timedMessage{message: message, time: last})
+		}
+	}
+	if order == MergeOrderTimestamp {
+		sort.SliceStable(timed, func(i, j int) bool {
+			return timed[i].time.Before(timed[j].time)
+		})
+	}
+
+	messages := make([]Message, len(timed))
+	for i, t := range timed {
+		messages[i] = t.message
+	}
+	return messages
+}
+
+// parseMessageDate parses the date of a message in one of messageDateLayouts, in local time like the web app.
+func parseMessageDate(date string) (time.Time, bool) {
+	date = strings.TrimSpace(date)
+	for _, layout := range messageDateLayouts {
+		if t, err := time.ParseInLocation(layout, date, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// WriteStoreJSON writes the store as a backup JSON file the web app can import, with the sessions
+// stored as an array.
+//
+// Fields the exporter does not model, such as the app's settings, the access control state, or unknown
+// fields of sessions, masks, and messages, are carried over from originals, the JSON documents the
+// sessions were read from: a field missing from the written store is taken from the first original
+// holding it. Sessions and messages are matched by ID, since merging reorders them, and other array
+// elements by position. Empty originals are ignored; without any, only the fields modeled by Session are written.
+//
+// It returns an error if an original is not valid JSON, or if encoding or writing fails.
+func WriteStoreJSON(w io.Writer, store ChatNextWebStore, originals ...[]byte) error {
+	data, err := json.Marshal(store)
+	if err != nil {
+		return err
+	}
+	var raws []json.RawMessage
+	for i, original := range originals {
+		if len(original) == 0 {
+			continue
+		}
+		if !json.Valid(original) {
+			return fmt.Errorf("original store %d is not valid JSON", i+1)
+		}
+		raws = append(raws, original)
+	}
+	if len(raws) > 0 {
+		if data, err = mergeUnknownFields(data, raws); err != nil {
+			return err
+		}
+	}
+	var output bytes.Buffer
+	if err := json.Indent(&output, data, "", "  "); err != nil {
+		return err
+	}
+	output.WriteByte('\n')
+	_, err = output.WriteTo(w)
+	return err
+}
+
+// mergeUnknownFields adds the fields of the originals that are missing from the object written into
+// it, recursing into the values found in both. Array elements holding an "id" are matched by it across
+// all originals, which may store them in an array or, like some forks, in an object keyed by ID; other
+// elements are matched by position. Values other than objects and arrays are kept as written.
+func mergeUnknownFields(written json.RawMessage, originals []json.RawMessage) (json.RawMessage, error) {
+	switch jsonKind(written) {
+	case '{':
+		object, err := parseRawObject(written)
+		if err != nil {
+			return nil, err
+		}
+		for _, original := range originals {
+			if jsonKind(original) != '{' {
+				continue
+			}
+			originalObject, err := parseRawObject(original)
+			if err != nil {
+				return nil, err
+			}
+			for _, field := range originalObject {
+				if object.index(field.key) < 0 {
+					object = append(object, field)
+				}
+			}
+		}
+		for i, field := range object {
+			var values []json.RawMessage
+			for _, original := range originals {
+				if value, ok := fieldValue(original, field.key); ok {
+					values = append(values, value)
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+			if object[i].value, err = mergeUnknownFields(field.value, values); err != nil {
+				return nil, err
+			}
+		}
+		return object.marshal(), nil
+	case '[':
+		var elements []json.RawMessage
+		if err := json.Unmarshal(written, &elements); err != nil {
+			return nil, err
+		}
+		byID := make(map[string][]json.RawMessage)
+		for _, original := range originals {
+			for _, element := range rawElements(original) {
+				if id := rawID(element); id != "" {
+					byID[id] = append(byID[id], element)
+				}
+			}
+		}
+		for i, element := range elements {
+			matches := byID[rawID(element)]
+			if rawID(element) == "" {
+				matches = nil
+				for _, original := range originals {
+					var originalElements []json.RawMessage
+					if jsonKind(original) == '[' && json.Unmarshal(original, &originalElements) == nil && i < len(originalElements) {
+						matches = append(matches, originalElements[i])
+					}
+				}
+			}
+			merged, err := mergeUnknownFields(element, matches)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = merged
+		}
+		return json.Marshal(elements)
+	default:
+		return written, nil
+	}
+}
+
+// rawField is a field of a JSON object.
+type rawField struct {
+	key   string
+	value json.RawMessage
+}
+
+// rawObject is a JSON object with its fields in their original order.
+type rawObject []rawField
+
+// parseRawObject parses the JSON object data, keeping the order of its fields.
+func parseRawObject(data json.RawMessage) (rawObject, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	var object rawObject
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		object = append(object, rawField{key: token.(string), value: value})
+	}
+	return object, nil
+}
+
+// index returns the position of the field key, or -1 if the object has none.
+func (o rawObject) index(key string) int {
+	for i, field := range o {
+		if field.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// marshal encodes the object with its fields in order.
+func (o rawObject) marshal() json.RawMessage {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.key)
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(field.value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes()
+}
+
+// fieldValue returns the value of the field key of the JSON object data, and false if data is not an
+// object or has no such field.
+func fieldValue(data json.RawMessage, key string) (json.RawMessage, bool) {
+	if jsonKind(data) != '{' {
+		return nil, false
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return nil, false
+	}
+	value, ok := fields[key]
+	return value, ok
+}
+
+// rawElements returns the elements of the JSON array data, or the values of the JSON object data, which
+// is how some forks store sessions keyed by ID.
+func rawElements(data json.RawMessage) []json.RawMessage {
+	switch jsonKind(data) {
+	case '[':
+		var elements []json.RawMessage
+		if json.Unmarshal(data, &elements) == nil {
+			return elements
+		}
+	case '{':
+		object, err := parseRawObject(data)
+		if err != nil {
+			return nil
+		}
+		elements := make([]json.RawMessage, len(object))
+		for i, field := range object {
+			elements[i] = field.value
+		}
+		return elements
+	}
+	return nil
+}
+
+// rawID returns the string "id" field of the JSON object data, or "" if it has none.
+func rawID(data json.RawMessage) string {
+	var element struct {
+		ID string `json:"id"`
+	}
+	if jsonKind(data) != '{' || json.Unmarshal(data, &element) != nil {
+		return ""
+	}
+	return element.ID
+}
+
+// jsonKind returns the first character of the JSON value data after white space, which tells objects,
+// arrays, and the other values apart, or 0 if data is empty.
+func jsonKind(data json.RawMessage) byte {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 {
+		return 0
+	}
+	return trimmed[0]
+}
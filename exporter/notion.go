@@ -0,0 +1,105 @@
+package exporter
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// notionMaxTextLength is the maximum number of characters the Notion API accepts in a single rich text object.
+const notionMaxTextLength = 2000
+
+// NotionBlock represents a block object of the Notion API block tree format.
+//
+// Only the heading_2 and paragraph block types are produced by the exporter.
+type NotionBlock struct {
+	Object    string           `json:"object"`
+	Type      string           `json:"type"`
+	Heading2  *NotionBlockText `json:"heading_2,omitempty"`
+	Paragraph *NotionBlockText `json:"paragraph,omitempty"`
+}
+
+// NotionBlockText holds the rich text content of a text-based Notion block.
+type NotionBlockText struct {
+	RichText []NotionRichText `json:"rich_text"`
+}
+
+// NotionRichText represents a single rich text object of the Notion API.
+type NotionRichText struct {
+	Type        string             `json:"type"`
+	Text        NotionText         `json:"text"`
+	Annotations *NotionAnnotations `json:"annotations,omitempty"`
+}
+
+// NotionText holds the plain content of a rich text object.
+type NotionText struct {
+	Content string `json:"content"`
+}
+
+// NotionAnnotations holds the styling of a rich text object.
+type NotionAnnotations struct {
+	Bold bool `json:"bold"`
+}
+
+// ExtractToNotionBlocks converts a slice of Session objects into a JSON array of Notion API block objects.
+//
+// Each session maps to a heading_2 block holding its topic, and each message maps to a paragraph block
+// whose rich text starts with the sender's role in bold, followed by the message content. Content longer
+// than the 2000 characters Notion accepts per rich text object is split over several rich text objects.
+//
+// The blocks are lossy: only topics, roles, and message contents are kept.
+//
+// The result can be appended to a page with the NotionUploader from the uploader package.
+func ExtractToNotionBlocks(sessions []Session) ([]byte, error) {
+	blocks := make([]NotionBlock, 0, len(sessions))
+	for _, session := range sessions {
+		topic := session.Topic
+		if topic == "" {
+			topic = session.ID
+		}
+		blocks = append(blocks, NotionBlock{
+			Object:   "block",
+			Type:     "heading_2",
+			Heading2: &NotionBlockText{RichText: notionRichText(topic)},
+		})
+
+		for _, message := range session.Messages {
+			richText := []NotionRichText{{
+				Type:        "text",
+				Text:        NotionText{Content: message.Role + ": "},
+				Annotations: &NotionAnnotations{Bold: true},
+			}}
+			richText = append(richText, notionRichText(message.Content)...)
+			blocks = append(blocks, NotionBlock{
+				Object:    "block",
+				Type:      "paragraph",
+				Paragraph: &NotionBlockText{RichText: richText},
+			})
+		}
+	}
+
+	return json.MarshalIndent(blocks, "", "  ")
+}
+
+// notionRichText splits content into rich text objects that respect the Notion length limit.
+// Splitting happens on rune boundaries so multi-byte characters are never cut in half.
+func notionRichText(content string) []NotionRichText {
+	var richText []NotionRichText
+	for len(content) > 0 {
+		chunk := content
+		if utf8.RuneCountInString(chunk) > notionMaxTextLength {
+			end, runes := 0, 0
+			for end < len(chunk) && runes < notionMaxTextLength {
+				_, size := utf8.DecodeRuneInString(chunk[end:])
+				end += size
+				runes++
+			}
+			chunk = chunk[:end]
+		}
+		richText = append(richText, NotionRichText{Type: "text", Text: NotionText{Content: chunk}})
+		content = content[len(chunk):]
+	}
+	if richText == nil {
+		richText = []NotionRichText{}
+	}
+	return richText
+}
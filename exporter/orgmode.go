@@ -0,0 +1,139 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// ExtractToOrgMode converts a slice of Session objects into an Emacs Org-mode document.
+//
+// Each session becomes a level-1 heading titled with the session topic, followed by a
+// property drawer holding the session ID and model. Each message becomes a level-2 heading
+// named after the role of its sender, with the message content as the body.
+//
+// Fenced code blocks (triple backticks) in message content are converted into
+// #+BEGIN_SRC / #+END_SRC blocks, keeping the language identifier when present.
+//
+// The document is meant for reading and is lossy: message IDs and dates, the memory prompt,
+// statistics, and all mask fields other than the model are dropped.
+//
+// To stream a large document to a writer instead of building it in memory, use WriteOrgMode.
+func ExtractToOrgMode(sessions []Session) (string, error) {
+	var builder strings.Builder
+	if _, err := (OrgModeDocument{Sessions: sessions}).WriteTo(&builder); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// WriteOrgMode writes the Org-mode document of ExtractToOrgMode to w one session at a time, stopping
+// at the next session once ctx is cancelled. When opts.BaseURL is set, the property drawer of every
+// session holds a :URL: linking back to it; see SessionURL.
+//
+// It returns an error if the context is cancelled or writing fails.
+func WriteOrgMode(ctx context.Context, w io.Writer, sessions []Session, opts ExportOptions) error {
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	_, err := OrgModeDocument{Sessions: sessions, BaseURL: opts.BaseURL}.WriteTo(contextWriter{ctx: ctx, w: w})
+	return err
+}
+
+// OrgModeDocument is an Emacs Org-mode document of sessions that can be streamed to a writer.
+//
+// It implements io.WriterTo and writes the same document as ExtractToOrgMode one session at a time.
+type OrgModeDocument struct {
+	Sessions []Session // Sessions are the sessions in the document.
+	BaseURL  string    // BaseURL adds a :URL: property linking back to each session; omitted if empty.
+}
+
+// WriteTo writes the Org-mode document to w.
+// It returns the number of bytes written and the first error encountered.
+func (d OrgModeDocument) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	for _, session := range d.Sessions {
+		topic := session.Topic
+		if topic == "" {
+			topic = "Untitled Session"
+		}
+		cw.WriteString("* " + orgHeadingText(topic) + "\n")
+		cw.WriteString(":PROPERTIES:\n")
+		cw.WriteString(":ID: " + session.ID + "\n")
+		if model := session.Model(); model != "" {
+			cw.WriteString(":MODEL: " + model + "\n")
+		}
+		if link := SessionURL(d.BaseURL, session.ID); link != "" {
+			cw.WriteString(":URL: " + link + "\n")
+		}
+		cw.WriteString(":END:\n")
+
+		for _, message := range session.Messages {
+			cw.WriteString("** " + orgRoleHeading(message.Role) + "\n")
+			cw.WriteString(orgBody(message.Content))
+		}
+		if cw.err != nil {
+			break
+		}
+	}
+	return cw.n, cw.err
+}
+
+// orgRoleHeading returns the heading used for a message role, e.g. "user" becomes "User".
+func orgRoleHeading(role string) string {
+	if role == "" {
+		return "Unknown"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+// orgHeadingText flattens text so that it fits on a single heading line.
+func orgHeadingText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// orgBody renders message content as an Org-mode body, converting fenced code blocks
+// into source blocks and escaping lines that Org-mode would otherwise treat as syntax.
+// The returned string always ends with a newline unless the content is empty.
+func orgBody(content string) string {
+	if content == "" {
+		return ""
+	}
+
+	var builder strings.Builder
+	inCode := false
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```") && !inCode:
+			inCode = true
+			language := strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			if language == "" {
+				builder.WriteString("#+BEGIN_SRC\n")
+			} else {
+				builder.WriteString("#+BEGIN_SRC " + language + "\n")
+			}
+		case trimmed == "```" && inCode:
+			inCode = false
+			builder.WriteString("#+END_SRC\n")
+		case inCode:
+			// Inside source blocks Org-mode escapes syntax-like lines with a leading comma.
+			if strings.HasPrefix(line, "*") || strings.HasPrefix(line, "#+") {
+				line = "," + line
+			}
+			builder.WriteString(line + "\n")
+		default:
+			// A leading asterisk would start a new heading, so indent it by one space.
+			if strings.HasPrefix(line, "*") {
+				line = " " + line
+			}
+			builder.WriteString(line + "\n")
+		}
+	}
+	if inCode {
+		// Close an unterminated code block so the document stays well formed.
+		builder.WriteString("#+END_SRC\n")
+	}
+	return builder.String()
+}
@@ -0,0 +1,82 @@
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ParseError reports where in the input a JSON syntax or type error occurred,
+// so that the file can be opened at the exact bad position. For syntax errors the position is
+// the offending character; for type errors it is the last byte of the value of the wrong type.
+type ParseError struct {
+	Line   int   // Line is the 1-based line number of the offending byte.
+	Column int   // Column is the 1-based byte column of the offending byte within its line.
+	Cause  error // Cause is the underlying *json.SyntaxError or *json.UnmarshalTypeError.
+}
+
+// Error returns the position followed by the message of the underlying error.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Cause)
+}
+
+// Unwrap returns the underlying error, so that errors.As can still reach the json error types.
+func (e *ParseError) Unwrap() error {
+	return e.Cause
+}
+
+// offsetReader wraps a reader and records the byte offset of every newline passing through it,
+// which allows a byte offset reported by the JSON decoder to be turned into a line and column
+// without keeping the input itself in memory.
+type offsetReader struct {
+	r        io.Reader
+	offset   int64
+	newlines []int64
+}
+
+// Read reads from the underlying reader and records the offsets of the newlines read.
+func (o *offsetReader) Read(p []byte) (int, error) {
+	n, err := o.r.Read(p)
+	for i, c := range p[:n] {
+		if c == '\n' {
+			o.newlines = append(o.newlines, o.offset+int64(i))
+		}
+	}
+	o.offset += int64(n)
+	return n, err
+}
+
+// position converts the offset reported by the JSON decoder, which counts the bytes read
+// up to and including the offending byte, into a 1-based line and column.
+func (o *offsetReader) position(offset int64) (line, column int) {
+	index := offset - 1
+	if index < 0 {
+		index = 0
+	}
+	// The number of newlines before the offending byte determines its line.
+	before := sort.Search(len(o.newlines), func(i int) bool { return o.newlines[i] >= index })
+	lineStart := int64(0)
+	if before > 0 {
+		lineStart = o.newlines[before-1] + 1
+	}
+	return before + 1, int(index-lineStart) + 1
+}
+
+// wrapParseError turns JSON syntax and type errors into a *ParseError carrying the position
+// in the input read through o. Other errors are returned unchanged.
+func (o *offsetReader) wrapParseError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		line, column := o.position(syntaxErr.Offset)
+		return &ParseError{Line: line, Column: column, Cause: err}
+	case errors.As(err, &typeErr):
+		line, column := o.position(typeErr.Offset)
+		return &ParseError{Line: line, Column: column, Cause: err}
+	default:
+		return err
+	}
+}
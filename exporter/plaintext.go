@@ -0,0 +1,92 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExtractToPlainText converts sessions into a plain-text transcript, for reading in a terminal or a
+// text editor.
+//
+// Each session starts with its topic underlined with equals signs, followed by a line holding the
+// session ID, the model, and the date of the last update when they are known. Each message starts with
+// the role of its sender in brackets, followed by its content. Markdown is rendered lightly: fenced
+// code blocks lose their fences and are indented by four spaces, below a line naming their language,
+// so that code stands out from prose. Sessions are separated by a blank line.
+//
+// To write the transcript to a writer instead of building it in memory, use WritePlainText.
+func ExtractToPlainText(sessions []Session) string {
+	var builder strings.Builder
+	// Writing to a strings.Builder never fails, and the context is never cancelled.
+	_ = WritePlainText(context.Background(), &builder, sessions, ExportOptions{})
+	return builder.String()
+}
+
+// WritePlainText writes the transcript of ExtractToPlainText to w one session at a time, stopping at
+// the next session once ctx is cancelled. When opts.BaseURL is set, the details line of every session
+// ends with a link back to it; see SessionURL.
+//
+// It returns an error if the context is cancelled or writing fails.
+func WritePlainText(ctx context.Context, w io.Writer, sessions []Session, opts ExportOptions) error {
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	builder := &countingWriter{w: contextWriter{ctx: ctx, w: w}}
+	for i, session := range sessions {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		topic := orgHeadingText(session.Topic)
+		if topic == "" {
+			topic = "Untitled Session"
+		}
+		builder.WriteString(topic + "\n" + strings.Repeat("=", utf8.RuneCountInString(topic)) + "\n")
+		details := []string{"ID: " + session.ID}
+		if model := session.Model(); model != "" {
+			details = append(details, "Model: "+model)
+		}
+		if date := summaryDate(session.Timestamp(DateFieldUpdated)); date != "" {
+			details = append(details, "Updated: "+date)
+		}
+		if link := SessionURL(opts.BaseURL, session.ID); link != "" {
+			details = append(details, "URL: "+link)
+		}
+		builder.WriteString(strings.Join(details, " | ") + "\n")
+
+		for _, message := range session.Messages {
+			builder.WriteString("\n[" + orgRoleHeading(message.Role) + "]\n")
+			builder.WriteString(plainTextBody(message.Content))
+		}
+		if builder.err != nil {
+			break
+		}
+	}
+	return builder.err
+}
+
+// plainTextBody renders message content for ExtractToPlainText. The returned string always ends with a
+// newline unless the content is empty.
+func plainTextBody(content string) string {
+	if content == "" {
+		return ""
+	}
+
+	var builder strings.Builder
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		fence := codeFence(lines[i])
+		if fence == "" {
+			builder.WriteString(strings.TrimRight(lines[i], " \t") + "\n")
+			continue
+		}
+		if info := strings.Fields(strings.TrimLeft(strings.TrimSpace(lines[i]), fence[:1])); len(info) > 0 {
+			builder.WriteString("  (" + info[0] + ")\n")
+		}
+		for i++; i < len(lines) && !isClosingFence(lines[i], fence); i++ {
+			builder.WriteString(strings.TrimRight("    "+lines[i], " \t") + "\n")
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// qaRecord is a question and answer pair of the QA JSONL format.
+type qaRecord struct {
+	Question string     `json:"question"`
+	Answer   string     `json:"answer"`
+	Metadata qaMetadata `json:"metadata"`
+}
+
+// qaMetadata identifies the session a qaRecord was taken from.
+type qaMetadata struct {
+	SessionID string `json:"session_id"`
+	Title     string `json:"title"`
+	URL       string `json:"url,omitempty"`
+}
+
+// ConvertSessionsToQAJSONL converts a slice of Session objects into question and answer pairs for
+// building retrieval-augmented generation (RAG) evaluation sets, with one
+// {"question": ..., "answer": ..., "metadata": {"session_id": ..., "title": ...}} record per line.
+//
+// Every user message directly followed by an assistant message becomes a record, where the title is
+// the session topic. System messages are skipped, so they never separate a question from its answer;
+// user messages without a reply and assistant messages without a question are left out. Like
+// ExtractToFineTuningJSONL, only the active branch of each session is used; see ActiveBranch.
+//
+// It returns an error if marshaling a record into JSON fails.
+//
+// To write the records to a writer instead of building them in memory, use WriteQAJSONL.
+func ConvertSessionsToQAJSONL(sessions []Session) (string, error) {
+	var builder strings.Builder
+	if err := WriteQAJSONL(context.Background(), &builder, sessions, ExportOptions{}); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// WriteQAJSONL writes the records of ConvertSessionsToQAJSONL to w one session at a time, stopping at
+// the next session once ctx is cancelled. When opts.BaseURL is set, the metadata of every record holds
+// a "url" linking back to its session; see SessionURL.
+//
+// It returns an error if the context is cancelled, marshaling a record into JSON fails, or writing fails.
+func WriteQAJSONL(ctx context.Context, w io.Writer, sessions []Session, opts ExportOptions) error {
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	builder := &countingWriter{w: contextWriter{ctx: ctx, w: w}}
+	for _, session := range sessions {
+		var question string
+		asked := false
+		for _, message := range ActiveBranch(session.Messages) {
+			switch {
+			case message.Role == RoleSystem:
+				continue
+			case message.Role == RoleAssistant && asked:
+				line, err := json.Marshal(qaRecord{
+					Question: question,
+					Answer:   message.Content,
+					Metadata: qaMetadata{SessionID: session.ID, Title: session.Topic, URL: SessionURL(opts.BaseURL, session.ID)},
+				})
+				if err != nil {
+					return err
+				}
+				builder.Write(line)
+				builder.WriteString("\n")
+				asked = false
+			case message.Role == RoleUser:
+				question, asked = message.Content, true
+			default:
+				asked = false
+			}
+		}
+		if builder.err != nil {
+			break
+		}
+	}
+	return builder.err
+}
@@ -0,0 +1,252 @@
+package exporter
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Names of the built-in redaction rules, as accepted by ParseRedactRules.
+const (
+	RedactKeys       = "keys"        // RedactKeys replaces API keys recognized by their prefix, such as sk-, ghp_, or AKIA.
+	RedactTokens     = "tokens"      // RedactTokens replaces long random-looking base64 or hex strings.
+	RedactCodeBlocks = "code-blocks" // RedactCodeBlocks replaces entire fenced code blocks.
+)
+
+// RedactRule removes one kind of sensitive text from message content before it is exported.
+type RedactRule struct {
+	Name string // Name identifies the rule in a RedactReport.
+
+	// Apply returns content with the sensitive text replaced and the number of replacements made.
+	Apply func(content string) (string, int)
+}
+
+// keyPattern matches API keys of common providers by their well-known prefixes.
+var keyPattern = regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|(?:AKIA|ASIA)[0-9A-Z]{16}\b)`)
+
+// tokenPattern matches candidates for redactHighEntropy: long runs of base64, base64url, or hex characters.
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_-]{32,}={0,2}`)
+
+// minCharsPerSlash is the least number of characters per slash of a token. Base64 draws the slash as one
+// of 64 symbols, so random strings hold few of them, while paths and URLs hold one every few characters.
+const minCharsPerSlash = 16
+
+// Minimum Shannon entropy in bits per character for a candidate to count as a token. Hex strings
+// draw from 16 symbols only, so they get a lower threshold than base64 strings.
+const (
+	minHexEntropy    = 3.0
+	minBase64Entropy = 4.0
+)
+
+// BuiltinRedactRules returns the built-in redaction rules in the order they are applied: code blocks
+// first, so that keys inside them are removed along with the block, then keys, then the remaining
+// tokens, so that a key with a known prefix is reported as a key rather than a token.
+func BuiltinRedactRules() []RedactRule {
+	return []RedactRule{
+		{Name: RedactCodeBlocks, Apply: redactCodeBlocks},
+		{Name: RedactKeys, Apply: redactKeys},
+		{Name: RedactTokens, Apply: redactHighEntropy},
+	}
+}
+
+// ParseRedactRules parses a comma-separated list of built-in rule names, or "all" for every rule,
+// and returns the selected rules in the order of BuiltinRedactRules. An empty list selects none.
+func ParseRedactRules(list string) ([]RedactRule, error) {
+	selected := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "":
+		case "all":
+			selected[RedactKeys], selected[RedactTokens], selected[RedactCodeBlocks] = true, true, true
+		case RedactKeys, RedactTokens, RedactCodeBlocks:
+			selected[name] = true
+		default:
+			return nil, fmt.Errorf("unknown redaction rule %q, expected keys, tokens, code-blocks, or all", name)
+		}
+	}
+	var rules []RedactRule
+	for _, rule := range BuiltinRedactRules() {
+		if selected[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// RedactReport describes what ApplyRedactRules replaced.
+type RedactReport struct {
+	Matches  map[string]int // Matches counts the replacements made by each rule, keyed by rule name.
+	Messages int            // Messages is the number of messages with at least one replacement.
+	Fields   int            // Fields is the number of session topics and memory prompts with at least one replacement.
+}
+
+// ApplyRedactRules applies the rules in order to the content of every message, and to the topic and
+// memory prompt of every session, since both are generated from the messages, and reports what they replaced.
+//
+// The input sessions are not modified; sessions with redacted text are replaced by deep copies.
+func ApplyRedactRules(sessions []Session, rules []RedactRule) ([]Session, RedactReport) {
+	report := RedactReport{Matches: make(map[string]int)}
+	if len(rules) == 0 {
+		return sessions, report
+	}
+
+	result := make([]Session, len(sessions))
+	for i, session := range sessions {
+		copied := false
+		// redact applies the rules to the text at field, copying the session before the first change.
+		redact := func(field func(*Session) *string) bool {
+			text, redacted := applyRedactRules(rules, *field(&session), report.Matches)
+			if !redacted {
+				return false
+			}
+			if !copied {
+				session, copied = session.DeepCopy(), true
+			}
+			*field(&session) = text
+			return true
+		}
+		for j := range session.Messages {
+			if redact(func(s *Session) *string { return &s.Messages[j].Content }) {
+				report.Messages++
+			}
+		}
+		for _, field := range []func(*Session) *string{
+			func(s *Session) *string { return &s.Topic },
+			func(s *Session) *string { return &s.MemoryPrompt },
+		} {
+			if redact(field) {
+				report.Fields++
+			}
+		}
+		result[i] = session
+	}
+	return result, report
+}
+
+// applyRedactRules applies the rules in order to text, adds their replacements to matches, and reports
+// whether any was made.
+func applyRedactRules(rules []RedactRule, text string, matches map[string]int) (string, bool) {
+	redacted := false
+	for _, rule := range rules {
+		var n int
+		if text, n = rule.Apply(text); n > 0 {
+			matches[rule.Name] += n
+			redacted = true
+		}
+	}
+	return text, redacted
+}
+
+// redactKeys replaces API keys with known prefixes.
+func redactKeys(content string) (string, int) {
+	n := 0
+	content = keyPattern.ReplaceAllStringFunc(content, func(string) string {
+		n++
+		return "[redacted key]"
+	})
+	return content, n
+}
+
+// redactHighEntropy replaces long base64 or hex strings that look random. Besides their entropy,
+// base64 candidates must mix upper case, lower case, and digits, and hex candidates digits and
+// letters, so that long words, identifiers, and numbers in ordinary prose are kept. Candidates with
+// a slash every few characters are paths or URLs rather than base64, and are kept as well.
+func redactHighEntropy(content string) (string, int) {
+	n := 0
+	content = tokenPattern.ReplaceAllStringFunc(content, func(candidate string) string {
+		if strings.Count(candidate, "/")*minCharsPerSlash > len(candidate) || !looksLikeToken(strings.TrimRight(candidate, "=")) {
+			return candidate
+		}
+		n++
+		return "[redacted token]"
+	})
+	return content, n
+}
+
+// looksLikeToken reports whether s is a random-looking base64 or hex string; see redactHighEntropy.
+func looksLikeToken(s string) bool {
+	var upper, lower, digit, hex bool
+	hex = true
+	for _, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			hex = false
+		}
+	}
+	if hex {
+		return digit && (upper || lower) && shannonEntropy(s) >= minHexEntropy
+	}
+	return upper && lower && digit && shannonEntropy(s) >= minBase64Entropy
+}
+
+// shannonEntropy returns the Shannon entropy of the characters of s in bits per character.
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// redactCodeBlocks replaces every fenced code block, from its opening ``` or ~~~ fence to the matching
+// closing fence, with a note of how many lines it held. A block that is never closed runs to the end
+// of the content, as in Markdown.
+func redactCodeBlocks(content string) (string, int) {
+	lines := strings.Split(content, "\n")
+	result := make([]string, 0, len(lines))
+	n := 0
+	for i := 0; i < len(lines); i++ {
+		fence := codeFence(lines[i])
+		if fence == "" {
+			result = append(result, lines[i])
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !isClosingFence(lines[end], fence) {
+			end++
+		}
+		result = append(result, fmt.Sprintf("[code block removed: %d lines]", min(end, len(lines))-i-1))
+		n++
+		i = end
+	}
+	if n == 0 {
+		return content, 0
+	}
+	return strings.Join(result, "\n"), n
+}
+
+// codeFence returns the fence that opens a code block on line, such as "```", or "" if there is none.
+func codeFence(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return ""
+	}
+	for _, c := range []string{"`", "~"} {
+		if fence := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, c))]; len(fence) >= 3 {
+			return fence
+		}
+	}
+	return ""
+}
+
+// isClosingFence reports whether line closes a code block opened by fence: a fence of the same
+// character at least as long, with nothing but white space after it.
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
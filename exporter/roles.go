@@ -0,0 +1,161 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Canonical message roles used by the exporter.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// UnknownRolePolicy determines what happens to messages whose role is neither canonical nor a known alias.
+type UnknownRolePolicy int
+
+const (
+	// UnknownRoleKeep keeps unknown roles as they are.
+	UnknownRoleKeep UnknownRolePolicy = iota
+
+	// UnknownRoleMapToUser treats messages with unknown roles as user messages.
+	UnknownRoleMapToUser
+
+	// UnknownRoleDrop removes messages with unknown roles.
+	UnknownRoleDrop
+)
+
+// ParseUnknownRolePolicy parses the name of an UnknownRolePolicy: "keep", "user", or "drop".
+func ParseUnknownRolePolicy(name string) (UnknownRolePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "keep":
+		return UnknownRoleKeep, nil
+	case "user":
+		return UnknownRoleMapToUser, nil
+	case "drop":
+		return UnknownRoleDrop, nil
+	default:
+		return UnknownRoleKeep, fmt.Errorf("unknown role policy %q, expected keep, user, or drop", name)
+	}
+}
+
+// DefaultRoleAliases maps role names found in the wild, including typos from old versions of the
+// web app, to the canonical roles. Keys are lower case.
+var DefaultRoleAliases = map[string]string{
+	"assisant":  RoleAssistant,
+	"assistent": RoleAssistant,
+	"bot":       RoleAssistant,
+	"ai":        RoleAssistant,
+	"model":     RoleAssistant,
+	"gpt":       RoleAssistant,
+	"human":     RoleUser,
+	"developer": RoleSystem,
+	"function":  RoleTool,
+}
+
+// RolePolicy describes how message roles are normalized.
+type RolePolicy struct {
+	Aliases map[string]string // Aliases maps lower case role names to canonical roles; nil means DefaultRoleAliases.
+	Unknown UnknownRolePolicy // Unknown determines what happens to roles that are neither canonical nor aliases.
+}
+
+// RoleReport describes the roles encountered by ApplyRolePolicy and what was done with them.
+type RoleReport struct {
+	RawRoles   map[string]int // RawRoles counts the messages per distinct role value as found in the input.
+	Normalized int            // Normalized is the number of messages whose role was rewritten.
+	Unknown    int            // Unknown is the number of messages whose role was not recognized.
+	Dropped    int            // Dropped is the number of messages removed because of their unknown role.
+}
+
+// DistinctRawRoles returns the distinct role values encountered, sorted alphabetically.
+func (r RoleReport) DistinctRawRoles() []string {
+	roles := make([]string, 0, len(r.RawRoles))
+	for role := range r.RawRoles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// ApplyRolePolicy normalizes the role of every message according to the policy and reports what it did.
+//
+// Roles are matched case-insensitively and with surrounding whitespace removed. Canonical roles and
+// known aliases are rewritten to the canonical spelling; other roles are handled by the unknown-role policy.
+// Applying the policy once, right after loading, guarantees that every export format sees the same roles.
+//
+// The input sessions are not modified; sessions with rewritten or dropped messages get new message slices.
+func ApplyRolePolicy(sessions []Session, policy RolePolicy) ([]Session, RoleReport) {
+	aliases := policy.Aliases
+	if aliases == nil {
+		aliases = DefaultRoleAliases
+	}
+	report := RoleReport{RawRoles: make(map[string]int)}
+
+	result := make([]Session, len(sessions))
+	for i, session := range sessions {
+		if session.Messages == nil {
+			result[i] = session
+			continue
+		}
+		messages := make([]Message, 0, len(session.Messages))
+		for _, message := range session.Messages {
+			report.RawRoles[message.Role]++
+
+			role, known := canonicalRole(message.Role, aliases)
+			if !known {
+				report.Unknown++
+				switch policy.Unknown {
+				case UnknownRoleDrop:
+					report.Dropped++
+					continue
+				case UnknownRoleMapToUser:
+					role = RoleUser
+				default:
+					role = message.Role
+				}
+			}
+			if role != message.Role {
+				report.Normalized++
+				message.Role = role
+			}
+			messages = append(messages, message)
+		}
+		session.Messages = messages
+		result[i] = session
+	}
+	return result, report
+}
+
+// NormalizeRoles rewrites the role of every message to its canonical spelling, using mapping to
+// translate other role names, such as "bot" or "human", to canonical roles. The keys of mapping are
+// matched case-insensitively; a nil mapping means DefaultRoleAliases. Roles that are neither canonical
+// nor mapped are kept as they are; use ApplyRolePolicy to map or drop them instead, or to get a report.
+//
+// The input sessions are not modified.
+func NormalizeRoles(sessions []Session, mapping map[string]string) []Session {
+	var aliases map[string]string
+	if mapping != nil {
+		aliases = make(map[string]string, len(mapping))
+		for role, canonical := range mapping {
+			aliases[strings.ToLower(strings.TrimSpace(role))] = canonical
+		}
+	}
+	normalized, _ := ApplyRolePolicy(sessions, RolePolicy{Aliases: aliases})
+	return normalized
+}
+
+// canonicalRole returns the canonical spelling of role, and whether role is canonical or a known alias.
+func canonicalRole(role string, aliases map[string]string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(role))
+	switch key {
+	case RoleSystem, RoleUser, RoleAssistant, RoleTool:
+		return key, true
+	}
+	if canonical, ok := aliases[key]; ok {
+		return canonical, true
+	}
+	return role, false
+}
@@ -0,0 +1,39 @@
+package exporter
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// SampleSessions returns a uniform random subset of n sessions, chosen by reservoir sampling, so that
+// every session of the store is equally likely to be picked wherever it is. The same seed always picks
+// the same sessions from the same input, which allows a sample to be regenerated.
+//
+// The sampled sessions keep their original order. If n is at least the number of sessions, all of them
+// are returned; if n is not positive, none are. The input slice is not modified.
+func SampleSessions(sessions []Session, n int, seed int64) []Session {
+	if n <= 0 {
+		return []Session{}
+	}
+	if n >= len(sessions) {
+		return append([]Session(nil), sessions...)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	reservoir := make([]int, n)
+	for i := range reservoir {
+		reservoir[i] = i
+	}
+	for i := n; i < len(sessions); i++ {
+		if j := rng.Intn(i + 1); j < n {
+			reservoir[j] = i
+		}
+	}
+	sort.Ints(reservoir)
+
+	sampled := make([]Session, n)
+	for i, index := range reservoir {
+		sampled[i] = sessions[index]
+	}
+	return sampled
+}
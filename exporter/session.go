@@ -11,6 +11,15 @@
 //   - Convert sessions to CSV with different formatting options
 //   - Create separate CSV files for sessions and messages
 //   - Extract sessions to a JSON format for Hugging Face datasets
+//   - Extract sessions to the JSONL format of OpenAI chat fine-tuning jobs
+//   - Parse an exported JSON dataset back into sessions
+//   - Write the formats to any io.Writer, such as a gzip writer or an HTTP response, with WriteSessionsCSV,
+//     WriteDataset, WriteFineTuningJSONL, and the other Write functions
+//   - Extract sessions to Emacs Org-mode documents
+//   - Extract sessions to Notion API block objects
+//   - Export a digest of the session summaries (memoryPrompt) as CSV or Markdown
+//   - Split long sessions into overlapping windows for model context limits
+//   - Normalize message roles with a configurable policy for unknown roles
 //
 // The package also handles fields in the source JSON that may be represented as either
 // strings or integers by using the custom StringOrInt type.
@@ -56,7 +65,7 @@
 //
 // To create separate CSV files for sessions and messages:
 //
-//	err = exporter.CreateSeparateCSVFiles(store.ChatNextWebStore.Sessions, "sessions.csv", "messages.csv")
+//	err = exporter.CreateSeparateCSVFiles(ctx, store.ChatNextWebStore.Sessions, "sessions.csv", "messages.csv", filesystem.RealFileSystem{}, exporter.CSVOptions{})
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
@@ -73,13 +82,22 @@
 package exporter
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -94,8 +112,18 @@ const (
 
 	// OutputFormatSeparateCSVFiles specifies the option to create separate CSV files for sessions and messages.
 	OutputFormatSeparateCSVFiles
+
+	// FormatOptionSessionOnly specifies the format with one row of metadata per session and no messages.
+	FormatOptionSessionOnly
+
+	// FormatOptionTurns specifies the format where each message is on a separate line with the index
+	// of its conversational turn; see GroupIntoTurns.
+	FormatOptionTurns
 )
 
+// sessionOnlyPreviewLength is the number of characters of the first user message kept by FormatOptionSessionOnly.
+const sessionOnlyPreviewLength = 200
+
 // StringOrInt is a custom type to handle JSON values that can be either strings or integers (Magic Golang 🎩 🪄).
 //
 // It implements the Unmarshaler interface to handle this mixed type when unmarshaling JSON data.
@@ -123,11 +151,19 @@ func (soi *StringOrInt) UnmarshalJSON(data []byte) error {
 
 // Message represents a single message within a chat session, including metadata
 // like the ID, date, role of the sender, and the content of the message itself.
+//
+// Stores that keep regenerated answers as branches also record the parent message and the branch
+// of each message; both are empty otherwise. See ActiveBranch.
 type Message struct {
-	ID      string `json:"id"`
-	Date    string `json:"date"`
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	ID       string `json:"id"`
+	Date     string `json:"date"`
+	Role     string `json:"role"`
+	Content  string `json:"content"`
+	ParentID string `json:"parentId,omitempty"`
+	BranchID string `json:"branchId,omitempty"`
+
+	// Estimated reports that Date was not recorded by the web app but estimated by EstimateMessageDates.
+	Estimated bool `json:"estimated,omitempty"`
 }
 
 // Stat represents statistics for a chat session, such as the count of tokens,
@@ -141,11 +177,19 @@ type Stat struct {
 // Mask represents an anonymization mask for a participant in a chat session,
 // including the participant's ID, avatar link, name, language, and creation timestamp.
 type Mask struct {
-	ID        StringOrInt `json:"id"` // Use the custom type for ID
-	Avatar    string      `json:"avatar"`
-	Name      string      `json:"name"`
-	Lang      string      `json:"lang"`
-	CreatedAt int64       `json:"createdAt"` // Assuming it's a Unix timestamp
+	ID          StringOrInt  `json:"id"` // Use the custom type for ID
+	Avatar      string       `json:"avatar"`
+	Name        string       `json:"name"`
+	Lang        string       `json:"lang"`
+	CreatedAt   int64        `json:"createdAt"`             // Assuming it's a Unix timestamp
+	ModelConfig *ModelConfig `json:"modelConfig,omitempty"` // Optional, only present in newer stores
+}
+
+// ModelConfig represents the model settings stored on a mask.
+//
+// Only the fields needed by the exporter are modeled; see the repairdata package for the full structure.
+type ModelConfig struct {
+	Model string `json:"model"`
 }
 
 // Session represents a single chat session, including session metadata,
@@ -159,11 +203,90 @@ type Session struct {
 	LastSummarizeIndex int       `json:"lastSummarizeIndex"`
 	Mask               Mask      `json:"mask"`
 	Messages           []Message `json:"messages"`
+	Tags               []string  `json:"tags,omitempty"` // Tags are set from a tags file by ApplySessionTags; the web app has none.
+}
+
+// DeepCopy returns a copy of the session that shares no mutable memory with s: the messages, the tags,
+// and the model configuration of the mask are copied, so that functions producing modified sessions
+// can change the copy without affecting the original. Strings are immutable and need no copying.
+// Nil slices stay nil, so the copy encodes to the same JSON.
+func (s Session) DeepCopy() Session {
+	c := s
+	c.Messages = slices.Clone(s.Messages)
+	c.Tags = slices.Clone(s.Tags)
+	if s.Mask.ModelConfig != nil {
+		modelConfig := *s.Mask.ModelConfig
+		c.Mask.ModelConfig = &modelConfig
+	}
+	return c
+}
+
+// Model returns the name of the model configured on the session's mask,
+// or an empty string if the store does not record one.
+func (s Session) Model() string {
+	if s.Mask.ModelConfig == nil {
+		return ""
+	}
+	return s.Mask.ModelConfig.Model
+}
+
+// String returns a compact, human-readable summary of the session for use in logs and error messages,
+// for example:
+//
+//	Session{id: "abc123", title: "Go concurrency", model: "gpt-4", messages: 14, created: 2024-01-15}
+//
+// The creation date is taken from the mask's CreatedAt timestamp (Unix milliseconds, shown in UTC)
+// and is reported as "unknown" when the store does not record one.
+func (s Session) String() string {
+	created := "unknown"
+	if s.Mask.CreatedAt > 0 {
+		created = time.UnixMilli(s.Mask.CreatedAt).UTC().Format("2006-01-02")
+	}
+	return fmt.Sprintf("Session{id: %q, title: %q, model: %q, messages: %d, created: %s}",
+		s.ID, s.Topic, s.Model(), len(s.Messages), created)
+}
+
+// GoString returns a Go-syntax representation of the session including all exported fields,
+// which is what the %#v verb prints. The mask's model configuration is printed by value
+// rather than as a pointer address.
+func (s Session) GoString() string {
+	modelConfig := "nil"
+	if s.Mask.ModelConfig != nil {
+		modelConfig = fmt.Sprintf("&%#v", *s.Mask.ModelConfig)
+	}
+	mask := fmt.Sprintf("exporter.Mask{ID:%q, Avatar:%q, Name:%q, Lang:%q, CreatedAt:%d, ModelConfig:%s}",
+		s.Mask.ID, s.Mask.Avatar, s.Mask.Name, s.Mask.Lang, s.Mask.CreatedAt, modelConfig)
+	return fmt.Sprintf("exporter.Session{ID:%q, Topic:%q, MemoryPrompt:%q, Stat:%#v, LastUpdate:%d, LastSummarizeIndex:%d, Mask:%s, Messages:%#v}",
+		s.ID, s.Topic, s.MemoryPrompt, s.Stat, s.LastUpdate, s.LastSummarizeIndex, mask, s.Messages)
+}
+
+// SessionsShape describes how the sessions of a store are laid out in the JSON input.
+type SessionsShape int
+
+const (
+	// SessionsShapeArray is the usual layout, where the sessions are stored as an array.
+	SessionsShapeArray SessionsShape = iota
+
+	// SessionsShapeObject is the layout of some forks of the web app, which store the sessions
+	// in an object keyed by session ID.
+	SessionsShapeObject
+)
+
+// String returns "array" or "object".
+func (s SessionsShape) String() string {
+	if s == SessionsShapeObject {
+		return "object"
+	}
+	return "array"
 }
 
 // Store encapsulates a collection of chat sessions.
 type Store struct {
 	Sessions []Session `json:"sessions"`
+
+	// Shape records the layout the sessions were read from by ReadJSONFromReader. It is not written
+	// back: stores are always marshaled with the sessions as an array.
+	Shape SessionsShape `json:"-"`
 }
 
 // ChatNextWebStore is a wrapper for Store that aligns with the expected JSON structure
@@ -177,27 +300,88 @@ type ChatNextWebStore struct {
 // It returns an error if the file cannot be opened, the JSON
 // is invalid, or the JSON format does not match the expected ChatNextWebStore format.
 func ReadJSONFromFile(filePath string) (ChatNextWebStore, error) {
-	// Variable `store` is of type ChatNextWebStore. It is used to store the unmarshaled JSON data.
-	var store ChatNextWebStore
+	return ReadJSONFromFileAs(filePath, InputFormatAuto)
+}
 
+// ReadJSONFromFileAs is like ReadJSONFromFile, but decodes the file as the given input format; see
+// ReadJSONFromReaderAs.
+func ReadJSONFromFileAs(filePath string, format InputFormat) (ChatNextWebStore, error) {
 	// Variable `file` is of type *os.File. It holds the pointer to the opened JSON file.
 	// Variable `err` is of type error. It is used to capture any errors that occur during the file opening and JSON decoding process.
 	file, err := os.Open(filePath)
 	if err != nil {
-		// If an error occurs while opening the file, the function returns the empty `store` and the error.
-		return store, err
+		// If an error occurs while opening the file, the function returns an empty store and the error.
+		return ChatNextWebStore{}, err
 	}
 	// Defer the closing of the file until the function exits.
 	// This ensures that the file is closed properly to free resources and avoid leaks.
 	defer file.Close()
 
-	// Variable `decoder` is of type *json.Decoder. It is used to decode the JSON file into the `store` struct.
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&store)
+	return ReadJSONFromReaderAs(file, format)
+}
+
+// ReadJSONFromReader decodes JSON from the given reader into a ChatNextWebStore struct.
+//
+// The sessions may be stored as an array or, as some forks of the web app do, as an object keyed by
+// session ID; see SessionsShape. Sessions read from an object are ordered by their last update, newest
+// first like in the web app, then by ID and key, and sessions without an ID take their key.
+//
+// Message content that is not valid UTF-8 in the input, as found in corrupted exports, is kept as it is
+// rather than replaced with U+FFFD, so that ApplyInvalidUTF8Policy can decide what happens to it.
+//
+// It returns an error if the JSON is invalid or does not match the expected ChatNextWebStore format.
+// Syntax and type errors are returned as a *ParseError holding the line and column of the bad input.
+// This allows the input to come from any source, such as a FileSystem implementation or a network stream.
+func ReadJSONFromReader(r io.Reader) (ChatNextWebStore, error) {
+	return ReadJSONFromReaderAs(r, InputFormatAuto)
+}
+
+// ReadJSONFromReaderAs is like ReadJSONFromReader, but decodes the sessions with the layout of the given
+// input format instead of detecting it, for input the detection gets wrong. InputFormatAuto detects the
+// layout like ReadJSONFromReader.
+//
+// When a format other than InputFormatAuto is given and the input cannot be decoded, the error names the
+// format that was assumed; a *ParseError can still be reached with errors.As.
+func ReadJSONFromReaderAs(r io.Reader, format InputFormat) (ChatNextWebStore, error) {
+	store, err := readStore(r, format)
+	if err != nil && err != io.EOF && format != InputFormatAuto {
+		err = fmt.Errorf("reading input as %s format: %w", format, err)
+	}
+	return store, err
+}
+
+// readStore decodes a store as the given input format for ReadJSONFromReaderAs.
+func readStore(r io.Reader, format InputFormat) (ChatNextWebStore, error) {
+	// Variable `store` is of type ChatNextWebStore. It is used to store the unmarshaled JSON data.
+	var store ChatNextWebStore
+
+	// The input is read through an offsetReader so that errors can be reported with a line and column.
+	// It is kept in memory, as the JSON decoder would do anyway, so that it can be decoded a second
+	// time when the sessions turn out to be stored as an object.
+	input := &offsetReader{r: r}
+	data, err := io.ReadAll(input)
 	if err != nil {
-		// If an error occurs during decoding, the function returns the empty `store` and the error.
 		return store, err
 	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return store, io.EOF
+	}
+	var keys []string
+	if format == InputFormatNextWebObject {
+		store, keys, err = decodeSessionsObject(data)
+	} else {
+		err = json.Unmarshal(data, &store)
+		if format == InputFormatAuto && isSessionsObjectError(err) {
+			store, keys, err = decodeSessionsObject(data)
+		}
+	}
+	if err == nil && !utf8.Valid(data) {
+		err = restoreInvalidUTF8(data, &store, keys)
+	}
+	if err != nil {
+		// If an error occurs during decoding, the function returns the empty `store` and the error.
+		return store, input.wrapParseError(err)
+	}
 
 	// Check if the `Sessions` field in `store.ChatNextWebStore` is nil, which indicates the JSON was not in the expected format.
 	if store.ChatNextWebStore.Sessions == nil {
@@ -209,12 +393,66 @@ func ReadJSONFromFile(filePath string) (ChatNextWebStore, error) {
 	return store, nil
 }
 
+// isSessionsObjectError reports whether err is the type error of decoding sessions stored as an object.
+func isSessionsObjectError(err error) bool {
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &typeErr) && typeErr.Value == "object" && typeErr.Field == "chat-next-web-store.sessions"
+}
+
+// decodeSessionsObject decodes a store whose sessions are stored as an object keyed by session ID,
+// in the order documented by ReadJSONFromReader. It also returns the keys of the sessions in that order.
+func decodeSessionsObject(data []byte) (ChatNextWebStore, []string, error) {
+	var object struct {
+		ChatNextWebStore struct {
+			Sessions map[string]Session `json:"sessions"`
+		} `json:"chat-next-web-store"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return ChatNextWebStore{}, nil, err
+	}
+
+	byKey := object.ChatNextWebStore.Sessions
+	if byKey == nil {
+		// Without sessions, the store does not match the expected format.
+		return ChatNextWebStore{}, nil, nil
+	}
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	// Keys are unique, so ordering by key last makes the order independent of the map iteration.
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := byKey[keys[i]], byKey[keys[j]]
+		if a.LastUpdate != b.LastUpdate {
+			return a.LastUpdate > b.LastUpdate
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return keys[i] < keys[j]
+	})
+
+	sessions := make([]Session, 0, len(keys))
+	for _, key := range keys {
+		session := byKey[key]
+		if session.ID == "" {
+			session.ID = key
+		}
+		sessions = append(sessions, session)
+	}
+	return ChatNextWebStore{ChatNextWebStore: Store{Sessions: sessions, Shape: SessionsShapeObject}}, keys, nil
+}
+
 // ConvertSessionsToCSV writes a slice of Session objects into a CSV file with support for context cancellation.
 //
 // It delegates the writing of sessions to format-specific functions based on the formatOption provided.
 //
 // The outputFilePath parameter specifies the path to the output CSV file.
 //
+// The CSV formats are lossy: session statistics, timestamps, and the mask are not written,
+// and the inline format additionally drops message IDs. Use ExtractToDataset when the export
+// needs to be read back; see ParseDatasetJSON.
+//
 // It returns an error if the context is cancelled, the format option is invalid, or writing to the CSV fails.
 func ConvertSessionsToCSV(ctx context.Context, sessions []Session, formatOption int, outputFilePath string) error {
 	outputFile, err := os.Create(outputFilePath)
@@ -223,10 +461,69 @@ func ConvertSessionsToCSV(ctx context.Context, sessions []Session, formatOption
 	}
 	defer outputFile.Close()
 
-	csvWriter := csv.NewWriter(outputFile)
-	defer csvWriter.Flush()
+	// The csv.Writer only buffers 4 KiB, so a larger buffer saves most of the write calls on big exports.
+	buffered := bufio.NewWriterSize(outputFile, csvFileBufferSize)
+	if err := WriteSessionsCSV(ctx, buffered, sessions, formatOption, CSVOptions{}); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
 
-	headers, err := getCSVHeaders(formatOption)
+// csvFileBufferSize is the size of the write buffer ConvertSessionsToCSV puts in front of the output file.
+const csvFileBufferSize = 64 * 1024
+
+// CSVOptions holds optional settings for the CSV writers.
+type CSVOptions struct {
+	// BaseURL is the address of the ChatGPT-Next-Web deployment the sessions come from.
+	// When set, a column with a link to each session is added; see SessionURL.
+	BaseURL string
+
+	// PrettyJSONInCells indents the messages JSON embedded in cells by FormatOptionJSON with two spaces.
+	// This makes the CSV human-readable at the cost of a larger file; the multi-line cells are quoted
+	// and escaped as usual by the CSV writer.
+	PrettyJSONInCells bool
+
+	// IncludeBranches adds a "branch_id" column to the message-level formats, the per-line format and
+	// the messages file of WriteSeparateCSV, to tell apart the branches of sessions exported with all
+	// of their branches; see ApplyBranchPolicy.
+	IncludeBranches bool
+
+	// DateField selects the timestamp used for date columns, such as the date of WriteSummariesCSV.
+	DateField DateField
+
+	// IncludeEstimated adds an "estimated" column to the formats with a row per message, the per-line
+	// and turns formats and the messages file of WriteSeparateCSV, telling whether the date of each
+	// message was estimated; see EstimateMessageDates.
+	IncludeEstimated bool
+
+	// IncludeTags adds a "tags" column with the comma-separated tags of each session to the formats of
+	// WriteSessionsCSV and the sessions file of WriteSeparateCSV; see ApplySessionTags.
+	IncludeTags bool
+
+	// IncludeEmptySessions writes a row with empty message columns for each session without messages
+	// in the per-line format, which otherwise writes no row for it, so that every session appears in
+	// the output; see SkipEmptySessions.
+	IncludeEmptySessions bool
+
+	// Encoding is the character encoding of the CSV output, for legacy tools that cannot read UTF-8;
+	// the zero Encoding writes UTF-8. Unencodable determines what happens to characters it cannot
+	// represent; see EncodingWriter.
+	Encoding    Encoding
+	Unencodable UnencodablePolicy
+}
+
+// WriteSessionsCSV writes a slice of Session objects as CSV to the provided writer with support for context cancellation.
+//
+// It behaves like ConvertSessionsToCSV but lets the caller decide where the output goes,
+// for example an in-memory buffer that is later saved through a FileSystem implementation.
+//
+// When opts.BaseURL is set, a "url" column ("session_url" for the per-line format) is appended to every row.
+//
+// It returns an error if the context is cancelled, the format option is invalid, or writing to the CSV fails.
+func WriteSessionsCSV(ctx context.Context, w io.Writer, sessions []Session, formatOption int, opts CSVOptions) error {
+	csvWriter := newCSVWriter(w, opts)
+
+	headers, err := sessionsCSVHeaders(formatOption, opts)
 	if err != nil {
 		return err
 	}
@@ -235,22 +532,55 @@ func ConvertSessionsToCSV(ctx context.Context, sessions []Session, formatOption
 		return err
 	}
 
+	return writeSessionRows(ctx, csvWriter, sessions, formatOption, opts)
+}
+
+// sessionsCSVHeaders returns the headers WriteSessionsCSV writes for the format option and CSV options.
+func sessionsCSVHeaders(formatOption int, opts CSVOptions) ([]string, error) {
+	headers, err := getCSVHeaders(formatOption)
+	if err != nil {
+		return nil, err
+	}
+	if opts.IncludeBranches && formatOption == FormatOptionPerLine {
+		headers = append(headers, "branch_id")
+	}
+	if opts.IncludeEstimated && (formatOption == FormatOptionPerLine || formatOption == FormatOptionTurns) {
+		headers = append(headers, "estimated")
+	}
+	if opts.IncludeTags {
+		headers = append(headers, "tags")
+	}
+	if opts.BaseURL != "" {
+		if formatOption == FormatOptionPerLine || formatOption == FormatOptionTurns {
+			headers = append(headers, "session_url")
+		} else {
+			headers = append(headers, "url")
+		}
+	}
+	return headers, nil
+}
+
+// writeSessionRows writes the rows of the sessions in the given format, without headers, and flushes the writer.
+func writeSessionRows(ctx context.Context, csvWriter *csv.Writer, sessions []Session, formatOption int, opts CSVOptions) error {
 	writeFunc, err := getWriteFunction(formatOption)
 	if err != nil {
 		return err
 	}
 
+	var buffer rowBuffer
 	for _, session := range sessions {
 		if err := checkContextCancellation(ctx); err != nil {
+			csvWriter.Flush()
 			return err
 		}
 
-		if err := writeFunc(csvWriter, session); err != nil {
+		if err := writeFunc(csvWriter, session, opts, &buffer); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	csvWriter.Flush()
+	return csvWriter.Error()
 }
 
 // getCSVHeaders returns the headers for the CSV file based on the formatOption.
@@ -263,15 +593,37 @@ func getCSVHeaders(formatOption int) ([]string, error) {
 		return []string{"session_id", "message_id", "date", "role", "content", "memoryPrompt"}, nil
 	case FormatOptionJSON:
 		return []string{"id", "topic", "memoryPrompt", "messages"}, nil
+	case FormatOptionSessionOnly:
+		return []string{"session_id", "title", "model", "message_count", "first_user_message", "created_at"}, nil
+	case FormatOptionTurns:
+		return []string{"session_id", "turn_index", "message_id", "date", "role", "content"}, nil
 	default:
 		return nil, fmt.Errorf("invalid format option")
 	}
 }
 
+// rowBuffer holds the memory reused for the rows of every session written by writeSessionRows,
+// so that converting a large store does not allocate a new row and cell per session.
+type rowBuffer struct {
+	record []string // record is the row passed to the csv.Writer.
+	cell   []byte   // cell holds the text of the inline messages cell.
+}
+
+// row sets the reused record to fields.
+func (b *rowBuffer) row(fields ...string) {
+	b.record = append(b.record[:0], fields...)
+}
+
+// add appends a field to the record set by row, for the optional columns.
+func (b *rowBuffer) add(field string) {
+	b.record = append(b.record, field)
+}
+
 // getWriteFunction returns a function that corresponds to the CSV writing strategy for the given formatOption.
-// The returned function takes a csv.Writer and a Session object to write the session data according to the format.
+// The returned function takes a csv.Writer, a Session object, the CSV options, and the buffer reused
+// across sessions to write the session data according to the format.
 // It returns an error if the formatOption is not recognized.
-func getWriteFunction(formatOption int) (func(*csv.Writer, Session) error, error) {
+func getWriteFunction(formatOption int) (func(*csv.Writer, Session, CSVOptions, *rowBuffer) error, error) {
 	switch formatOption {
 	case FormatOptionInline:
 		return writeInlineFormat, nil
@@ -279,6 +631,10 @@ func getWriteFunction(formatOption int) (func(*csv.Writer, Session) error, error
 		return writePerLineFormat, nil
 	case FormatOptionJSON:
 		return writeJSONFormat, nil
+	case FormatOptionSessionOnly:
+		return writeSessionOnlyFormat, nil
+	case FormatOptionTurns:
+		return writeTurnsFormat, nil
 	default:
 		return nil, fmt.Errorf("invalid format option")
 	}
@@ -287,21 +643,78 @@ func getWriteFunction(formatOption int) (func(*csv.Writer, Session) error, error
 // writeInlineFormat writes session data in an inline format to the provided csv.Writer.
 // Messages are concatenated into a single string with a delimiter.
 // It returns an error if writing to the CSV fails.
-func writeInlineFormat(csvWriter *csv.Writer, session Session) error {
-	var messageContents []string
-	for _, message := range session.Messages {
-		messageContents = append(messageContents, fmt.Sprintf("[%s, %s] \"%s\"", message.Role, message.Date, message.Content))
+func writeInlineFormat(csvWriter *csv.Writer, session Session, opts CSVOptions, buffer *rowBuffer) error {
+	buffer.cell = appendInlineMessages(buffer.cell[:0], session.Messages)
+	messages := string(buffer.cell)
+	buffer.row(session.ID, session.Topic, session.MemoryPrompt, messages)
+	if opts.IncludeTags {
+		buffer.add(strings.Join(session.Tags, ","))
+	}
+	if opts.BaseURL != "" {
+		buffer.add(SessionURL(opts.BaseURL, session.ID))
+	}
+	return csvWriter.Write(buffer.record)
+}
+
+// inlineMessageOverhead is the number of bytes inlineMessages adds around the fields of a message:
+// the brackets, comma, quotes, and the "; " delimiter.
+const inlineMessageOverhead = len(`[, ] ""; `)
+
+// inlineMessages joins messages into the `[role, date] "content"; ...` cell of the inline format.
+func inlineMessages(messages []Message) string {
+	return string(appendInlineMessages(nil, messages))
+}
+
+// appendInlineMessages appends the inline messages cell to dst and returns the extended slice.
+// A first pass over the messages sizes dst, so it grows at most once.
+func appendInlineMessages(dst []byte, messages []Message) []byte {
+	estimatedSize := 0
+	for _, message := range messages {
+		estimatedSize += len(message.Role) + len(message.Date) + len(message.Content) + inlineMessageOverhead
+	}
+	dst = slices.Grow(dst, estimatedSize)
+
+	for i, message := range messages {
+		if i > 0 {
+			dst = append(dst, "; "...)
+		}
+		dst = append(dst, '[')
+		dst = append(dst, message.Role...)
+		dst = append(dst, ", "...)
+		dst = append(dst, message.Date...)
+		dst = append(dst, "] \""...)
+		dst = append(dst, message.Content...)
+		dst = append(dst, '"')
 	}
-	sessionData := []string{session.ID, session.Topic, session.MemoryPrompt, strings.Join(messageContents, "; ")}
-	return csvWriter.Write(sessionData)
+	return dst
 }
 
 // writePerLineFormat writes each message of a session on a new line in the provided csv.Writer.
 // It returns an error if writing to the CSV fails.
-func writePerLineFormat(csvWriter *csv.Writer, session Session) error {
-	for _, message := range session.Messages {
-		sessionData := []string{session.ID, message.ID, message.Date, message.Role, message.Content, session.MemoryPrompt}
-		if err := csvWriter.Write(sessionData); err != nil {
+func writePerLineFormat(csvWriter *csv.Writer, session Session, opts CSVOptions, buffer *rowBuffer) error {
+	// The link and the tags are the same for every message of the session.
+	sessionURL := SessionURL(opts.BaseURL, session.ID)
+	tags := strings.Join(session.Tags, ",")
+	messages := session.Messages
+	if len(messages) == 0 && opts.IncludeEmptySessions {
+		// A zero message leaves the message columns of the header-only row empty.
+		messages = []Message{{}}
+	}
+	for _, message := range messages {
+		buffer.row(session.ID, message.ID, message.Date, message.Role, message.Content, session.MemoryPrompt)
+		if opts.IncludeBranches {
+			buffer.add(message.BranchID)
+		}
+		if opts.IncludeEstimated {
+			buffer.add(strconv.FormatBool(message.Estimated))
+		}
+		if opts.IncludeTags {
+			buffer.add(tags)
+		}
+		if opts.BaseURL != "" {
+			buffer.add(sessionURL)
+		}
+		if err := csvWriter.Write(buffer.record); err != nil {
 			return err
 		}
 	}
@@ -309,14 +722,98 @@ func writePerLineFormat(csvWriter *csv.Writer, session Session) error {
 }
 
 // writeJSONFormat writes session data with messages as a JSON string to the provided csv.Writer.
+// The JSON is compact unless opts.PrettyJSONInCells is set.
 // It returns an error if marshaling messages to JSON or writing to the CSV fails.
-func writeJSONFormat(csvWriter *csv.Writer, session Session) error {
-	messagesJSON, err := json.Marshal(session.Messages)
+func writeJSONFormat(csvWriter *csv.Writer, session Session, opts CSVOptions, buffer *rowBuffer) error {
+	var messagesJSON []byte
+	var err error
+	if opts.PrettyJSONInCells {
+		// The indented JSON spans several lines, so the csv.Writer always quotes the cell.
+		messagesJSON, err = json.MarshalIndent(session.Messages, "", "  ")
+	} else {
+		messagesJSON, err = json.Marshal(session.Messages)
+	}
 	if err != nil {
 		return err
 	}
-	sessionData := []string{session.ID, session.Topic, session.MemoryPrompt, string(messagesJSON)}
-	return csvWriter.Write(sessionData)
+	messages := string(messagesJSON)
+	buffer.row(session.ID, session.Topic, session.MemoryPrompt, messages)
+	if opts.IncludeTags {
+		buffer.add(strings.Join(session.Tags, ","))
+	}
+	if opts.BaseURL != "" {
+		buffer.add(SessionURL(opts.BaseURL, session.ID))
+	}
+	return csvWriter.Write(buffer.record)
+}
+
+// writeSessionOnlyFormat writes a single row of metadata for the session to the provided csv.Writer:
+// its ID, topic, model, number of messages, the start of its first user message, and its creation time.
+// It returns an error if writing to the CSV fails.
+func writeSessionOnlyFormat(csvWriter *csv.Writer, session Session, opts CSVOptions, buffer *rowBuffer) error {
+	firstUserMessage := ""
+	for _, message := range session.Messages {
+		if message.Role == RoleUser {
+			firstUserMessage = truncateRunes(message.Content, sessionOnlyPreviewLength)
+			break
+		}
+	}
+	buffer.row(session.ID, session.Topic, session.Model(), strconv.Itoa(len(session.Messages)), firstUserMessage, createdAt(session))
+	if opts.IncludeTags {
+		buffer.add(strings.Join(session.Tags, ","))
+	}
+	if opts.BaseURL != "" {
+		buffer.add(SessionURL(opts.BaseURL, session.ID))
+	}
+	return csvWriter.Write(buffer.record)
+}
+
+// writeTurnsFormat writes each message of a session on a new line in the provided csv.Writer, with the
+// index of the turn GroupIntoTurns puts it in.
+// It returns an error if writing to the CSV fails.
+func writeTurnsFormat(csvWriter *csv.Writer, session Session, opts CSVOptions, buffer *rowBuffer) error {
+	sessionURL := SessionURL(opts.BaseURL, session.ID)
+	tags := strings.Join(session.Tags, ",")
+	for _, turn := range GroupIntoTurns([]Session{session})[0].Turns {
+		index := strconv.Itoa(turn.Index)
+		for _, message := range turn.Messages {
+			buffer.row(session.ID, index, message.ID, message.Date, message.Role, message.Content)
+			if opts.IncludeEstimated {
+				buffer.add(strconv.FormatBool(message.Estimated))
+			}
+			if opts.IncludeTags {
+				buffer.add(tags)
+			}
+			if opts.BaseURL != "" {
+				buffer.add(sessionURL)
+			}
+			if err := csvWriter.Write(buffer.record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// truncateRunes returns the first n characters of s.
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// createdAt returns the creation time of the session in RFC 3339 format, or an empty string if it is
+// unknown. Like Session.Timestamp, it falls back to the last update.
+func createdAt(session Session) string {
+	created := session.Timestamp(DateFieldCreated)
+	if created <= 0 {
+		return ""
+	}
+	return time.UnixMilli(created).UTC().Format(time.RFC3339)
 }
 
 // checkContextCancellation checks if the context has been cancelled.
@@ -353,11 +850,24 @@ func WriteSessionData(csvWriter *csv.Writer, sessions []Session) error {
 
 // WriteMessageData writes message data to the provided csv.Writer.
 func WriteMessageData(csvWriter *csv.Writer, sessions []Session) error {
+	return writeMessageRows(csvWriter, sessions, CSVOptions{})
+}
+
+// writeMessageRows writes one row per message to the provided csv.Writer,
+// followed by the branch of the message when opts.IncludeBranches is set and whether its date was
+// estimated when opts.IncludeEstimated is set.
+func writeMessageRows(csvWriter *csv.Writer, sessions []Session, opts CSVOptions) error {
 	for _, session := range sessions {
 		for _, message := range session.Messages {
 			messageData := []string{
 				session.ID, message.ID, message.Date, message.Role, message.Content, session.MemoryPrompt,
 			}
+			if opts.IncludeBranches {
+				messageData = append(messageData, message.BranchID)
+			}
+			if opts.IncludeEstimated {
+				messageData = append(messageData, strconv.FormatBool(message.Estimated))
+			}
 			if err := csvWriter.Write(messageData); err != nil {
 				return fmt.Errorf("failed to write message data: %w", err)
 			}
@@ -366,96 +876,220 @@ func WriteMessageData(csvWriter *csv.Writer, sessions []Session) error {
 	return nil
 }
 
-// initializeCSVFile creates and initializes a CSV file with the given name and headers.
-func initializeCSVFile(fileName string, headers []string) (*os.File, *csv.Writer, error) {
-	file, err := os.Create(fileName)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create file %s: %w", fileName, err)
-	}
-
-	csvWriter := csv.NewWriter(file)
-
-	if err := WriteHeaders(csvWriter, headers); err != nil {
-		file.Close() // ignore error; we're already handling an error
-		return nil, nil, err
-	}
+// FileWriter saves files. It is the subset of filesystem.FileSystem needed by the exporter, declared
+// here because the filesystem package itself depends on the exporter.
+type FileWriter interface {
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
 
-	return file, csvWriter, nil
+// FileStreamer is implemented by file writers that can stream a file instead of saving it at once, such
+// as the view of filesystem.Atomic. StreamFile saves the data write produces as the named file, and
+// saves nothing if write returns an error. CreateSeparateCSVFiles uses it when available.
+type FileStreamer interface {
+	StreamFile(name string, perm fs.FileMode, write func(w io.Writer) error) error
 }
 
-// closeCSVWriter closes the csv.Writer and the underlying file, and checks for errors.
-func closeCSVWriter(csvWriter *csv.Writer, file *os.File) error {
-	csvWriter.Flush()
-	if err := csvWriter.Error(); err != nil {
-		file.Close() // ignore error; we're already handling an error
-		return fmt.Errorf("failed to flush data: %w", err)
+// streamFile saves the data write produces as the named file of fsys: streamed if fsys implements
+// FileStreamer, otherwise rendered in memory and saved with WriteFile.
+func streamFile(fsys FileWriter, name string, write func(w io.Writer) error) error {
+	if streamer, ok := fsys.(FileStreamer); ok {
+		return streamer.StreamFile(name, 0644, write)
 	}
-
-	if err := file.Close(); err != nil {
-		return fmt.Errorf("failed to close file: %w", err)
+	var output bytes.Buffer
+	if err := write(&output); err != nil {
+		return err
 	}
-
-	return nil
+	return fsys.WriteFile(name, output.Bytes(), 0644)
 }
 
 // CreateSeparateCSVFiles creates two separate CSV files for sessions and messages from a slice of Session objects.
 //
-// It takes the file names as parameters and returns an error if the files cannot be created or if writing the data fails.
-//
-// Errors from closing files or flushing data to the CSV writers are captured and will be returned after all operations are attempted.
+// The files hold the same data as the outputs of WriteSeparateCSV, so opts applies to them in the same
+// way. Each is streamed to fsys if it implements FileStreamer, and rendered in memory and saved with
+// WriteFile otherwise. The sessions file is saved first; nothing is saved of a file whose rendering fails.
 //
-// Error messages are logged to the console.
-func CreateSeparateCSVFiles(sessions []Session, sessionsFileName string, messagesFileName string) (err error) {
-	// Create and initialize the sessions CSV file.
-	var sessionsFile *os.File
-	var sessionsWriter *csv.Writer
-	sessionsFile, sessionsWriter, err = initializeCSVFile(sessionsFileName, []string{"id", "topic", "memoryPrompt"})
+// It returns an error if the context is cancelled or writing the data or saving either file fails.
+func CreateSeparateCSVFiles(ctx context.Context, sessions []Session, sessionsFileName, messagesFileName string, fsys FileWriter, opts CSVOptions) error {
+	err := streamFile(fsys, sessionsFileName, func(w io.Writer) error {
+		return writeSessionsFileCSV(ctx, w, sessions, opts)
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to write file %s: %w", sessionsFileName, err)
 	}
-	defer func() {
-		if cerr := closeCSVWriter(sessionsWriter, sessionsFile); cerr != nil {
-			err = cerr
-		}
-	}()
+	err = streamFile(fsys, messagesFileName, func(w io.Writer) error {
+		return writeMessagesFileCSV(ctx, w, sessions, opts)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %w", messagesFileName, err)
+	}
+	return nil
+}
 
-	// Write session data.
-	if err = WriteSessionData(sessionsWriter, sessions); err != nil {
+// WriteSeparateCSV writes the sessions CSV and the messages CSV of a slice of Session objects
+// to the two provided writers, using the same layout as CreateSeparateCSVFiles.
+// When opts.BaseURL is set, a "url" column is appended to the sessions CSV, and when opts.IncludeBranches
+// is set, a "branch_id" column is appended to the messages CSV, followed by an "estimated" column when
+// opts.IncludeEstimated is set. opts.IncludeTags adds a "tags" column
+// to the sessions CSV.
+//
+// It returns an error if the context is cancelled or writing the data to either writer fails.
+func WriteSeparateCSV(ctx context.Context, sessionsOutput io.Writer, messagesOutput io.Writer, sessions []Session, opts CSVOptions) error {
+	if err := writeSessionsFileCSV(ctx, sessionsOutput, sessions, opts); err != nil {
 		return err
 	}
+	return writeMessagesFileCSV(ctx, messagesOutput, sessions, opts)
+}
 
-	// Create and initialize the messages CSV file.
-	var messagesFile *os.File
-	var messagesWriter *csv.Writer
-	messagesFile, messagesWriter, err = initializeCSVFile(messagesFileName, []string{"session_id", "message_id", "date", "role", "content", "memoryPrompt"})
-	if err != nil {
+// writeSessionsFileCSV writes the sessions CSV of WriteSeparateCSV to w.
+func writeSessionsFileCSV(ctx context.Context, w io.Writer, sessions []Session, opts CSVOptions) error {
+	sessionsWriter := newCSVWriter(w, opts)
+	sessionHeaders := []string{"id", "topic", "memoryPrompt"}
+	if opts.IncludeTags {
+		sessionHeaders = append(sessionHeaders, "tags")
+	}
+	if opts.BaseURL != "" {
+		sessionHeaders = append(sessionHeaders, "url")
+	}
+	if err := WriteHeaders(sessionsWriter, sessionHeaders); err != nil {
 		return err
 	}
-	defer func() {
-		if cerr := closeCSVWriter(messagesWriter, messagesFile); cerr != nil {
-			err = cerr
+	for _, session := range sessions {
+		if err := checkContextCancellation(ctx); err != nil {
+			return err
+		}
+		sessionData := []string{session.ID, session.Topic, session.MemoryPrompt}
+		if opts.IncludeTags {
+			sessionData = append(sessionData, strings.Join(session.Tags, ","))
 		}
-	}()
+		if err := sessionsWriter.Write(withURLColumn(sessionData, opts.BaseURL, session.ID)); err != nil {
+			return fmt.Errorf("failed to write session data: %w", err)
+		}
+	}
+	sessionsWriter.Flush()
+	if err := sessionsWriter.Error(); err != nil {
+		return fmt.Errorf("failed to flush data: %w", err)
+	}
+	return nil
+}
 
-	// Write message data.
-	if err = WriteMessageData(messagesWriter, sessions); err != nil {
+// writeMessagesFileCSV writes the messages CSV of WriteSeparateCSV to w.
+func writeMessagesFileCSV(ctx context.Context, w io.Writer, sessions []Session, opts CSVOptions) error {
+	messagesWriter := newCSVWriter(w, opts)
+	messageHeaders := []string{"session_id", "message_id", "date", "role", "content", "memoryPrompt"}
+	if opts.IncludeBranches {
+		messageHeaders = append(messageHeaders, "branch_id")
+	}
+	if opts.IncludeEstimated {
+		messageHeaders = append(messageHeaders, "estimated")
+	}
+	if err := WriteHeaders(messagesWriter, messageHeaders); err != nil {
 		return err
 	}
-
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	if err := writeMessageRows(messagesWriter, sessions, opts); err != nil {
+		return err
+	}
+	messagesWriter.Flush()
+	if err := messagesWriter.Error(); err != nil {
+		return fmt.Errorf("failed to flush data: %w", err)
+	}
 	return nil
 }
 
 // ExtractToDataset converts a slice of Session objects into a JSON formatted string suitable for use as a dataset in machine learning applications.
 //
+// Every field of the sessions is preserved, so the result can be read back with ParseDatasetJSON.
+// To stream a large dataset to a writer instead of building it in memory, use WriteDataset.
+//
 // It returns an error if marshaling the sessions into JSON format fails.
 func ExtractToDataset(sessions []Session) (string, error) {
-	dataset := make(map[string][]Session)
-	dataset["dataset"] = sessions
+	return ExtractToDatasetWithOptions(sessions, ExportOptions{})
+}
 
-	jsonData, err := json.MarshalIndent(dataset, "", "  ")
-	if err != nil {
-		return "", err
+// ParseDatasetJSON parses the output of ExtractToDataset back into a slice of Session objects.
+//
+// The dataset format is lossless, so sessions written by ExtractToDataset are reproduced exactly.
+// Output of ExtractToDatasetWithOptions is accepted as well; the extra "url" field and the session
+// header records of ExportOptions.IncludeSessionMetadata are ignored.
+// This lets users who only kept the exported dataset get back to the original structures.
+//
+// It returns an error if the data is not valid JSON or has no "dataset" array.
+func ParseDatasetJSON(data []byte) ([]Session, error) {
+	var dataset struct {
+		Dataset []struct {
+			Type string `json:"type"`
+			Session
+		} `json:"dataset"`
+	}
+	if err := json.Unmarshal(data, &dataset); err != nil {
+		return nil, err
 	}
+	if dataset.Dataset == nil {
+		return nil, fmt.Errorf("JSON does not match the expected dataset format")
+	}
+	sessions := make([]Session, 0, len(dataset.Dataset))
+	for _, record := range dataset.Dataset {
+		if record.Type != sessionHeaderType {
+			sessions = append(sessions, record.Session)
+		}
+	}
+	return sessions, nil
+}
+
+// ExportOptions holds optional settings for the dataset, fine-tuning, and text exports.
+type ExportOptions struct {
+	// BaseURL is the address of the ChatGPT-Next-Web deployment the sessions come from.
+	// When set, every session carries a "url" field linking back to it; see SessionURL.
+	BaseURL string
+
+	// IncludeWeight adds a per-message "weight" to the examples written by ExtractToFineTuningJSONL.
+	IncludeWeight bool
+
+	// WeightFunction computes the weight of a message when IncludeWeight is set; nil means DefaultWeight.
+	WeightFunction func(m Message) float64
 
-	return string(jsonData), nil
+	// JSONArray makes ConvertSessions write the JSON Lines formats, finetune and qa-jsonl, as one JSON
+	// array instead; see WriteJSONLAsArray.
+	JSONArray bool
+
+	// IncludeSessionMetadata precedes every session of a dataset with a sessionHeader record, so that
+	// consumers reading the records in order can use it as a boundary marker between sessions.
+	IncludeSessionMetadata bool
+}
+
+// sessionHeaderType is the "type" of the records written by ExportOptions.IncludeSessionMetadata.
+const sessionHeaderType = "session_header"
+
+// sessionHeader is the metadata record of a session written by ExportOptions.IncludeSessionMetadata.
+type sessionHeader struct {
+	Type      string `json:"type"`       // Type is always sessionHeaderType.
+	ID        string `json:"id"`         // ID is the session ID.
+	Title     string `json:"title"`      // Title is the session topic.
+	Model     string `json:"model"`      // Model is the model of the session, empty if not recorded.
+	CreatedAt string `json:"created_at"` // CreatedAt is the creation time in RFC 3339 format, empty if unknown.
+}
+
+// newSessionHeader returns the metadata record of the session.
+func newSessionHeader(session Session) sessionHeader {
+	return sessionHeader{Type: sessionHeaderType, ID: session.ID, Title: session.Topic, Model: session.Model(), CreatedAt: createdAt(session)}
+}
+
+// linkedSession is a Session with a link back to the live conversation.
+type linkedSession struct {
+	Session
+	URL string `json:"url,omitempty"`
+}
+
+// ExtractToDatasetWithOptions behaves like ExtractToDataset, but adds the metadata described by opts
+// to every session in the dataset.
+//
+// It returns an error if marshaling the sessions into JSON format fails.
+func ExtractToDatasetWithOptions(sessions []Session, opts ExportOptions) (string, error) {
+	var builder strings.Builder
+	if _, err := (Dataset{Sessions: sessions, Options: opts}).WriteTo(&builder); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
 }
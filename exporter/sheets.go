@@ -0,0 +1,78 @@
+package exporter
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/uploader"
+)
+
+// sheetsMaxCellLength is the maximum number of characters Google Sheets accepts in a single cell.
+const sheetsMaxCellLength = 50000
+
+// sheetsTruncationMarker ends the cells ExtractToSheetValues shortened to sheetsMaxCellLength.
+const sheetsTruncationMarker = " [truncated]"
+
+// ExtractToSheetValues converts a slice of Session objects into the rows of a spreadsheet, holding the
+// same header and cells as the CSV written by WriteSessionsCSV with FormatOptionInline and opts.
+//
+// Google Sheets rejects cells of more than 50,000 characters, so longer cells, typically the messages
+// of long sessions, are cut short and end with " [truncated]".
+//
+// The result can be written to a Google spreadsheet with the GoogleSheetsUploader from the uploader package.
+//
+// It returns an error if the context is cancelled.
+func ExtractToSheetValues(ctx context.Context, sessions []Session, opts CSVOptions) ([][]string, error) {
+	headers, err := sessionsCSVHeaders(FormatOptionInline, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sessions)+1)
+	rows = append(rows, headers)
+	for _, session := range sessions {
+		if err := checkContextCancellation(ctx); err != nil {
+			return nil, err
+		}
+		row := []string{session.ID, session.Topic, session.MemoryPrompt, inlineMessages(session.Messages)}
+		row = withURLColumn(row, opts.BaseURL, session.ID)
+		for i, cell := range row {
+			row[i] = truncateSheetCell(cell)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// truncateSheetCell shortens cell to sheetsMaxCellLength characters, including the truncation marker.
+func truncateSheetCell(cell string) string {
+	if utf8.RuneCountInString(cell) <= sheetsMaxCellLength {
+		return cell
+	}
+	keep := sheetsMaxCellLength - utf8.RuneCountInString(sheetsTruncationMarker)
+	return string([]rune(cell)[:keep]) + sheetsTruncationMarker
+}
+
+// ExtractToGoogleSheets writes the sessions into a new sheet named "Sessions YYYY-MM-DD", after the
+// current date, of the Google spreadsheet spreadsheetID. The sheet holds the rows of ExtractToSheetValues
+// with the default CSVOptions. creds is the JSON key of a service account the spreadsheet is shared with.
+//
+// It returns an error if the key is invalid, a sheet of that name already exists, the context is
+// cancelled, or the Google Sheets API rejects a request.
+func ExtractToGoogleSheets(ctx context.Context, sessions []Session, spreadsheetID string, creds []byte) error {
+	return ExtractToGoogleSheetsWithOptions(ctx, sessions, spreadsheetID, creds, CSVOptions{})
+}
+
+// ExtractToGoogleSheetsWithOptions is ExtractToGoogleSheets with the cells built according to opts.
+func ExtractToGoogleSheetsWithOptions(ctx context.Context, sessions []Session, spreadsheetID string, creds []byte, opts CSVOptions) error {
+	sheets, err := uploader.NewGoogleSheetsUploader(creds)
+	if err != nil {
+		return err
+	}
+	rows, err := ExtractToSheetValues(ctx, sessions, opts)
+	if err != nil {
+		return err
+	}
+	return sheets.Upload(ctx, spreadsheetID, uploader.SessionsSheetTitle(time.Now()), rows)
+}
@@ -0,0 +1,39 @@
+package exporter
+
+// SkipReport accounts for every session of a store in an export: each session is either exported or
+// left out for exactly one of the reasons counted here, so Input always equals Exported plus Skipped.
+type SkipReport struct {
+	Input        int `json:"input"`        // Input is the number of sessions read from the store.
+	Exported     int `json:"exported"`     // Exported is the number of sessions passed to the output format.
+	Deduplicated int `json:"deduplicated"` // Deduplicated is the number of sessions dropped by DuplicateIDKeepNewest.
+	Filtered     int `json:"filtered"`     // Filtered is the number of sessions not matching the SessionFilter.
+	Empty        int `json:"empty"`        // Empty is the number of sessions without messages removed by SkipEmptySessions.
+	Unchanged    int `json:"unchanged"`    // Unchanged is the number of sessions left out of an incremental export.
+	NotSampled   int `json:"not_sampled"`  // NotSampled is the number of sessions left out by SampleSessions.
+}
+
+// Skipped returns the number of sessions left out for any reason.
+func (r SkipReport) Skipped() int {
+	return r.Deduplicated + r.Filtered + r.Empty + r.Unchanged + r.NotSampled
+}
+
+// Balanced reports whether the counts add up, that is, whether every input session was either
+// exported or skipped for a single reason.
+func (r SkipReport) Balanced() bool {
+	return r.Input == r.Exported+r.Skipped()
+}
+
+// SkipEmptySessions returns the sessions that have at least one message in their original order,
+// together with the number of sessions removed. Sessions created in the web app but never used have
+// no messages, as do sessions whose messages were all dropped by ApplyInvalidUTF8Policy,
+// ApplyBranchPolicy, or ApplyRolePolicy, so it should run after them.
+// The input slice is not modified.
+func SkipEmptySessions(sessions []Session) ([]Session, int) {
+	result := make([]Session, 0, len(sessions))
+	for _, session := range sessions {
+		if len(session.Messages) > 0 {
+			result = append(result, session)
+		}
+	}
+	return result, len(sessions) - len(result)
+}
@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"fmt"
+)
+
+// SplitSummary reports what SplitSessions did.
+type SplitSummary struct {
+	SplitSessions int // SplitSessions is the number of input sessions that were split.
+	Parts         int // Parts is the total number of parts those sessions were split into.
+}
+
+// SplitSessions splits sessions with more than maxMessages messages into overlapping windows
+// of at most maxMessages messages, which is useful when sessions exceed a model's context limit.
+//
+// Consecutive windows share up to overlap messages. Message order is kept, and a user message is
+// never separated from the assistant reply that immediately follows it; to honor this a window may
+// hold one message more than maxMessages when maxMessages is too small to fit the pair.
+//
+// Each part of a split session is a new session with the ID suffixed by "-partN" and the topic
+// suffixed by "(part N/M)". Sessions that fit into a single window are returned unchanged.
+//
+// It returns an error if maxMessages is less than 1 or overlap is not smaller than maxMessages.
+func SplitSessions(sessions []Session, maxMessages int, overlap int) ([]Session, SplitSummary, error) {
+	var summary SplitSummary
+	if maxMessages < 1 {
+		return nil, summary, fmt.Errorf("maxMessages must be at least 1, got %d", maxMessages)
+	}
+	if overlap < 0 || overlap >= maxMessages {
+		return nil, summary, fmt.Errorf("overlap must be between 0 and %d, got %d", maxMessages-1, overlap)
+	}
+
+	result := make([]Session, 0, len(sessions))
+	for _, session := range sessions {
+		windows := splitWindows(session.Messages, maxMessages, overlap)
+		if len(windows) <= 1 {
+			result = append(result, session)
+			continue
+		}
+
+		summary.SplitSessions++
+		summary.Parts += len(windows)
+		for i, window := range windows {
+			part := session
+			part.ID = fmt.Sprintf("%s-part%d", session.ID, i+1)
+			part.Topic = fmt.Sprintf("%s (part %d/%d)", session.Topic, i+1, len(windows))
+			part.Messages = session.Messages[window[0]:window[1]]
+			result = append(result, part.DeepCopy())
+		}
+	}
+	return result, summary, nil
+}
+
+// splitWindows returns the [start, end) index ranges of the windows a message list is split into.
+func splitWindows(messages []Message, maxMessages int, overlap int) [][2]int {
+	n := len(messages)
+	if n <= maxMessages {
+		return [][2]int{{0, n}}
+	}
+
+	var windows [][2]int
+	start := 0
+	for {
+		end := start + maxMessages
+		if end >= n {
+			windows = append(windows, [2]int{start, n})
+			return windows
+		}
+		// Never cut between a user message and the assistant reply that follows it.
+		if isReplyPair(messages, end-1) {
+			if end-1 > start {
+				end--
+			} else {
+				end++
+			}
+		}
+		windows = append(windows, [2]int{start, end})
+		if end >= n {
+			return windows
+		}
+
+		next := end - overlap
+		// Do not start a window with a reply whose question was left in the previous window.
+		if next > 0 && isReplyPair(messages, next-1) {
+			next--
+		}
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+}
+
+// isReplyPair reports whether messages[i] is a user message immediately followed by an assistant reply.
+func isReplyPair(messages []Message, i int) bool {
+	return i >= 0 && i+1 < len(messages) && messages[i].Role == "user" && messages[i+1].Role == "assistant"
+}
@@ -0,0 +1,208 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConflictPolicy determines how MergeStores resolves sessions that diverged in both stores.
+type ConflictPolicy int
+
+const (
+	// ConflictAsk leaves every conflict to the Resolve function of the StoreMergeOptions, which
+	// typically asks the user.
+	ConflictAsk ConflictPolicy = iota
+
+	// ConflictPreferNewest keeps the version updated last, or the left one if both were updated at
+	// the same time.
+	ConflictPreferNewest
+
+	// ConflictPreferLeft always keeps the version of the left store.
+	ConflictPreferLeft
+
+	// ConflictPreferRight always keeps the version of the right store.
+	ConflictPreferRight
+
+	// ConflictKeepBoth keeps both versions, giving the right one a new ID with a numeric suffix.
+	ConflictKeepBoth
+)
+
+// ParseConflictPolicy parses the name of a ConflictPolicy: "ask", "prefer-newest", "prefer-left",
+// "prefer-right", or "keep-both".
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "ask":
+		return ConflictAsk, nil
+	case "prefer-newest":
+		return ConflictPreferNewest, nil
+	case "prefer-left":
+		return ConflictPreferLeft, nil
+	case "prefer-right":
+		return ConflictPreferRight, nil
+	case "keep-both":
+		return ConflictKeepBoth, nil
+	default:
+		return ConflictAsk, fmt.Errorf("unknown conflict policy %q, expected ask, prefer-newest, prefer-left, prefer-right, or keep-both", name)
+	}
+}
+
+// ConflictChoice is the version of a conflicting session kept by MergeStores.
+type ConflictChoice int
+
+const (
+	KeepLeft  ConflictChoice = iota // KeepLeft keeps the version of the left store.
+	KeepRight                       // KeepRight keeps the version of the right store.
+	KeepBoth                        // KeepBoth keeps both versions, the right one under a new ID.
+)
+
+// StoreConflict holds the two versions of a session whose messages diverged in both stores, so that
+// neither is a continuation of the other.
+type StoreConflict struct {
+	Left  Session // Left is the session as found in the left store.
+	Right Session // Right is the session as found in the right store.
+}
+
+// StoreMergeOptions controls how MergeStores resolves conflicts.
+type StoreMergeOptions struct {
+	Policy ConflictPolicy // Policy resolves every conflict, unless it is ConflictAsk.
+
+	// Resolve chooses the version to keep of each conflict with ConflictAsk, in the order of the left
+	// store. An error aborts the merge.
+	Resolve func(conflict StoreConflict) (ConflictChoice, error)
+}
+
+// StoreMergeReport describes how MergeStores combined the sessions of two stores.
+type StoreMergeReport struct {
+	Unchanged     int `json:"unchanged"`      // Unchanged counts the sessions with the same messages in both stores.
+	LeftOnly      int `json:"left_only"`      // LeftOnly counts the sessions only found in the left store.
+	RightOnly     int `json:"right_only"`     // RightOnly counts the sessions only found in the right store.
+	FastForwarded int `json:"fast_forwarded"` // FastForwarded counts the sessions continued in one store only, whose longer version was kept.
+	KeptLeft      int `json:"kept_left"`      // KeptLeft counts the conflicts resolved with the left version.
+	KeptRight     int `json:"kept_right"`     // KeptRight counts the conflicts resolved with the right version.
+	KeptBoth      int `json:"kept_both"`      // KeptBoth counts the conflicts resolved by keeping both versions.
+}
+
+// Conflicts returns the number of sessions that diverged in both stores.
+func (r StoreMergeReport) Conflicts() int {
+	return r.KeptLeft + r.KeptRight + r.KeptBoth
+}
+
+// errNoResolver is returned by MergeStores for a conflict with ConflictAsk but no Resolve function.
+var errNoResolver = errors.New("a conflict needs to be resolved, but no conflict policy or resolver was given")
+
+// MergeStores combines the sessions of two stores, such as backups of two devices, matching them by ID
+// with DiffStores.
+//
+// Sessions found in one store only are kept. Of a session with the same messages in both stores, the
+// version updated last is kept, so that a topic renamed on one device survives. If the messages of
+// one version start with all messages of the other, the conversation was only continued on one side,
+// and the longer version is kept. The remaining sessions diverged in both stores; each of them is
+// resolved by the policy or, with ConflictAsk, by opts.Resolve.
+//
+// The merged store holds the sessions of the left store in their order, each followed by the right
+// version of a conflict resolved with KeepBoth, and then the sessions only found in the right store.
+// Neither store is modified. It returns an error if resolving a conflict fails.
+func MergeStores(left, right *ChatNextWebStore, opts StoreMergeOptions) (ChatNextWebStore, StoreMergeReport, error) {
+	var report StoreMergeReport
+	diff := DiffStores(left, right)
+	report.RightOnly = len(diff.Added)
+
+	rightSessions := make(map[string]Session)
+	if right != nil {
+		for _, session := range right.ChatNextWebStore.Sessions {
+			rightSessions[session.ID] = session
+		}
+	}
+	modified := make(map[string]bool, len(diff.Modified))
+	for _, session := range diff.Modified {
+		modified[session.After.ID] = true
+	}
+
+	var merged []Session
+	if left != nil {
+		for _, leftSession := range left.ChatNextWebStore.Sessions {
+			rightSession, ok := rightSessions[leftSession.ID]
+			switch {
+			case !ok:
+				report.LeftOnly++
+				merged = append(merged, leftSession)
+			case !modified[leftSession.ID]:
+				report.Unchanged++
+				merged = append(merged, newerSession(leftSession, rightSession))
+			case continues(rightSession.Messages, leftSession.Messages):
+				report.FastForwarded++
+				merged = append(merged, rightSession)
+			case continues(leftSession.Messages, rightSession.Messages):
+				report.FastForwarded++
+				merged = append(merged, leftSession)
+			default:
+				choice, err := resolveConflict(StoreConflict{Left: leftSession, Right: rightSession}, opts)
+				if err != nil {
+					return ChatNextWebStore{}, report, fmt.Errorf("session %q: %w", leftSession.ID, err)
+				}
+				switch choice {
+				case KeepRight:
+					report.KeptRight++
+					merged = append(merged, rightSession)
+				case KeepBoth:
+					report.KeptBoth++
+					merged = append(merged, leftSession, rightSession)
+				default:
+					report.KeptLeft++
+					merged = append(merged, leftSession)
+				}
+			}
+		}
+	}
+	merged = append(merged, diff.Added...)
+
+	// Both versions of a conflict kept with KeepBoth share an ID; the right one is renamed.
+	merged, _, _ = ResolveDuplicateIDs(merged, DuplicateIDKeepBoth)
+	var store ChatNextWebStore
+	store.ChatNextWebStore.Sessions = merged
+	return store, report, nil
+}
+
+// resolveConflict chooses the version of a conflict to keep according to the options.
+func resolveConflict(conflict StoreConflict, opts StoreMergeOptions) (ConflictChoice, error) {
+	switch opts.Policy {
+	case ConflictPreferNewest:
+		if conflict.Right.Timestamp(DateFieldUpdated) > conflict.Left.Timestamp(DateFieldUpdated) {
+			return KeepRight, nil
+		}
+		return KeepLeft, nil
+	case ConflictPreferLeft:
+		return KeepLeft, nil
+	case ConflictPreferRight:
+		return KeepRight, nil
+	case ConflictKeepBoth:
+		return KeepBoth, nil
+	}
+	if opts.Resolve == nil {
+		return KeepLeft, errNoResolver
+	}
+	return opts.Resolve(conflict)
+}
+
+// newerSession returns the session updated last of two versions, or left if both were updated at the same time.
+func newerSession(left, right Session) Session {
+	if right.Timestamp(DateFieldUpdated) > left.Timestamp(DateFieldUpdated) {
+		return right
+	}
+	return left
+}
+
+// continues reports whether longer starts with every message of shorter, compared by role and content
+// like MessagesHash, and adds at least one message.
+func continues(longer, shorter []Message) bool {
+	if len(longer) <= len(shorter) {
+		return false
+	}
+	for i, message := range shorter {
+		if longer[i].Role != message.Role || longer[i].Content != message.Content {
+			return false
+		}
+	}
+	return true
+}
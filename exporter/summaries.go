@@ -0,0 +1,129 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteSummariesCSV writes a digest of the sessions as CSV to the provided writer: one row per session
+// with its ID, topic, date, and memoryPrompt, the rolling summary ChatGPT-Next-Web keeps of the
+// conversation. Messages are not included.
+//
+// The date is the day of the timestamp selected by opts.DateField in UTC, left empty when the store records
+// neither timestamp.
+// Sessions without a summary have an empty memoryPrompt cell. When opts.BaseURL is set, a "url" column
+// is appended to every row.
+//
+// It returns an error if the context is cancelled or writing to the CSV fails.
+func WriteSummariesCSV(ctx context.Context, w io.Writer, sessions []Session, opts CSVOptions) error {
+	csvWriter := newCSVWriter(w, opts)
+	headers := []string{"id", "topic", "date", "memoryPrompt"}
+	if opts.BaseURL != "" {
+		headers = append(headers, "url")
+	}
+	if err := WriteHeaders(csvWriter, headers); err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := checkContextCancellation(ctx); err != nil {
+			return err
+		}
+		record := []string{session.ID, session.Topic, summaryDate(session.Timestamp(opts.DateField)), session.MemoryPrompt}
+		if err := csvWriter.Write(withURLColumn(record, opts.BaseURL, session.ID)); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// ExtractToSummariesMarkdown converts the summaries of a slice of Session objects into a Markdown digest.
+//
+// Each session becomes a level-2 heading titled with the session topic, followed by its ID and date
+// and the memoryPrompt as a paragraph. The paragraph is omitted for sessions without a summary.
+// Topics and summaries are escaped with escapeMarkdown, so they are shown as written, except that
+// fenced code blocks in summaries are kept verbatim and render as code.
+// The date is taken from the timestamp selected by field. Messages are not included.
+//
+// To write the digest to a writer instead of building it in memory, use WriteSummariesMarkdown.
+func ExtractToSummariesMarkdown(sessions []Session, field DateField) string {
+	var builder strings.Builder
+	// Writing to a strings.Builder never fails, and the context is never cancelled.
+	_ = WriteSummariesMarkdown(context.Background(), &builder, sessions, CSVOptions{DateField: field})
+	return builder.String()
+}
+
+// WriteSummariesMarkdown writes the digest of ExtractToSummariesMarkdown to w one session at a time,
+// stopping at the next session once ctx is cancelled. The date is taken from the timestamp selected by
+// opts.DateField, and when opts.BaseURL is set, every session lists a link back to it; see SessionURL.
+//
+// It returns an error if the context is cancelled or writing fails.
+func WriteSummariesMarkdown(ctx context.Context, w io.Writer, sessions []Session, opts CSVOptions) error {
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	builder := &countingWriter{w: contextWriter{ctx: ctx, w: w}}
+	builder.WriteString("# Session Summaries\n")
+	for _, session := range sessions {
+		topic := session.Topic
+		if topic == "" {
+			topic = "Untitled Session"
+		}
+		builder.WriteString("\n## " + escapeMarkdown(strings.ReplaceAll(topic, "\n", " ")) + "\n\n")
+		builder.WriteString("- ID: `" + session.ID + "`\n")
+		if date := summaryDate(session.Timestamp(opts.DateField)); date != "" {
+			builder.WriteString("- Date: " + date + "\n")
+		}
+		if link := SessionURL(opts.BaseURL, session.ID); link != "" {
+			builder.WriteString("- URL: " + link + "\n")
+		}
+		if summary := strings.TrimSpace(session.MemoryPrompt); summary != "" {
+			builder.WriteString("\n" + escapeMarkdownText(summary) + "\n")
+		}
+		if builder.err != nil {
+			break
+		}
+	}
+	return builder.err
+}
+
+// markdownEscaper puts a backslash before every character with a meaning in Markdown.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `{`, `\{`, `}`, `\}`, `[`, `\[`, `]`, `\]`,
+	`(`, `\(`, `)`, `\)`, `#`, `\#`, `+`, `\+`, `-`, `\-`, `.`, `\.`, `!`, `\!`, `|`, `\|`,
+)
+
+// escapeMarkdown escapes the Markdown syntax characters in s, including the pipe of table syntax,
+// so that text taken from the sessions cannot turn into headings, lists, code, links, or tables.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// escapeMarkdownText escapes multi-line text like escapeMarkdown, but passes fenced code blocks,
+// from the opening ``` or ~~~ fence with its optional language to the closing fence, through
+// verbatim. Escaping them would show the backslashes inside the code and break the fences.
+func escapeMarkdownText(s string) string {
+	lines := strings.Split(s, "\n")
+	for i := 0; i < len(lines); i++ {
+		fence := codeFence(lines[i])
+		if fence == "" {
+			lines[i] = escapeMarkdown(lines[i])
+			continue
+		}
+		i++
+		for i < len(lines) && !isClosingFence(lines[i], fence) {
+			i++
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// summaryDate formats a Unix millisecond timestamp as a UTC date, or returns "" for a zero timestamp.
+func summaryDate(millis int64) string {
+	if millis <= 0 {
+		return ""
+	}
+	return time.UnixMilli(millis).UTC().Format("2006-01-02")
+}
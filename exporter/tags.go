@@ -0,0 +1,159 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SessionTags holds the tags of sessions keyed by session ID, as saved in a tags file next to the
+// store. The store itself is never changed, so tags survive re-exports of the web app's data.
+type SessionTags map[string][]string
+
+// ParseSessionTags parses a tags file previously encoded as JSON, normalizing every tag like ParseTags.
+//
+// It returns an error if the data is not a JSON object of string arrays.
+func ParseSessionTags(data []byte) (SessionTags, error) {
+	var tags SessionTags
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		return nil, fmt.Errorf("JSON does not match the expected tags format")
+	}
+	for id, sessionTags := range tags {
+		tags.Set(id, sessionTags)
+	}
+	return tags, nil
+}
+
+// Set replaces the tags of the session with the given ID. Tags are normalized like ParseTags,
+// and a session without tags is removed.
+func (t SessionTags) Set(id string, tags []string) {
+	normalized := ParseTags(strings.Join(tags, ","))
+	if len(normalized) == 0 {
+		delete(t, id)
+		return
+	}
+	t[id] = normalized
+}
+
+// ParseTags splits tags entered by a user at commas and white space. Tags are lower-cased, and the
+// result is sorted without duplicates, so the same tags always produce the same output.
+func ParseTags(input string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	sort.Strings(fields)
+	tags := fields[:0]
+	for i, tag := range fields {
+		if i == 0 || tag != fields[i-1] {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ApplySessionTags returns the sessions with the Tags field set from the tags file, so that every
+// export format carries them. Sessions missing from the tags file have no tags.
+// The input slice is not modified.
+func ApplySessionTags(sessions []Session, tags SessionTags) []Session {
+	result := make([]Session, len(sessions))
+	for i, session := range sessions {
+		session.Tags = tags[session.ID]
+		result[i] = session
+	}
+	return result
+}
+
+// SessionFilter selects sessions by their tags; see ParseSessionFilter. The exclusion fields remove
+// the sessions matching any of them, even when they match Tags.
+type SessionFilter struct {
+	Tags []string // Tags lists the tags a session must all carry.
+
+	ExcludeModels      []string // ExcludeModels lists models whose sessions are removed, compared case-insensitively.
+	ExcludeSessionIDs  []string // ExcludeSessionIDs lists the IDs of sessions that are removed.
+	ExcludeSearchQuery string   // ExcludeSearchQuery removes sessions whose topic or messages contain it, ignoring case.
+}
+
+// IsZero reports whether the filter has no terms, so that it matches every session.
+func (f SessionFilter) IsZero() bool {
+	return len(f.Tags) == 0 && len(f.ExcludeModels) == 0 && len(f.ExcludeSessionIDs) == 0 && f.ExcludeSearchQuery == ""
+}
+
+// ParseSessionFilter parses a filter expression of terms separated by white space, such as
+// "tag:work tag:2024". A session matches if it matches every term. The only kind of term is
+// "tag:<name>", matching sessions that carry the tag; tag names are compared case-insensitively.
+//
+// It returns an error if a term is not understood.
+func ParseSessionFilter(expr string) (SessionFilter, error) {
+	var filter SessionFilter
+	for _, term := range strings.Fields(expr) {
+		name, tag, found := strings.Cut(term, ":")
+		if !found || !strings.EqualFold(name, "tag") || tag == "" {
+			return SessionFilter{}, fmt.Errorf("unknown filter term %q, expected tag:<name>", term)
+		}
+		filter.Tags = append(filter.Tags, strings.ToLower(tag))
+	}
+	return filter, nil
+}
+
+// Match reports whether the session matches the filter. The zero filter matches every session.
+func (f SessionFilter) Match(session Session) bool {
+	if f.excludes(session) {
+		return false
+	}
+	for _, want := range f.Tags {
+		found := false
+		for _, tag := range session.Tags {
+			if strings.EqualFold(tag, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// excludes reports whether the session matches one of the exclusion fields of the filter.
+func (f SessionFilter) excludes(session Session) bool {
+	for _, model := range f.ExcludeModels {
+		if strings.EqualFold(session.Model(), model) {
+			return true
+		}
+	}
+	for _, id := range f.ExcludeSessionIDs {
+		if session.ID == id {
+			return true
+		}
+	}
+	if f.ExcludeSearchQuery == "" {
+		return false
+	}
+	query := strings.ToLower(f.ExcludeSearchQuery)
+	if strings.Contains(strings.ToLower(session.Topic), query) {
+		return true
+	}
+	for _, message := range session.Messages {
+		if strings.Contains(strings.ToLower(message.Content), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterSessions returns the sessions matching the filter in their original order.
+func FilterSessions(sessions []Session, filter SessionFilter) []Session {
+	matched := make([]Session, 0, len(sessions))
+	for _, session := range sessions {
+		if filter.Match(session) {
+			matched = append(matched, session)
+		}
+	}
+	return matched
+}
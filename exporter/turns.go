@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// Turn is a conversational turn: a user prompt together with the messages answering it.
+type Turn struct {
+	Index    int       `json:"turn_index"` // Index numbers the turns of a session from 1; turn 0 holds the messages before the first user message.
+	Messages []Message `json:"messages"`   // Messages lists the messages of the turn in their original order.
+}
+
+// SessionTurns holds the turns of a session, as returned by GroupIntoTurns.
+type SessionTurns struct {
+	SessionID string `json:"session_id"`    // SessionID is the ID of the session.
+	Topic     string `json:"topic"`         // Topic is the topic of the session.
+	URL       string `json:"url,omitempty"` // URL links back to the session when WriteTurnsJSON is given a base URL.
+	Turns     []Turn `json:"turns"`         // Turns lists the turns of the session in order.
+}
+
+// GroupIntoTurns groups the messages of every session into turns, which matches how people reason
+// about a conversation better than a flat list of messages.
+//
+// A turn starts with a user message and holds every following message up to the next user message,
+// so several consecutive assistant messages, such as a reply continued after hitting the length limit,
+// belong to the same turn, and so do system and tool messages in between. Consecutive user messages
+// form a single prompt and start a single turn. Messages before the first user message, such as a
+// system prompt or an assistant greeting, form turn 0, which is omitted if there are none.
+//
+// The messages are grouped as they are; to group only the active branch, apply ApplyBranchPolicy first.
+func GroupIntoTurns(sessions []Session) []SessionTurns {
+	result := make([]SessionTurns, len(sessions))
+	for i, session := range sessions {
+		turns := []Turn{}
+		previousRole := ""
+		for _, message := range session.Messages {
+			startsTurn := message.Role == RoleUser && previousRole != RoleUser
+			if len(turns) == 0 && !startsTurn {
+				turns = append(turns, Turn{Index: 0})
+			}
+			if startsTurn {
+				index := 1
+				if len(turns) > 0 {
+					index = turns[len(turns)-1].Index + 1
+				}
+				turns = append(turns, Turn{Index: index})
+			}
+			last := &turns[len(turns)-1]
+			last.Messages = append(last.Messages, message)
+			previousRole = message.Role
+		}
+		result[i] = SessionTurns{SessionID: session.ID, Topic: session.Topic, Turns: turns}
+	}
+	return result
+}
+
+// WriteTurnsJSON writes the sessions grouped into turns by GroupIntoTurns to w as an indented JSON array.
+// When opts.BaseURL is set, every session carries a "url" linking back to it; see SessionURL.
+//
+// It returns an error if the context is cancelled or encoding or writing the JSON fails.
+func WriteTurnsJSON(ctx context.Context, w io.Writer, sessions []Session, opts ExportOptions) error {
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	turns := GroupIntoTurns(sessions)
+	for i := range turns {
+		turns[i].URL = SessionURL(opts.BaseURL, turns[i].SessionID)
+	}
+	encoder := json.NewEncoder(contextWriter{ctx: ctx, w: w})
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(turns)
+}
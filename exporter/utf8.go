@@ -0,0 +1,218 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// InvalidUTF8Policy determines what ApplyInvalidUTF8Policy does with messages whose content is not valid UTF-8.
+type InvalidUTF8Policy int
+
+const (
+	// InvalidUTF8Sanitize replaces every invalid byte with U+FFFD and keeps the message.
+	InvalidUTF8Sanitize InvalidUTF8Policy = iota
+
+	// InvalidUTF8Skip drops the message.
+	InvalidUTF8Skip
+
+	// InvalidUTF8Error refuses to export a store with such messages.
+	InvalidUTF8Error
+)
+
+// ParseInvalidUTF8Policy parses the name of an InvalidUTF8Policy: "sanitize", "skip", or "error".
+func ParseInvalidUTF8Policy(name string) (InvalidUTF8Policy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "sanitize":
+		return InvalidUTF8Sanitize, nil
+	case "skip":
+		return InvalidUTF8Skip, nil
+	case "error":
+		return InvalidUTF8Error, nil
+	default:
+		return InvalidUTF8Sanitize, fmt.Errorf("unknown invalid UTF-8 policy %q, expected sanitize, skip, or error", name)
+	}
+}
+
+// InvalidUTF8Message identifies a message whose content is not valid UTF-8.
+type InvalidUTF8Message struct {
+	SessionID string // SessionID is the ID of the session holding the message.
+	MessageID string // MessageID is the ID of the message.
+}
+
+// InvalidUTF8Report describes the messages found by ApplyInvalidUTF8Policy and what was done with them.
+type InvalidUTF8Report struct {
+	Messages []InvalidUTF8Message // Messages lists the messages with invalid content in store order.
+	Skipped  int                  // Skipped is the number of messages removed by InvalidUTF8Skip.
+}
+
+// ApplyInvalidUTF8Policy finds the messages whose content is not valid UTF-8, such as binary data
+// pasted into a chat or bytes mangled by a broken export, and handles them according to the policy.
+// Only ReadJSONFromReader keeps such content as it is; see there.
+//
+// The input slice is not modified. It returns an error naming the first such message if the policy
+// is InvalidUTF8Error and any were found.
+func ApplyInvalidUTF8Policy(sessions []Session, policy InvalidUTF8Policy) ([]Session, InvalidUTF8Report, error) {
+	var report InvalidUTF8Report
+	for _, session := range sessions {
+		for _, message := range session.Messages {
+			if !utf8.ValidString(message.Content) {
+				report.Messages = append(report.Messages, InvalidUTF8Message{SessionID: session.ID, MessageID: message.ID})
+			}
+		}
+	}
+	if len(report.Messages) == 0 {
+		return sessions, report, nil
+	}
+	if policy == InvalidUTF8Error {
+		first := report.Messages[0]
+		return nil, report, fmt.Errorf("%d message(s) are not valid UTF-8, the first is message %q of session %q", len(report.Messages), first.MessageID, first.SessionID)
+	}
+
+	result := make([]Session, len(sessions))
+	for i, session := range sessions {
+		messages := make([]Message, 0, len(session.Messages))
+		for _, message := range session.Messages {
+			if !utf8.ValidString(message.Content) {
+				if policy == InvalidUTF8Skip {
+					report.Skipped++
+					continue
+				}
+				message.Content = sanitizeUTF8(message.Content)
+			}
+			messages = append(messages, message)
+		}
+		session.Messages = messages
+		result[i] = session
+	}
+	return result, report, nil
+}
+
+// sanitizeUTF8 replaces every invalid byte of s with U+FFFD, like encoding/json does when decoding.
+func sanitizeUTF8(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// rawMessageContents holds the undecoded content of the messages of a session.
+type rawMessageContents struct {
+	Messages []struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+}
+
+// restoreInvalidUTF8 puts the original bytes back into the content of the messages of store whose
+// content is not valid UTF-8 in data, which json.Unmarshal has replaced with U+FFFD. keys holds the
+// order of sessions stored as an object, as returned by decodeSessionsObject.
+func restoreInvalidUTF8(data []byte, store *ChatNextWebStore, keys []string) error {
+	var raw []rawMessageContents
+	if store.ChatNextWebStore.Shape == SessionsShapeObject {
+		var object struct {
+			ChatNextWebStore struct {
+				Sessions map[string]rawMessageContents `json:"sessions"`
+			} `json:"chat-next-web-store"`
+		}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			raw = append(raw, object.ChatNextWebStore.Sessions[key])
+		}
+	} else {
+		var array struct {
+			ChatNextWebStore struct {
+				Sessions []rawMessageContents `json:"sessions"`
+			} `json:"chat-next-web-store"`
+		}
+		if err := json.Unmarshal(data, &array); err != nil {
+			return err
+		}
+		raw = array.ChatNextWebStore.Sessions
+	}
+
+	sessions := store.ChatNextWebStore.Sessions
+	for i := 0; i < len(raw) && i < len(sessions); i++ {
+		messages := sessions[i].Messages
+		for j, message := range raw[i].Messages {
+			if j >= len(messages) || utf8.Valid(message.Content) {
+				continue
+			}
+			if content, ok := unquoteJSONString(message.Content); ok {
+				messages[j].Content = content
+			}
+		}
+	}
+	return nil
+}
+
+// unquoteJSONString decodes the JSON string literal quoted like encoding/json does, except that bytes
+// that are not valid UTF-8 are copied as they are. It reports false if quoted is not a string literal.
+func unquoteJSONString(quoted []byte) (string, bool) {
+	if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+		return "", false
+	}
+	quoted = quoted[1 : len(quoted)-1]
+	b := make([]byte, 0, len(quoted))
+	for i := 0; i < len(quoted); i++ {
+		if quoted[i] != '\\' {
+			b = append(b, quoted[i])
+			continue
+		}
+		i++
+		if i == len(quoted) {
+			return "", false
+		}
+		switch quoted[i] {
+		case '"', '\\', '/':
+			b = append(b, quoted[i])
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'u':
+			r, ok := unquoteJSONRune(quoted[i+1:])
+			if !ok {
+				return "", false
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				high := r
+				r = utf8.RuneError
+				if len(quoted) > i+2 && quoted[i+1] == '\\' && quoted[i+2] == 'u' {
+					if low, ok := unquoteJSONRune(quoted[i+3:]); ok {
+						if pair := utf16.DecodeRune(high, low); pair != utf8.RuneError {
+							r = pair
+							i += 6
+						}
+					}
+				}
+			}
+			b = utf8.AppendRune(b, r)
+		default:
+			return "", false
+		}
+	}
+	return string(b), true
+}
+
+// unquoteJSONRune decodes the four hex digits of a \u escape at the start of hex.
+func unquoteJSONRune(hex []byte) (rune, bool) {
+	if len(hex) < 4 {
+		return 0, false
+	}
+	r, err := strconv.ParseUint(string(hex[:4]), 16, 16)
+	return rune(r), err == nil
+}
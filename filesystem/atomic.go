@@ -0,0 +1,102 @@
+package filesystem
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// AtomicWriter is implemented by file systems that replace files atomically on their own, either
+// because they can do better than a temporary file and a rename, or because, as decorators, they
+// need to see the final file name rather than the temporary one. AtomicWriteFile uses it when available.
+type AtomicWriter interface {
+	AtomicWriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// atomicTempCounter makes the names of concurrent temporary files of AtomicWriteFile unique within the process.
+var atomicTempCounter atomic.Uint64
+
+// AtomicWriteFile writes data to the named file of fsys so that the file either keeps its previous
+// contents or holds all of data, even if the program crashes midway.
+//
+// If fsys implements AtomicWriter, the write is delegated to it. Otherwise, if fsys implements Renamer,
+// the data is written to a temporary file in the same directory, which is then renamed over the target;
+// renaming within a directory is atomic on most file systems. The temporary file is removed if any step
+// fails. File systems without either replace the file with WriteFile, which suits in-memory ones.
+func AtomicWriteFile(fsys FileSystem, path string, data []byte, perm os.FileMode) error {
+	if writer, ok := fsys.(AtomicWriter); ok {
+		return writer.AtomicWriteFile(path, data, perm)
+	}
+	renamer, ok := fsys.(Renamer)
+	if !ok {
+		return fsys.WriteFile(path, data, perm)
+	}
+
+	tmpName := filepath.Join(filepath.Dir(path),
+		fmt.Sprintf(".%s.tmp-%d-%d", filepath.Base(path), os.Getpid(), atomicTempCounter.Add(1)))
+	err := fsys.WriteFile(tmpName, data, perm)
+	if err == nil {
+		err = renamer.Rename(tmpName, path)
+	}
+	if err != nil {
+		Remove(fsys, tmpName) // ignore error; we're already handling an error
+		return err
+	}
+	return nil
+}
+
+// Atomic returns a view of fsys whose WriteFile replaces files with AtomicWriteFile. It allows functions
+// that only know how to call WriteFile, such as exporter.CreateSeparateCSVFiles, to write atomically.
+func Atomic(fsys FileSystem) FileSystem {
+	return atomicFileSystem{FileSystem: fsys}
+}
+
+// atomicFileSystem is the FileSystem returned by Atomic.
+type atomicFileSystem struct {
+	FileSystem
+}
+
+// WriteFile replaces the named file atomically through the wrapped file system.
+func (a atomicFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return AtomicWriteFile(a.FileSystem, name, data, perm)
+}
+
+// AtomicWriteFile replaces the named file atomically through the wrapped file system.
+func (a atomicFileSystem) AtomicWriteFile(name string, data []byte, perm fs.FileMode) error {
+	return AtomicWriteFile(a.FileSystem, name, data, perm)
+}
+
+// CreateTempFile starts a file through the wrapped file system with CreateTempFile, which is committed
+// atomically in any case.
+func (a atomicFileSystem) CreateTempFile(dir string, perm fs.FileMode) (TempFile, error) {
+	return CreateTempFile(a.FileSystem, dir, perm)
+}
+
+// Remove removes the named file through the wrapped file system with Remove.
+func (a atomicFileSystem) Remove(name string) error {
+	return Remove(a.FileSystem, name)
+}
+
+// Rename renames the named file through the wrapped file system with Rename.
+func (a atomicFileSystem) Rename(oldpath, newpath string) error {
+	return Rename(a.FileSystem, oldpath, newpath)
+}
+
+// Chmod changes the mode of the named file through the wrapped file system with Chmod.
+func (a atomicFileSystem) Chmod(name string, mode fs.FileMode) error {
+	return Chmod(a.FileSystem, name, mode)
+}
+
+// MkdirAll creates the directory through the wrapped file system with MkdirAll.
+func (a atomicFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return MkdirAll(a.FileSystem, path, perm)
+}
+
+// StreamFile saves the data write produces as the named file through the wrapped file system, see StreamFile.
+// It makes the view an exporter.FileStreamer, so that exports of several files are streamed.
+func (a atomicFileSystem) StreamFile(name string, perm fs.FileMode, write func(w io.Writer) error) error {
+	return StreamFile(a.FileSystem, name, perm, write)
+}
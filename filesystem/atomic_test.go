@@ -0,0 +1,100 @@
+package filesystem_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// renameFailingFileSystem is a MockFileSystem whose Rename always fails, as when a crash or I/O error
+// happens between writing the temporary file and moving it into place.
+type renameFailingFileSystem struct {
+	*filesystem.MockFileSystem
+}
+
+// Rename always fails.
+func (r renameFailingFileSystem) Rename(oldpath, newpath string) error {
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EIO}
+}
+
+// TestAtomicWriteFile verifies that AtomicWriteFile replaces files without leaving temporary files behind
+// and keeps the previous content when the rename fails.
+func TestAtomicWriteFile(t *testing.T) {
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["out/data.json"] = []byte("old")
+	if err := filesystem.AtomicWriteFile(mockFS, "out/data.json", []byte("new"), 0644); err != nil {
+		t.Fatalf("AtomicWriteFile() returned an error: %v", err)
+	}
+	if len(mockFS.Files) != 1 || string(mockFS.Files["out/data.json"]) != "new" {
+		t.Errorf("files after AtomicWriteFile() = %v, want only the replaced file", mockFS.Files)
+	}
+
+	failing := renameFailingFileSystem{filesystem.NewMockFileSystem()}
+	failing.Files["data.json"] = []byte("old")
+	if err := filesystem.AtomicWriteFile(failing, "data.json", []byte("new"), 0644); err == nil {
+		t.Error("AtomicWriteFile() ignored a failed rename")
+	}
+	if len(failing.Files) != 1 || string(failing.Files["data.json"]) != "old" {
+		t.Errorf("files after a failed AtomicWriteFile() = %v, want the previous content only", failing.Files)
+	}
+
+	// Without Rename, the file is replaced with WriteFile.
+	withoutRename := struct{ filesystem.FileSystem }{filesystem.NewMockFileSystem()}
+	if err := filesystem.AtomicWriteFile(withoutRename, "data.json", []byte("new"), 0644); err != nil {
+		t.Errorf("AtomicWriteFile() without Rename returned %v", err)
+	}
+	if err := filesystem.Rename(withoutRename, "data.json", "moved.json"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Rename() without Renamer returned %v, want errors.ErrUnsupported", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	os.WriteFile(path, []byte("old"), 0644)
+	locked := filesystem.LockingFileSystem{FileSystem: filesystem.RealFileSystem{}}
+	if err := filesystem.AtomicWriteFile(locked, path, []byte("new"), 0600); err != nil {
+		t.Fatalf("AtomicWriteFile() on the real file system returned an error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("real file content = %q, want %q", data, "new")
+	}
+	if info, err := os.Stat(path); err == nil && os.PathSeparator == '/' && info.Mode().Perm() != 0600 {
+		t.Errorf("real file mode = %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("AtomicWriteFile() left files behind: %v", entries)
+	}
+}
+
+// TestStreamFile verifies that StreamFile saves the streamed data on success and keeps the previous
+// content of the file, without leaving temporary files behind, when writing fails.
+func TestStreamFile(t *testing.T) {
+	write := func(data string, err error) func(w io.Writer) error {
+		return func(w io.Writer) error {
+			io.WriteString(w, data)
+			return err
+		}
+	}
+	failure := errors.New("render failed")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "export.csv")
+	for _, fsys := range []filesystem.FileSystem{filesystem.RealFileSystem{}, filesystem.NewMockFileSystem()} {
+		if err := filesystem.StreamFile(fsys, path, 0644, write("old\n", nil)); err != nil {
+			t.Fatalf("StreamFile(%T) returned an error: %v", fsys, err)
+		}
+		if err := filesystem.StreamFile(fsys, path, 0644, write("partial", failure)); !errors.Is(err, failure) {
+			t.Errorf("StreamFile(%T) with a failing write returned %v, want %v", fsys, err, failure)
+		}
+		if data, _ := fsys.ReadFile(path); string(data) != "old\n" {
+			t.Errorf("StreamFile(%T) left %q, want the previous content", fsys, data)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("StreamFile() left files behind: %v", entries)
+	}
+}
@@ -0,0 +1,123 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// ContextReader is implemented by file systems that can abort reading a file when a context is
+// cancelled, such as RealFileSystem. ReadFileContext uses it when available.
+type ContextReader interface {
+	ReadFileContext(ctx context.Context, name string) ([]byte, error)
+}
+
+// ContextWriter is implemented by file systems that can abort writing a file when a context is
+// cancelled, such as RealFileSystem. WriteFileContext uses it when available.
+type ContextWriter interface {
+	WriteFileContext(ctx context.Context, name string, data []byte, perm fs.FileMode) error
+}
+
+// contextChunkSize is the amount of data RealFileSystem reads or writes between checks for cancellation.
+const contextChunkSize = 1 << 20
+
+// ReadFileContext reads the named file of fsys, returning the context's error if ctx is cancelled.
+//
+// If fsys implements ContextReader, the read is delegated to it and can be interrupted midway.
+// Otherwise ctx is only checked before calling ReadFile, which suits in-memory file systems.
+func ReadFileContext(ctx context.Context, fsys FileSystem, name string) ([]byte, error) {
+	if reader, ok := fsys.(ContextReader); ok {
+		return reader.ReadFileContext(ctx, name)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fsys.ReadFile(name)
+}
+
+// WriteFileContext writes data to the named file of fsys, returning the context's error if ctx is cancelled.
+//
+// If fsys implements ContextWriter, the write is delegated to it and can be interrupted midway, which
+// leaves a partially written file, as a failed WriteFile does. Otherwise ctx is only checked before
+// calling WriteFile.
+func WriteFileContext(ctx context.Context, fsys FileSystem, name string, data []byte, perm fs.FileMode) error {
+	if writer, ok := fsys.(ContextWriter); ok {
+		return writer.WriteFileContext(ctx, name, data, perm)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fsys.WriteFile(name, data, perm)
+}
+
+// ReadFileContext reads the named file in chunks of contextChunkSize, checking ctx before each, so
+// that reading a large file from a slow mount can be interrupted.
+func (rfs RealFileSystem) ReadFileContext(ctx context.Context, name string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		buf.Grow(int(info.Size()) + bytes.MinRead)
+	}
+	if _, err := buf.ReadFrom(contextChunkReader{ctx: ctx, r: file}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFileContext writes data to the named file like WriteFile, in chunks of contextChunkSize,
+// checking ctx before each. A cancelled write leaves the part written so far in the file.
+func (rfs RealFileSystem) WriteFileContext(ctx context.Context, name string, data []byte, perm fs.FileMode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	for len(data) > 0 && err == nil {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		chunk := data[:min(len(data), contextChunkSize)]
+		_, err = file.Write(chunk)
+		data = data[len(chunk):]
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// contextChunkReader reads at most contextChunkSize bytes at a time from r, failing with the
+// context's error once ctx is cancelled.
+type contextChunkReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read reads the next chunk from the wrapped reader unless the context is cancelled.
+func (c contextChunkReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) > contextChunkSize {
+		p = p[:contextChunkSize]
+	}
+	return c.r.Read(p)
+}
+
+// isContextError reports whether err comes from a cancelled or expired context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
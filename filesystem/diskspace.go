@@ -0,0 +1,24 @@
+package filesystem
+
+import "errors"
+
+// ErrDiskSpaceUnsupported is returned by RealDiskSpace on platforms where free space cannot be determined.
+var ErrDiskSpaceUnsupported = errors.New("free disk space cannot be determined on this platform")
+
+// DiskSpace reports how much space is available on the file system holding a path.
+//
+// It is an interface so that disk space checks can be tested without filling a real disk.
+type DiskSpace interface {
+	// Available returns the number of bytes available to the current user on the file system
+	// holding path, which must exist.
+	Available(path string) (uint64, error)
+}
+
+// RealDiskSpace implements DiskSpace by querying the operating system,
+// using statfs on Unix systems and GetDiskFreeSpaceEx on Windows.
+type RealDiskSpace struct{}
+
+// Available returns the number of bytes available to the current user on the file system holding path.
+func (RealDiskSpace) Available(path string) (uint64, error) {
+	return availableSpace(path)
+}
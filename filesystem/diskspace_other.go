@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !windows
+
+package filesystem
+
+// availableSpace is not implemented on this platform.
+func availableSpace(path string) (uint64, error) {
+	return 0, ErrDiskSpaceUnsupported
+}
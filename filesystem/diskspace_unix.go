@@ -0,0 +1,14 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package filesystem
+
+import "syscall"
+
+// availableSpace returns the blocks available to unprivileged users times the block size.
+func availableSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -4,6 +4,9 @@
 package filesystem
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"io/fs"
 	"os"
 )
@@ -12,6 +15,11 @@ import (
 // files, writing to files, and retrieving file information. This allows for implementations
 // that can interact with the file system or provide mock functionality for testing purposes.
 // FileSystem interface now includes ReadFile method.
+//
+// File systems can additionally implement AtomicWriter, ContextReader, ContextWriter, Opener, Appender,
+// TempFileCreator, Chmoder, Remover, Renamer, and DirMaker; the functions AtomicWriteFile, ReadFileContext,
+// WriteFileContext, Open, AppendFile, CreateTempFile, Chmod, Remove, Rename, and MkdirAll fall back to
+// the methods above or report errors.ErrUnsupported otherwise.
 type FileSystem interface {
 	Create(name string) (*os.File, error)
 	WriteFile(name string, data []byte, perm fs.FileMode) error
@@ -50,6 +58,96 @@ func (rfs RealFileSystem) Stat(name string) (os.FileInfo, error) {
 	return os.Stat(name)
 }
 
+// Remove removes the named file or empty directory.
+// It wraps the os.Remove function.
+func (rfs RealFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Remover is implemented by file systems that can delete files, such as RealFileSystem.
+type Remover interface {
+	Remove(name string) error
+}
+
+// Remove removes the named file of fsys if fsys implements Remover.
+// It returns an error wrapping errors.ErrUnsupported otherwise.
+func Remove(fsys FileSystem, name string) error {
+	if remover, ok := fsys.(Remover); ok {
+		return remover.Remove(name)
+	}
+	return fmt.Errorf("cannot remove %s: %w", name, errors.ErrUnsupported)
+}
+
+// Renamer is implemented by file systems that can move files, such as RealFileSystem.
+type Renamer interface {
+	Rename(oldpath, newpath string) error
+}
+
+// Rename renames (moves) oldpath to newpath of fsys if fsys implements Renamer.
+// It returns an error wrapping errors.ErrUnsupported otherwise.
+func Rename(fsys FileSystem, oldpath, newpath string) error {
+	if renamer, ok := fsys.(Renamer); ok {
+		return renamer.Rename(oldpath, newpath)
+	}
+	return fmt.Errorf("cannot rename %s: %w", oldpath, errors.ErrUnsupported)
+}
+
+// Rename renames (moves) oldpath to newpath, replacing newpath if it already exists.
+// It wraps the os.Rename function.
+func (rfs RealFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// AtomicWriteFile replaces the named file atomically: data is written to a file of CreateTempFile,
+// which is synced to disk before it is renamed into place.
+func (rfs RealFileSystem) AtomicWriteFile(name string, data []byte, perm fs.FileMode) error {
+	return StreamFile(rfs, name, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// Chmod changes the permission bits of the named file.
+// It wraps the os.Chmod function.
+func (rfs RealFileSystem) Chmod(name string, mode fs.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+// Chmoder is implemented by file systems that keep Unix permission bits, such as RealFileSystem.
+type Chmoder interface {
+	Chmod(name string, mode fs.FileMode) error
+}
+
+// Chmod changes the permission bits of the named file of fsys if fsys implements Chmoder.
+// It returns an error wrapping errors.ErrUnsupported for file systems without permissions, such as
+// in-memory ones.
+func Chmod(fsys FileSystem, name string, mode fs.FileMode) error {
+	if chmoder, ok := fsys.(Chmoder); ok {
+		return chmoder.Chmod(name, mode)
+	}
+	return fmt.Errorf("cannot change the mode of %s: %w", name, errors.ErrUnsupported)
+}
+
+// MkdirAll creates the directory path along with any missing parents.
+// It wraps the os.MkdirAll function and does nothing if the directory already exists.
+func (rfs RealFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// DirMaker is implemented by file systems that have directories, such as RealFileSystem.
+type DirMaker interface {
+	MkdirAll(path string, perm fs.FileMode) error
+}
+
+// MkdirAll creates the directory path of fsys along with any missing parents if fsys implements
+// DirMaker. It returns an error wrapping errors.ErrUnsupported otherwise.
+func MkdirAll(fsys FileSystem, path string, perm fs.FileMode) error {
+	if maker, ok := fsys.(DirMaker); ok {
+		return maker.MkdirAll(path, perm)
+	}
+	return fmt.Errorf("cannot create directory %s: %w", path, errors.ErrUnsupported)
+}
+
 // FileExists checks if a file exists in the file system at the given path.
 // It returns a boolean indicating existence, and an error for any underlying
 // filesystem issues encountered.
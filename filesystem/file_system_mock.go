@@ -13,6 +13,7 @@ import (
 	"context"
 	"io/fs"
 	"os"
+	"syscall"
 	"time"
 	"unsafe" // this package is used to convert MockFile as Expert in the Real World.
 
@@ -26,17 +27,19 @@ var _ FileSystem = (*MockFileSystem)(nil)
 // It uses a map to store file names and associated data, allowing for the simulation of file creation,
 // reading, and writing without actual file system interaction.
 type MockFileSystem struct {
-	Files                 map[string][]byte // Files maps file names to file contents.
-	WriteFileCalled       bool              // Track if WriteFile has been called.
-	WriteFilePath         string            // Track the path provided to WriteFile.
-	WriteFileData         []byte            // Track the data provided to WriteFile.
-	WriteFilePerm         fs.FileMode       // Track the file permissions provided to WriteFile.
-	FileExistsCalled      bool              // Track if FileExists has been called.
-	FileExistsErr         error             // Track the error to return from FileExists.
-	FileExistsShouldError bool              // Track if FileExists should return an error.
-	ReadFileCalled        bool              // this field to track if ReadFile has been caled.
-	ReadFileData          []byte            // Optionally track the data provided to ReadFile.
-	ReadFileErr           error             // Optionally track the error provider to ReadFile.
+	Files                 map[string][]byte      // Files maps file names to file contents.
+	Dirs                  map[string]bool        // Dirs holds the directories created with MkdirAll.
+	Modes                 map[string]fs.FileMode // Modes holds the permission bits set with Chmod.
+	WriteFileCalled       bool                   // Track if WriteFile has been called.
+	WriteFilePath         string                 // Track the path provided to WriteFile.
+	WriteFileData         []byte                 // Track the data provided to WriteFile.
+	WriteFilePerm         fs.FileMode            // Track the file permissions provided to WriteFile.
+	FileExistsCalled      bool                   // Track if FileExists has been called.
+	FileExistsErr         error                  // Track the error to return from FileExists.
+	FileExistsShouldError bool                   // Track if FileExists should return an error.
+	ReadFileCalled        bool                   // this field to track if ReadFile has been caled.
+	ReadFileData          []byte                 // Optionally track the data provided to ReadFile.
+	ReadFileErr           error                  // Optionally track the error provider to ReadFile.
 }
 
 // MockExporter is a mock implementation of the exporter.Exporter interface for testing purposes.
@@ -51,6 +54,7 @@ type MockExporter struct {
 // It provides basic implementations of the fs.FileInfo interface methods.
 type mockFileInfo struct {
 	name string // name is the file name.
+	dir  bool   // dir reports whether the entry is a directory.
 	*bytes.Buffer
 }
 
@@ -80,6 +84,7 @@ func (m *MockExporter) ConvertSessionsToCSV(ctx context.Context, sessions []expo
 func NewMockFileSystem() *MockFileSystem {
 	return &MockFileSystem{
 		Files: make(map[string][]byte),
+		Dirs:  make(map[string]bool),
 	}
 }
 
@@ -90,6 +95,9 @@ func (m *MockFileSystem) Stat(name string) (fs.FileInfo, error) {
 		// Return mock file information.
 		return mockFileInfo{name: name}, nil
 	}
+	if m.Dirs[name] {
+		return mockFileInfo{name: name, dir: true}, nil
+	}
 	return nil, os.ErrNotExist
 }
 
@@ -139,6 +147,62 @@ func (m *MockFileSystem) FileExists(name string) (bool, error) {
 	return exists, nil
 }
 
+// Remove simulates removing a file or directory from the mock file system.
+// It returns an error if neither exists.
+func (m *MockFileSystem) Remove(name string) error {
+	if _, ok := m.Files[name]; ok {
+		delete(m.Files, name)
+		return nil
+	}
+	if m.Dirs[name] {
+		delete(m.Dirs, name)
+		return nil
+	}
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+}
+
+// Rename simulates renaming a file by moving its content to the new name, replacing any file there.
+// Renaming the file last written updates WriteFilePath, so that the final name of a file written
+// with AtomicWriteFile is tracked. It returns an error if the file does not exist.
+func (m *MockFileSystem) Rename(oldpath, newpath string) error {
+	data, ok := m.Files[oldpath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	delete(m.Files, oldpath)
+	m.Files[newpath] = data
+	if m.WriteFilePath == oldpath {
+		m.WriteFilePath = newpath
+	}
+	return nil
+}
+
+// Chmod simulates changing the permission bits of a file by recording them in the Modes map.
+// It returns an error if the file does not exist.
+func (m *MockFileSystem) Chmod(name string, mode fs.FileMode) error {
+	if _, ok := m.Files[name]; !ok {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrNotExist}
+	}
+	if m.Modes == nil {
+		m.Modes = make(map[string]fs.FileMode)
+	}
+	m.Modes[name] = mode
+	return nil
+}
+
+// MkdirAll simulates creating a directory by recording it in the Dirs map.
+// It fails if a file of the same name exists.
+func (m *MockFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	if _, ok := m.Files[path]; ok {
+		return &fs.PathError{Op: "mkdir", Path: path, Err: syscall.ENOTDIR}
+	}
+	if m.Dirs == nil {
+		m.Dirs = make(map[string]bool)
+	}
+	m.Dirs[path] = true
+	return nil
+}
+
 // Implement the Close method if needed for testing
 func (mf *MockFileSystem) Close() error {
 	return nil
@@ -186,7 +250,7 @@ func (m mockFileInfo) ModTime() time.Time {
 
 // IsDir reports whether the file is a directory.
 func (m mockFileInfo) IsDir() bool {
-	return false // Dummy value, always false.
+	return m.dir
 }
 
 // Sys returns the underlying data source (can return nil).
@@ -0,0 +1,75 @@
+package filesystem_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestChmod verifies that Chmod is forwarded through the decorators and reported as unsupported by
+// file systems without permissions instead of being ignored.
+func TestChmod(t *testing.T) {
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["tool"] = []byte("binary")
+	for _, fsys := range []filesystem.FileSystem{
+		mockFS,
+		filesystem.LockingFileSystem{FileSystem: mockFS},
+		filesystem.NewRetryFS(context.Background(), mockFS, filesystem.RetryPolicy{}),
+		filesystem.Atomic(mockFS),
+	} {
+		delete(mockFS.Modes, "tool")
+		if err := filesystem.Chmod(fsys, "tool", 0755); err != nil || mockFS.Modes["tool"] != 0755 {
+			t.Errorf("Chmod(%T) = %v with mode %v, want mode 0755", fsys, err, mockFS.Modes["tool"])
+		}
+	}
+
+	withoutModes := struct{ filesystem.FileSystem }{mockFS}
+	if err := filesystem.Chmod(withoutModes, "tool", 0755); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Chmod() without Chmoder returned %v, want errors.ErrUnsupported", err)
+	}
+}
+
+// TestFileContext verifies that the real file system reads and writes files larger than a chunk with
+// ReadFileContext and WriteFileContext, that a cancelled context stops them, and that file systems
+// without context support fall back to their plain methods.
+func TestFileContext(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "large.json")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 160*1024)
+	realFS := filesystem.RealFileSystem{}
+	if err := filesystem.WriteFileContext(ctx, realFS, path, data, 0644); err != nil {
+		t.Fatalf("WriteFileContext() returned an error: %v", err)
+	}
+	read, err := filesystem.ReadFileContext(ctx, filesystem.NewRetryFS(ctx, filesystem.LockingFileSystem{FileSystem: realFS}, filesystem.RetryPolicy{Attempts: 3}), path)
+	if err != nil || !bytes.Equal(read, data) {
+		t.Fatalf("ReadFileContext() read %d bytes (err %v), want %d", len(read), err, len(data))
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := filesystem.ReadFileContext(cancelled, realFS, path); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadFileContext() with a cancelled context returned %v", err)
+	}
+	if err := filesystem.WriteFileContext(cancelled, realFS, path+".new", data, 0644); !errors.Is(err, context.Canceled) {
+		t.Errorf("WriteFileContext() with a cancelled context returned %v", err)
+	}
+	if _, err := os.Stat(path + ".new"); !os.IsNotExist(err) {
+		t.Errorf("a cancelled WriteFileContext() created the file: %v", err)
+	}
+	if filesystem.IsTransient(context.DeadlineExceeded) {
+		t.Error("an expired context is retried as a transient error")
+	}
+
+	mockFS := filesystem.NewMockFileSystem()
+	if err := filesystem.WriteFileContext(ctx, mockFS, "out.csv", []byte("id\n"), 0644); err != nil || string(mockFS.Files["out.csv"]) != "id\n" {
+		t.Errorf("WriteFileContext() on the mock file system = %v with %q", err, mockFS.Files["out.csv"])
+	}
+	if _, err := filesystem.ReadFileContext(cancelled, mockFS, "out.csv"); !errors.Is(err, context.Canceled) || mockFS.ReadFileCalled {
+		t.Errorf("ReadFileContext() on the mock file system with a cancelled context returned %v", err)
+	}
+}
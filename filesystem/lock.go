@@ -0,0 +1,210 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// ErrLocked is returned when another process holds the lock on a file.
+var ErrLocked = errors.New("another export is writing this file")
+
+// LockSuffix is appended to a file name to form the name of its sidecar lock file.
+const LockSuffix = ".lock"
+
+// FileLock is an exclusive advisory lock on a file, held on a sidecar lock file next to it
+// (flock on Unix, LockFileEx on Windows). The operating system releases the lock when the
+// process exits, so a crashed run never leaves a file locked; at most a stale sidecar remains,
+// which the next run simply locks again.
+type FileLock struct {
+	file *os.File
+	path string
+}
+
+// LockFile acquires the exclusive lock for the named file without waiting.
+// If another process holds it, an error wrapping ErrLocked is returned immediately.
+func LockFile(name string) (*FileLock, error) {
+	path := name + LockSuffix
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if err := lockFile(file); err != nil {
+			file.Close()
+			if errors.Is(err, ErrLocked) {
+				return nil, fmt.Errorf("%s: %w", name, ErrLocked)
+			}
+			return nil, err
+		}
+
+		// The previous holder removes the sidecar when it unlocks. If that happened between our
+		// open and lock, we hold a lock on a file nobody else can see, so start over.
+		opened, err := file.Stat()
+		if err != nil {
+			unlockFile(file)
+			file.Close()
+			return nil, err
+		}
+		current, err := os.Stat(path)
+		if err == nil && os.SameFile(opened, current) {
+			return &FileLock{file: file, path: path}, nil
+		}
+		unlockFile(file)
+		file.Close()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+}
+
+// Unlock removes the sidecar lock file and releases the lock. Calling Unlock more than once has no effect.
+func (l *FileLock) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+	// Remove the sidecar while the lock is still held, so that no other process can lock it in between.
+	// On Windows an open file cannot be removed, so there it is removed after closing instead.
+	removeErr := os.Remove(l.path)
+	unlockFile(l.file)
+	err := l.file.Close()
+	l.file = nil
+	if removeErr != nil {
+		if retryErr := os.Remove(l.path); retryErr != nil && !errors.Is(retryErr, fs.ErrNotExist) {
+			return retryErr
+		}
+	}
+	return err
+}
+
+// LockingFileSystem is a FileSystem decorator that holds the exclusive lock of a file, see LockFile,
+// while writing it. Two runs writing the same file therefore cannot interleave their output;
+// the second one fails fast with an error wrapping ErrLocked.
+type LockingFileSystem struct {
+	FileSystem // FileSystem is the wrapped file system.
+}
+
+// WriteFile writes the named file through the wrapped file system while holding its lock.
+// The lock is released whether or not the write succeeds.
+func (l LockingFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) (err error) {
+	lock, err := LockFile(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := lock.Unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+	return l.FileSystem.WriteFile(name, data, perm)
+}
+
+// WriteFileContext writes the named file through the wrapped file system with WriteFileContext while
+// holding its lock. The lock is released whether or not the write succeeds.
+func (l LockingFileSystem) WriteFileContext(ctx context.Context, name string, data []byte, perm fs.FileMode) (err error) {
+	lock, err := LockFile(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := lock.Unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+	return WriteFileContext(ctx, l.FileSystem, name, data, perm)
+}
+
+// ReadFileContext reads the named file through the wrapped file system with ReadFileContext.
+// Reading does not take the lock.
+func (l LockingFileSystem) ReadFileContext(ctx context.Context, name string) ([]byte, error) {
+	return ReadFileContext(ctx, l.FileSystem, name)
+}
+
+// Remove removes the named file through the wrapped file system with Remove.
+func (l LockingFileSystem) Remove(name string) error {
+	return Remove(l.FileSystem, name)
+}
+
+// Rename renames the named file through the wrapped file system with Rename.
+func (l LockingFileSystem) Rename(oldpath, newpath string) error {
+	return Rename(l.FileSystem, oldpath, newpath)
+}
+
+// Chmod changes the mode of the named file through the wrapped file system with Chmod.
+func (l LockingFileSystem) Chmod(name string, mode fs.FileMode) error {
+	return Chmod(l.FileSystem, name, mode)
+}
+
+// MkdirAll creates the directory through the wrapped file system with MkdirAll.
+func (l LockingFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return MkdirAll(l.FileSystem, path, perm)
+}
+
+// Open opens the named file through the wrapped file system with Open. Reading does not take the lock.
+func (l LockingFileSystem) Open(name string) (io.ReadCloser, error) {
+	return Open(l.FileSystem, name)
+}
+
+// AppendFile appends to the named file through the wrapped file system with AppendFile while holding
+// its lock. The lock is released whether or not the append succeeds.
+func (l LockingFileSystem) AppendFile(name string, data []byte, perm fs.FileMode) (err error) {
+	lock, err := LockFile(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := lock.Unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+	return AppendFile(l.FileSystem, name, data, perm)
+}
+
+// CreateTempFile starts a file through the wrapped file system with CreateTempFile, which is committed
+// while holding the lock of its final name.
+func (l LockingFileSystem) CreateTempFile(dir string, perm fs.FileMode) (TempFile, error) {
+	file, err := CreateTempFile(l.FileSystem, dir, perm)
+	if err != nil {
+		return nil, err
+	}
+	return lockingTempFile{TempFile: file}, nil
+}
+
+// lockingTempFile is the TempFile of LockingFileSystem.
+type lockingTempFile struct {
+	TempFile
+}
+
+// Commit commits the file under name while holding its lock. The lock is released whether or not the
+// commit succeeds, and the file is discarded if the lock cannot be taken.
+func (l lockingTempFile) Commit(name string) (err error) {
+	lock, err := LockFile(name)
+	if err != nil {
+		l.TempFile.Abort()
+		return err
+	}
+	defer func() {
+		if unlockErr := lock.Unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+	return l.TempFile.Commit(name)
+}
+
+// AtomicWriteFile replaces the named file atomically through the wrapped file system, see AtomicWriteFile,
+// while holding the lock of the final name. The lock is released whether or not the write succeeds.
+func (l LockingFileSystem) AtomicWriteFile(name string, data []byte, perm fs.FileMode) (err error) {
+	lock, err := LockFile(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := lock.Unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+	return AtomicWriteFile(l.FileSystem, name, data, perm)
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !netbsd && !openbsd && !windows
+
+package filesystem
+
+import "os"
+
+// lockFile is a no-op on platforms without a supported advisory locking primitive.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on platforms without a supported advisory locking primitive.
+func unlockFile(file *os.File) error {
+	return nil
+}
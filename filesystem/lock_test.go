@@ -0,0 +1,38 @@
+package filesystem_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestLockingFileSystem verifies that a write fails fast while another holder has the lock of the
+// destination, and that the lock and its sidecar file are released after writing.
+func TestLockingFileSystem(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.csv")
+	lockedFS := filesystem.LockingFileSystem{FileSystem: &filesystem.RealFileSystem{}}
+
+	lock, err := filesystem.LockFile(output)
+	if err != nil {
+		t.Fatalf("LockFile() returned an error: %v", err)
+	}
+	if err := lockedFS.WriteFile(output, []byte("id\n"), 0644); !errors.Is(err, filesystem.ErrLocked) {
+		t.Errorf("WriteFile() on a locked file returned %v, want ErrLocked", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("the locked file must not be written: %v", err)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock() returned an error: %v", err)
+	}
+
+	if err := lockedFS.WriteFile(output, []byte("id\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() after unlocking returned an error: %v", err)
+	}
+	if _, err := os.Stat(output + filesystem.LockSuffix); !os.IsNotExist(err) {
+		t.Errorf("the sidecar lock file was left behind: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// probeFilePrefix is the prefix of the temporary file written by CheckWritableDir.
+const probeFilePrefix = ".session-exporter-probe-"
+
+// CheckReadable verifies that name is an existing, readable regular file in fsys,
+// so that a missing or unreadable input is reported before any prompt is answered.
+// The returned error tells the user what is wrong in plain words.
+func CheckReadable(fsys FileSystem, name string) error {
+	info, err := fsys.Stat(name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("input file %s does not exist", name)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("input file %s is not accessible by the current user", name)
+	case err != nil:
+		return fmt.Errorf("cannot access input file %s: %w", name, err)
+	case info.IsDir():
+		return fmt.Errorf("input %s is a directory, not a file", name)
+	}
+
+	file, err := Open(fsys, name)
+	if err == nil {
+		// Reading a single byte proves readability without loading a large input twice.
+		_, err = file.Read(make([]byte, 1))
+		file.Close()
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("input file %s is not readable by the current user", name)
+		}
+		return fmt.Errorf("cannot read input file %s: %w", name, err)
+	}
+	return nil
+}
+
+// CheckWritableDir verifies that files can be written to dir in fsys. A missing directory is created.
+// Writability is tested by writing and removing a small probe file, which catches read-only mounts
+// and ACLs that permission bits alone do not reveal. The returned error tells the user what is wrong
+// in plain words.
+func CheckWritableDir(fsys FileSystem, dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+
+	info, err := fsys.Stat(dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := MkdirAll(fsys, dir, 0755); err != nil {
+			return fmt.Errorf("directory %s does not exist and cannot be created: %w", dir, err)
+		}
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("directory %s is not accessible by the current user", dir)
+	case err != nil:
+		return fmt.Errorf("cannot access directory %s: %w", dir, err)
+	case !info.IsDir():
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	probe := filepath.Join(dir, fmt.Sprintf("%s%d", probeFilePrefix, os.Getpid()))
+	if err := fsys.WriteFile(probe, nil, 0600); err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("directory %s is not writable by the current user", dir)
+		}
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	if err := Remove(fsys, probe); err != nil {
+		return fmt.Errorf("cannot remove probe file %s: %w", probe, err)
+	}
+	return nil
+}
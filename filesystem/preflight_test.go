@@ -0,0 +1,52 @@
+package filesystem_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestPreflightChecks verifies that unreadable inputs and unwritable output directories are reported
+// with actionable messages, that a missing output directory is created, and that no probe file is left behind.
+func TestPreflightChecks(t *testing.T) {
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["input.json"] = []byte("{}")
+	mockFS.Files["out.csv"] = []byte("id\n")
+	mockFS.Dirs["/data"] = true
+
+	if err := filesystem.CheckReadable(mockFS, "input.json"); err != nil {
+		t.Errorf("CheckReadable() on an existing file returned %v", err)
+	}
+	if err := filesystem.CheckReadable(mockFS, "missing.json"); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("CheckReadable() on a missing file returned %v", err)
+	}
+	if err := filesystem.CheckReadable(mockFS, "/data"); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("CheckReadable() on a directory returned %v", err)
+	}
+	mockFS.Files["empty.json"] = nil
+	if err := filesystem.CheckReadable(mockFS, "empty.json"); err != nil {
+		t.Errorf("CheckReadable() on an empty file returned %v", err)
+	}
+	delete(mockFS.Files, "empty.json")
+
+	// A file system without directories cannot create a missing one, which is reported rather than ignored.
+	withoutDirs := struct{ filesystem.FileSystem }{mockFS}
+	if err := filesystem.CheckWritableDir(withoutDirs, "/data/missing"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("CheckWritableDir() without MkdirAll returned %v, want errors.ErrUnsupported", err)
+	}
+
+	if err := filesystem.CheckWritableDir(mockFS, "/data/export"); err != nil {
+		t.Fatalf("CheckWritableDir() on a missing directory returned %v", err)
+	}
+	if !mockFS.Dirs["/data/export"] {
+		t.Error("CheckWritableDir() did not create the missing directory")
+	}
+	if len(mockFS.Files) != 2 {
+		t.Errorf("CheckWritableDir() left a probe file behind: %v", mockFS.Files)
+	}
+	if err := filesystem.CheckWritableDir(mockFS, "out.csv"); err == nil || !strings.Contains(err.Error(), "is not a directory") {
+		t.Errorf("CheckWritableDir() on a file returned %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy describes how often and how patiently a failed read is retried.
+//
+// The zero value performs a single attempt without retrying, which preserves the behavior
+// of calling the FileSystem directly.
+type RetryPolicy struct {
+	Attempts int           // Attempts is the total number of attempts, including the first one.
+	Backoff  time.Duration // Backoff is the delay before the first retry; it doubles after every retry.
+}
+
+// ReadFileWithRetry reads the named file through the provided FileSystem, retrying transient
+// failures according to the policy. Errors that are not transient, such as a file that does not
+// exist or a permission problem, are returned immediately without retrying.
+//
+// This is intended for input files on network mounts (e.g. NFS or SMB) that occasionally stall.
+func ReadFileWithRetry(rfs FileSystem, name string, policy RetryPolicy) ([]byte, error) {
+	return ReadFileWithRetryContext(context.Background(), rfs, name, policy)
+}
+
+// ReadFileWithRetryContext behaves like ReadFileWithRetry, but reads the file with ReadFileContext
+// and stops waiting for the next attempt, returning the context's error, when ctx is cancelled.
+func ReadFileWithRetryContext(ctx context.Context, rfs FileSystem, name string, policy RetryPolicy) ([]byte, error) {
+	attempts := policy.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := policy.Backoff
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var data []byte
+		data, err = ReadFileContext(ctx, rfs, name)
+		if err == nil {
+			return data, nil
+		}
+		if !IsTransient(err) || attempt == attempts {
+			break
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+	return nil, err
+}
+
+// IsTransient reports whether err looks like a temporary I/O failure that may succeed when retried.
+//
+// Missing files, permission errors, and cancelled or expired contexts are never transient. I/O errors, timeouts, stale handles and
+// interrupted or busy resources, which are typical of flaky network filesystems, are.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) || errors.Is(err, fs.ErrInvalid) || isContextError(err) {
+		return false
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case syscall.EIO, syscall.EAGAIN, syscall.EINTR, syscall.EBUSY,
+			syscall.ETIMEDOUT, syscall.ESTALE, syscall.ECONNRESET, syscall.ECONNABORTED:
+			return true
+		}
+		return false
+	}
+
+	// Fall back to the conventional Timeout method of network errors.
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// RetryFS is a FileSystem decorator that retries operations failing with a transient error,
+// such as the intermittent I/O errors of SMB or NFS mounts, so that a single hiccup does not
+// abort a long export.
+//
+// Every operation is attempted according to Policy. The delay between attempts doubles after
+// each retry, with up to 50% random jitter added so that concurrent writers do not retry in
+// lockstep. Errors classified as permanent by IsTransient, such as permission-denied or
+// not-exist, are returned immediately. Waiting between attempts ends early, returning the
+// context's error, when the context is cancelled.
+type RetryFS struct {
+	FileSystem // FileSystem is the wrapped file system.
+
+	Policy RetryPolicy // Policy controls how often and how patiently operations are retried.
+
+	// Logf, when set, is called before every retry with a description of the failed attempt,
+	// which is useful for verbose output.
+	Logf func(format string, args ...interface{})
+
+	ctx context.Context
+}
+
+// NewRetryFS wraps rfs so that its operations are retried on transient errors according to policy.
+// Waiting for a retry is aborted when ctx is cancelled.
+func NewRetryFS(ctx context.Context, rfs FileSystem, policy RetryPolicy) *RetryFS {
+	return &RetryFS{FileSystem: rfs, Policy: policy, ctx: ctx}
+}
+
+// Create creates the named file through the wrapped file system, retrying transient failures.
+func (r *RetryFS) Create(name string) (*os.File, error) {
+	var file *os.File
+	err := r.retry("create", name, func() (err error) {
+		file, err = r.FileSystem.Create(name)
+		return err
+	})
+	return file, err
+}
+
+// WriteFile writes the named file through the wrapped file system, retrying transient failures.
+// Each attempt rewrites the whole file, so a partially written file is replaced on success.
+func (r *RetryFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return r.retry("write", name, func() error {
+		return r.FileSystem.WriteFile(name, data, perm)
+	})
+}
+
+// AtomicWriteFile replaces the named file atomically through the wrapped file system, see AtomicWriteFile,
+// retrying transient failures. A failed attempt leaves the previous content of the file in place.
+func (r *RetryFS) AtomicWriteFile(name string, data []byte, perm fs.FileMode) error {
+	return r.retry("write", name, func() error {
+		return AtomicWriteFile(r.FileSystem, name, data, perm)
+	})
+}
+
+// Rename renames the named file through the wrapped file system with Rename, retrying transient failures.
+func (r *RetryFS) Rename(oldpath, newpath string) error {
+	return r.retry("rename", oldpath, func() error {
+		return Rename(r.FileSystem, oldpath, newpath)
+	})
+}
+
+// Remove removes the named file through the wrapped file system with Remove, retrying transient failures.
+func (r *RetryFS) Remove(name string) error {
+	return r.retry("remove", name, func() error {
+		return Remove(r.FileSystem, name)
+	})
+}
+
+// MkdirAll creates the directory through the wrapped file system with MkdirAll, retrying transient failures.
+func (r *RetryFS) MkdirAll(path string, perm fs.FileMode) error {
+	return r.retry("mkdir", path, func() error {
+		return MkdirAll(r.FileSystem, path, perm)
+	})
+}
+
+// Chmod changes the mode of the named file through the wrapped file system with Chmod, retrying
+// transient failures.
+func (r *RetryFS) Chmod(name string, mode fs.FileMode) error {
+	return r.retry("chmod", name, func() error {
+		return Chmod(r.FileSystem, name, mode)
+	})
+}
+
+// ReadFile reads the named file through the wrapped file system, retrying transient failures.
+func (r *RetryFS) ReadFile(name string) ([]byte, error) {
+	var data []byte
+	err := r.retry("read", name, func() (err error) {
+		data, err = r.FileSystem.ReadFile(name)
+		return err
+	})
+	return data, err
+}
+
+// ReadFileContext reads the named file through the wrapped file system with ReadFileContext,
+// retrying transient failures. A cancelled context is never retried.
+func (r *RetryFS) ReadFileContext(ctx context.Context, name string) ([]byte, error) {
+	var data []byte
+	err := r.retry("read", name, func() (err error) {
+		data, err = ReadFileContext(ctx, r.FileSystem, name)
+		return err
+	})
+	return data, err
+}
+
+// WriteFileContext writes the named file through the wrapped file system with WriteFileContext,
+// retrying transient failures. A cancelled context is never retried.
+func (r *RetryFS) WriteFileContext(ctx context.Context, name string, data []byte, perm fs.FileMode) error {
+	return r.retry("write", name, func() error {
+		return WriteFileContext(ctx, r.FileSystem, name, data, perm)
+	})
+}
+
+// Open opens the named file through the wrapped file system with Open, retrying transient failures.
+// Reading the opened file is not retried.
+func (r *RetryFS) Open(name string) (io.ReadCloser, error) {
+	var file io.ReadCloser
+	err := r.retry("open", name, func() (err error) {
+		file, err = Open(r.FileSystem, name)
+		return err
+	})
+	return file, err
+}
+
+// AppendFile appends to the named file through the wrapped file system with AppendFile. Appending is
+// not retried, since a failed attempt may already have appended part of the data.
+func (r *RetryFS) AppendFile(name string, data []byte, perm fs.FileMode) error {
+	return AppendFile(r.FileSystem, name, data, perm)
+}
+
+// CreateTempFile starts a file through the wrapped file system with CreateTempFile, retrying transient
+// failures. Writing and committing the file are not retried.
+func (r *RetryFS) CreateTempFile(dir string, perm fs.FileMode) (TempFile, error) {
+	var file TempFile
+	err := r.retry("create", dir, func() (err error) {
+		file, err = CreateTempFile(r.FileSystem, dir, perm)
+		return err
+	})
+	return file, err
+}
+
+// Stat describes the named file through the wrapped file system, retrying transient failures.
+func (r *RetryFS) Stat(name string) (os.FileInfo, error) {
+	var info os.FileInfo
+	err := r.retry("stat", name, func() (err error) {
+		info, err = r.FileSystem.Stat(name)
+		return err
+	})
+	return info, err
+}
+
+// FileExists checks for the named file through the wrapped file system, retrying transient failures.
+func (r *RetryFS) FileExists(name string) (bool, error) {
+	var exists bool
+	err := r.retry("stat", name, func() (err error) {
+		exists, err = r.FileSystem.FileExists(name)
+		return err
+	})
+	return exists, err
+}
+
+// retry runs op until it succeeds, fails permanently, or the attempts of the policy are used up.
+func (r *RetryFS) retry(op string, name string, fn func() error) error {
+	attempts := r.Policy.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := r.Policy.Backoff
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if !IsTransient(err) || attempt == attempts {
+			break
+		}
+
+		delay := backoff
+		if backoff > 0 {
+			delay += time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+		}
+		if r.Logf != nil {
+			r.Logf("%s %s failed (attempt %d of %d): %v; retrying in %s", op, name, attempt, attempts, err, delay.Round(time.Millisecond))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package filesystem_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// flakyWriteFileSystem wraps the mock file system and fails the first writes with the configured error.
+type flakyWriteFileSystem struct {
+	*filesystem.MockFileSystem
+	failures int   // failures is the number of writes that fail before writes succeed.
+	err      error // err is the error returned by failing writes.
+	writes   int   // writes counts the calls to WriteFile.
+}
+
+// WriteFile fails with the configured error until the configured number of failures is reached.
+func (f *flakyWriteFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f.writes++
+	if f.writes <= f.failures {
+		return f.err
+	}
+	return f.MockFileSystem.WriteFile(name, data, perm)
+}
+
+// TestRetryFS verifies that transient write errors are retried and logged, that permanent errors
+// are returned immediately, and that a cancelled context aborts the wait for a retry.
+func TestRetryFS(t *testing.T) {
+	transient := &os.PathError{Op: "write", Path: "out.csv", Err: syscall.EIO}
+	policy := filesystem.RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+	flaky := &flakyWriteFileSystem{MockFileSystem: filesystem.NewMockFileSystem(), failures: 2, err: transient}
+	retryFS := filesystem.NewRetryFS(context.Background(), flaky, policy)
+	var logged []string
+	retryFS.Logf = func(format string, args ...interface{}) { logged = append(logged, fmt.Sprintf(format, args...)) }
+	if err := retryFS.WriteFile("out.csv", []byte("id\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() returned an error after transient failures: %v", err)
+	}
+	if flaky.writes != 3 || len(logged) != 2 || !strings.Contains(logged[0], "attempt 1 of 3") {
+		t.Errorf("expected 3 writes and 2 logged retries, got %d writes and %q", flaky.writes, logged)
+	}
+
+	denied := &os.PathError{Op: "write", Path: "out.csv", Err: fs.ErrPermission}
+	flaky = &flakyWriteFileSystem{MockFileSystem: filesystem.NewMockFileSystem(), failures: 5, err: denied}
+	if err := filesystem.NewRetryFS(context.Background(), flaky, policy).WriteFile("out.csv", nil, 0644); !errors.Is(err, fs.ErrPermission) || flaky.writes != 1 {
+		t.Errorf("permission errors must not be retried: err %v after %d writes", err, flaky.writes)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	flaky = &flakyWriteFileSystem{MockFileSystem: filesystem.NewMockFileSystem(), failures: 5, err: transient}
+	slow := filesystem.RetryPolicy{Attempts: 3, Backoff: time.Hour}
+	if err := filesystem.NewRetryFS(ctx, flaky, slow).WriteFile("out.csv", nil, 0644); err != context.Canceled || flaky.writes != 1 {
+		t.Errorf("a cancelled context should abort the retry: err %v after %d writes", err, flaky.writes)
+	}
+}
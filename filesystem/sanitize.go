@@ -0,0 +1,125 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxFilenameLength is the byte length names are trimmed to when no maximum is given.
+// It is the limit of a single path component on common file systems such as ext4, APFS, and NTFS.
+const DefaultMaxFilenameLength = 255
+
+// untitledFilename is used when nothing of the original text survives sanitization.
+const untitledFilename = "untitled"
+
+// reservedWindowsNames are device names that Windows refuses as file names, with or without an extension.
+var reservedWindowsNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFilename turns user content, such as a session topic, into a file name that is valid on the
+// current platform and at most maxLen bytes long (DefaultMaxFilenameLength if maxLen is not positive).
+// The result has no extension; callers append their own. Use a FilenameSet when several names are
+// derived in the same directory, so that topics which sanitize to the same name do not overwrite each other.
+func SanitizeFilename(topic string, maxLen int) string {
+	return SanitizeFilenameFor(runtime.GOOS, topic, maxLen)
+}
+
+// SanitizeFilenameFor is like SanitizeFilename but applies the rules of the given GOOS,
+// which is useful when the files are meant for another system.
+//
+// Path separators, control characters, and invalid UTF-8 are replaced by '_' everywhere. On Windows,
+// the characters <>:"\|?* are replaced as well, trailing dots and spaces are removed, and reserved
+// device names such as CON or NUL get a '_' prefix. On macOS, ':' is replaced. Other Unicode text,
+// including emoji and CJK characters, is kept, and names are never cut in the middle of a character.
+func SanitizeFilenameFor(goos, topic string, maxLen int) string {
+	if maxLen <= 0 {
+		maxLen = DefaultMaxFilenameLength
+	}
+	illegal := "/"
+	switch goos {
+	case "windows":
+		illegal = `/<>:"\|?*`
+	case "darwin":
+		illegal = "/:"
+	}
+
+	var builder strings.Builder
+	for _, r := range strings.ToValidUTF8(topic, "_") {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(illegal, r) {
+			builder.WriteByte('_')
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	name := strings.TrimSpace(truncateFilename(builder.String(), maxLen))
+	if goos == "windows" {
+		name = strings.TrimRight(name, ". ")
+		base, _, _ := strings.Cut(name, ".")
+		if reservedWindowsNames[strings.ToUpper(strings.TrimSpace(base))] {
+			name = truncateFilename("_"+name, maxLen)
+		}
+	}
+	if strings.Trim(name, ".") == "" {
+		name = untitledFilename
+	}
+	return name
+}
+
+// truncateFilename cuts name to at most maxLen bytes without splitting a UTF-8 sequence.
+func truncateFilename(name string, maxLen int) string {
+	if len(name) <= maxLen {
+		return name
+	}
+	cut := max(maxLen, 0)
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return name[:cut]
+}
+
+// FilenameSet hands out sanitized file names that are unique within one directory.
+// Names are compared case-insensitively, because Windows and macOS treat "Notes" and "notes" as the same file.
+// The zero value is not usable; create a set with NewFilenameSet.
+type FilenameSet struct {
+	goos string
+	used map[string]bool
+}
+
+// NewFilenameSet creates an empty FilenameSet that applies the rules of the current platform.
+func NewFilenameSet() *FilenameSet {
+	return &FilenameSet{goos: runtime.GOOS, used: make(map[string]bool)}
+}
+
+// Name returns the sanitized name of topic. If the name was already handed out, a short hash of
+// the topic is appended, followed by a counter if identical topics still collide. The result,
+// suffix included, is at most maxLen bytes long (DefaultMaxFilenameLength if maxLen is not positive).
+func (s *FilenameSet) Name(topic string, maxLen int) string {
+	if maxLen <= 0 {
+		maxLen = DefaultMaxFilenameLength
+	}
+	name := SanitizeFilenameFor(s.goos, topic, maxLen)
+	if s.used[strings.ToLower(name)] {
+		sum := sha256.Sum256([]byte(topic))
+		hash := hex.EncodeToString(sum[:4])
+		for i := 1; ; i++ {
+			suffix := "-" + hash
+			if i > 1 {
+				suffix += fmt.Sprintf("-%d", i)
+			}
+			candidate := truncateFilename(name, maxLen-len(suffix)) + suffix
+			if !s.used[strings.ToLower(candidate)] {
+				name = candidate
+				break
+			}
+		}
+	}
+	s.used[strings.ToLower(name)] = true
+	return name
+}
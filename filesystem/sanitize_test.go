@@ -0,0 +1,64 @@
+package filesystem_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestSanitizeFilename verifies that adversarial topics become valid, bounded file names on each
+// platform and that a FilenameSet keeps names unique when topics sanitize to the same name.
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		topic    string
+		maxLen   int
+		expected string
+	}{
+		{"Plain", "linux", "Go Concurrency", 0, "Go Concurrency"},
+		{"SlashOnUnix", "linux", "a/b: c?", 0, "a_b: c?"},
+		{"IllegalOnWindows", "windows", `a/b: c? <d> "e" f|g*h\i`, 0, "a_b_ c_ _d_ _e_ f_g_h_i"},
+		{"ColonOnMac", "darwin", "10:30 standup", 0, "10_30 standup"},
+		{"ControlCharacters", "linux", "line1\nline2\ttab\x00", 0, "line1_line2_tab_"},
+		{"InvalidUTF8", "linux", "bad\xffbyte", 0, "bad_byte"},
+		{"UnicodeKept", "windows", "Unicode 🎩🪄 Beyoğlu 日本語", 0, "Unicode 🎩🪄 Beyoğlu 日本語"},
+		{"TrailingDotsOnWindows", "windows", "Notes... ", 0, "Notes"},
+		{"TrailingDotsOnUnix", "linux", "Notes...", 0, "Notes..."},
+		{"ReservedName", "windows", "con", 0, "_con"},
+		{"ReservedNameWithExtension", "windows", "NUL.txt", 0, "_NUL.txt"},
+		{"ReservedNameOnUnix", "linux", "CON", 0, "CON"},
+		{"Empty", "linux", "   ", 0, "untitled"},
+		{"DotDot", "linux", "..", 0, "untitled"},
+		{"OnlyDotsOnWindows", "windows", "...", 0, "untitled"},
+		{"TruncatedAtRuneBoundary", "linux", "日本語", 7, "日本"},
+		{"TruncatedTrailingDotOnWindows", "windows", "ab. cd", 4, "ab"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := filesystem.SanitizeFilenameFor(tc.goos, tc.topic, tc.maxLen)
+			if got != tc.expected {
+				t.Errorf("SanitizeFilenameFor(%q, %q, %d) = %q, want %q", tc.goos, tc.topic, tc.maxLen, got, tc.expected)
+			}
+		})
+	}
+
+	if got := filesystem.SanitizeFilename(strings.Repeat("🎩", 100), 0); len(got) > filesystem.DefaultMaxFilenameLength {
+		t.Errorf("SanitizeFilename() returned %d bytes, want at most %d", len(got), filesystem.DefaultMaxFilenameLength)
+	}
+
+	names := filesystem.NewFilenameSet()
+	seen := make(map[string]bool)
+	for _, topic := range []string{"a/b", "a_b", "A_B", "a/b", "", strings.Repeat("x", 40) + "1", strings.Repeat("x", 40) + "2"} {
+		name := names.Name(topic, 32)
+		if len(name) > 32 {
+			t.Errorf("Name(%q) = %q is longer than 32 bytes", topic, name)
+		}
+		if seen[strings.ToLower(name)] {
+			t.Errorf("Name(%q) = %q was handed out before", topic, name)
+		}
+		seen[strings.ToLower(name)] = true
+	}
+}
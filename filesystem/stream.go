@@ -0,0 +1,97 @@
+package filesystem
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Opener is implemented by file systems that can stream a file instead of reading it at once, such as
+// RealFileSystem. Open uses it when available.
+type Opener interface {
+	Open(name string) (io.ReadCloser, error)
+}
+
+// Appender is implemented by file systems that can add data to the end of a file in place, such as
+// RealFileSystem. AppendFile uses it when available.
+type Appender interface {
+	AppendFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// Open opens the named file of fsys for reading.
+//
+// If fsys implements Opener, the file is streamed from it, so that a large file is never held in memory.
+// Otherwise the file is read with ReadFile and served from memory, which suits in-memory file systems.
+func Open(fsys FileSystem, name string) (io.ReadCloser, error) {
+	if opener, ok := fsys.(Opener); ok {
+		return opener.Open(name)
+	}
+	data, err := fsys.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// AppendFile adds data to the end of the named file of fsys, creating the file with perm if it does
+// not exist.
+//
+// If fsys implements Appender, the data is appended in place, without reading the file. Otherwise the
+// file is read and replaced with AtomicWriteFile by its previous content followed by data, which suits
+// in-memory and archive file systems.
+func AppendFile(fsys FileSystem, name string, data []byte, perm fs.FileMode) error {
+	if appender, ok := fsys.(Appender); ok {
+		return appender.AppendFile(name, data, perm)
+	}
+	existing, err := fsys.ReadFile(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return AtomicWriteFile(fsys, name, append(existing, data...), perm)
+}
+
+// Open opens the named file for reading.
+// It wraps the os.Open function.
+func (rfs RealFileSystem) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+// AppendFile adds data to the end of the named file, creating it with perm if it does not exist, and
+// syncs it to disk. The file is opened in append mode, so its previous content is neither read nor
+// rewritten.
+func (rfs RealFileSystem) AppendFile(name string, data []byte, perm fs.FileMode) error {
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ProgressReader is an io.Reader that reports the progress of reads from R, such as a download or a
+// large input file being streamed, to a callback.
+type ProgressReader struct {
+	R        io.Reader               // R is the underlying reader.
+	Total    int64                   // Total is the expected number of bytes, or -1 if it is unknown.
+	Progress func(read, total int64) // Progress is called after every read with the bytes read so far and Total.
+
+	read int64
+}
+
+// Read reads from the underlying reader and reports the bytes read so far.
+func (p *ProgressReader) Read(b []byte) (int, error) {
+	n, err := p.R.Read(b)
+	if n > 0 {
+		p.read += int64(n)
+		p.Progress(p.read, p.Total)
+	}
+	return n, err
+}
@@ -0,0 +1,89 @@
+package filesystem_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestOpen verifies that Open streams files of the real file system and serves those of file systems
+// without Opener from memory.
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "input.json")
+	os.WriteFile(path, []byte("{}"), 0644)
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files[path] = []byte("{}")
+
+	for _, fsys := range []filesystem.FileSystem{filesystem.RealFileSystem{}, mockFS} {
+		file, err := filesystem.Open(fsys, path)
+		if err != nil {
+			t.Fatalf("Open(%T) returned an error: %v", fsys, err)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil || string(data) != "{}" {
+			t.Errorf("reading the file of Open(%T) = %q, %v, want %q", fsys, data, err, "{}")
+		}
+		if _, err := filesystem.Open(fsys, filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+			t.Errorf("Open(%T) on a missing file returned %v, want a not-exist error", fsys, err)
+		}
+	}
+}
+
+// TestAppendFile verifies that AppendFile creates missing files and adds data after the existing content,
+// both in place and through the fallback for file systems without Appender.
+func TestAppendFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.csv")
+	for _, fsys := range []filesystem.FileSystem{filesystem.RealFileSystem{}, filesystem.NewMockFileSystem()} {
+		for _, data := range []string{"id\n", "1\n", "2\n"} {
+			if err := filesystem.AppendFile(fsys, path, []byte(data), 0644); err != nil {
+				t.Fatalf("AppendFile(%T) returned an error: %v", fsys, err)
+			}
+		}
+		if data, _ := fsys.ReadFile(path); string(data) != "id\n1\n2\n" {
+			t.Errorf("file after AppendFile(%T) = %q, want %q", fsys, data, "id\n1\n2\n")
+		}
+	}
+}
+
+// TestCreateTempFile verifies that files of CreateTempFile only appear under their final name once
+// committed, and that aborted files leave nothing behind.
+func TestCreateTempFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "export.csv")
+	for _, fsys := range []filesystem.FileSystem{filesystem.RealFileSystem{}, filesystem.NewMockFileSystem()} {
+		file, err := filesystem.CreateTempFile(fsys, dir, 0644)
+		if err != nil {
+			t.Fatalf("CreateTempFile(%T) returned an error: %v", fsys, err)
+		}
+		io.WriteString(file, "id\n")
+		if exists, _ := fsys.FileExists(path); exists {
+			t.Errorf("CreateTempFile(%T) made the file visible before Commit", fsys)
+		}
+		if err := file.Commit(path); err != nil {
+			t.Fatalf("Commit() of CreateTempFile(%T) returned an error: %v", fsys, err)
+		}
+		if data, _ := fsys.ReadFile(path); string(data) != "id\n" {
+			t.Errorf("committed file of CreateTempFile(%T) = %q, want %q", fsys, data, "id\n")
+		}
+
+		aborted, err := filesystem.CreateTempFile(fsys, dir, 0644)
+		if err != nil {
+			t.Fatalf("CreateTempFile(%T) returned an error: %v", fsys, err)
+		}
+		io.WriteString(aborted, "discarded")
+		if err := aborted.Abort(); err != nil {
+			t.Errorf("Abort() of CreateTempFile(%T) returned an error: %v", fsys, err)
+		}
+		if data, _ := fsys.ReadFile(path); string(data) != "id\n" {
+			t.Errorf("file after an aborted CreateTempFile(%T) = %q, want %q", fsys, data, "id\n")
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("CreateTempFile() left files behind: %v", entries)
+	}
+}
@@ -0,0 +1,126 @@
+package filesystem
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// TempFile is a file being written by CreateTempFile. Its data only becomes visible once it is
+// committed under its final name, so that a failed or canceled export never leaves a truncated file.
+type TempFile interface {
+	io.Writer
+
+	// Commit makes the written data the content of the named file, replacing it atomically. The name
+	// is chosen only now, so that the data can be kept under another name, such as that of a partial
+	// export, when writing was interrupted.
+	Commit(name string) error
+
+	// Abort discards the written data. It has no effect after Commit.
+	Abort() error
+}
+
+// TempFileCreator is implemented by file systems that can stream a file to disk before it is renamed
+// into place, such as RealFileSystem. CreateTempFile uses it when available.
+type TempFileCreator interface {
+	CreateTempFile(dir string, perm fs.FileMode) (TempFile, error)
+}
+
+// CreateTempFile starts a file in the directory dir of fsys that is written with perm once committed.
+//
+// If fsys implements TempFileCreator, the data is streamed to a temporary file, so that a large export
+// is never held in memory. Otherwise it is collected in memory and written with AtomicWriteFile on
+// Commit, which suits in-memory and archive file systems.
+func CreateTempFile(fsys FileSystem, dir string, perm fs.FileMode) (TempFile, error) {
+	if creator, ok := fsys.(TempFileCreator); ok {
+		return creator.CreateTempFile(dir, perm)
+	}
+	return &memoryTempFile{fsys: fsys, perm: perm}, nil
+}
+
+// StreamFile saves the data write produces as the named file of fsys. The data is streamed to a file of
+// CreateTempFile in the directory of name, which is committed only if write returns nil and discarded
+// otherwise, so the named file keeps its previous content unless the whole of the data was written.
+func StreamFile(fsys FileSystem, name string, perm fs.FileMode, write func(w io.Writer) error) error {
+	file, err := CreateTempFile(fsys, filepath.Dir(name), perm)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Abort()
+		return err
+	}
+	return file.Commit(name)
+}
+
+// memoryTempFile is the TempFile of CreateTempFile for file systems without TempFileCreator.
+type memoryTempFile struct {
+	bytes.Buffer
+	fsys FileSystem
+	perm fs.FileMode
+}
+
+// Commit writes the collected data to the named file with AtomicWriteFile.
+func (m *memoryTempFile) Commit(name string) error {
+	return AtomicWriteFile(m.fsys, name, m.Bytes(), m.perm)
+}
+
+// Abort discards the collected data.
+func (m *memoryTempFile) Abort() error {
+	m.Reset()
+	return nil
+}
+
+// CreateTempFile creates a temporary file in dir that is synced to disk and renamed over the target
+// on Commit.
+func (rfs RealFileSystem) CreateTempFile(dir string, perm fs.FileMode) (TempFile, error) {
+	file, err := os.CreateTemp(dir, ".export-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &realTempFile{File: file, perm: perm}, nil
+}
+
+// realTempFile is the TempFile of RealFileSystem.
+type realTempFile struct {
+	*os.File
+	perm fs.FileMode
+	done bool // done reports that the file was committed or aborted.
+}
+
+// Commit syncs the temporary file to disk and renames it to name. The temporary file is removed if
+// any step fails.
+func (r *realTempFile) Commit(name string) error {
+	if r.done {
+		return errors.New("temporary file already committed or aborted")
+	}
+	r.done = true
+	tmpName := r.Name()
+	err := r.Sync()
+	if closeErr := r.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, r.perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, name)
+	}
+	if err != nil {
+		os.Remove(tmpName) // ignore error; we're already handling an error
+	}
+	return err
+}
+
+// Abort closes and removes the temporary file.
+func (r *realTempFile) Abort() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	r.Close()
+	return os.Remove(r.Name())
+}
@@ -0,0 +1,215 @@
+package filesystem
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Ensure ZipFileSystem adheres to the FileSystem interface.
+var _ FileSystem = (*ZipFileSystem)(nil)
+
+// ZipFileSystem implements the FileSystem interface on top of an in-memory zip archive,
+// so that several exports can be bundled into a single archive file.
+//
+// Files written with WriteFile are kept in memory and can be read back with ReadFile.
+// Nothing is written to disk until Close is called, which writes the archive to the output path.
+// Writing the same name twice replaces the earlier content instead of adding a duplicate entry.
+// File names are stored as archive entry names: slash-separated and relative to the archive root,
+// so that "./out.csv", "/out.csv", and "out.csv" name the same entry.
+type ZipFileSystem struct {
+	outputPath string
+	files      map[string]zipEntry
+	nextOrder  int // nextOrder is the order of the next new file, which never repeats, even after Remove.
+	closed     bool
+}
+
+// zipEntry holds the content and metadata of a file stored in a ZipFileSystem.
+type zipEntry struct {
+	data    []byte
+	perm    fs.FileMode
+	modTime time.Time
+	order   int // order preserves the order in which files were first written.
+}
+
+// NewZipFileSystem creates a ZipFileSystem that writes its archive to outputPath when closed.
+// It returns an error if outputPath is empty or its directory does not exist.
+func NewZipFileSystem(outputPath string) (*ZipFileSystem, error) {
+	if outputPath == "" {
+		return nil, errors.New("zip output path must not be empty")
+	}
+	if dir := filepath.Dir(outputPath); dir != "." {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("directory of zip output %s does not exist", outputPath)
+		}
+	}
+	return &ZipFileSystem{outputPath: outputPath, files: make(map[string]zipEntry)}, nil
+}
+
+// entryName returns the archive entry name of a file name: slash-separated, without a leading "/" or "./".
+func entryName(name string) string {
+	name = filepath.ToSlash(name)
+	for {
+		switch {
+		case strings.HasPrefix(name, "/"):
+			name = name[1:]
+		case strings.HasPrefix(name, "./"):
+			name = name[2:]
+		default:
+			return name
+		}
+	}
+}
+
+// Create is not supported by ZipFileSystem, because archive entries cannot be exposed as *os.File.
+// Use WriteFile instead.
+func (z *ZipFileSystem) Create(name string) (*os.File, error) {
+	return nil, &fs.PathError{Op: "create", Path: name, Err: errors.ErrUnsupported}
+}
+
+// WriteFile adds a file with the given name and data to the archive, replacing any earlier file of the same name.
+func (z *ZipFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if z.closed {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrClosed}
+	}
+	name = entryName(name)
+	order := z.nextOrder
+	if existing, ok := z.files[name]; ok {
+		order = existing.order
+	} else {
+		z.nextOrder++
+	}
+	z.files[name] = zipEntry{data: append([]byte(nil), data...), perm: perm, modTime: time.Now(), order: order}
+	return nil
+}
+
+// ReadFile returns the content of a file previously written to the archive.
+func (z *ZipFileSystem) ReadFile(name string) ([]byte, error) {
+	entry, ok := z.files[entryName(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), entry.data...), nil
+}
+
+// Stat returns the FileInfo of a file previously written to the archive.
+func (z *ZipFileSystem) Stat(name string) (os.FileInfo, error) {
+	entry, ok := z.files[entryName(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return zipFileInfo{name: path.Base(name), entry: entry}, nil
+}
+
+// FileExists reports whether a file with the given name has been written to the archive.
+func (z *ZipFileSystem) FileExists(name string) (bool, error) {
+	_, ok := z.files[entryName(name)]
+	return ok, nil
+}
+
+// Remove deletes a file previously written to the archive.
+func (z *ZipFileSystem) Remove(name string) error {
+	if _, ok := z.files[entryName(name)]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(z.files, entryName(name))
+	return nil
+}
+
+// Rename moves a file previously written to the archive to a new name, replacing any file of that name.
+// The file keeps its position in the archive.
+func (z *ZipFileSystem) Rename(oldpath, newpath string) error {
+	entry, ok := z.files[entryName(oldpath)]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	delete(z.files, entryName(oldpath))
+	z.files[entryName(newpath)] = entry
+	return nil
+}
+
+// AtomicWriteFile adds the file to the archive like WriteFile. Nothing reaches the disk before Close,
+// which writes the whole archive, so no temporary entry is needed.
+func (z *ZipFileSystem) AtomicWriteFile(name string, data []byte, perm fs.FileMode) error {
+	return z.WriteFile(name, data, perm)
+}
+
+// MkdirAll does nothing, because directories in a zip archive are implied by the names of its files.
+func (z *ZipFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return nil
+}
+
+// Names returns the names of the files in the archive in the order they were first written.
+func (z *ZipFileSystem) Names() []string {
+	names := make([]string, 0, len(z.files))
+	for name := range z.files {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return z.files[names[i]].order < z.files[names[j]].order })
+	return names
+}
+
+// Close writes the archive with all files to the output path. Calling Close more than once has no effect.
+func (z *ZipFileSystem) Close() error {
+	if z.closed {
+		return nil
+	}
+	z.closed = true
+
+	var buf bytes.Buffer
+	if err := z.writeTo(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(z.outputPath, buf.Bytes(), 0644)
+}
+
+// writeTo encodes the archive into w.
+func (z *ZipFileSystem) writeTo(w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	for _, name := range z.Names() {
+		entry := z.files[name]
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: entry.modTime}
+		header.SetMode(entry.perm)
+		fileWriter, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to zip: %w", name, err)
+		}
+		if _, err := fileWriter.Write(entry.data); err != nil {
+			return fmt.Errorf("failed to add %s to zip: %w", name, err)
+		}
+	}
+	return zipWriter.Close()
+}
+
+// zipFileInfo describes a file stored in a ZipFileSystem.
+type zipFileInfo struct {
+	name  string
+	entry zipEntry
+}
+
+// Name returns the base name of the file.
+func (i zipFileInfo) Name() string { return i.name }
+
+// Size returns the size of the file content in bytes.
+func (i zipFileInfo) Size() int64 { return int64(len(i.entry.data)) }
+
+// Mode returns the permissions the file was written with.
+func (i zipFileInfo) Mode() fs.FileMode { return i.entry.perm }
+
+// ModTime returns the time the file was written.
+func (i zipFileInfo) ModTime() time.Time { return i.entry.modTime }
+
+// IsDir reports whether the file is a directory, which is never the case.
+func (i zipFileInfo) IsDir() bool { return false }
+
+// Sys returns nil, as there is no underlying data source.
+func (i zipFileInfo) Sys() interface{} { return nil }
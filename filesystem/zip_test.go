@@ -0,0 +1,91 @@
+package filesystem_test
+
+import (
+	"archive/zip"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestZipFileSystem verifies that files written to a ZipFileSystem can be read back
+// and end up in the archive written on Close.
+func TestZipFileSystem(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "exports.zip")
+	zipFS, err := filesystem.NewZipFileSystem(archivePath)
+	if err != nil {
+		t.Fatalf("NewZipFileSystem() returned an error: %v", err)
+	}
+
+	files := map[string]string{"sessions.csv": "id,topic\n", "messages.csv": "session_id\n"}
+	for name, content := range files {
+		if err := zipFS.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() returned an error: %v", err)
+		}
+	}
+	if data, err := zipFS.ReadFile("sessions.csv"); err != nil || string(data) != files["sessions.csv"] {
+		t.Errorf("ReadFile() = %q, %v", data, err)
+	}
+	if exists, _ := zipFS.FileExists("missing.csv"); exists {
+		t.Errorf("FileExists() reported a file that was never written")
+	}
+	if err := zipFS.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		t.Fatalf("failed to open the written archive: %v", err)
+	}
+	defer archive.Close()
+	if len(archive.File) != len(files) {
+		t.Fatalf("archive holds %d files, want %d", len(archive.File), len(files))
+	}
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(content) != files[file.Name] {
+			t.Errorf("%s = %q, want %q", file.Name, content, files[file.Name])
+		}
+	}
+}
+
+// TestZipFileSystemNames verifies that the archive keeps the order files were first written in after
+// one is removed, and that file names are stored as entry names relative to the archive root.
+func TestZipFileSystemNames(t *testing.T) {
+	zipFS, err := filesystem.NewZipFileSystem(filepath.Join(t.TempDir(), "exports.zip"))
+	if err != nil {
+		t.Fatalf("NewZipFileSystem() returned an error: %v", err)
+	}
+	for _, name := range []string{"a.csv", "./files/b.png", "/c.csv"} {
+		if err := zipFS.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatalf("WriteFile(%s) returned an error: %v", name, err)
+		}
+	}
+	if err := zipFS.Remove("./a.csv"); err != nil {
+		t.Fatalf("Remove(./a.csv) returned an error: %v", err)
+	}
+	if err := zipFS.WriteFile("d.csv", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := zipFS.WriteFile("files/b.png", []byte("replaced"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"files/b.png", "c.csv", "d.csv"}
+	if names := zipFS.Names(); !slices.Equal(names, want) {
+		t.Errorf("Names() = %v, want %v", names, want)
+	}
+	if exists, _ := zipFS.FileExists("./c.csv"); !exists {
+		t.Error("FileExists(./c.csv) did not find the file written as /c.csv")
+	}
+	if data, err := zipFS.ReadFile("/files/b.png"); err != nil || string(data) != "replaced" {
+		t.Errorf("ReadFile(/files/b.png) = %q, %v, want the replaced content", data, err)
+	}
+}
@@ -0,0 +1,476 @@
+// @flags.go:
+// This file holds the command-line flags understood by the CLI tool together with
+// the environment variables that can be used to set them permanently.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/tablecli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/uploader"
+)
+
+const (
+	// EnvNoBanner is the environment variable that disables the startup banner when set to a truthy value.
+	EnvNoBanner = "EXPORTER_NO_BANNER"
+	// EnvBannerStyle is the environment variable selecting the style of the startup banner.
+	EnvBannerStyle = "EXPORTER_BANNER_STYLE"
+	// EnvBannerDelay is the environment variable holding the delay per character or frame of the startup banner.
+	EnvBannerDelay = "EXPORTER_BANNER_DELAY"
+	// EnvBaseURL is the environment variable holding the ChatGPT-Next-Web address used for session links.
+	EnvBaseURL = "EXPORTER_BASE_URL"
+)
+
+// cliOptions holds the options collected from the command line and the environment.
+type cliOptions struct {
+	NoBanner        bool                       // NoBanner skips the startup banner entirely.
+	Banner          bannercli.Config           // Banner determines the style and speed of the startup banner.
+	Format          string                     // Format preselects the output format by name instead of prompting for it.
+	ReadRetry       filesystem.RetryPolicy     // ReadRetry controls retrying transient failures when reading the input file.
+	OutputZip       string                     // OutputZip bundles all output files into the zip archive at this path.
+	JSONOutput      bool                       // JSONOutput prints a machine-readable JSON summary and moves all other text to stderr.
+	UnknownRoles    exporter.UnknownRolePolicy // UnknownRoles determines what happens to messages with unrecognized roles.
+	Redact          []exporter.RedactRule      // Redact lists the redaction rules applied to message content; empty redacts nothing.
+	PreserveOrder   bool                       // PreserveOrder keeps the original key order when repairing data.
+	BaseURL         string                     // BaseURL adds a link to each session in the exports when set.
+	Attachments     string                     // Attachments is the sidecar directory attachments are extracted into; empty disables extraction.
+	Download        bool                       // Download fetches linked remote attachments in addition to inline ones.
+	StateFile       string                     // StateFile enables incremental exports using the session hashes stored at this path.
+	Full            bool                       // Full exports every session in incremental mode while still refreshing the state file.
+	PrettyJSON      bool                       // PrettyJSON indents the messages JSON embedded in the "JSON String in CSV" format.
+	RepairOut       string                     // RepairOut is the path the repaired file is written to instead of prompting for it.
+	Retry           filesystem.RetryPolicy     // Retry controls retrying transient failures of output file operations.
+	Verbose         bool                       // Verbose prints additional diagnostics, such as every retried file operation.
+	StripJSON       bool                       // StripJSON removes trailing commas and comments from the input when repairing data.
+	FineTuneWeights bool                       // FineTuneWeights adds a weight to the assistant messages of the fine-tuning export.
+	JSONLArray      bool                       // JSONLArray writes the fine-tuning export as a single JSON array instead of JSONL.
+	Benchmark       int                        // Benchmark runs the selected conversion this many times in memory and reports its throughput.
+	LogFormat       string                     // LogFormat selects human-readable text or structured JSON lines for diagnostics.
+	AllBranches     bool                       // AllBranches keeps the abandoned branches of regenerated answers instead of only the active one.
+	DateField       exporter.DateField         // DateField selects the session timestamp used for listing and date columns.
+	Diff            string                     // Diff is the path of an older export to compare the input file with instead of exporting.
+	MergeStore      string                     // MergeStore is the path of another backup to merge into the input file instead of exporting.
+	MergeConflicts  exporter.ConflictPolicy    // MergeConflicts determines how MergeStore resolves sessions that diverged in both files.
+	HubRepo         string                     // HubRepo is the Hugging Face dataset repository the dataset export is uploaded to.
+	HubDryRun       bool                       // HubDryRun prints the plan of the upload to HubRepo instead of uploading.
+	SheetsID        string                     // SheetsID is the Google spreadsheet the sessions are written into instead of exporting files.
+	SheetsKey       string                     // SheetsKey is the path of the service account JSON key used for SheetsID.
+	AppendDedup     bool                       // AppendDedup appends only sessions missing from an existing single CSV file instead of overwriting it.
+	Inspect         bool                       // Inspect pretty-prints the raw input by streaming it instead of exporting.
+	InspectLimit    int                        // InspectLimit restricts the output of Inspect to the first sessions; 0 shows all.
+	InspectSession  string                     // InspectSession restricts the output of Inspect to the session with this ID.
+	TempOut         bool                       // TempOut writes the export to a new temporary file and prints only its path to stdout.
+	DuplicateIDs    exporter.DuplicateIDPolicy // DuplicateIDs determines how sessions sharing an ID are resolved.
+	InputFormat     exporter.InputFormat       // InputFormat forces the layout the input files are decoded with.
+	MessageDates    exporter.MessageDatePolicy // MessageDates determines what happens to messages without a date.
+	RerunOnHUP      bool                       // RerunOnHUP keeps the program running after an export and replays it on every SIGHUP.
+	HealthCheck     bool                       // HealthCheck verifies that the binary can parse a store and write a file, then exits.
+	SelfTest        bool                       // SelfTest runs the health checks and exports every format in memory, then exits.
+	InvalidUTF8     exporter.InvalidUTF8Policy // InvalidUTF8 determines what happens to messages whose content is not valid UTF-8.
+	TagsFile        string                     // TagsFile is the path of the tags file; empty uses the one in the state directory.
+	Tag             bool                       // Tag walks the sessions to edit their tags interactively instead of exporting.
+	TagsColumn      bool                       // TagsColumn adds a tags column to the CSV formats.
+	Merge           bool                       // Merge combines sessions picked from the list into one and saves the store as backup JSON instead of exporting.
+	View            bool                       // View reads the sessions in a terminal pager instead of exporting.
+	Filter          exporter.SessionFilter     // Filter restricts the export to the sessions it matches.
+	IncludeEmpty    bool                       // IncludeEmpty exports sessions without messages instead of skipping them.
+	Encoding        exporter.Encoding          // Encoding is the character encoding of the CSV output.
+	Unencodable     exporter.UnencodablePolicy // Unencodable determines what happens to characters Encoding cannot represent.
+	Sample          int                        // Sample exports a uniform random subset of this many sessions; 0 exports all.
+	SampleSeed      int64                      // SampleSeed seeds the choice of Sample; a random seed is picked unless -sample-seed is set.
+	AnonymizeIDs    bool                       // AnonymizeIDs replaces session IDs with sequential anonymous ones.
+	IDMap           string                     // IDMap is the path of a CSV file mapping the anonymous session IDs to the original ones.
+	SessionHeaders  bool                       // SessionHeaders precedes every session of the dataset with a metadata record.
+	Update          bool                       // Update replaces the binary with the latest release instead of exporting.
+	UpdateYes       bool                       // UpdateYes applies the update of Update without asking for confirmation.
+	SinceTag        string                     // SinceTag prints the notes of the releases newer than this version instead of exporting.
+	FailFast        bool                       // FailFast stops a batch of input files at the first file that fails instead of exporting the rest.
+
+	// estimatedDates is set by exportInput, not by a flag, when the date of an exported message was estimated,
+	// so that the message-level CSV formats add an "estimated" column.
+	estimatedDates bool
+}
+
+// hiddenFlags lists the flags left out of the usage text. They are meant for maintainers rather than everyday use.
+var hiddenFlags = map[string]bool{
+	"benchmark": true,
+}
+
+// parseFlags parses the given command-line arguments (without the program name) into cliOptions.
+// Values from the environment are applied first so that an explicit flag always takes precedence.
+func parseFlags(args []string, getenv func(string) string) (cliOptions, error) {
+	var opts cliOptions
+	opts.NoBanner = envBool(getenv(EnvNoBanner))
+	opts.BaseURL = getenv(EnvBaseURL)
+	opts.Banner = bannercli.DefaultConfig()
+	bannerStyleDefault := "auto"
+	if style := getenv(EnvBannerStyle); style != "" {
+		bannerStyleDefault = style
+	}
+	if delay := getenv(EnvBannerDelay); delay != "" {
+		var err error
+		if opts.Banner.Delay, err = time.ParseDuration(delay); err != nil {
+			return opts, fmt.Errorf("%s: %w", EnvBannerDelay, err)
+		}
+	}
+
+	flagSet := flag.NewFlagSet("ChatGPT-Next-Web-Session-Exporter", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	flagSet.BoolVar(&opts.NoBanner, "no-banner", opts.NoBanner, "skip the startup banner (env "+EnvNoBanner+")")
+	bannerStyle := flagSet.String("banner-style", bannerStyleDefault, "style of the startup banner: typing, animated, binary, none, or auto for typing on a terminal and none otherwise (env "+EnvBannerStyle+")")
+	flagSet.DurationVar(&opts.Banner.Delay, "banner-delay", opts.Banner.Delay, "delay per character or frame of the typing and animated banners (env "+EnvBannerDelay+")")
+	flagSet.IntVar(&opts.Banner.Repeat, "banner-repeat", opts.Banner.Repeat, "number of times the animated banner scrolls")
+	flagSet.StringVar(&opts.Format, "format", "", "output format to use instead of prompting: csv, dataset, orgmode, list, finetune, summaries, epub, text, qa-jsonl, turns-json, or atom")
+	list := flagSet.Bool("list", false, "print a table of the sessions to browse them without exporting; short for -format list")
+	flagSet.StringVar(&opts.OutputZip, "output-zip", "", "write all output files into a single zip archive at this path")
+	flagSet.BoolVar(&opts.TempOut, "tempout", false, "write the export to a new temporary file without prompting for names, and print only its path to stdout; requires -format")
+	flagSet.BoolVar(&opts.JSONOutput, "json-output", false, "print a JSON summary of the run to stdout and all other text to stderr")
+	unknownRoles := flagSet.String("unknown-roles", "keep", "what to do with messages whose role is not recognized: keep, user, or drop")
+	encoding := flagSet.String("encoding", "utf-8", "character encoding of the CSV output: utf-8, windows-1252, iso-8859-1, or iso-8859-15")
+	unencodable := flagSet.String("unencodable", "replace", "what to do with characters the -encoding cannot represent: replace (with the substitute control character 0x1A) or error")
+	redact := flagSet.String("redact", "", "comma-separated redaction rules applied to message content: keys, tokens, code-blocks, or all")
+	flagSet.BoolVar(&opts.PreserveOrder, "preserve-order", false, "keep the original field ordering when repairing data")
+	flagSet.BoolVar(&opts.StripJSON, "strip-json-artifacts", false, "remove trailing commas and // or /* */ comments from the input when repairing data")
+	flagSet.StringVar(&opts.RepairOut, "repair-out", "", "path of the repaired file instead of prompting for it (default repaired_<input file name>)")
+	flagSet.StringVar(&opts.BaseURL, "base-url", opts.BaseURL, "address of the ChatGPT-Next-Web deployment used to add a link to each session (env "+EnvBaseURL+")")
+	flagSet.StringVar(&opts.Attachments, "extract-attachments", "", "extract inline attachments into this directory and reference them by relative path")
+	flagSet.BoolVar(&opts.Download, "download-attachments", false, "also download linked attachments when -extract-attachments is set")
+	flagSet.BoolVar(&opts.SessionHeaders, "dataset-session-headers", false, "precede every session of the dataset with a session_header record holding its ID, title, model, and creation time")
+	flagSet.BoolVar(&opts.FineTuneWeights, "fine-tune-weights", false, "add a weight of 1 to every assistant message of the finetune format")
+	flagSet.BoolVar(&opts.JSONLArray, "jsonl-array", false, "write the finetune format as a single JSON array instead of JSONL, for tools that require one JSON value per file")
+	flagSet.BoolVar(&opts.AllBranches, "all-branches", false, "keep every branch of regenerated answers and add a branch_id column to message-level CSV formats")
+	inputFormat := flagSet.String("input-format", "auto", "layout to decode the input files with instead of detecting it: auto, nextweb (sessions as an array), or nextweb-object (sessions as an object keyed by ID)")
+	messageDates := flagSet.String("message-dates", "estimate", "what to do with messages without a date of their own: estimate (interpolate between the session dates, marked as estimated) or blank")
+	duplicateIDs := flagSet.String("duplicate-ids", "keep-both", "how to resolve sessions sharing an ID: keep-both (suffix the later IDs), newest, or abort")
+	invalidUTF8 := flagSet.String("on-invalid-utf8", "sanitize", "what to do with messages whose content is not valid UTF-8: sanitize (replace the invalid bytes), skip, or error")
+	flagSet.StringVar(&opts.TagsFile, "tags-file", "", "path of the tags file (default tags.json in the state directory)")
+	flagSet.BoolVar(&opts.TagsColumn, "tags-column", false, "add a tags column with the tags of each session from the tags file to the CSV formats")
+	flagSet.BoolVar(&opts.Tag, "tag", false, "walk the sessions and enter tags for each, saved to the tags file, instead of exporting")
+	flagSet.BoolVar(&opts.Merge, "merge", false, "pick two or more sessions from the list and merge them into one, then save the store as backup JSON instead of exporting")
+	flagSet.BoolVar(&opts.View, "view", false, "pick a session from the list and read the sessions in a terminal pager instead of exporting; without a terminal, print their transcripts")
+	filter := flagSet.String("filter", "", "export only the sessions matching every term, such as \"tag:work tag:2024\"")
+	excludeModels := flagSet.String("exclude-model", "", "comma-separated models whose sessions are left out of the export, even when they match -filter")
+	excludeIDs := flagSet.String("exclude-session-id", "", "comma-separated IDs of sessions left out of the export, even when they match -filter")
+	excludeSearch := flagSet.String("exclude-search", "", "leave out sessions whose topic or messages contain this text, ignoring case, even when they match -filter")
+	flagSet.BoolVar(&opts.IncludeEmpty, "include-empty", false, "export sessions without messages instead of skipping them, as header-only rows in the per-line CSV format")
+	flagSet.IntVar(&opts.Sample, "sample", 0, "export a uniform random subset of N sessions picked from the whole file")
+	flagSet.Int64Var(&opts.SampleSeed, "sample-seed", 0, "seed of -sample, to export the same sample again (default a random seed, which is printed)")
+	flagSet.BoolVar(&opts.AnonymizeIDs, "anonymize-ids", false, "replace session IDs with sequential anonymous ones (s0001, s0002, ...) in the export")
+	flagSet.StringVar(&opts.IDMap, "id-map", "", "with -anonymize-ids, write the mapping of anonymous to original session IDs to this CSV file, or auto for one per input file in the state directory")
+	dateField := flagSet.String("date-field", "updated", "session timestamp used for dates: created or updated, falling back to the other when missing")
+	flagSet.StringVar(&opts.HubRepo, "hf-repo", "", "upload the dataset export to this Hugging Face dataset repository (owner/name), using the token in "+uploader.EnvHuggingFaceToken)
+	flagSet.BoolVar(&opts.HubDryRun, "hf-dry-run", false, "print what -hf-repo would create and upload without changing the repository")
+	flagSet.StringVar(&opts.SheetsID, "sheets-id", "", "write the sessions into a new sheet of this Google spreadsheet instead of exporting files")
+	flagSet.StringVar(&opts.SheetsKey, "sheets-key", getenv(uploader.EnvGoogleCredentials), "path of the service account JSON key used by -sheets-id (env "+uploader.EnvGoogleCredentials+")")
+	flagSet.BoolVar(&opts.AppendDedup, "append-dedup", false, "append only the sessions whose IDs an existing CSV file does not contain yet, instead of overwriting it")
+	flagSet.BoolVar(&opts.PrettyJSON, "pretty-json-cells", false, "indent the messages JSON embedded in CSV cells by the JSON String in CSV format")
+	flagSet.BoolVar(&opts.Inspect, "inspect", false, "pretty-print the raw input file, streaming it with bounded memory, instead of exporting")
+	flagSet.IntVar(&opts.InspectLimit, "inspect-limit", 0, "with -inspect, show only the first N sessions")
+	flagSet.StringVar(&opts.InspectSession, "inspect-session", "", "with -inspect, show only the session with this ID")
+	flagSet.StringVar(&opts.MergeStore, "merge-store", "", "merge the sessions of this other backup, such as one from another device, into the input file and save the result as backup JSON instead of exporting")
+	mergeConflicts := flagSet.String("merge-conflicts", "ask", "with -merge-store, how to resolve sessions that diverged in both files: ask, prefer-newest, prefer-left (the input file), prefer-right, or keep-both")
+	flagSet.StringVar(&opts.Diff, "diff", "", "compare the input file with this older export and print the sessions added, removed, and modified instead of exporting")
+	flagSet.BoolVar(&opts.RerunOnHUP, "rerun-on-hup", false, "after the export, keep running and re-run it with the same answers on every SIGHUP until interrupted")
+	flagSet.BoolVar(&opts.FailFast, "fail-fast", false, "when the input is a directory or glob pattern, stop at the first file that fails and exit with its error")
+	keepGoing := flagSet.Bool("keep-going", false, "when the input is a directory or glob pattern, export every file and report the failures at the end (the default)")
+	flagSet.StringVar(&opts.StateFile, "incremental", "", "only export sessions that are new or changed since the run that saved this state file, or auto for one per input file in the state directory")
+	flagSet.BoolVar(&opts.Full, "full", false, "export all sessions even when -incremental is set, and refresh the state file")
+	flagSet.IntVar(&opts.Retry.Attempts, "retry", 0, "number of extra attempts when writing or checking output files fails transiently")
+	flagSet.DurationVar(&opts.Retry.Backoff, "retry-backoff", 500*time.Millisecond, "delay before the first output retry, doubled (plus jitter) after each retry")
+	flagSet.BoolVar(&opts.Verbose, "verbose", false, "print additional diagnostics, such as retried file operations and a preview of the first sessions, to stderr")
+	flagSet.IntVar(&opts.ReadRetry.Attempts, "read-retries", 0, "number of extra attempts when reading the input file fails transiently")
+	flagSet.DurationVar(&opts.ReadRetry.Backoff, "read-backoff", 500*time.Millisecond, "delay before the first input read retry, doubled after each retry")
+	flagSet.StringVar(&opts.LogFormat, "log-format", LogFormatText, "format of diagnostics: text, or json for structured JSON lines on stderr")
+	flagSet.BoolVar(&opts.Update, "update", false, "check for a newer release and replace this binary with it, keeping a backup, instead of exporting")
+	flagSet.BoolVar(&opts.UpdateYes, "update-yes", false, "with -update, replace the binary without asking, as needed when stdin is not a terminal")
+	flagSet.StringVar(&opts.SinceTag, "since-tag", "", "print the release notes of every release newer than this version, newest first, instead of exporting; \"current\" means the running version")
+	flagSet.BoolVar(&opts.HealthCheck, "healthcheck", false, "check that an embedded store can be parsed and written to a temporary file, then exit with status 0 if it can")
+	flagSet.BoolVar(&opts.SelfTest, "selftest", false, "run the health checks and export an embedded store in every format in memory, then exit; -verbose prints a table of all checks")
+	flagSet.IntVar(&opts.Benchmark, "benchmark", 0, "run the selected conversion this many times in memory and report sessions/sec and MB/sec instead of exporting")
+
+	if err := flagSet.Parse(args); err != nil {
+		printVisibleDefaults(os.Stderr, flagSet)
+		return opts, err
+	}
+
+	var err error
+	if opts.UnknownRoles, err = exporter.ParseUnknownRolePolicy(*unknownRoles); err != nil {
+		return opts, err
+	}
+	if opts.Redact, err = exporter.ParseRedactRules(*redact); err != nil {
+		return opts, err
+	}
+	if opts.Encoding, err = exporter.ParseEncoding(*encoding); err != nil {
+		return opts, err
+	}
+	if opts.Unencodable, err = exporter.ParseUnencodablePolicy(*unencodable); err != nil {
+		return opts, err
+	}
+	if opts.DateField, err = exporter.ParseDateField(*dateField); err != nil {
+		return opts, err
+	}
+	if opts.DuplicateIDs, err = exporter.ParseDuplicateIDPolicy(*duplicateIDs); err != nil {
+		return opts, err
+	}
+	if opts.InputFormat, err = exporter.ParseInputFormat(*inputFormat); err != nil {
+		return opts, err
+	}
+	if opts.MessageDates, err = exporter.ParseMessageDatePolicy(*messageDates); err != nil {
+		return opts, err
+	}
+	if opts.InvalidUTF8, err = exporter.ParseInvalidUTF8Policy(*invalidUTF8); err != nil {
+		return opts, err
+	}
+	if opts.Filter, err = exporter.ParseSessionFilter(*filter); err != nil {
+		return opts, err
+	}
+	opts.Filter.ExcludeModels = splitList(*excludeModels)
+	opts.Filter.ExcludeSessionIDs = splitList(*excludeIDs)
+	opts.Filter.ExcludeSearchQuery = *excludeSearch
+	if opts.MergeConflicts, err = exporter.ParseConflictPolicy(*mergeConflicts); err != nil {
+		return opts, err
+	}
+	if opts.Banner.Style, err = parseBannerStyle(*bannerStyle); err != nil {
+		return opts, err
+	}
+	if opts.NoBanner {
+		opts.Banner.Style = bannercli.StyleNone
+	}
+	if opts.Banner.Delay < 0 {
+		return opts, fmt.Errorf("-banner-delay must not be negative")
+	}
+	if opts.Banner.Repeat < 0 {
+		return opts, fmt.Errorf("-banner-repeat must not be negative")
+	}
+
+	if opts.ReadRetry.Attempts < 0 {
+		return opts, fmt.Errorf("-read-retries must not be negative")
+	}
+	if opts.Retry.Attempts < 0 {
+		return opts, fmt.Errorf("-retry must not be negative")
+	}
+	// The flags count retries, while the policies count the first attempt as well.
+	opts.ReadRetry.Attempts++
+	opts.Retry.Attempts++
+
+	opts.BaseURL = strings.TrimSpace(opts.BaseURL)
+	if opts.BaseURL != "" {
+		if u, err := url.Parse(opts.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return opts, fmt.Errorf("-base-url must be an absolute URL such as https://chat.example.com")
+		}
+	}
+
+	if opts.Download && opts.Attachments == "" {
+		return opts, fmt.Errorf("-download-attachments requires -extract-attachments")
+	}
+
+	opts.LogFormat = strings.ToLower(strings.TrimSpace(opts.LogFormat))
+	if opts.LogFormat != LogFormatText && opts.LogFormat != LogFormatJSON {
+		return opts, fmt.Errorf("-log-format must be text or json")
+	}
+
+	if opts.Benchmark < 0 {
+		return opts, fmt.Errorf("-benchmark must not be negative")
+	}
+
+	if opts.HubDryRun && opts.HubRepo == "" {
+		return opts, fmt.Errorf("-hf-dry-run requires -hf-repo")
+	}
+	if owner, name, ok := strings.Cut(opts.HubRepo, "/"); opts.HubRepo != "" && (!ok || owner == "" || name == "" || strings.Contains(name, "/")) {
+		return opts, fmt.Errorf("-hf-repo must have the form owner/name")
+	}
+
+	if opts.SheetsID != "" && opts.SheetsKey == "" {
+		return opts, fmt.Errorf("-sheets-id requires -sheets-key or %s", uploader.EnvGoogleCredentials)
+	}
+	if opts.SheetsID != "" && (opts.Format != "" || opts.TempOut || opts.OutputZip != "") {
+		return opts, fmt.Errorf("-sheets-id cannot be combined with -format, -tempout, or -output-zip")
+	}
+
+	if opts.AppendDedup && opts.OutputZip != "" {
+		return opts, fmt.Errorf("-append-dedup cannot be combined with -output-zip")
+	}
+
+	if opts.InspectLimit < 0 {
+		return opts, fmt.Errorf("-inspect-limit must not be negative")
+	}
+	if (opts.InspectLimit > 0 || opts.InspectSession != "") && !opts.Inspect {
+		return opts, fmt.Errorf("-inspect-limit and -inspect-session require -inspect")
+	}
+
+	if opts.HealthCheck && opts.SelfTest {
+		return opts, fmt.Errorf("-healthcheck cannot be combined with -selftest")
+	}
+	if opts.UpdateYes && !opts.Update {
+		return opts, fmt.Errorf("-update-yes requires -update")
+	}
+	opts.SinceTag = strings.TrimSpace(opts.SinceTag)
+	if opts.SinceTag != "" && opts.Update {
+		return opts, fmt.Errorf("-since-tag cannot be combined with -update, which shows the same release notes")
+	}
+
+	if opts.Sample < 0 {
+		return opts, fmt.Errorf("-sample must not be negative")
+	}
+	// Any seed, including 0, can be given explicitly, so the flag is checked for being set rather than for its value.
+	seedSet := false
+	flagSet.Visit(func(f *flag.Flag) {
+		seedSet = seedSet || f.Name == "sample-seed"
+	})
+	if seedSet && opts.Sample == 0 {
+		return opts, fmt.Errorf("-sample-seed requires -sample")
+	}
+	if opts.Sample > 0 && !seedSet {
+		opts.SampleSeed = time.Now().UnixNano()
+	}
+	if opts.IDMap != "" && !opts.AnonymizeIDs {
+		return opts, fmt.Errorf("-id-map requires -anonymize-ids")
+	}
+	if opts.MergeConflicts != exporter.ConflictAsk && opts.MergeStore == "" {
+		return opts, fmt.Errorf("-merge-conflicts requires -merge-store")
+	}
+
+	if opts.FailFast && *keepGoing {
+		return opts, fmt.Errorf("-fail-fast and -keep-going are mutually exclusive")
+	}
+
+	if opts.Full && opts.StateFile == "" {
+		return opts, fmt.Errorf("-full requires -incremental")
+	}
+
+	opts.Format = strings.ToLower(strings.TrimSpace(opts.Format))
+	if *list {
+		if opts.Format != "" && opts.Format != "list" {
+			return opts, fmt.Errorf("-list cannot be combined with -format %s", opts.Format)
+		}
+		opts.Format = "list"
+	}
+	if _, ok := outputOptionForFormat(opts.Format); opts.Format != "" && !ok {
+		return opts, fmt.Errorf("unknown output format %q", opts.Format)
+	}
+	if opts.Benchmark > 0 && opts.Format == "list" {
+		return opts, fmt.Errorf("-benchmark requires a format that writes files")
+	}
+	if opts.AppendDedup && opts.Format != "" && opts.Format != "csv" {
+		return opts, fmt.Errorf("-append-dedup requires the csv format")
+	}
+	if opts.HubRepo != "" && opts.Format != "" && opts.Format != "dataset" {
+		return opts, fmt.Errorf("-hf-repo requires the dataset format")
+	}
+	if opts.TempOut {
+		switch {
+		case (opts.Format == "" || opts.Format == "list") && opts.MergeStore == "":
+			return opts, fmt.Errorf("-tempout requires -format with a format that writes a file, or -merge-store")
+		case opts.JSONOutput:
+			return opts, fmt.Errorf("-tempout cannot be combined with -json-output")
+		case opts.OutputZip != "" || opts.AppendDedup || opts.StateFile != "" || opts.Attachments != "":
+			return opts, fmt.Errorf("-tempout cannot be combined with -output-zip, -append-dedup, -incremental, or -extract-attachments")
+		}
+	}
+	return opts, nil
+}
+
+// printVisibleDefaults prints the usage text of all flags in flagSet except the hidden ones.
+func printVisibleDefaults(w io.Writer, flagSet *flag.FlagSet) {
+	visible := flag.NewFlagSet(flagSet.Name(), flag.ContinueOnError)
+	visible.SetOutput(w)
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			visible.Var(f.Value, f.Name, f.Usage)
+			visible.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	visible.PrintDefaults()
+}
+
+// outputOptionForFormat maps an output format name given on the command line to the
+// corresponding option of the output format menu. It reports false if the name is unknown.
+func outputOptionForFormat(format string) (string, bool) {
+	switch format {
+	case "csv":
+		return `1`, true
+	case "dataset":
+		return `2`, true
+	case "orgmode", "org":
+		return `3`, true
+	case "list":
+		return `4`, true
+	case "finetune", "jsonl":
+		return `5`, true
+	case "summaries":
+		return `6`, true
+	case "epub":
+		return `8`, true
+	case "text":
+		return `9`, true
+	case "qa-jsonl":
+		return `10`, true
+	case "turns-json":
+		return `11`, true
+	case "atom":
+		return `12`, true
+	default:
+		return "", false
+	}
+}
+
+// outputFormatName is the inverse of outputOptionForFormat: it returns the name of the output format
+// selected by a menu option, or "unknown" for an invalid option.
+func outputFormatName(option string) string {
+	for _, name := range []string{"csv", "dataset", "orgmode", "list", "finetune", "summaries", "epub", "text", "qa-jsonl", "turns-json", "atom"} {
+		if candidate, _ := outputOptionForFormat(name); candidate == option {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// interactiveOutput reports whether standard output is a terminal, which selects the banner style "auto" resolves to.
+var interactiveOutput = func() bool {
+	return tablecli.IsTerminal(os.Stdout)
+}
+
+// parseBannerStyle parses the value of -banner-style. "auto" resolves to the typing banner when the output
+// is a terminal, and to no banner when it is not, so that scripts and CI logs are not slowed down by it.
+func parseBannerStyle(name string) (bannercli.Style, error) {
+	if strings.ToLower(strings.TrimSpace(name)) == "auto" {
+		if interactiveOutput() {
+			return bannercli.StyleTyping, nil
+		}
+		return bannercli.StyleNone, nil
+	}
+	return bannercli.ParseStyle(name)
+}
+
+// envBool reports whether an environment variable value should be treated as enabled.
+// Any value accepted by strconv.ParseBool is honored, as well as "yes" and "on".
+func envBool(value string) bool {
+	value = strings.TrimSpace(strings.ToLower(value))
+	switch value {
+	case "yes", "on":
+		return true
+	}
+	enabled, err := strconv.ParseBool(value)
+	return err == nil && enabled
+}
+
+// splitList splits a comma-separated flag value into its trimmed, non-empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
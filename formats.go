@@ -0,0 +1,81 @@
+// @formats.go:
+// This file implements the formats command and the matching menu option, which describe the columns
+// and structure of every export format, with an example rendered from a small built-in sample store.
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/tablecli"
+)
+
+// formatsCommand is the first argument that runs the formats command instead of the usual export flow.
+const formatsCommand = "formats"
+
+// printFormats describes every registered export format: its name and file extension, its fields,
+// and an example of its output for exporter.ExampleSessions.
+func printFormats(ctx context.Context, w io.Writer) error {
+	sessions := exporter.ExampleSessions()
+	for i, format := range exporter.Formats() {
+		doc := format.Describe()
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s (%s)\n%s\n\n", doc.Name, doc.Extension, doc.Description)
+
+		table := &tablecli.Table{Headers: []string{"Field", "Type", "Description"}, FlexColumn: -1}
+		for _, field := range doc.Fields {
+			table.AddRow(field.Name, field.Type, field.Description)
+		}
+		if err := table.Render(w, 0); err != nil {
+			return err
+		}
+
+		files, err := renderFormat(ctx, format, sessions)
+		if err != nil {
+			return fmt.Errorf("rendering the example of %s: %w", doc.Name, err)
+		}
+		fmt.Fprintln(w, "\nExample:")
+		for _, file := range files {
+			if file.name != "" {
+				fmt.Fprintf(w, "  %s:\n", file.name)
+			}
+			if doc.Binary {
+				fmt.Fprintf(w, "  (binary file of %s)\n", formatSize(int64(file.data.Len())))
+				continue
+			}
+			for _, line := range strings.Split(strings.TrimRight(file.data.String(), "\n"), "\n") {
+				fmt.Fprintln(w, "  "+line)
+			}
+		}
+	}
+	return nil
+}
+
+// renderedFile is a file of the output of a format rendered by renderFormat.
+type renderedFile struct {
+	name string       // name is the file name given by an exporter.MultiFileFormat, empty for other formats.
+	data bytes.Buffer // data is the content of the file.
+}
+
+// renderFormat writes the sessions in the format with the default options, and returns the single file
+// of its output, or every file of an exporter.MultiFileFormat in the order it wrote them.
+func renderFormat(ctx context.Context, format exporter.Format, sessions []exporter.Session) ([]*renderedFile, error) {
+	multi, ok := format.(exporter.MultiFileFormat)
+	if !ok {
+		file := &renderedFile{}
+		return []*renderedFile{file}, format.Write(ctx, &file.data, sessions, exporter.Options{})
+	}
+	var files []*renderedFile
+	err := multi.WriteFiles(ctx, func(name string) io.Writer {
+		file := &renderedFile{name: name}
+		files = append(files, file)
+		return &file.data
+	}, sessions, exporter.Options{})
+	return files, err
+}
@@ -1,3 +1,10 @@
 module github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter
 
 go 1.21.5
+
+require (
+	golang.org/x/oauth2 v0.24.0
+	golang.org/x/text v0.21.0
+)
+
+require cloud.google.com/go/compute/metadata v0.3.0 // indirect
@@ -0,0 +1,47 @@
+// @hub.go:
+// This file implements the optional upload of the dataset export to a dataset repository on the
+// Hugging Face Hub, enabled with -hf-repo and previewed with -hf-dry-run.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/uploader"
+)
+
+// hubDatasetFileName is the name of the dataset in the repository when it was not saved to a file.
+const hubDatasetFileName = "dataset.json"
+
+// newHubUploader returns an uploader authenticated with the token of the HF_TOKEN environment variable.
+// A dry run works without a token, as long as the repository is public or missing.
+func newHubUploader(dryRun bool) *uploader.HuggingFaceUploader {
+	hub := uploader.NewHuggingFaceUploader(os.Getenv(uploader.EnvHuggingFaceToken))
+	hub.DryRun = dryRun
+	return hub
+}
+
+// pushDatasetToHub uploads the dataset content to the repository repoID, naming it after the file it was
+// saved to, or hubDatasetFileName when it was not saved. Progress and the plan of a dry run go to w.
+func pushDatasetToHub(ctx context.Context, w io.Writer, hub *uploader.HuggingFaceUploader, repoID, fileName, content string) error {
+	if hub.Token == "" && !hub.DryRun {
+		return fmt.Errorf("environment variable %s is not set", uploader.EnvHuggingFaceToken)
+	}
+	path := hubDatasetFileName
+	if fileName != "" {
+		path = filepath.Base(fileName)
+	}
+	hub.Out = w
+
+	files := []uploader.HubFile{{Path: path, Content: []byte(content)}}
+	if err := hub.Upload(ctx, repoID, files, "Upload "+path+" with ChatGPT-Next-Web-Session-Exporter"); err != nil {
+		return err
+	}
+	if !hub.DryRun {
+		fmt.Fprintf(w, "Dataset uploaded to https://huggingface.co/datasets/%s\n", repoID)
+	}
+	return nil
+}
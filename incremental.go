@@ -0,0 +1,187 @@
+// @incremental.go:
+// This file implements incremental exports: a state file records a content hash for every session
+// after a successful export, so that the next run with -incremental only exports the sessions that
+// are new or changed since then.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/appdirs"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// loadExportState reads the export state saved by a previous run.
+// A missing state file is not an error; it yields an empty state, so every session counts as new.
+func loadExportState(rfs filesystem.FileSystem, path string) (exporter.ExportState, error) {
+	data, err := rfs.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return exporter.ExportState{}, nil
+	}
+	if err != nil {
+		return exporter.ExportState{}, err
+	}
+	state, err := exporter.ParseExportState(data)
+	if err != nil {
+		return exporter.ExportState{}, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// saveExportState records the hashes of all sessions in the state file at path, creating its
+// directory if needed. The file is replaced atomically, so a crash while saving leaves the previous
+// state intact.
+func saveExportState(rfs filesystem.FileSystem, path string, sessions []exporter.Session) error {
+	data, err := json.MarshalIndent(exporter.NewExportState(sessions), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := appdirs.Ensure(rfs, filepath.Dir(path)); err != nil {
+		return err
+	}
+	return filesystem.AtomicWriteFile(rfs, path, data, 0644)
+}
+
+// selectIncrementalSessions loads the state file and returns the sessions that should be exported:
+// the new and changed ones, or all of them when full is set. The classification is printed to run
+// and recorded in the run summary either way.
+func selectIncrementalSessions(run *runState, rfs filesystem.FileSystem, path string, full bool, sessions []exporter.Session) ([]exporter.Session, error) {
+	previous, err := loadExportState(rfs, path)
+	if err != nil {
+		return nil, err
+	}
+	changed, report := exporter.FilterChangedSessions(sessions, previous)
+	if run.summary != nil {
+		run.summary.Incremental = &report
+	}
+	run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("%d new, %d changed, %d unchanged session(s) since the last export.",
+		report.New, report.Changed, report.Unchanged), "new", report.New, "changed", report.Changed, "unchanged", report.Unchanged)
+	if full {
+		return sessions, nil
+	}
+	return changed, nil
+}
+
+// writeTrackingFileSystem wraps a FileSystem and counts the files successfully written through it,
+// so that the export state is only saved after an export actually produced output.
+type writeTrackingFileSystem struct {
+	filesystem.FileSystem
+	writes  int
+	written []string // written lists the names of the files written, in order.
+}
+
+// WriteFile writes the file through the wrapped FileSystem and counts it on success.
+func (t *writeTrackingFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := t.FileSystem.WriteFile(name, data, perm); err != nil {
+		return err
+	}
+	t.writes++
+	t.written = append(t.written, name)
+	return nil
+}
+
+// AtomicWriteFile replaces the file atomically through the wrapped FileSystem and counts it on success.
+func (t *writeTrackingFileSystem) AtomicWriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := filesystem.AtomicWriteFile(t.FileSystem, name, data, perm); err != nil {
+		return err
+	}
+	t.writes++
+	t.written = append(t.written, name)
+	return nil
+}
+
+// AppendFile appends to the file through the wrapped FileSystem and counts it on success.
+func (t *writeTrackingFileSystem) AppendFile(name string, data []byte, perm fs.FileMode) error {
+	if err := filesystem.AppendFile(t.FileSystem, name, data, perm); err != nil {
+		return err
+	}
+	t.writes++
+	t.written = append(t.written, name)
+	return nil
+}
+
+// Remove removes the file through the wrapped FileSystem.
+func (t *writeTrackingFileSystem) Remove(name string) error {
+	return filesystem.Remove(t.FileSystem, name)
+}
+
+// MkdirAll creates the directory through the wrapped FileSystem.
+func (t *writeTrackingFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return filesystem.MkdirAll(t.FileSystem, path, perm)
+}
+
+// Open opens the file through the wrapped FileSystem.
+func (t *writeTrackingFileSystem) Open(name string) (io.ReadCloser, error) {
+	return filesystem.Open(t.FileSystem, name)
+}
+
+// CreateTempFile starts a file through the wrapped FileSystem that is counted once committed.
+func (t *writeTrackingFileSystem) CreateTempFile(dir string, perm fs.FileMode) (filesystem.TempFile, error) {
+	file, err := filesystem.CreateTempFile(t.FileSystem, dir, perm)
+	if err != nil {
+		return nil, err
+	}
+	return trackedTempFile{TempFile: file, tracker: t}, nil
+}
+
+// trackedTempFile is the TempFile of writeTrackingFileSystem.
+type trackedTempFile struct {
+	filesystem.TempFile
+	tracker *writeTrackingFileSystem
+}
+
+// Commit commits the file and counts it on success.
+func (t trackedTempFile) Commit(name string) error {
+	if err := t.TempFile.Commit(name); err != nil {
+		return err
+	}
+	t.tracker.writes++
+	t.tracker.written = append(t.tracker.written, name)
+	return nil
+}
+
+// withoutUnsampled returns sessions without those of selected that -sample left out of sampled, so that the
+// state saved after the export does not mark them as exported and a later run still picks them up.
+func withoutUnsampled(sessions, selected, sampled []exporter.Session) []exporter.Session {
+	kept := make(map[string]bool, len(sampled))
+	for _, session := range sampled {
+		kept[session.ID] = true
+	}
+	unsampled := make(map[string]bool, len(selected)-len(sampled))
+	for _, session := range selected {
+		if !kept[session.ID] {
+			unsampled[session.ID] = true
+		}
+	}
+	if len(unsampled) == 0 {
+		return sessions
+	}
+	remaining := make([]exporter.Session, 0, len(sessions)-len(unsampled))
+	for _, session := range sessions {
+		if !unsampled[session.ID] {
+			remaining = append(remaining, session)
+		}
+	}
+	return remaining
+}
+
+// finishIncrementalExport saves the export state for all sessions if the export wrote at least one
+// file and failed is false, meaning no error was reported, and tells the user about it.
+func finishIncrementalExport(run *runState, rfs filesystem.FileSystem, path string, tracker *writeTrackingFileSystem, failed bool, sessions []exporter.Session) error {
+	if tracker.writes == 0 || failed {
+		run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("Nothing was exported; the state file %s was left unchanged.", path), "state_file", path)
+		return nil
+	}
+	if err := saveExportState(rfs, path, sessions); err != nil {
+		return fmt.Errorf("saving state file: %w", err)
+	}
+	run.logDiagnostic(slog.LevelInfo, "Export state saved to "+path, "state_file", path)
+	return nil
+}
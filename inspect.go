@@ -0,0 +1,104 @@
+// @inspect.go:
+// This file implements the -inspect mode, which pretty-prints the raw input file for a look at its
+// structure. The input is streamed, so even stores too large for an editor can be inspected.
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// inspectBufferSize is the size of the buffers used to stream the input and output of -inspect.
+const inspectBufferSize = 64 * 1024
+
+// inspectInput pretty-prints the store at jsonFilePath to run or, if the user chooses to save the
+// output, to a file, following the usual prompts for saving output.
+func inspectInput(ctx context.Context, run *runState, reader *bufio.Reader, rfs filesystem.FileSystem, jsonFilePath string, opts exporter.InspectOptions) error {
+	saveOutput, err := promptForInput(ctx, run, reader, PromptSaveOutputToFile)
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(saveOutput) != "yes" {
+		return inspectTo(ctx, run, rfs, jsonFilePath, opts, nil)
+	}
+
+	fileName, err := promptForInput(ctx, run, reader, fmt.Sprintf(PromptEnterFileName, FileTypeInspect))
+	if err != nil {
+		return err
+	}
+	if fileName == "" {
+		run.printStatus("No file name entered. Operation cancelled.")
+		return nil
+	}
+	fileName += fileExtension(FileTypeInspect)
+
+	overwrite, err := confirmOverwrite(rfs, ctx, run, reader, fileName)
+	if err != nil {
+		return err
+	}
+	if !overwrite {
+		run.printStatus("Operation cancelled by the user.")
+		return nil
+	}
+
+	err = inspectToFile(ctx, rfs, fileName, jsonFilePath, opts, inspectProgress(run))
+	fmt.Fprintln(run) // end the progress line
+	if err != nil {
+		return err
+	}
+	run.printStatus(fmt.Sprintf("%s output saved to %s", strings.ToTitle(FileTypeInspect), fileName))
+	return nil
+}
+
+// inspectToFile streams the pretty-printed store into fileName of rfs. Like the other exports, the file is
+// replaced atomically, see filesystem.StreamFile. progress, if not nil, is called as the input is read.
+func inspectToFile(ctx context.Context, rfs filesystem.FileSystem, fileName, jsonFilePath string, opts exporter.InspectOptions, progress func(read, total int64)) error {
+	return filesystem.StreamFile(rfs, fileName, 0644, func(w io.Writer) error {
+		out := bufio.NewWriterSize(w, inspectBufferSize)
+		if err := inspectTo(ctx, out, rfs, jsonFilePath, opts, progress); err != nil {
+			return err
+		}
+		return out.Flush()
+	})
+}
+
+// inspectTo streams the store at jsonFilePath of rfs pretty-printed to w. progress, if not nil, is
+// called with the bytes of the input read so far and its size.
+func inspectTo(ctx context.Context, w io.Writer, rfs filesystem.FileSystem, jsonFilePath string, opts exporter.InspectOptions, progress func(read, total int64)) error {
+	file, err := filesystem.Open(rfs, jsonFilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var input io.Reader = file
+	if progress != nil {
+		total := int64(-1)
+		if info, err := rfs.Stat(jsonFilePath); err == nil {
+			total = info.Size()
+		}
+		input = &filesystem.ProgressReader{R: file, Total: total, Progress: progress}
+	}
+	return exporter.InspectStore(ctx, bufio.NewReaderSize(input, inspectBufferSize), w, opts)
+}
+
+// inspectProgress returns a progress callback that keeps a percentage of the input read on a single
+// line of w, rewriting it only when the percentage changes.
+func inspectProgress(w io.Writer) func(read, total int64) {
+	last := -1
+	return func(read, total int64) {
+		if total <= 0 {
+			return
+		}
+		if percent := int(read * 100 / total); percent != last {
+			last = percent
+			fmt.Fprintf(w, "\rInspecting the input: %d%%", percent)
+		}
+	}
+}
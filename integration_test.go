@@ -0,0 +1,124 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/testsupport"
+)
+
+// integrationFiles maps the single-file formats exported by TestIntegrationPipeline to their output files.
+var integrationFiles = map[string]string{
+	"csv-inline":   "inline.csv",
+	"csv-per-line": "perline.csv",
+	"csv-json":     "json.csv",
+	"dataset":      "dataset.json",
+}
+
+// TestIntegrationPipeline runs the whole export pipeline on an in-memory file system: a fixture store
+// is repaired, loaded, and filtered like the CLI tool does, sorted newest first, then exported in every
+// CSV format and as a dataset, and every output file is compared with its golden file in testdata/golden.
+//
+// Run it with `go test -tags=integration`, setting UPDATE_GOLDEN=1 to regenerate the golden files.
+func TestIntegrationPipeline(t *testing.T) {
+	testingJSON, err := os.ReadFile("testing.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	branchedJSON, err := json.Marshal(testsupport.BranchedStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallJSON, err := json.Marshal(testsupport.SmallStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fixture := range []struct {
+		name  string
+		data  []byte
+		order []string // order lists the session IDs newest first.
+	}{
+		{"testing", testingJSON, []string{"tnPorY4BK-yew1DFhVRGY"}},
+		{"branched", branchedJSON, []string{"branched", "partial", "linear"}}, // Without timestamps, the store order is kept.
+		{"small", smallJSON, []string{"session-2", "session-1"}},
+	} {
+		t.Run(fixture.name, func(t *testing.T) {
+			ctx := context.Background()
+			fsys := filesystem.NewMockFileSystem()
+			fsys.Files["input.json"] = fixture.data
+
+			repairedPath, _, err := repairJSONData(fsys, ctx, "input.json", "repaired.json", repairdata.RepairOptions{})
+			if err != nil {
+				t.Fatalf("repairJSONData() returned an error: %v", err)
+			}
+			store, err := loadStore(ctx, fsys, repairedPath, exporter.InputFormatAuto, filesystem.RetryPolicy{Attempts: 1})
+			if err != nil {
+				t.Fatalf("loadStore() returned an error: %v", err)
+			}
+
+			// The filter stage of exportInput.
+			sessions, _, err := exporter.ResolveDuplicateIDs(store.ChatNextWebStore.Sessions, exporter.DuplicateIDKeepBoth)
+			if err != nil {
+				t.Fatalf("ResolveDuplicateIDs() returned an error: %v", err)
+			}
+			sessions, _ = exporter.ApplyBranchPolicy(sessions, false)
+			sessions, _ = exporter.ApplyRolePolicy(sessions, exporter.RolePolicy{})
+
+			// The sort stage orders the sessions newest first, as the web app lists them, keeping the store
+			// order of sessions updated at the same time.
+			sort.SliceStable(sessions, func(i, j int) bool {
+				return sessions[i].Timestamp(exporter.DateFieldUpdated) > sessions[j].Timestamp(exporter.DateFieldUpdated)
+			})
+			ids := make([]string, len(sessions))
+			for i, session := range sessions {
+				ids[i] = session.ID
+			}
+			if !slices.Equal(ids, fixture.order) {
+				t.Errorf("sorted sessions = %v, want %v", ids, fixture.order)
+			}
+
+			for format, name := range integrationFiles {
+				var output bytes.Buffer
+				if err := exporter.ConvertSessions(ctx, &output, sessions, format, exporter.Options{}); err != nil {
+					t.Fatalf("ConvertSessions(%s) returned an error: %v", format, err)
+				}
+				if err := filesystem.AtomicWriteFile(fsys, name, output.Bytes(), 0644); err != nil {
+					t.Fatalf("AtomicWriteFile(%s) returned an error: %v", name, err)
+				}
+			}
+			if err := exporter.CreateSeparateCSVFiles(context.Background(), sessions, "sessions.csv", "messages.csv", filesystem.Atomic(fsys), exporter.CSVOptions{}); err != nil {
+				t.Fatalf("CreateSeparateCSVFiles() returned an error: %v", err)
+			}
+
+			for _, name := range []string{"inline.csv", "perline.csv", "json.csv", "sessions.csv", "messages.csv", "dataset.json"} {
+				output, err := fsys.ReadFile(name)
+				if err != nil {
+					t.Fatalf("%s was not written: %v", name, err)
+				}
+				testsupport.GoldenCompare(t, "integration_"+fixture.name+"_"+name, output)
+			}
+
+			// The dataset is the lossless format, so it must read back into the exported sessions.
+			dataset, _ := fsys.ReadFile("dataset.json")
+			parsed, err := exporter.ParseDatasetJSON(dataset)
+			if err != nil {
+				t.Fatalf("ParseDatasetJSON() returned an error: %v", err)
+			}
+			if !reflect.DeepEqual(parsed, sessions) {
+				t.Errorf("the dataset does not read back into the exported sessions")
+			}
+		})
+	}
+}
@@ -9,7 +9,7 @@ package interactivity
 import (
 	"bufio"
 	"context"
-	"fmt"
+	"io"
 	"strings"
 
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
@@ -26,10 +26,11 @@ type result struct {
 
 // ConfirmOverwrite checks if a file with the given fileName exists in the provided filesystem.
 // If the file does exist, it prompts the user for confirmation to overwrite the file.
-// The function reads the user's input via the provided bufio.Reader and expects a 'yes' or 'no' response.
+// The prompt is printed with the provided Printer, and the function reads the user's input via the provided
+// bufio.Reader, expecting a 'yes' or 'no' response.
 // A context.Context is used to handle cancellation of the input request.
 // It returns a boolean indicating whether the file should be overwritten and any error encountered.
-func ConfirmOverwrite(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, fileName string) (bool, error) {
+func ConfirmOverwrite(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, printer Printer, fileName string) (bool, error) {
 	exists, err := rfs.FileExists(fileName)
 	if err != nil {
 		// Handle the error properly, perhaps by returning it.
@@ -41,7 +42,7 @@ func ConfirmOverwrite(rfs filesystem.FileSystem, ctx context.Context, reader *bu
 	}
 
 	// If the file exists, ask the user for confirmation.
-	fmt.Printf("File '%s' already exists. Overwrite? (yes/no): ", fileName)
+	printer.Printf("File '%s' already exists. Overwrite? (yes/no): ", fileName)
 
 	// Call promptForInput without the extra string argument.
 	overwrite, err := promptForInput(ctx, reader)
@@ -51,6 +52,27 @@ func ConfirmOverwrite(rfs filesystem.FileSystem, ctx context.Context, reader *bu
 	return strings.ToLower(overwrite) == "yes", nil
 }
 
+// Confirm prints the prompt with the provided Printer and reads a yes or no answer via the provided bufio.Reader.
+// An empty line selects defaultYes; otherwise only "y" and "yes" confirm, in any case. An answer at the
+// end of the input is accepted, but end of input without an answer is returned as io.EOF rather than
+// taken as the default. A context.Context is used to handle cancellation of the input request.
+// It returns whether the user confirmed and any error encountered.
+func Confirm(ctx context.Context, reader *bufio.Reader, printer Printer, prompt string, defaultYes bool) (bool, error) {
+	printer.Print(prompt)
+	answer, err := promptForInput(ctx, reader)
+	if err != nil && (err != io.EOF || answer == "") {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return defaultYes, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
 // promptForInput waits for a line of user input read from the provided bufio.Reader.
 // It takes a context.Context to support cancellation.
 // The function trims the newline character from the input and returns the resulting string.
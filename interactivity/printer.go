@@ -0,0 +1,79 @@
+package interactivity
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Printer is where the interactivity functions write their prompts.
+//
+// It is an interface so that prompts can be tested without capturing the standard output of the process.
+type Printer interface {
+	Print(s string)
+	Println(s string)
+	Printf(format string, args ...interface{})
+}
+
+// writerPrinter is the Printer writing to an io.Writer.
+type writerPrinter struct {
+	w io.Writer
+}
+
+// NewStdoutPrinter returns a Printer writing to os.Stdout, for use outside of tests.
+func NewStdoutPrinter() Printer {
+	return NewPrinter(os.Stdout)
+}
+
+// NewPrinter returns a Printer writing to w, such as standard error when standard output is reserved
+// for machine-readable output.
+func NewPrinter(w io.Writer) Printer {
+	return writerPrinter{w: w}
+}
+
+func (p writerPrinter) Print(s string) {
+	fmt.Fprint(p.w, s)
+}
+
+func (p writerPrinter) Println(s string) {
+	fmt.Fprintln(p.w, s)
+}
+
+func (p writerPrinter) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(p.w, format, args...)
+}
+
+// BufferPrinter is a Printer keeping everything printed in memory, for tests to inspect the prompts.
+type BufferPrinter struct {
+	builder strings.Builder
+}
+
+// NewBufferPrinter returns an empty BufferPrinter, both as itself, to read the output back with String,
+// and as the Printer to pass to the interactivity functions.
+func NewBufferPrinter() (*BufferPrinter, Printer) {
+	buffer := &BufferPrinter{}
+	return buffer, buffer
+}
+
+func (b *BufferPrinter) Print(s string) {
+	b.builder.WriteString(s)
+}
+
+func (b *BufferPrinter) Println(s string) {
+	b.builder.WriteString(s + "\n")
+}
+
+func (b *BufferPrinter) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(&b.builder, format, args...)
+}
+
+// String returns everything printed so far.
+func (b *BufferPrinter) String() string {
+	return b.builder.String()
+}
+
+// Reset discards everything printed so far.
+func (b *BufferPrinter) Reset() {
+	b.builder.Reset()
+}
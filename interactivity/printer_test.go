@@ -0,0 +1,97 @@
+package interactivity_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+)
+
+// TestNewPrinter verifies that the Printer returned by NewPrinter writes everything to its writer.
+func TestNewPrinter(t *testing.T) {
+	var out bytes.Buffer
+	printer := interactivity.NewPrinter(&out)
+	printer.Print("a")
+	printer.Println("b")
+	printer.Printf("%s=%d\n", "c", 3)
+	if want := "ab\nc=3\n"; out.String() != want {
+		t.Errorf("NewPrinter() wrote %q, want %q", out.String(), want)
+	}
+}
+
+// TestBufferPrinter verifies that a BufferPrinter keeps everything printed through its Printer until it is reset.
+func TestBufferPrinter(t *testing.T) {
+	output, printer := interactivity.NewBufferPrinter()
+	printer.Print("a")
+	printer.Println("b")
+	printer.Printf("%s=%d", "c", 3)
+	if want := "ab\nc=3"; output.String() != want {
+		t.Errorf("BufferPrinter.String() = %q, want %q", output.String(), want)
+	}
+
+	output.Reset()
+	if output.String() != "" {
+		t.Errorf("BufferPrinter.String() = %q after Reset, want it empty", output.String())
+	}
+	printer.Println("d")
+	if output.String() != "d\n" {
+		t.Errorf("BufferPrinter.String() = %q, want %q", output.String(), "d\n")
+	}
+}
+
+// TestConfirmOverwritePrompt verifies the prompt ConfirmOverwrite prints through a BufferPrinter and the
+// answers it accepts.
+func TestConfirmOverwritePrompt(t *testing.T) {
+	tests := []struct {
+		name       string
+		fileExists bool
+		input      string
+		want       bool
+		wantPrompt string
+	}{
+		{"FileDoesNotExist", false, "", true, ""},
+		{"Yes", true, "yes\n", true, "File 'out.csv' already exists. Overwrite? (yes/no): "},
+		{"YesInUpperCase", true, "YES\n", true, "File 'out.csv' already exists. Overwrite? (yes/no): "},
+		{"No", true, "no\n", false, "File 'out.csv' already exists. Overwrite? (yes/no): "},
+		{"AnythingElse", true, "y\n", false, "File 'out.csv' already exists. Overwrite? (yes/no): "},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockFS := filesystem.NewMockFileSystem()
+			if tc.fileExists {
+				mockFS.Files["out.csv"] = []byte("id\n")
+			}
+			output, printer := interactivity.NewBufferPrinter()
+			reader := bufio.NewReader(strings.NewReader(tc.input))
+
+			got, err := interactivity.ConfirmOverwrite(mockFS, context.Background(), reader, printer, "out.csv")
+			if err != nil {
+				t.Fatalf("ConfirmOverwrite() returned an error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("ConfirmOverwrite() = %v, want %v", got, tc.want)
+			}
+			if output.String() != tc.wantPrompt {
+				t.Errorf("ConfirmOverwrite() printed %q, want %q", output.String(), tc.wantPrompt)
+			}
+		})
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["out.csv"] = []byte("id\n")
+	// The user never answers, so only the cancellation can end the prompt.
+	silent, _ := io.Pipe()
+	_, printer := interactivity.NewBufferPrinter()
+	if _, err := interactivity.ConfirmOverwrite(mockFS, cancelled, bufio.NewReader(silent), printer, "out.csv"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ConfirmOverwrite() with a cancelled context returned %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,48 @@
+// @logging.go:
+// This file routes the diagnostics of the CLI tool, such as warnings, reports, and errors, either to the
+// familiar "[GopherHelper]" text lines or, with -log-format json, to structured JSON lines on stderr.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats accepted by the -log-format flag.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// newStructuredLogger returns a logger writing one JSON object with time, level, msg, and the attached
+// fields per line to w.
+func newStructuredLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// logDiagnostic reports a diagnostic. In text mode, text is printed to the output of the run with the
+// "[GopherHelper]" prefix. In JSON mode, text becomes the message of a structured record of the given
+// level, and attrs, alternating keys and values as for slog, carry the details as separate fields.
+func (run *runState) logDiagnostic(level slog.Level, text string, attrs ...any) {
+	run.logDiagnosticTo(run.out, level, text, attrs...)
+}
+
+// logDiagnosticTo is like logDiagnostic, but prints the text mode diagnostic to w instead of the output of the run.
+func (run *runState) logDiagnosticTo(w io.Writer, level slog.Level, text string, attrs ...any) {
+	if run.logger != nil {
+		run.logger.Log(context.Background(), level, text, attrs...)
+		return
+	}
+	fmt.Fprintf(w, "[GopherHelper] %s\n", text)
+}
+
+// logErrorMessage logs a message passed to printError as a structured error record.
+// The surrounding blank lines and the "[GopherHelper]" prefix of the text mode are removed.
+func (run *runState) logErrorMessage(message string) {
+	message = strings.TrimSpace(message)
+	message = strings.TrimSpace(strings.TrimPrefix(message, "[GopherHelper]"))
+	run.logger.Error(message)
+}
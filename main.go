@@ -5,21 +5,30 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/appdirs"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/attachments"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
-	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/tablecli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/updater"
 )
 
 const (
@@ -30,26 +39,106 @@ const (
 	OutputFormatPerLine     = exporter.FormatOptionPerLine
 	OutputFormatSeparateCSV = exporter.OutputFormatSeparateCSVFiles // Assuming this is the separate CSV files format
 	OutputFormatJSONInCSV   = exporter.FormatOptionJSON             // Assuming this is the JSON format
+	OutputFormatSessionOnly = exporter.FormatOptionSessionOnly      // One row of metadata per session, without messages
+	OutputFormatTurns       = exporter.FormatOptionTurns            // One row per message with its conversational turn
 
 	// File type
-	FileTypeDataset = "dataset"
+	FileTypeDataset  = "dataset"
+	FileTypeOrgMode  = "orgmode"
+	FileTypeFineTune = "finetune"
+
+	FileTypeFineTuneArray = "finetune JSON array"
+
+	FileTypeSummariesCSV      = "summaries CSV"
+	FileTypeSummariesMarkdown = "summaries Markdown"
+	FileTypeInspect           = "inspected JSON"
+	FileTypeEPUB              = "EPUB e-book"
 
 	// Prompt messages
 	PromptEnterJSONFilePath        = "Enter the path to the JSON file: "
 	PromptRepairData               = "Do you want to repair data? (yes/no): "
-	PromptSelectOutputFormat       = "Select the output format:\n1) CSV\n2) Hugging Face Dataset\n"
-	PromptSelectCSVOutputFormat    = "Select the message output format:\n1) Inline Formatting\n2) One Message Per Line\n3) JSON String in CSV\n4) Separate Files for Sessions and Messages\n"
+	PromptEnterRepairedFilePath    = "Enter the path to save the repaired file (leave empty for %s): "
+	PromptSelectOutputFormat       = "Select the output format:\n1) CSV\n2) Hugging Face Dataset\n3) Emacs Org-mode\n4) List Sessions (no export)\n5) OpenAI Fine-Tuning JSONL\n6) Session Summaries (CSV or Markdown)\n7) Describe Output Formats (no export)\n8) EPUB E-Book\n9) Plain Text\n10) Question-Answer JSONL\n11) Conversation Turns JSON\n12) Atom Feed\n"
+	PromptSelectCSVOutputFormat    = "Select the message output format:\n1) Inline Formatting\n2) One Message Per Line\n3) JSON String in CSV\n4) Separate Files for Sessions and Messages\n5) One Row Per Session (no messages)\n6) One Message Per Line, Grouped Into Turns\n"
+	PromptSelectSummariesFormat    = "Select the summaries format:\n1) CSV\n2) Markdown\n"
 	PromptEnterCSVFileName         = "Enter the name of the CSV file to save: "
 	PromptEnterSessionsCSVFileName = "Enter the name of the sessions CSV file to save: "
 	PromptEnterMessagesCSVFileName = "Enter the name of the messages CSV file to save: "
 	PromptSaveOutputToFile         = "Do you want to save the output to a file? (yes/no)\n"
 	PromptEnterFileName            = "Enter the name of the %s file to save: "
+	PromptSplitMaxMessages         = "Split long sessions into parts of at most how many messages? (leave empty to keep sessions whole): "
+	PromptSplitOverlap             = "How many messages should consecutive parts share? (default 0): "
 )
 
+// exitUpdateSkipped is the exit status of -update when a newer release was found but not applied,
+// because it could not be confirmed without a terminal.
+const exitUpdateSkipped = 3
+
 // main initializes the application, setting up context for cancellation and
 // starting the user interaction flow for data processing and exporting.
 func main() {
-	bannercli.PrintTypingBanner("ChatGPT Session Exporter", 100*time.Millisecond)
+	// The formats command only describes the export formats, so it needs neither flags nor input.
+	if len(os.Args) > 1 && os.Args[1] == formatsCommand {
+		if err := printFormats(context.Background(), os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "[GopherHelper] %s\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	// The paths command only prints where persisted data is kept, for debugging.
+	if len(os.Args) > 1 && os.Args[1] == pathsCommand {
+		if err := printPaths(os.Stdout, os.Getenv); err != nil {
+			fmt.Fprintf(os.Stderr, "[GopherHelper] %s\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Parse command-line flags and environment options before anything is printed.
+	opts, err := parseFlags(os.Args[1:], os.Getenv)
+	if err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "[GopherHelper] %s\n", err)
+		os.Exit(2)
+	}
+
+	// The health checks verify the binary itself, without input, prompts, or network access.
+	if opts.HealthCheck || opts.SelfTest {
+		checks := healthChecks()
+		if opts.SelfTest {
+			checks = selfTestChecks()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), selfCheckTimeout)
+		passed := runSelfChecks(ctx, os.Stdout, checks, opts.Verbose)
+		cancel()
+		if !passed {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// The run receives the friendly text, including prompts. In JSON output mode, standard output is
+	// reserved for the summary, and with -tempout for the path of the temporary file, so the text goes
+	// to standard error instead.
+	run := newRunState(os.Stdout)
+	run.banner = opts.Banner
+	if opts.JSONOutput {
+		run.summary = newRunSummary(os.Stdout)
+		run.out = os.Stderr
+	}
+	if opts.TempOut {
+		run.out = os.Stderr
+	}
+
+	// Structured logs go to standard error, so they never mix with the prompts or the JSON summary.
+	if opts.LogFormat == LogFormatJSON {
+		run.logger = newStructuredLogger(os.Stderr)
+	}
+
+	// The startup banner is left out of non-interactive runs, and can be disabled or sped up for frequent ones.
+	bannercli.Print(opts.Banner, "ChatGPT Session Exporter", run)
 	// Prepare a cancellable context for handling graceful shutdown.
 	// This context will be passed down to functions that support cancellation.
 	ctx, cancel := context.WithCancel(context.Background())
@@ -57,97 +146,783 @@ func main() {
 
 	// Configure signal handling to gracefully terminate the application.
 	// This listens for system signals like SIGINT (Ctrl+C) and terminates the application.
-	setupSignalHandling(cancel)
+	var hangups chan struct{}
+	if opts.RerunOnHUP {
+		hangups = make(chan struct{}, 1)
+	}
+	setupSignalHandling(run, cancel, hangups)
 
 	// Initialize a buffered reader for user input.
 	reader := bufio.NewReader(os.Stdin)
 
+	// With -since-tag, the notes of every release newer than the given version are printed and nothing is exported.
+	if opts.SinceTag != "" {
+		printChangelog(ctx, run, opts.SinceTag)
+		run.exit(0)
+	}
+
+	// With -update, the binary is replaced with the latest release and nothing is exported. The notes of
+	// every release since the running version are shown, and the confirmation is read from the same
+	// reader, skipped rather than awaited without a terminal.
+	if opts.Update {
+		updateOptions := updater.UpdateOptions{AutoConfirm: opts.UpdateYes, Interactive: tablecli.IsTerminal(os.Stdin), In: reader, Out: run, Changelog: true}
+		err := updater.UpdateApplication(ctx, &filesystem.RealFileSystem{}, updateOptions)
+		if errors.Is(err, updater.ErrConfirmationRequired) {
+			run.printError(fmt.Sprintf("Error: %s. Use -update-yes to update without a terminal.\n", err))
+			run.exit(exitUpdateSkipped)
+		}
+		if err != nil {
+			run.printError(fmt.Sprintf("Error updating: %s\n", err))
+			run.exit(1)
+		}
+		run.exit(0)
+	}
+
 	// Collect the JSON file path from the user.
-	jsonFilePath, err := promptForInput(ctx, reader, PromptEnterJSONFilePath)
+	jsonFilePath, err := promptForInput(ctx, run, reader, PromptEnterJSONFilePath)
 	if err != nil {
-		handleInputError(err)
+		handleInputError(run, err)
 		return
 	}
 
-	// Offer the user an option to repair the data before processing.
-	repairData, err := promptForInput(ctx, reader, PromptRepairData)
+	// A directory or glob pattern selects several input files, which are exported in turn.
+	var inputFS filesystem.FileSystem = filesystem.RealFileSystem{}
+	inputPaths, err := expandInputPaths(inputFS, jsonFilePath)
 	if err != nil {
-		handleInputError(err)
-		return
+		run.printError(fmt.Sprintf("Error: %s\n", err))
+		run.exit(1)
+	}
+	// Report a missing or unreadable input file now rather than after all of the prompts.
+	for _, path := range inputPaths {
+		if err := filesystem.CheckReadable(inputFS, path); err != nil {
+			run.printError(fmt.Sprintf("Error: %s\n", err))
+			run.exit(1)
+		}
+	}
+	batch := len(inputPaths) > 1
+	if batch && (opts.Diff != "" || opts.MergeStore != "" || opts.Inspect) {
+		run.printError("Error: -diff, -merge-store, and -inspect require a single input file\n")
+		run.exit(1)
+	}
+	jsonFilePath = inputPaths[0]
+
+	// With -diff, the input file is compared with the older export and nothing is exported.
+	if opts.Diff != "" {
+		if err := filesystem.CheckReadable(inputFS, opts.Diff); err != nil {
+			run.printError(fmt.Sprintf("Error: %s\n", err))
+			run.exit(1)
+		}
+		if err := runDiff(ctx, &filesystem.RealFileSystem{}, run, opts.Diff, jsonFilePath, opts.InputFormat, opts.ReadRetry); err != nil {
+			run.printError(fmt.Sprintf("Error reading or parsing the JSON file: %s\n", err))
+			run.exit(1)
+		}
+		run.exit(0)
+	}
+
+	// With -merge-store, the sessions of another backup are merged into the input file and nothing is exported.
+	if opts.MergeStore != "" {
+		if err := filesystem.CheckReadable(inputFS, opts.MergeStore); err != nil {
+			run.printError(fmt.Sprintf("Error: %s\n", err))
+			run.exit(1)
+		}
+		path, err := mergeStores(ctx, lockedRealFileSystem(), run, reader, jsonFilePath, opts)
+		if err != nil {
+			run.printError(fmt.Sprintf("Error merging the JSON files: %s\n", err))
+			run.exit(1)
+		}
+		if opts.TempOut {
+			fmt.Fprintln(os.Stdout, path)
+		}
+		run.exit(0)
+	}
+
+	// With -inspect, the input is pretty-printed as it is streamed, without loading the whole store.
+	if opts.Inspect {
+		inspectOptions := exporter.InspectOptions{MaxSessions: opts.InspectLimit, SessionID: opts.InspectSession}
+		if err := inspectInput(ctx, run, reader, lockedRealFileSystem(), jsonFilePath, inspectOptions); err != nil {
+			run.printError(fmt.Sprintf("Error inspecting the JSON file: %s\n", err))
+			run.exit(1)
+		}
+		run.exit(0)
+	}
+
+	// Offer the user an option to repair the data before processing; a batch is exported as it is.
+	repairData := "no"
+	if !batch {
+		repairData, err = promptForInput(ctx, run, reader, PromptRepairData)
+		if err != nil {
+			handleInputError(run, err)
+			return
+		}
 	}
 
 	if strings.ToLower(repairData) == "yes" {
+		run.setSummaryFormat("repair")
 		// Create an instance of your real file system implementation.
-		realFS := &filesystem.RealFileSystem{}
+		realFS := run.withSummary(withRetry(ctx, run, lockedRealFileSystem(), opts))
+		// Ask where the repaired file should go unless it was given on the command line.
+		repairedPath, err := promptRepairedPath(realFS, ctx, run, reader, jsonFilePath, opts.RepairOut)
+		if err != nil {
+			handleInputError(run, err)
+			return
+		}
+		if repairedPath == "" {
+			run.printStatus("Operation cancelled by the user.")
+			run.exit(0)
+		}
+		if err := filesystem.CheckWritableDir(realFS, filepath.Dir(repairedPath)); err != nil {
+			run.printError(fmt.Sprintf("Error: %s\n", err))
+			run.exit(1)
+		}
 		// Pass the real file system instance when calling repairJSONData.
-		newFilePath, err := repairJSONData(realFS, ctx, jsonFilePath)
+		repairOptions := repairdata.DefaultRepairOptions()
+		repairOptions.PreserveFieldOrder = opts.PreserveOrder
+		repairOptions.StripArtifacts = opts.StripJSON
+		newFilePath, report, err := repairJSONData(realFS, ctx, jsonFilePath, repairedPath, repairOptions)
 		if err != nil {
 			errorMessage := fmt.Sprintf("Error: %s\n", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-			os.Exit(1)
+			run.printError(errorMessage)
+			run.exit(1)
+		}
+		if opts.StripJSON {
+			run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("Removed %d trailing comma(s), %d line comment(s), and %d block comment(s).",
+				report.Artifacts.TrailingCommas, report.Artifacts.LineComments, report.Artifacts.BlockComments),
+				"trailing_commas", report.Artifacts.TrailingCommas, "line_comments", report.Artifacts.LineComments, "block_comments", report.Artifacts.BlockComments)
+		}
+		if len(report.Changes) > 0 {
+			run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("Replaced %d null string value(s) with empty strings.", len(report.Changes)),
+				"changes", report.Changes)
 		}
 		successMessage := fmt.Sprintf("Repaired JSON data has been saved to: %s\n", newFilePath)
-		bannercli.PrintTypingBanner(successMessage, 100*time.Millisecond)
-		os.Exit(0)
+		run.printStatus(successMessage)
+		run.exit(0)
+	}
+
+	export := func(ctx context.Context, reader *bufio.Reader) error {
+		return exportBatch(run, inputPaths, opts.FailFast, func(path string) error {
+			return exportInput(ctx, run, reader, path, opts, os.Stdout)
+		})
+	}
+	// With -rerun-on-hup, the answers given during the export are recorded, so that SIGHUP can replay it.
+	exportCtx := ctx
+	var recording *exportRecording
+	if hangups != nil {
+		recording = &exportRecording{}
+		exportCtx = withRecording(ctx, recording)
+	}
+	if err := export(exportCtx, reader); err != nil {
+		handleExportError(run, err)
+	}
+	if hangups != nil {
+		rerunOnHangup(ctx, run, hangups, recording, export)
+	}
+
+	if run.summary != nil {
+		run.exit(0)
+	}
+}
+
+// exportInput loads the store at jsonFilePath and exports its sessions as selected by the options
+// and the answers read from reader, showing prompts and progress on run. The path of a -tempout file is
+// printed to pathOut.
+// It returns the first error that stops the export, including context.Canceled and io.EOF when the
+// user cancels or the input ends, and nil when the export is done or the user declined it.
+func exportInput(ctx context.Context, run *runState, reader *bufio.Reader, jsonFilePath string, opts cliOptions, pathOut io.Writer) error {
+	// Errors reported before this export must not keep its incremental state from being saved.
+	errorsBefore := run.errors
+
+	// Load and parse the JSON file into session data, retrying transient read failures if requested.
+	store, storeData, err := loadStoreData(ctx, &filesystem.RealFileSystem{}, jsonFilePath, opts.InputFormat, opts.ReadRetry)
+	if err != nil {
+		return fmt.Errorf("reading or parsing the JSON file: %w", err)
+	}
+	if opts.Verbose {
+		shape := store.ChatNextWebStore.Shape
+		run.logDiagnosticTo(os.Stderr, slog.LevelInfo, fmt.Sprintf("Read %d session(s) stored as an %s.", len(store.ChatNextWebStore.Sessions), shape),
+			"sessions", len(store.ChatNextWebStore.Sessions), "shape", shape.String())
+		printSessionPreview(run, os.Stderr, store.ChatNextWebStore.Sessions)
+	}
+
+	// With -merge, sessions are merged and the store is written back as a backup instead of exported. The
+	// sessions are merged as loaded, since the steps below only prepare them for the export.
+	if opts.Merge {
+		_, err := mergeSessions(run.withSummary(withRetry(ctx, run, lockedRealFileSystem(), opts)), ctx, run, reader, store.ChatNextWebStore.Sessions, storeData, opts.DateField)
+		return err
+	}
+
+	// Tags are kept in a tags file rather than in the store, so that they survive re-exports of the web app's data.
+	// They are applied while the sessions still carry their stored IDs, which the tags file is keyed by.
+	tagsPath := opts.TagsFile
+	if tagsPath == "" {
+		if tagsPath, err = defaultTagsPath(os.Getenv); err != nil {
+			return fmt.Errorf("locating the tags file: %w", err)
+		}
+	}
+	tags, err := loadSessionTags(&filesystem.RealFileSystem{}, tagsPath)
+	if err != nil {
+		return fmt.Errorf("reading tags file: %w", err)
+	}
+	tagged := exporter.ApplySessionTags(store.ChatNextWebStore.Sessions, tags)
+
+	// Make session IDs unique before the other steps, so that every format and the incremental state agree on them.
+	sessions, duplicates, err := exporter.ResolveDuplicateIDs(tagged, opts.DuplicateIDs)
+	printDuplicateReport(run, duplicates)
+	if err != nil {
+		return fmt.Errorf("%w; use -duplicate-ids keep-both or newest to export them anyway", err)
+	}
+	// Every session left out from here on is counted, so that the report at the end accounts for all of them.
+	skipped := exporter.SkipReport{Input: len(store.ChatNextWebStore.Sessions), Deduplicated: duplicates.Dropped}
+
+	// Deal with binary or corrupted message content before any format has to encode it.
+	sessions, invalidUTF8, err := exporter.ApplyInvalidUTF8Policy(sessions, opts.InvalidUTF8)
+	if err != nil {
+		return fmt.Errorf("%w; use -on-invalid-utf8 sanitize or skip to export them anyway", err)
+	}
+	printInvalidUTF8Report(run, invalidUTF8)
+
+	if !opts.Filter.IsZero() {
+		matched := exporter.FilterSessions(sessions, opts.Filter)
+		skipped.Filtered = len(sessions) - len(matched)
+		sessions = matched
+		run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("%d session(s) match the filter.", len(sessions)), "matched", len(sessions))
+	}
+
+	// With -tag, the sessions are tagged instead of exported; with -filter, only the matching ones.
+	if opts.Tag {
+		if err := tagSessions(ctx, run, reader, sessions, tags); err != nil {
+			return err
+		}
+		if err := saveSessionTags(&filesystem.RealFileSystem{}, tagsPath, tags); err != nil {
+			return fmt.Errorf("saving tags file: %w", err)
+		}
+		run.printStatus("Tags saved to " + tagsPath)
+		return nil
+	}
+	if counts := tagCounts(sessions); counts != nil {
+		run.logDiagnostic(slog.LevelInfo, "Tags: "+strings.Join(sortedTags(counts), ", "), "tags", counts)
+		if run.summary != nil {
+			run.summary.Tags = counts
+		}
+	}
+
+	// Keep only the active branch of regenerated answers unless all branches were requested. This happens
+	// before role normalization, so that dropped messages cannot break the chain of parent messages.
+	sessions, abandoned := exporter.ApplyBranchPolicy(sessions, opts.AllBranches)
+	if abandoned > 0 {
+		run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("%d message(s) of abandoned branches left out; use -all-branches to keep them.", abandoned),
+			"abandoned", abandoned)
+	}
+
+	// Normalize message roles once so that every export format sees the same roles.
+	sessions, roleReport := exporter.ApplyRolePolicy(sessions, exporter.RolePolicy{Unknown: opts.UnknownRoles})
+	printRoleReport(run, roleReport)
+
+	// Redact secrets and code before any format sees the content.
+	sessions, redactReport := exporter.ApplyRedactRules(sessions, opts.Redact)
+	printRedactReport(run, redactReport)
+
+	// Sessions without messages, including those emptied by the steps above, are skipped unless requested.
+	if !opts.IncludeEmpty {
+		sessions, skipped.Empty = exporter.SkipEmptySessions(sessions)
+	}
+
+	// With -view, the sessions are read in the terminal instead of exported.
+	if opts.View {
+		return viewSessions(ctx, run, reader, sessions, opts.DateField)
+	}
+
+	// In incremental mode only the sessions that changed since the last export are exported,
+	// while the state saved afterwards covers all of them, except those -sample leaves out.
+	allSessions := sessions
+	stateFile, err := resolveAutoPath(os.Getenv, opts.StateFile, "incremental", jsonFilePath, ".json")
+	if err != nil {
+		return fmt.Errorf("locating the state file: %w", err)
+	}
+	if stateFile != "" {
+		sessions, err = selectIncrementalSessions(run, &filesystem.RealFileSystem{}, stateFile, opts.Full, sessions)
+		if err != nil {
+			return fmt.Errorf("reading state file: %w", err)
+		}
+		skipped.Unchanged = len(allSessions) - len(sessions)
+		if len(sessions) == 0 {
+			reportSkippedSessions(run, skipped)
+			run.printStatus("No new or changed sessions since the last export. Nothing to do.")
+			return nil
+		}
+	}
+
+	// With -sample, only a reproducible random subset of the sessions is exported.
+	if opts.Sample > 0 {
+		seed := opts.SampleSeed
+		sampled := exporter.SampleSessions(sessions, opts.Sample, seed)
+		skipped.NotSampled = len(sessions) - len(sampled)
+		allSessions = withoutUnsampled(allSessions, sessions, sampled)
+		sessions = sampled
+		run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("Sampled %d session(s) with seed %d; use -sample-seed %d to export the same sample again.", len(sessions), seed, seed),
+			"sampled", len(sessions), "seed", seed)
+	}
+
+	// With -anonymize-ids, the exported sessions are numbered instead of carrying their real IDs. The
+	// incremental state saved afterwards still uses the real IDs.
+	if opts.AnonymizeIDs {
+		var mapping map[string]string
+		sessions, mapping = exporter.AnonymizeSessionIDs(sessions)
+		idMap, err := resolveAutoPath(os.Getenv, opts.IDMap, "id-maps", jsonFilePath, ".csv")
+		if err != nil {
+			return fmt.Errorf("locating the session ID mapping: %w", err)
+		}
+		if idMap != "" {
+			if err := writeIDMapping(filesystem.RealFileSystem{}, idMap, mapping); err != nil {
+				return fmt.Errorf("writing the session ID mapping: %w", err)
+			}
+			run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("Anonymized %d session ID(s); the mapping was written to %s.", len(mapping), idMap),
+				"anonymized", len(mapping), "id_map", idMap)
+		}
+	}
+
+	// Messages of older stores lack dates of their own; they are estimated only for the export, so
+	// that the incremental state and backups never carry made-up dates.
+	var estimated int
+	sessions, estimated = exporter.EstimateMessageDates(sessions, opts.MessageDates)
+	opts.estimatedDates = estimated > 0
+	if estimated > 0 {
+		run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("Estimated the date of %d message(s) without one from the dates of their sessions; use -message-dates blank to leave them empty.", estimated),
+			"estimated", estimated)
+	}
+
+	// With -sheets-id, the sessions are written into a Google spreadsheet instead of files.
+	if opts.SheetsID != "" {
+		skipped.Exported = len(sessions)
+		if err := uploadToSheets(ctx, run, filesystem.RealFileSystem{}, opts, sessions); err != nil {
+			return err
+		}
+		reportSkippedSessions(run, skipped)
+		return nil
+	}
+
+	// Query the user for the preferred output format unless it was given on the command line.
+	outputOption, ok := outputOptionForFormat(opts.Format)
+	if !ok {
+		outputOption, err = promptForInput(ctx, run, reader, PromptSelectOutputFormat)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The hidden -benchmark flag measures the conversion in memory instead of exporting anything.
+	if opts.Benchmark > 0 {
+		if err := runBenchmarks(ctx, run, outputFormatName(outputOption), sessions, opts.Benchmark, opts); err != nil {
+			return fmt.Errorf("running benchmark: %w", err)
+		}
+		return nil
+	}
+
+	// With -tempout, the export is written to a temporary file without prompting, and its path is printed.
+	skipped.Exported = len(sessions)
+	if opts.TempOut {
+		path, err := writeTempOutput(ctx, "", outputOption, sessions, opts.exportOptions())
+		if err != nil {
+			return fmt.Errorf("writing the temporary output file: %w", err)
+		}
+		reportSkippedSessions(run, skipped)
+		fmt.Fprintln(pathOut, path)
+		return nil
+	}
+
+	// Make sure the destination is writable and the export fits on it before anything is written.
+	outputDir := "."
+	if opts.OutputZip != "" {
+		outputDir = filepath.Dir(opts.OutputZip)
+	}
+	if err := preflightOutput(filesystem.RealFileSystem{}, outputDir, opts); err != nil {
+		return err
+	}
+	estimate := estimateOutputSize(sessions, outputFormatName(outputOption))
+	fits, err := confirmDiskSpace(ctx, run, reader, filesystem.RealDiskSpace{}, outputDir, estimate)
+	if err != nil {
+		return err
+	}
+	if !fits {
+		run.printStatus("Operation cancelled by the user.")
+		return nil
+	}
+
+	// Create an instance of your real file system implementation, or bundle all
+	// output files into a single zip archive when requested.
+	outputFS := withRetry(ctx, run, lockedRealFileSystem(), opts)
+	var zipFS *filesystem.ZipFileSystem
+	if opts.OutputZip != "" {
+		zipFS, err = filesystem.NewZipFileSystem(opts.OutputZip)
+		if err != nil {
+			return err
+		}
+		outputFS = zipFS
+	}
+	outputFS = run.withSummary(outputFS)
+
+	// Track the files written by the export, so that the incremental state is only saved once something was exported.
+	tracker := &writeTrackingFileSystem{FileSystem: outputFS}
+
+	// Pass the file system instance when calling processOutputOption.
+	err = processOutputOption(tracker, ctx, run, reader, outputOption, sessions, opts)
+	if recording := recordingFrom(ctx); recording != nil {
+		recording.recordFiles(tracker.written)
+	}
+	if err != nil {
+		return err
+	}
+
+	if zipFS != nil && len(zipFS.Names()) > 0 {
+		if err := closeZipLocked(zipFS, opts.OutputZip); err != nil {
+			return fmt.Errorf("writing zip archive: %w", err)
+		}
+		if run.summary != nil {
+			if info, err := os.Stat(opts.OutputZip); err == nil {
+				run.summary.Files = append(run.summary.Files, fileSummary{Path: opts.OutputZip, Bytes: int(info.Size())})
+			}
+		}
+		run.printStatus(fmt.Sprintf("Output files bundled into %s\n", opts.OutputZip))
+	}
+
+	if stateFile != "" {
+		if err := finishIncrementalExport(run, &filesystem.RealFileSystem{}, stateFile, tracker, run.errors > errorsBefore, allSessions); err != nil {
+			return err
+		}
+	}
+	reportSkippedSessions(run, skipped)
+	return nil
+}
+
+// printChangelog prints the notes of the releases newer than since, newest first, to run.
+// A since of "current" selects the version of the running binary.
+func printChangelog(ctx context.Context, run *runState, since string) {
+	version := since
+	if strings.EqualFold(version, "current") {
+		version = ""
 	}
+	releases, err := updater.ReleasesSince(ctx, version)
+	if err != nil {
+		run.printError(fmt.Sprintf("Error fetching releases: %s\n", err))
+		run.exit(1)
+	}
+	if len(releases) == 0 {
+		fmt.Fprintf(run, "No releases newer than %s.\n", since)
+		return
+	}
+	fmt.Fprint(run, updater.FormatChangelog(releases))
+}
+
+// writeIDMapping writes the mapping of anonymous to original session IDs to path. It is written
+// outside of any -output-zip archive and readable by the owner only, since it undoes the anonymization.
+func writeIDMapping(rfs filesystem.FileSystem, path string, mapping map[string]string) error {
+	var output bytes.Buffer
+	if err := exporter.WriteIDMapping(&output, mapping); err != nil {
+		return err
+	}
+	if err := appdirs.Ensure(rfs, filepath.Dir(path)); err != nil {
+		return err
+	}
+	return filesystem.AtomicWriteFile(rfs, path, output.Bytes(), 0600)
+}
+
+// preflightOutput checks that the output directory, and the attachments directory when attachments
+// are written next to the export, are writable before any conversion starts.
+func preflightOutput(rfs filesystem.FileSystem, outputDir string, opts cliOptions) error {
+	if err := filesystem.CheckWritableDir(rfs, outputDir); err != nil {
+		return err
+	}
+	if opts.Attachments != "" && opts.OutputZip == "" {
+		return filesystem.CheckWritableDir(rfs, opts.Attachments)
+	}
+	return nil
+}
+
+// csvOptions returns the CSV writer options selected on the command line.
+func (opts cliOptions) csvOptions() exporter.CSVOptions {
+	return exporter.CSVOptions{BaseURL: opts.BaseURL, PrettyJSONInCells: opts.PrettyJSON, IncludeBranches: opts.AllBranches, DateField: opts.DateField, IncludeTags: opts.TagsColumn,
+		IncludeEstimated: opts.estimatedDates, IncludeEmptySessions: opts.IncludeEmpty, Encoding: opts.Encoding, Unencodable: opts.Unencodable}
+}
+
+// exportOptions returns the options of exporter.ConvertSessions set by the command-line flags.
+func (opts cliOptions) exportOptions() exporter.Options {
+	return exporter.Options{CSV: opts.csvOptions(), Export: exporter.ExportOptions{BaseURL: opts.BaseURL, IncludeWeight: opts.FineTuneWeights, JSONArray: opts.JSONLArray, IncludeSessionMetadata: opts.SessionHeaders}}
+}
+
+// csvFormatNames maps the options of the CSV format menu to the format names of exporter.ConvertSessions.
+var csvFormatNames = map[int]string{
+	OutputFormatInline:      "csv-inline",
+	OutputFormatPerLine:     "csv-per-line",
+	OutputFormatJSONInCSV:   "csv-json",
+	OutputFormatSeparateCSV: "csv-separate",
+	OutputFormatSessionOnly: "csv-sessions",
+	OutputFormatTurns:       "csv-turns",
+}
+
+// lockedRealFileSystem returns the real file system with every write made under an exclusive file lock,
+// so that two concurrent runs writing the same output file fail fast instead of interleaving their output.
+func lockedRealFileSystem() filesystem.FileSystem {
+	return filesystem.LockingFileSystem{FileSystem: &filesystem.RealFileSystem{}}
+}
+
+// closeZipLocked writes the zip archive while holding the lock of its output path.
+func closeZipLocked(zipFS *filesystem.ZipFileSystem, outputPath string) error {
+	lock, err := filesystem.LockFile(outputPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	return zipFS.Close()
+}
+
+// withRetry wraps rfs in a filesystem.RetryFS when -retry is set, so that transient failures of output
+// file operations, typical of network drives, are retried. With -verbose every retry is logged to stderr.
+func withRetry(ctx context.Context, run *runState, rfs filesystem.FileSystem, opts cliOptions) filesystem.FileSystem {
+	if opts.Retry.Attempts <= 1 {
+		return rfs
+	}
+	retryFS := filesystem.NewRetryFS(ctx, rfs, opts.Retry)
+	if opts.Verbose {
+		retryFS.Logf = func(format string, args ...interface{}) {
+			run.logDiagnosticTo(os.Stderr, slog.LevelWarn, fmt.Sprintf(format, args...))
+		}
+	}
+	return retryFS
+}
+
+// loadStore reads the JSON file at jsonFilePath through the provided file system and parses it into session data.
+// The file is decoded as format, which detects the layout with exporter.InputFormatAuto. Transient read
+// failures, typical of network-mounted input files, are retried according to the policy, and reading a
+// large file stops when ctx is cancelled.
+func loadStore(ctx context.Context, rfs filesystem.FileSystem, jsonFilePath string, format exporter.InputFormat, policy filesystem.RetryPolicy) (exporter.ChatNextWebStore, error) {
+	store, _, err := loadStoreData(ctx, rfs, jsonFilePath, format, policy)
+	return store, err
+}
 
-	// Load and parse the JSON file into session data.
-	store, err := exporter.ReadJSONFromFile(jsonFilePath)
+// loadStoreData is loadStore that also returns the JSON the store was parsed from, so that a backup
+// written from the store can keep the fields the exporter does not model.
+func loadStoreData(ctx context.Context, rfs filesystem.FileSystem, jsonFilePath string, format exporter.InputFormat, policy filesystem.RetryPolicy) (exporter.ChatNextWebStore, []byte, error) {
+	data, err := filesystem.ReadFileWithRetryContext(ctx, rfs, jsonFilePath, policy)
 	if err != nil {
-		errorMessage := fmt.Sprintf("Error reading or parsing the JSON file: %s\n", err)
-		bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-		os.Exit(1)
+		return exporter.ChatNextWebStore{}, nil, err
 	}
+	store, err := exporter.ReadJSONFromReaderAs(bytes.NewReader(data), format)
+	return store, data, err
+}
 
-	// Query the user for the preferred output format and process accordingly.
-	outputOption, err := promptForInput(ctx, reader, PromptSelectOutputFormat)
+// extractAttachments writes the attachments found in the sessions into the directory given by opts.Attachments
+// and returns the sessions with every attachment replaced by a path reference relative to the directory of
+// outputPath, the output file the references are written to. The sessions are returned unchanged without
+// -attachments. The directory is created first, which file systems without directories, such as a zip archive, ignore.
+func extractAttachments(ctx context.Context, run *runState, rfs filesystem.FileSystem, opts cliOptions, outputPath string, sessions []exporter.Session) ([]exporter.Session, error) {
+	if opts.Attachments == "" {
+		return sessions, nil
+	}
+	if err := filesystem.MkdirAll(rfs, opts.Attachments, 0755); err != nil {
+		return nil, fmt.Errorf("extracting attachments: %w", err)
+	}
+
+	extractor := &attachments.Extractor{FS: rfs, Dir: opts.Attachments, Base: filepath.Dir(outputPath), Download: opts.Download}
+	extracted, report, err := extractor.Extract(ctx, sessions)
 	if err != nil {
-		handleInputError(err)
+		return nil, fmt.Errorf("extracting attachments: %w", err)
+	}
+	if report.Replaced > 0 || report.Failed > 0 {
+		run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("%d attachment reference(s) replaced, %d file(s) (%d bytes) written to %s, %d left inline.",
+			report.Replaced, len(report.Files), report.Bytes, opts.Attachments, report.Failed),
+			"replaced", report.Replaced, "files", len(report.Files), "bytes", report.Bytes, "dir", opts.Attachments, "inline", report.Failed)
+	}
+	return extracted, nil
+}
+
+// printRoleReport lists the distinct raw role values found in the input and what role normalization did.
+// Nothing is printed when every role was already canonical.
+func printRoleReport(run *runState, report exporter.RoleReport) {
+	if report.Normalized == 0 && report.Unknown == 0 {
+		return
+	}
+	roles := make([]string, 0, len(report.RawRoles))
+	for _, role := range report.DistinctRawRoles() {
+		roles = append(roles, fmt.Sprintf("%q (%d)", role, report.RawRoles[role]))
+	}
+	run.logDiagnostic(slog.LevelInfo, "Roles found: "+strings.Join(roles, ", "), "roles", report.RawRoles)
+	run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("%d message role(s) normalized, %d unknown, %d dropped.", report.Normalized, report.Unknown, report.Dropped),
+		"normalized", report.Normalized, "unknown", report.Unknown, "dropped", report.Dropped)
+}
+
+// printRedactReport prints the number of replacements made by each redaction rule.
+// Nothing is printed when nothing was redacted.
+func printRedactReport(run *runState, report exporter.RedactReport) {
+	if report.Messages == 0 && report.Fields == 0 {
+		return
+	}
+	matches := make([]string, 0, len(report.Matches))
+	for _, rule := range exporter.BuiltinRedactRules() {
+		if n := report.Matches[rule.Name]; n > 0 {
+			matches = append(matches, fmt.Sprintf("%s (%d)", rule.Name, n))
+		}
+	}
+	run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("Redacted in %d message(s) and %d topic(s) or memory prompt(s): %s.", report.Messages, report.Fields, strings.Join(matches, ", ")),
+		"messages", report.Messages, "fields", report.Fields, "matches", report.Matches)
+}
+
+// reportSkippedSessions prints how many of the input sessions were exported and how many were left
+// out for each reason, and records the counts in the run summary. The counts always add up to the
+// number of input sessions; a mismatch would mean a step dropped sessions without counting them.
+func reportSkippedSessions(run *runState, report exporter.SkipReport) {
+	if run.summary != nil {
+		run.summary.Sessions = &report
+	}
+	if !report.Balanced() {
+		run.logDiagnostic(slog.LevelWarn, fmt.Sprintf("%d session(s) read but %d exported and %d skipped.", report.Input, report.Exported, report.Skipped()),
+			"input", report.Input, "exported", report.Exported, "skipped", report.Skipped())
+	}
+	if report.Skipped() == 0 {
+		return
+	}
+	run.logDiagnostic(slog.LevelInfo, fmt.Sprintf("%d of %d session(s) exported; skipped %d empty, %d filtered, %d deduplicated, %d unchanged, %d not sampled.",
+		report.Exported, report.Input, report.Empty, report.Filtered, report.Deduplicated, report.Unchanged, report.NotSampled),
+		"input", report.Input, "exported", report.Exported, "empty", report.Empty, "filtered", report.Filtered,
+		"deduplicated", report.Deduplicated, "unchanged", report.Unchanged, "not_sampled", report.NotSampled)
+}
+
+// Limits of the session preview printed with -verbose.
+const (
+	previewSessions = 3  // previewSessions is the number of sessions previewed.
+	previewLength   = 80 // previewLength is the number of characters of the first message shown.
+)
+
+// printSessionPreview prints the topic, message count, and the start of the first message of the
+// first few parsed sessions, so that a wrong file, or one parsed into empty sessions, is noticed
+// before anything is exported.
+func printSessionPreview(run *runState, w io.Writer, sessions []exporter.Session) {
+	for i, session := range sessions[:min(len(sessions), previewSessions)] {
+		topic, snippet := session.Topic, "(no messages)"
+		if topic == "" {
+			topic = "(no topic)"
+		}
+		if len(session.Messages) > 0 {
+			first := session.Messages[0]
+			snippet = first.Role + ": " + previewSnippet(first.Content)
+		}
+		run.logDiagnosticTo(w, slog.LevelInfo, fmt.Sprintf("Session %d: %s (%d message(s)) - %s", i+1, topic, len(session.Messages), snippet),
+			"index", i+1, "id", session.ID, "topic", session.Topic, "messages", len(session.Messages), "first_message", snippet)
+	}
+}
+
+// previewSnippet returns content on a single line, cut to previewLength characters with an ellipsis.
+func previewSnippet(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(content) <= previewLength {
+		return content
+	}
+	return string([]rune(content)[:previewLength-1]) + "…"
+}
+
+// printDuplicateReport reports the session IDs shared by several sessions and how they were resolved.
+func printDuplicateReport(run *runState, report exporter.DuplicateReport) {
+	if len(report.Duplicates) == 0 {
 		return
 	}
+	ids := make([]string, len(report.Duplicates))
+	for i, duplicate := range report.Duplicates {
+		ids[i] = fmt.Sprintf("%q (%d)", duplicate.ID, duplicate.Count)
+	}
+	run.logDiagnostic(slog.LevelWarn, "Session IDs shared by several sessions: "+strings.Join(ids, ", "), "duplicates", report.Duplicates)
+	switch {
+	case len(report.Renamed) > 0:
+		run.logDiagnostic(slog.LevelWarn, fmt.Sprintf("%d session(s) kept under a new ID with a numeric suffix.", len(report.Renamed)),
+			"renamed", report.Renamed)
+	case report.Dropped > 0:
+		run.logDiagnostic(slog.LevelWarn, fmt.Sprintf("Kept the newest session of each shared ID; %d older session(s) left out.", report.Dropped),
+			"dropped", report.Dropped)
+	}
+}
 
-	// Create an instance of your real file system implementation.
-	realFS := &filesystem.RealFileSystem{}
-	// Pass the real file system instance when calling processOutputOption.
-	processOutputOption(realFS, ctx, reader, outputOption, store.ChatNextWebStore.Sessions)
+// printInvalidUTF8Report warns about the messages whose content is not valid UTF-8 and how they were handled.
+func printInvalidUTF8Report(run *runState, report exporter.InvalidUTF8Report) {
+	if len(report.Messages) == 0 {
+		return
+	}
+	text := fmt.Sprintf("%d message(s) are not valid UTF-8; the invalid bytes were replaced with U+FFFD.", len(report.Messages))
+	if report.Skipped > 0 {
+		text = fmt.Sprintf("%d message(s) are not valid UTF-8 and were left out.", report.Skipped)
+	}
+	run.logDiagnostic(slog.LevelWarn, text, "messages", report.Messages, "skipped", report.Skipped)
 }
 
 // handleInputError checks the type of error and handles it accordingly.
-func handleInputError(err error) {
+func handleInputError(run *runState, err error) {
 	if err == context.Canceled || err == io.EOF {
 		// Handle a context cancellation or EOF, if applicable
-		bannercli.PrintTypingBanner("\nReason: Operation canceled or end of input. Exiting program.", 100*time.Millisecond)
-		os.Exit(0)
+		run.printStatus("\nReason: Operation canceled or end of input. Exiting program.")
+		run.exit(0)
 	} else {
 		// Format the error message before passing it to PrintTypingBanner
 		errorMessage := fmt.Sprintf("\n[GopherHelper] Error reading input: %s\n", err)
-		bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-		os.Exit(1)
+		run.printError(errorMessage)
+		run.exit(1)
 	}
 }
 
+// handleExportError ends the program after an export failed with err: gracefully if the user canceled
+// it or the input ended, and with status code 1 otherwise.
+func handleExportError(run *runState, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, io.EOF) {
+		run.printStatus("\nReason: Operation canceled or end of input. Exiting program.")
+		run.exit(0)
+	}
+	run.printError(fmt.Sprintf("\n[GopherHelper] Error: %s\n", err))
+	run.exit(1)
+}
+
 // setupSignalHandling configures the application to respond to interrupt signals for
 // graceful shutdown. It utilizes the provided cancel function to terminate operations
 // when an interrupt signal (SIGINT) or termination signal (SIGTERM) is received.
 // The function uses a goroutine and a channel to listen for these signals, ensuring
 // that the signal handling does not block the main execution flow of the program.
-func setupSignalHandling(cancel context.CancelFunc) {
+//
+// When hangups is not nil, a hangup signal (SIGHUP) no longer terminates the program; instead a
+// re-run of the last export is requested on hangups. Hangups arriving before the previous one
+// is handled are merged into it.
+func setupSignalHandling(w io.Writer, cancel context.CancelFunc, hangups chan<- struct{}) {
 	// Prepare a channel to listen for system interrupt signals.
 	signals := make(chan os.Signal, 1)
 	// Register the channel to receive notification of SIGINT and SIGTERM signals.
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	if hangups != nil {
+		signal.Notify(signals, syscall.SIGHUP)
+	}
 	// Start a new goroutine that will block waiting for a signal.
 	go func() {
-		<-signals // Wait for a signal
-		fmt.Println("\n[GopherHelper] Exiting gracefully...")
-		cancel() // Cancel the context
+		for sig := range signals {
+			if sig == syscall.SIGHUP {
+				select {
+				case hangups <- struct{}{}:
+				default:
+				}
+				continue
+			}
+			fmt.Fprintln(w, "\n[GopherHelper] Exiting gracefully...")
+			cancel() // Cancel the context
+			return
+		}
 	}()
 }
 
-// promptForInput displays a prompt to the user and returns the trimmed input response.
+// promptForInput displays a prompt to the user on w and returns the trimmed input response.
 // It supports context cancellation, which can interrupt the blocking read operation.
-func promptForInput(ctx context.Context, reader *bufio.Reader, prompt string) (string, error) {
-	fmt.Print(prompt)
+// When ctx carries an exportRecording, see withRecording, the answer is recorded, or while the export is
+// replayed, the recorded answers are returned in order instead of reading input.
+func promptForInput(ctx context.Context, w io.Writer, reader *bufio.Reader, prompt string) (string, error) {
+	fmt.Fprint(w, prompt)
+	recording := recordingFrom(ctx)
+	if recording != nil && recording.replay {
+		answer, err := recording.nextAnswer()
+		if err == nil {
+			fmt.Fprintln(w, answer)
+		}
+		return answer, err
+	}
 	type result struct {
 		input string
 		err   error
@@ -163,299 +938,676 @@ func promptForInput(ctx context.Context, reader *bufio.Reader, prompt string) (s
 	case <-ctx.Done():
 		return "", ctx.Err()
 	case res := <-resultChan:
-		return strings.TrimSpace(res.input), res.err
+		answer := strings.TrimSpace(res.input)
+		if res.err == nil && recording != nil {
+			recording.record(answer)
+		}
+		return answer, res.err
 	}
 }
 
 // processOutputOption directs the processing flow based on the user's choice of output format.
 // It now respects the context for cancellation, ensuring long-running operations can be interrupted.
-func processOutputOption(fs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, outputOption string, sessions []exporter.Session) {
+// It returns the error that stopped the export, or nil when the export is done or the user declined it.
+func processOutputOption(fs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, outputOption string, sessions []exporter.Session, opts cliOptions) error {
+	run.setSummaryFormat(outputFormatName(outputOption))
 	switch outputOption {
 	case `1`:
-		processCSVOption(fs, ctx, reader, sessions)
+		return processCSVOption(fs, ctx, run, reader, sessions, opts)
 	case `2`:
-		processDatasetOption(fs, ctx, reader, sessions)
+		return processDatasetOption(fs, ctx, run, reader, sessions, opts)
+	case `3`:
+		return processOrgModeOption(fs, ctx, run, reader, sessions, opts)
+	case `4`:
+		if err := listSessions(run, reader, sessions, opts.DateField, tablecli.IsTerminal(os.Stdout)); err != nil {
+			return err
+		}
+		printSizeEstimates(run, sessions)
+	case `5`:
+		return processFineTuneOption(fs, ctx, run, reader, sessions, opts)
+	case `6`:
+		return processSummariesOption(fs, ctx, run, reader, sessions, opts)
+	case `7`:
+		if err := printFormats(ctx, run); err != nil {
+			return fmt.Errorf("describing the output formats: %w", err)
+		}
+	case `8`:
+		return processEPUBOption(fs, ctx, run, reader, sessions, opts)
+	case `9`, `10`, `11`, `12`:
+		return processRegisteredFormatOption(fs, ctx, run, reader, sessions, opts, outputFormatName(outputOption))
 	default:
-		bannercli.PrintTypingBanner("\nInvalid output option.", 100*time.Millisecond)
+		run.printError("\nInvalid output option.")
 	}
+	return nil
 }
 
 // processCSVOption prompts the user for the CSV format option and performs the corresponding actions based on the selected option.
 // It takes a reader to read user input, and a slice of sessions as input.
-// If the format option is 3, it prompts the user for the names of the sessions and messages CSV files to save, and calls exporter.CreateSeparateCSVFiles to create separate CSV files for sessions and messages.
-// If the format option is not 3, it prompts the user for the name of the CSV file to save, and calls exporter.ConvertSessionsToCSV to convert sessions to CSV based on the selected format option.
+// If the format option is 3, it prompts the user for the names of the sessions and messages CSV files to save, and calls exporter.WriteSeparateCSV to create separate CSV files for sessions and messages.
+// If the format option is not 3, it prompts the user for the name of the CSV file to save, and calls exporter.WriteSessionsCSV to convert sessions to CSV based on the selected format option.
 // It prints the output file names or error messages accordingly.
-func processCSVOption(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, sessions []exporter.Session) {
+func processCSVOption(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, opts cliOptions) error {
 	// Prompt the user for the CSV format option
-	formatOptionStr, err := promptForInput(ctx, reader, PromptSelectCSVOutputFormat)
+	formatOptionStr, err := promptForInput(ctx, run, reader, PromptSelectCSVOutputFormat)
 	if err != nil {
-		if err == context.Canceled || err == io.EOF {
-			// If the error is context.Canceled or io.EOF, exit gracefully.
-			bannercli.PrintTypingBanner("\n[GopherHelper] Exiting gracefully...\nReason: Operation canceled or end of input. Exiting program.", 100*time.Millisecond)
-			os.Exit(0)
-		} else {
-			// For other types of errors, print the error message and exit with status code 1.
-			errorMessage := fmt.Sprintf("\n[GopherHelper] Error reading input: %s\n", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-			os.Exit(1)
-		}
+		return err
 	}
 
 	formatOption, err := strconv.Atoi(formatOptionStr)
 	if err != nil {
 		// If the format option is not a valid number, print an error message and return.
-		bannercli.PrintTypingBanner("\nInvalid format option.", 100*time.Millisecond)
-		return
+		run.printError("\nInvalid format option.")
+		return nil
 	}
 
 	// Execute the CSV conversion based on the selected format option.
-	executeCSVConversion(rfs, ctx, reader, formatOption, sessions)
+	return executeCSVConversion(rfs, ctx, run, reader, formatOption, sessions, opts)
 }
 
 // processDatasetOption handles the conversion of session data to a Hugging Face Dataset format.
 // It is now context-aware and will respect cancellation requests.
-func processDatasetOption(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, sessions []exporter.Session) {
-	datasetOutput, err := exporter.ExtractToDataset(sessions)
+func processDatasetOption(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, opts cliOptions) error {
+	// Optionally split long sessions into overlapping windows before building the dataset.
+	sessions, err := promptSplitSessions(ctx, run, reader, sessions)
 	if err != nil {
-		if err == context.Canceled || err == io.EOF {
-			// If the error is context.Canceled or io.EOF, exit gracefully.
-			bannercli.PrintTypingBanner("\n[GopherHelper] Exiting gracefully...\nReason: Operation canceled or end of input. Exiting program.", 100*time.Millisecond)
-			os.Exit(0)
-		} else {
-			// For other types of errors, print the error message and exit with status code 1.
-			errorMessage := fmt.Sprintf("\n[GopherHelper] Error reading input: %s\n", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-			os.Exit(1)
+		return err
+	}
+
+	var datasetOutput string
+	convert := func(sessions []exporter.Session) (string, error) {
+		output, err := exporter.ExtractToDatasetWithOptions(sessions, opts.exportOptions().Export)
+		if err != nil {
+			return "", fmt.Errorf("converting to a dataset: %w", err)
+		}
+		datasetOutput = output
+		return output, nil
+	}
+	fileName, err := exportToFile(rfs, ctx, run, reader, sessions, opts, FileTypeDataset, convert)
+	if err != nil {
+		return err
+	}
+
+	// Optionally push the dataset to the Hugging Face Hub once the export is complete.
+	if opts.HubRepo != "" {
+		// A dataset that was not saved is converted for the upload alone, with its attachments kept inline.
+		if fileName == "" {
+			if _, err := convert(sessions); err != nil {
+				return err
+			}
+		}
+		if err := pushDatasetToHub(ctx, run, newHubUploader(opts.HubDryRun), opts.HubRepo, fileName, datasetOutput); err != nil {
+			return fmt.Errorf("uploading the dataset to the Hugging Face Hub: %w", err)
 		}
 	}
-	saveToFile(rfs, ctx, reader, datasetOutput, "dataset")
+	return nil
 }
 
-// saveToFile prompts the user to save the provided content to a file of the specified type.
-// This function now also accepts a context, allowing file operations to be cancelable.
-func saveToFile(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, content string, fileType string) {
-	// Ask user if they want to save the output to a file
-	saveOutput, err := promptForInput(ctx, reader, PromptSaveOutputToFile)
+// promptSplitSessions asks the user whether long sessions should be split into windows of a maximum
+// number of messages, and if so with how much overlap. Leaving the maximum empty keeps sessions whole.
+// It reports how many sessions were split and returns the resulting sessions.
+func promptSplitSessions(ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session) ([]exporter.Session, error) {
+	maxMessagesStr, err := promptForInput(ctx, run, reader, PromptSplitMaxMessages)
 	if err != nil {
-		handleInputError(err)
-		return
+		return nil, err
+	}
+	if maxMessagesStr == "" {
+		return sessions, nil
+	}
+	maxMessages, err := strconv.Atoi(maxMessagesStr)
+	if err != nil || maxMessages < 1 {
+		run.printStatus("Invalid maximum number of messages. Sessions are kept whole.")
+		return sessions, nil
 	}
 
-	if strings.ToLower(saveOutput) == "yes" {
-		// Determine the file name here (or pass it as a parameter)
-		fileName, err := promptForInput(ctx, reader, fmt.Sprintf(PromptEnterFileName, fileType))
-		if err != nil {
-			handleInputError(err)
-			return
+	overlapStr, err := promptForInput(ctx, run, reader, PromptSplitOverlap)
+	if err != nil {
+		return nil, err
+	}
+	overlap := 0
+	if overlapStr != "" {
+		if overlap, err = strconv.Atoi(overlapStr); err != nil {
+			run.printStatus("Invalid overlap. Sessions are kept whole.")
+			return sessions, nil
 		}
+	}
 
-		// Ensure the fileName is not empty
-		if fileName == "" {
-			bannercli.PrintTypingBanner("No file name entered. Operation cancelled.", 100*time.Millisecond)
-			return
-		}
+	split, summary, err := exporter.SplitSessions(sessions, maxMessages, overlap)
+	if err != nil {
+		run.printStatus(fmt.Sprintf("Cannot split sessions: %s. Sessions are kept whole.", err))
+		return sessions, nil
+	}
+	splitMessage := fmt.Sprintf("Split %d session(s) into %d part(s).", summary.SplitSessions, summary.Parts)
+	run.printStatus(splitMessage)
+	return split, nil
+}
 
-		// Append the appropriate file extension based on the fileType
-		if fileType == FileTypeDataset {
-			fileName += ".json"
-		} else {
-			fileName += ".csv" // Assuming default fileType is CSV
+// listSessions prints the sessions as an aligned table of index, date, topic, message count, and model.
+// On an interactive terminal the table is paged to fit the screen; otherwise it is printed in full
+// so that it can be piped to other tools.
+func listSessions(w io.Writer, reader *bufio.Reader, sessions []exporter.Session, dateField exporter.DateField, interactive bool) error {
+	table := sessionsTable(sessions, dateField)
+	if !interactive {
+		table.Render(w, 0)
+		return nil
+	}
+	// Leave room for the header, the separator, and the paging prompt.
+	pageSize := tablecli.TerminalHeight() - 3
+	return table.Page(w, reader, tablecli.TerminalWidth(), pageSize)
+}
+
+// sessionsTable builds the session listing table, dating the sessions by dateField and truncating
+// the topic column first when space is short.
+func sessionsTable(sessions []exporter.Session, dateField exporter.DateField) *tablecli.Table {
+	table := &tablecli.Table{
+		Headers:    []string{"#", "Date", "Topic", "Messages", "Model"},
+		FlexColumn: 2,
+	}
+	for i, session := range sessions {
+		table.AddRow(strconv.Itoa(i+1), formatSessionDate(session.Timestamp(dateField)), session.Topic,
+			strconv.Itoa(len(session.Messages)), session.Model())
+	}
+	return table
+}
+
+// formatSessionDate formats a Unix millisecond timestamp for display, or returns "-" if it is unset.
+func formatSessionDate(millis int64) string {
+	if millis <= 0 {
+		return "-"
+	}
+	return time.UnixMilli(millis).Format("2006-01-02 15:04")
+}
+
+// processOrgModeOption handles the conversion of session data to an Emacs Org-mode document.
+func processOrgModeOption(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, opts cliOptions) error {
+	_, err := exportToFile(rfs, ctx, run, reader, sessions, opts, FileTypeOrgMode, func(sessions []exporter.Session) (string, error) {
+		var orgOutput bytes.Buffer
+		if err := exporter.WriteOrgMode(ctx, &orgOutput, sessions, opts.exportOptions().Export); err != nil {
+			return "", fmt.Errorf("converting to Org-mode: %w", err)
 		}
+		return orgOutput.String(), nil
+	})
+	return err
+}
+
+// processFineTuneOption handles the conversion of session data to the JSONL format of OpenAI fine-tuning jobs.
+// Like the dataset option, it offers to split long sessions first so that examples fit the model's context.
+func processFineTuneOption(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, opts cliOptions) error {
+	sessions, err := promptSplitSessions(ctx, run, reader, sessions)
+	if err != nil {
+		return err
+	}
 
-		// Check if the file exists and confirm overwrite if necessary
-		overwrite, err := interactivity.ConfirmOverwrite(rfs, ctx, reader, fileName)
+	exportOpts := opts.exportOptions().Export
+	_, err = exportToFile(rfs, ctx, run, reader, sessions, opts, fineTuningFileType(exportOpts), func(sessions []exporter.Session) (string, error) {
+		jsonlOutput, err := fineTuningOutput(sessions, exportOpts)
 		if err != nil {
-			handleInputError(err)
-			return
+			return "", fmt.Errorf("converting to fine-tuning JSONL: %w", err)
 		}
-		if !overwrite {
-			bannercli.PrintTypingBanner("Operation cancelled by the user.", 100*time.Millisecond)
-			return
+		return jsonlOutput, nil
+	})
+	return err
+}
+
+// fineTuningFileType returns the file type of the fine-tuning examples written by fineTuningOutput.
+func fineTuningFileType(opts exporter.ExportOptions) string {
+	if opts.JSONArray {
+		return FileTypeFineTuneArray
+	}
+	return FileTypeFineTune
+}
+
+// fineTuningOutput converts sessions into fine-tuning examples, as JSONL or with opts.JSONArray as a
+// single JSON array.
+func fineTuningOutput(sessions []exporter.Session, opts exporter.ExportOptions) (string, error) {
+	if opts.JSONArray {
+		return exporter.ExtractToFineTuningJSONArray(sessions, opts)
+	}
+	return exporter.ExtractToFineTuningJSONL(sessions, opts)
+}
+
+// processEPUBOption handles the conversion of session data to an EPUB e-book with a chapter per session.
+func processEPUBOption(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, opts cliOptions) error {
+	_, err := exportToFile(rfs, ctx, run, reader, sessions, opts, FileTypeEPUB, func(sessions []exporter.Session) (string, error) {
+		var epubOutput bytes.Buffer
+		if err := exporter.WriteEPUB(ctx, &epubOutput, sessions, exporter.EPUBOptions{}); err != nil {
+			return "", fmt.Errorf("converting to EPUB: %w", err)
 		}
+		return epubOutput.String(), nil
+	})
+	return err
+}
 
-		// Now that we've confirmed, attempt to write the file
-		err = rfs.WriteFile(fileName, []byte(content), 0644)
-		if err != nil {
-			errorMessage := fmt.Sprintf("Error writing file: %s", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-			return
+// processRegisteredFormatOption handles the conversion of session data to a format of the exporter registry
+// that takes no further choices, such as plain text or an Atom feed. The format name doubles as the file type.
+func processRegisteredFormatOption(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, opts cliOptions, format string) error {
+	_, err := exportToFile(rfs, ctx, run, reader, sessions, opts, format, func(sessions []exporter.Session) (string, error) {
+		var output bytes.Buffer
+		if err := exporter.ConvertSessions(ctx, &output, sessions, format, opts.exportOptions()); err != nil {
+			return "", fmt.Errorf("converting to %s: %w", format, err)
 		}
+		return output.String(), nil
+	})
+	return err
+}
 
-		successMessage := fmt.Sprintf("%s output saved to %s", strings.ToTitle(fileType), fileName)
-		bannercli.PrintTypingBanner(successMessage, 100*time.Millisecond)
-	} else {
-		bannercli.PrintTypingBanner("Save to file operation cancelled by the user.", 100*time.Millisecond)
+// processSummariesOption handles the export of a digest of the session summaries (memoryPrompt) without messages,
+// as CSV or Markdown.
+func processSummariesOption(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, opts cliOptions) error {
+	formatOption, err := promptForInput(ctx, run, reader, PromptSelectSummariesFormat)
+	if err != nil {
+		return err
+	}
+
+	switch formatOption {
+	case `1`:
+		var csvOutput bytes.Buffer
+		if err := exporter.WriteSummariesCSV(ctx, &csvOutput, sessions, opts.csvOptions()); err != nil {
+			return fmt.Errorf("converting summaries to CSV: %w", err)
+		}
+		_, err = saveToFile(rfs, ctx, run, reader, csvOutput.String(), FileTypeSummariesCSV)
+	case `2`:
+		var mdOutput bytes.Buffer
+		if err := exporter.WriteSummariesMarkdown(ctx, &mdOutput, sessions, opts.csvOptions()); err != nil {
+			return fmt.Errorf("converting summaries to Markdown: %w", err)
+		}
+		_, err = saveToFile(rfs, ctx, run, reader, mdOutput.String(), FileTypeSummariesMarkdown)
+	default:
+		run.printError("\nInvalid summaries format option.")
+	}
+	return err
+}
+
+// saveToFile prompts the user to save the provided content to a file of the specified type.
+// This function now also accepts a context, allowing file operations to be cancelable.
+// It returns the name of the saved file, or "" when the user chose not to save it.
+func saveToFile(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, content string, fileType string) (string, error) {
+	return exportToFile(rfs, ctx, run, reader, nil, cliOptions{}, fileType, func([]exporter.Session) (string, error) {
+		return content, nil
+	})
+}
+
+// exportToFile prompts the user for the file to save output of the specified type to, moves the attachments
+// of the sessions next to it with -attachments, and saves the content that convert makes of the resulting sessions.
+// The output file is resolved first, so that the attachment references are relative to its directory.
+// It returns the name of the saved file, or "" when the user chose not to save it.
+func exportToFile(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, opts cliOptions, fileType string, convert func([]exporter.Session) (string, error)) (string, error) {
+	fileName, err := promptOutputFileName(rfs, ctx, run, reader, fileType)
+	if err != nil || fileName == "" {
+		return "", err
+	}
+
+	sessions, err = extractAttachments(ctx, run, rfs, opts, fileName, sessions)
+	if err != nil {
+		return "", err
+	}
+	content, err := convert(sessions)
+	if err != nil {
+		return "", err
+	}
+
+	// Now that we've confirmed, attempt to write the file
+	err = filesystem.AtomicWriteFile(rfs, fileName, []byte(content), 0644)
+	if err != nil {
+		return "", fmt.Errorf("writing file: %w", err)
+	}
+
+	successMessage := fmt.Sprintf("%s output saved to %s", strings.ToTitle(fileType), fileName)
+	run.printStatus(successMessage)
+	return fileName, nil
+}
+
+// promptOutputFileName asks the user whether to save output of the specified type to a file, and if so
+// for its name, and confirms overwriting an existing file. It returns the file name with the extension
+// of the file type, or "" when the user chose not to save the output.
+func promptOutputFileName(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, fileType string) (string, error) {
+	// Ask user if they want to save the output to a file
+	saveOutput, err := promptForInput(ctx, run, reader, PromptSaveOutputToFile)
+	if err != nil {
+		return "", err
+	}
+	if strings.ToLower(saveOutput) != "yes" {
+		run.printStatus("Save to file operation cancelled by the user.")
+		return "", nil
+	}
+
+	// Determine the file name here (or pass it as a parameter)
+	fileName, err := promptForInput(ctx, run, reader, fmt.Sprintf(PromptEnterFileName, fileType))
+	if err != nil {
+		return "", err
+	}
+
+	// Ensure the fileName is not empty
+	if fileName == "" {
+		run.printStatus("No file name entered. Operation cancelled.")
+		return "", nil
+	}
+
+	// Append the appropriate file extension based on the fileType
+	fileName += fileExtension(fileType)
+
+	// Check if the file exists and confirm overwrite if necessary
+	overwrite, err := confirmOverwrite(rfs, ctx, run, reader, fileName)
+	if err != nil {
+		return "", err
+	}
+	if !overwrite {
+		run.printStatus("Operation cancelled by the user.")
+		return "", nil
+	}
+	return fileName, nil
+}
+
+// fileExtension returns the file extension, including the dot, used when saving output of the given fileType.
+func fileExtension(fileType string) string {
+	switch fileType {
+	case FileTypeDataset:
+		return ".json"
+	case FileTypeOrgMode:
+		return ".org"
+	case FileTypeFineTune:
+		return ".jsonl"
+	case FileTypeSummariesCSV:
+		return ".csv"
+	case FileTypeSummariesMarkdown:
+		return ".md"
+	case FileTypeInspect, FileTypeBackup, FileTypeFineTuneArray:
+		return ".json"
+	case FileTypeEPUB:
+		return ".epub"
+	}
+	// Formats of the exporter registry offered by the menu as is are saved with the extension they document.
+	for _, format := range exporter.Formats() {
+		if doc := format.Describe(); doc.Name == fileType {
+			return doc.Extension
+		}
 	}
+	return ".csv" // Assuming default fileType is CSV
 }
 
 // handleInputCancellation checks the error type and handles context cancellation and EOF.
-func handleInputCancellation(err error) {
+func handleInputCancellation(run *runState, err error) {
 	if err == context.Canceled || err == io.EOF {
-		bannercli.PrintTypingBanner("\n[GopherHelper] Exiting gracefully...\nReason: Operation canceled or end of input. Exiting program.", 100*time.Millisecond)
-		os.Exit(0)
+		run.printStatus("\n[GopherHelper] Exiting gracefully...\nReason: Operation canceled or end of input. Exiting program.")
+		run.exit(0)
 	} else {
 		errorMessage := fmt.Sprintf("\nError reading input: %s\n", err)
-		bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-		os.Exit(1)
+		run.printError(errorMessage)
+		run.exit(1)
+	}
+}
+
+// defaultRepairedPath returns the path the repaired copy of jsonFilePath is written to by default:
+// the same directory, with the file name prefixed by "repaired_".
+func defaultRepairedPath(jsonFilePath string) string {
+	return filepath.Join(filepath.Dir(jsonFilePath), "repaired_"+filepath.Base(jsonFilePath))
+}
+
+// promptRepairedPath determines where the repaired file is written. The path given on the command line
+// is used if set; otherwise the user is asked, and an empty answer (or the end of input) selects
+// defaultRepairedPath. Either way an existing file is only replaced after ConfirmOverwrite.
+// It returns an empty path if the user declines to overwrite an existing file.
+func promptRepairedPath(rfs filesystem.FileSystem, ctx context.Context, w io.Writer, reader *bufio.Reader, jsonFilePath string, repairOut string) (string, error) {
+	repairedPath := repairOut
+	if repairedPath == "" {
+		defaultPath := defaultRepairedPath(jsonFilePath)
+		input, err := promptForInput(ctx, w, reader, fmt.Sprintf(PromptEnterRepairedFilePath, defaultPath))
+		if err != nil && !(err == io.EOF && input == "") {
+			return "", err
+		}
+		repairedPath = input
+		if repairedPath == "" {
+			repairedPath = defaultPath
+		}
+	}
+
+	overwrite, err := confirmOverwrite(rfs, ctx, w, reader, repairedPath)
+	if err != nil {
+		return "", err
+	}
+	if !overwrite {
+		return "", nil
 	}
+	return repairedPath, nil
 }
 
 // repairJSONData attempts to repair malformed JSON data at the provided file path.
-// Despite accepting a context parameter, it currently does not support cancellation.
-// The function reads the broken JSON, repairs it, and writes the repaired JSON back to a new file.
-func repairJSONData(rfs filesystem.FileSystem, ctx context.Context, jsonFilePath string) (string, error) {
+// Reading the broken JSON stops when ctx is cancelled.
+// The function reads the broken JSON, repairs it according to the options, and writes the repaired JSON
+// to repairedPath, or to defaultRepairedPath if repairedPath is empty. It also returns what the repair removed.
+func repairJSONData(rfs filesystem.FileSystem, ctx context.Context, jsonFilePath string, repairedPath string, opts repairdata.RepairOptions) (string, repairdata.RepairReport, error) {
 	// Read the broken JSON data using the file system interface
-	data, err := rfs.ReadFile(jsonFilePath)
+	data, err := filesystem.ReadFileContext(ctx, rfs, jsonFilePath)
 	if err != nil {
-		return "", err // Handle the error properly
+		return "", repairdata.RepairReport{}, err // Handle the error properly
 	}
 
 	// Repair the JSON data (this is where you fix the JSON string)
-	repairedData, repairErr := repairdata.RepairSessionData(data)
+	repairedData, report, repairErr := repairdata.RepairSessionDataWithReport(data, opts)
 	if repairErr != nil {
-		return "", repairErr // Handle the error properly
+		return "", report, repairErr // Handle the error properly
 	}
 
-	// Define the path for the repaired file
-	repairedPath := "repaired_" + jsonFilePath
+	// Fall back to the default path for the repaired file
+	if repairedPath == "" {
+		repairedPath = defaultRepairedPath(jsonFilePath)
+	}
 
 	// Write the repaired JSON data using the file system interface
-	err = rfs.WriteFile(repairedPath, repairedData, 0644)
+	err = filesystem.AtomicWriteFile(rfs, repairedPath, repairedData, 0644)
 	if err != nil {
-		return "", err // Handle the error properly
+		return "", report, err // Handle the error properly
 	}
 
 	// Return the path to the repaired file
-	return repairedPath, nil
+	return repairedPath, report, nil
 }
 
 // executeCSVConversion handles the CSV conversion process based on the user-selected format option.
 // It is now context-aware, allowing for cancellation during the CSV conversion process.
-func executeCSVConversion(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, formatOption int, sessions []exporter.Session) {
-	var csvFileName string
-	var err error
-
-	// Check if the format option is valid before proceeding
-	if formatOption != OutputFormatInline && formatOption != OutputFormatPerLine &&
-		formatOption != OutputFormatSeparateCSV && formatOption != OutputFormatJSONInCSV {
-		bannercli.PrintTypingBanner("Invalid CSV format option.", 100*time.Millisecond)
-		return
+func executeCSVConversion(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, formatOption int, sessions []exporter.Session, opts cliOptions) error {
+	if _, ok := csvFormatNames[formatOption]; !ok {
+		run.printError("Invalid CSV format option.")
+		return nil
 	}
 
-	// If the format option is not for separate CSV files, prompt for a single CSV file name.
-	if formatOption != OutputFormatSeparateCSV {
-		csvFileName, err = promptForInput(ctx, reader, PromptEnterCSVFileName)
-		if err != nil {
-			handleInputError(err)
-			return
-		}
+	// Separate CSV files prompt for their own file names.
+	if formatOption == OutputFormatSeparateCSV {
+		return createSeparateCSVFiles(rfs, ctx, run, reader, sessions, opts)
 	}
 
-	switch formatOption {
-	case OutputFormatInline, OutputFormatPerLine, OutputFormatJSONInCSV:
-		// Call the function to convert sessions to a single CSV file
-		convertToSingleCSV(rfs, ctx, reader, sessions, formatOption, csvFileName)
-	case OutputFormatSeparateCSV:
-		// Call the function to create separate CSV files for sessions and messages
-		createSeparateCSVFiles(rfs, ctx, reader, sessions)
-	default:
-		bannercli.PrintTypingBanner("Invalid format option.", 100*time.Millisecond)
+	csvFileName, err := promptForInput(ctx, run, reader, PromptEnterCSVFileName)
+	if err != nil {
+		return err
 	}
+	return convertToSingleCSV(rfs, ctx, run, reader, sessions, formatOption, csvFileName, opts)
 }
 
 // createSeparateCSVFiles prompts the user for file names and creates separate CSV files for sessions and messages.
 // This function is context-aware and supports cancellation during the prompt for input.
-func createSeparateCSVFiles(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, sessions []exporter.Session) {
-	sessionsFileName, err := promptForInput(ctx, reader, PromptEnterSessionsCSVFileName)
+func createSeparateCSVFiles(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, opts cliOptions) error {
+	sessionsFileName, err := promptForInput(ctx, run, reader, PromptEnterSessionsCSVFileName)
 	if err != nil {
-		handleInputError(err)
-		return
+		return err
 	}
 
 	// Confirm overwrite for sessions CSV file
-	overwrite, err := interactivity.ConfirmOverwrite(rfs, ctx, reader, sessionsFileName)
+	overwrite, err := confirmOverwrite(rfs, ctx, run, reader, sessionsFileName)
 	if err != nil {
-		handleInputError(err)
-		return
+		return err
 	}
 	if !overwrite {
-		bannercli.PrintTypingBanner("Operation cancelled by the user for sessions file.", 100*time.Millisecond)
-		return
+		run.printStatus("Operation cancelled by the user for sessions file.")
+		return nil
 	}
 
-	messagesFileName, err := promptForInput(ctx, reader, PromptEnterMessagesCSVFileName)
+	messagesFileName, err := promptForInput(ctx, run, reader, PromptEnterMessagesCSVFileName)
 	if err != nil {
-		handleInputError(err)
-		return
+		return err
 	}
 
 	// Confirm overwrite for messages CSV file
-	overwrite, err = interactivity.ConfirmOverwrite(rfs, ctx, reader, messagesFileName)
+	overwrite, err = confirmOverwrite(rfs, ctx, run, reader, messagesFileName)
 	if err != nil {
-		handleInputError(err)
-		return
+		return err
 	}
 	if !overwrite {
-		bannercli.PrintTypingBanner("Operation cancelled by the user for messages file.", 100*time.Millisecond)
-		return
+		run.printStatus("Operation cancelled by the user for messages file.")
+		return nil
 	}
 
-	err = exporter.CreateSeparateCSVFiles(sessions, sessionsFileName, messagesFileName)
+	sessions, err = extractAttachments(ctx, run, rfs, opts, messagesFileName, sessions)
 	if err != nil {
-		if err == context.Canceled || err == io.EOF {
-			// If the error is context.Canceled or io.EOF, exit gracefully.
-			bannercli.PrintTypingBanner("\n[GopherHelper] Exiting gracefully...\nReason: Operation canceled or end of input. Exiting program.", 100*time.Millisecond)
-			os.Exit(0)
-		} else {
-			// For other types of errors, print the error message and exit with status code 1.
-			errorMessage := fmt.Sprintf("\nError creating CSV files: %s\n", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-			os.Exit(1)
-		}
+		return err
+	}
+
+	// Both files are saved through the file system, so that they end up wherever it points, such as a zip archive.
+	err = exporter.CreateSeparateCSVFiles(ctx, sessions, sessionsFileName, messagesFileName, filesystem.Atomic(rfs), opts.csvOptions())
+	if err != nil {
+		return fmt.Errorf("creating CSV files: %w", err)
 	}
 
 	successMessageSessions := fmt.Sprintf("Sessions data saved to %s\n", sessionsFileName)
-	bannercli.PrintTypingBanner(successMessageSessions, 100*time.Millisecond)
+	run.printStatus(successMessageSessions)
 
 	successMessageMessages := fmt.Sprintf("Messages data saved to %s\n", messagesFileName)
-	bannercli.PrintTypingBanner(successMessageMessages, 100*time.Millisecond)
+	run.printStatus(successMessageMessages)
+	return nil
 }
 
 // convertToSingleCSV converts the session data to a single CSV file using the specified format option.
 // It now checks for context cancellation and halts the operation if a cancellation is requested.
-func convertToSingleCSV(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, sessions []exporter.Session, formatOption int, csvFileName string) {
+// A canceled conversion saves the completed sessions with savePartialCSV and returns context.Canceled.
+func convertToSingleCSV(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, formatOption int, csvFileName string, opts cliOptions) error {
+	// With -append-dedup, an existing file is extended with the new sessions instead of being overwritten.
+	if opts.AppendDedup {
+		if exists, err := rfs.FileExists(csvFileName); err == nil && exists {
+			sessions, err := extractAttachments(ctx, run, rfs, opts, csvFileName, sessions)
+			if err != nil {
+				return err
+			}
+			return appendToSingleCSV(rfs, ctx, run, sessions, formatOption, csvFileName, opts.csvOptions())
+		}
+	}
+
 	// Confirm overwrite if the file already exists
-	overwrite, err := interactivity.ConfirmOverwrite(rfs, ctx, reader, csvFileName)
+	overwrite, err := confirmOverwrite(rfs, ctx, run, reader, csvFileName)
 	if err != nil {
-		errorMessage := fmt.Sprintf("Failed to check file existence: %s\n", err)
-		bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-		return // Handle the error as appropriate for your application
+		return fmt.Errorf("checking file existence: %w", err)
 	}
 	if !overwrite {
-		bannercli.PrintTypingBanner("Operation cancelled by the user.", 100*time.Millisecond)
+		run.printStatus("Operation cancelled by the user.")
+		return nil
+	}
+	sessions, err = extractAttachments(ctx, run, rfs, opts, csvFileName, sessions)
+	if err != nil {
+		return err
+	}
+
+	// The CSV is streamed to a temporary file, which only replaces the named file once it is complete.
+	file, err := filesystem.CreateTempFile(rfs, filepath.Dir(csvFileName), 0644)
+	if err != nil {
+		return fmt.Errorf("converting sessions to CSV: %w", err)
+	}
+	output := &csvRecordCounter{w: file}
+	err = exporter.ConvertSessions(ctx, output, sessions, csvFormatNames[formatOption], opts.exportOptions())
+	if errors.Is(err, context.Canceled) {
+		savePartialCSV(run, file, csvFileName, output.records)
+		return err
+	}
+	if err == nil {
+		err = file.Commit(csvFileName)
+	} else {
+		file.Abort()
+	}
+	if err != nil {
+		return fmt.Errorf("converting sessions to CSV: %w", err)
+	}
+
+	successMessage := fmt.Sprintf("CSV output saved to %s\n", csvFileName)
+	run.printStatus(successMessage)
+	return nil
+}
+
+// partialCSVFileName returns the name a CSV export canceled before it was complete is saved under:
+// csvFileName with ".partial.csv" in place of its ".csv" extension.
+func partialCSVFileName(csvFileName string) string {
+	return strings.TrimSuffix(csvFileName, filepath.Ext(csvFileName)) + ".partial.csv"
+}
+
+// savePartialCSV commits the output of a CSV export canceled by the user, which holds the rows of every
+// session completed before the cancellation, under partialCSVFileName, so that the work done on a huge
+// export is not lost. The file named by the user is left untouched. Nothing is saved if not a single
+// session was completed, since the output then holds at most the header of its records.
+func savePartialCSV(run *runState, file filesystem.TempFile, csvFileName string, records int) {
+	if records <= 1 {
+		file.Abort()
+		run.printStatus("Operation was canceled by the user.")
+		return
+	}
+	partialFileName := partialCSVFileName(csvFileName)
+	if err := file.Commit(partialFileName); err != nil {
+		run.printError(fmt.Sprintf("Operation was canceled by the user, and saving the partial CSV output failed: %s\n", err))
 		return
 	}
+	run.printStatus("Canceled; completed sessions saved to " + partialFileName)
+}
 
-	err = exporter.ConvertSessionsToCSV(ctx, sessions, formatOption, csvFileName)
+// appendToSingleCSV appends the rows of the sessions that are not yet present in the existing CSV file,
+// matched by session ID, and reports how many sessions were appended and skipped. The existing rows are
+// streamed only to collect their IDs, and the new rows are appended to the file in place, which is left
+// untouched when nothing is new.
+func appendToSingleCSV(rfs filesystem.FileSystem, ctx context.Context, run *runState, sessions []exporter.Session, formatOption int, csvFileName string, csvOpts exporter.CSVOptions) error {
+	file, err := filesystem.Open(rfs, csvFileName)
+	if err != nil {
+		return fmt.Errorf("reading the existing CSV file: %w", err)
+	}
+	existing := &lastByteReader{r: file}
+	var newRows bytes.Buffer
+	summary, err := exporter.AppendNewSessionsCSV(ctx, existing, &newRows, sessions, formatOption, csvOpts)
+	file.Close()
+	if err == nil && summary.Appended > 0 {
+		// A file whose last row lacks a line break gets one, so that the first new row starts on a line of its own.
+		rows := newRows.Bytes()
+		if existing.read && existing.last != '\n' {
+			rows = append([]byte{'\n'}, rows...)
+		}
+		err = filesystem.AppendFile(rfs, csvFileName, rows, 0644)
+	}
 	if err != nil {
-		if err == context.Canceled {
-			bannercli.PrintTypingBanner("Operation was canceled by the user.", 100*time.Millisecond)
-		} else {
-			errorMessage := fmt.Sprintf("Failed to convert sessions to CSV: %s\n", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
+		if errors.Is(err, context.Canceled) {
+			return err
 		}
-		return // Handle the error as appropriate for your application
+		return fmt.Errorf("appending sessions to CSV: %w", err)
 	}
 
-	successMessage := fmt.Sprintf("CSV output saved to %s\n", csvFileName)
-	bannercli.PrintTypingBanner(successMessage, 100*time.Millisecond)
+	successMessage := fmt.Sprintf("Appended %d new session(s) to %s; %d session(s) already present were skipped.\n",
+		summary.Appended, csvFileName, summary.Skipped)
+	run.printStatus(successMessage)
+	return nil
+}
+
+// lastByteReader is an io.Reader that remembers the last byte read from r.
+type lastByteReader struct {
+	r    io.Reader
+	last byte // last is the last byte read, valid if read is set.
+	read bool // read reports that at least one byte was read.
+}
+
+// Read reads from r and records the last byte read.
+func (l *lastByteReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	if n > 0 {
+		l.last, l.read = p[n-1], true
+	}
+	return n, err
 }
 
 // writeContentToFile collects a file name from the user and writes the provided content to the specified file.
 // It now includes context support to handle potential cancellation during file writing.
-// Note: Do not refactor or modify this function; doing so will disrupt the associated magic method in main_test.go.
-func writeContentToFile(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, content string, fileType string) error {
-	fileName, err := promptForInput(ctx, reader, fmt.Sprintf(PromptEnterFileName, fileType))
+// The file is written atomically through rfs, so that a failed write never leaves a truncated file behind;
+// TestWriteContentToFile checks that no temporary file is left over.
+func writeContentToFile(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, content string, fileType string) error {
+	fileName, err := promptForInput(ctx, run, reader, fmt.Sprintf(PromptEnterFileName, fileType))
 	if err != nil {
 		return err
 	}
@@ -465,12 +1617,12 @@ func writeContentToFile(rfs filesystem.FileSystem, ctx context.Context, reader *
 	}
 
 	// Use the provided FileSystem interface to write the file content directly
-	err = rfs.WriteFile(fileName, []byte(content), 0644)
+	err = filesystem.AtomicWriteFile(rfs, fileName, []byte(content), 0644)
 	if err != nil {
 		return err
 	}
 
 	successMessage := fmt.Sprintf("%s output saved to %s\n", strings.ToTitle(fileType), fileName)
-	bannercli.PrintTypingBanner(successMessage, 100*time.Millisecond)
+	run.printStatus(successMessage)
 	return nil // Ensure that you return nil if there were no errors
 }
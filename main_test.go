@@ -10,15 +10,33 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/appdirs"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/testsupport"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/uploader"
 )
 
 // loadTestSessions is a helper function that loads test session data from a JSON file.
@@ -45,11 +63,8 @@ func loadTestSessions(jsonPath string) (exporter.ChatNextWebStore, error) {
 // TestProcessCSVOption verifies the functionality of processCSVOption function.
 // It simulates user input, captures the output, and checks if the CSV file is created successfully.
 func TestProcessCSVOption(t *testing.T) {
-	// Attempt to load session data from a JSON file to prepare for testing.
-	store, err := loadTestSessions("testing.json")
-	if err != nil {
-		t.Fatalf("Failed to load sessions from JSON: %v", err)
-	}
+	// Use the canonical small fixture store so the test data stays current with the exporter.
+	store := testsupport.SmallStore()
 
 	// Simulate user input by creating a reader that will return the input as if typed by a user.
 	// Assuming that the option for OutputFormatSeparateCSVFiles is 4 as per the constants defined in session.go.
@@ -63,21 +78,10 @@ func TestProcessCSVOption(t *testing.T) {
 	// Create an instance of the mock file system
 	mockFS := filesystem.NewMockFileSystem()
 
-	// Capture the output of the function by redirecting stdout.
-	oldStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	// Invoke the processCSVOption function, which should process the input and generate CSV files.
-	processCSVOption(mockFS, ctx, reader, store.ChatNextWebStore.Sessions)
-
-	// Close the write-end of the pipe to finish capturing the output.
-	w.Close()
-	os.Stdout = oldStdout // Restore the original stdout.
-
-	// Read the captured output from the read-end of the pipe into a buffer for assertion.
+	// Invoke the processCSVOption function, which should process the input and generate CSV files,
+	// capturing its output in a buffer for assertion.
 	var buf bytes.Buffer
-	io.Copy(&buf, r)
+	processCSVOption(mockFS, ctx, newRunState(&buf), reader, store.ChatNextWebStore.Sessions, cliOptions{})
 
 	// Convert the captured output into a string for easy comparison.
 	outputStr := buf.String()
@@ -88,13 +92,16 @@ func TestProcessCSVOption(t *testing.T) {
 	if !strings.Contains(outputStr, expectedOutputSession) {
 		t.Errorf("Expected output to contain: %s, got: %s", expectedOutputSession, outputStr)
 	}
-	// Clean up by removing the test output files.
-	defer os.Remove("output_sessions.csv")
-	defer os.Remove("output_messages.csv")
-
 	if !strings.Contains(outputStr, expectedOutputMessage) {
 		t.Errorf("Expected output to contain: %s, got: %s", expectedOutputMessage, outputStr)
 	}
+
+	// The files are written through the mock file system, so nothing touches the disk.
+	for _, name := range []string{"output_sessions.csv", "output_messages.csv"} {
+		if _, ok := mockFS.Files[name]; !ok {
+			t.Errorf("Expected %s to be written to the file system", name)
+		}
+	}
 }
 
 // TestPromptForInput verifies that promptForInput function correctly captures and returns user input.
@@ -108,7 +115,7 @@ func TestPromptForInput(t *testing.T) {
 	defer cancel()
 
 	// Invoke promptForInput and capture the result.
-	result, err := promptForInput(ctx, reader, "Enter input: ")
+	result, err := promptForInput(ctx, io.Discard, reader, "Enter input: ")
 	if err != nil {
 		t.Fatalf("promptForInput() returned an error: %v", err)
 	}
@@ -128,7 +135,7 @@ func TestPromptForInputCancellation(t *testing.T) {
 		cancel() // Cancel the context immediately
 	}()
 
-	_, err := promptForInput(ctx, reader, "Enter input: ")
+	_, err := promptForInput(ctx, io.Discard, reader, "Enter input: ")
 	// testing for windows now
 	if err != context.Canceled && err != nil && err != io.EOF {
 		t.Fatalf("Expected context.Canceled or io.EOF error, got: %v", err)
@@ -165,7 +172,7 @@ func TestRepairJSONDataFromFile(t *testing.T) {
 		defer cancel()
 
 		// Attempt to repair the JSON data and expect a valid file path to the repaired JSON.
-		repairedPath, err := repairJSONData(realFS, ctx, brokenJSONPath)
+		repairedPath, _, err := repairJSONData(realFS, ctx, brokenJSONPath, "", repairdata.RepairOptions{})
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
@@ -192,14 +199,15 @@ func TestRepairJSONDataFromFile(t *testing.T) {
 		defer cancel()
 
 		// Attempt to repair JSON data from a non-existent file and expect an error.
-		_, err := repairJSONData(realFS, ctx, "nonexistent.json")
+		_, _, err := repairJSONData(realFS, ctx, "nonexistent.json", "", repairdata.RepairOptions{})
 		if err == nil {
 			t.Errorf("Expected an error for a non-existent file path, got nil")
 		}
 	})
 }
 
-// TestWriteContentToFile verifies that writeContentToFile function writes the expected content to a file.
+// TestWriteContentToFile verifies that writeContentToFile function writes the expected content to a file
+// atomically, leaving no temporary file behind, and reports where it was saved.
 func TestWriteContentToFile(t *testing.T) {
 	// Create a cancellable context to simulate context cancellation.
 	ctx, cancel := context.WithCancel(context.Background())
@@ -219,7 +227,10 @@ func TestWriteContentToFile(t *testing.T) {
 	mockFS := filesystem.NewMockFileSystem()
 
 	// Invoke the function to write content to a file with "dataset" as the file type.
-	writeContentToFile(mockFS, ctx, reader, content, "dataset")
+	var output bytes.Buffer
+	if err := writeContentToFile(mockFS, ctx, newRunState(&output), reader, content, "dataset"); err != nil {
+		t.Fatalf("writeContentToFile() returned an error: %v", err)
+	}
 
 	// Verify that the WriteFile method was called on the mock file system.
 	if !mockFS.WriteFileCalled {
@@ -236,6 +247,14 @@ func TestWriteContentToFile(t *testing.T) {
 	if string(mockFS.Files[expectedFileName]) != content {
 		t.Errorf("WriteFile was called with the wrong content: got %v, want %v", string(mockFS.Files[expectedFileName]), content)
 	}
+
+	// The atomic write leaves nothing but the output file, and the user is told where it went.
+	if len(mockFS.Files) != 1 {
+		t.Errorf("the file system holds %d files after the write, want only %s", len(mockFS.Files), expectedFileName)
+	}
+	if !strings.Contains(output.String(), "saved to "+expectedFileName) {
+		t.Errorf("output does not report the saved file: %q", output.String())
+	}
 }
 
 // TestConfirmOverwrite tests the ConfirmOverwrite function from the interactivity package.
@@ -272,8 +291,14 @@ func TestConfirmOverwrite(t *testing.T) {
 			// Simulate user input.
 			reader := bufio.NewReader(strings.NewReader(tc.userInput))
 
-			// Call the ConfirmOverwrite function.
-			result, err := interactivity.ConfirmOverwrite(mockFS, context.Background(), reader, "testing.json")
+			// Call the ConfirmOverwrite function, capturing its prompt.
+			output, printer := interactivity.NewBufferPrinter()
+			result, err := interactivity.ConfirmOverwrite(mockFS, context.Background(), reader, printer, "testing.json")
+
+			// Verify that the user is only prompted when the file exists.
+			if prompted := strings.Contains(output.String(), "File 'testing.json' already exists. Overwrite?"); prompted != tc.fileExists {
+				t.Errorf("ConfirmOverwrite() printed %q, want a prompt only for an existing file", output.String())
+			}
 
 			// Verify the result.
 			if result != tc.expectedResult {
@@ -311,7 +336,7 @@ func TestWriteContentToFile_ContextCancellation(t *testing.T) {
 
 	// Call the function to be tested with the cancelled context.
 	// Since the context is already cancelled, we expect the function to return an error.
-	err := writeContentToFile(mockFS, ctx, reader, content, "dataset")
+	err := writeContentToFile(mockFS, ctx, newRunState(io.Discard), reader, content, "dataset")
 
 	// Check if the error returned is the expected context.Canceled error.
 	// If the function does not handle context cancellation correctly, this test will fail.
@@ -324,3 +349,1517 @@ func TestWriteContentToFile_ContextCancellation(t *testing.T) {
 		t.Error("WriteFile should not have been called after context cancellation")
 	}
 }
+
+// TestParseFlagsNoBanner verifies that the startup banner can be disabled by flag or environment variable,
+// and that an explicit flag takes precedence over the environment.
+func TestParseFlagsNoBanner(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      string
+		expected bool
+	}{
+		{"Default", nil, "", false},
+		{"Flag", []string{"-no-banner"}, "", true},
+		{"Env", nil, "1", true},
+		{"EnvYes", nil, "yes", true},
+		{"FlagOverridesEnv", []string{"-no-banner=false"}, "true", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			getenv := func(key string) string {
+				if key == EnvNoBanner {
+					return tc.env
+				}
+				return ""
+			}
+			opts, err := parseFlags(tc.args, getenv)
+			if err != nil {
+				t.Fatalf("parseFlags() returned an error: %v", err)
+			}
+			if opts.NoBanner != tc.expected {
+				t.Errorf("NoBanner = %v, want %v", opts.NoBanner, tc.expected)
+			}
+		})
+	}
+}
+
+// TestParseFlagsBanner verifies that the style, delay, and repeat count of the startup banner come from the
+// flags and the environment, and that the automatic style depends on whether the output is a terminal.
+func TestParseFlagsBanner(t *testing.T) {
+	defer func(original func() bool) { interactiveOutput = original }(interactiveOutput)
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		interactive bool
+		want        bannercli.Config
+	}{
+		{"AutoTerminal", nil, nil, true, bannercli.DefaultConfig()},
+		{"AutoNonInteractive", nil, nil, false, bannercli.Config{Style: bannercli.StyleNone, Delay: 100 * time.Millisecond, Repeat: 3}},
+		{"Flags", []string{"-banner-style", "animated", "-banner-delay", "0", "-banner-repeat", "1"}, nil, false, bannercli.Config{Style: bannercli.StyleAnimated, Repeat: 1}},
+		{"Env", nil, map[string]string{EnvBannerStyle: "binary", EnvBannerDelay: "5ms"}, true, bannercli.Config{Style: bannercli.StyleBinary, Delay: 5 * time.Millisecond, Repeat: 3}},
+		{"FlagOverridesEnv", []string{"-banner-style", "typing"}, map[string]string{EnvBannerStyle: "binary"}, false, bannercli.DefaultConfig()},
+		{"NoBanner", []string{"-banner-style", "typing", "-no-banner"}, nil, true, bannercli.Config{Style: bannercli.StyleNone, Delay: 100 * time.Millisecond, Repeat: 3}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			interactiveOutput = func() bool { return tc.interactive }
+			opts, err := parseFlags(tc.args, func(key string) string { return tc.env[key] })
+			if err != nil {
+				t.Fatalf("parseFlags() returned an error: %v", err)
+			}
+			if opts.Banner != tc.want {
+				t.Errorf("Banner = %+v, want %+v", opts.Banner, tc.want)
+			}
+		})
+	}
+
+	for _, args := range [][]string{{"-banner-style", "blink"}, {"-banner-delay", "-1s"}, {"-banner-repeat", "-1"}} {
+		if _, err := parseFlags(args, func(string) string { return "" }); err == nil {
+			t.Errorf("parseFlags(%q) returned no error", args)
+		}
+	}
+	if _, err := parseFlags(nil, func(key string) string { return map[string]string{EnvBannerDelay: "soon"}[key] }); err == nil {
+		t.Error("parseFlags() accepted an invalid " + EnvBannerDelay)
+	}
+}
+
+// TestPrintStatus verifies that status messages are printed at once when the banner is off, as in
+// non-interactive runs, and typed out at the delay of the banner otherwise.
+func TestPrintStatus(t *testing.T) {
+	defer func(original func() bool) { interactiveOutput = original }(interactiveOutput)
+	interactiveOutput = func() bool { return false }
+	opts, err := parseFlags(nil, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("parseFlags() returned an error: %v", err)
+	}
+	message := "Output files bundled into sessions.zip"
+
+	var out bytes.Buffer
+	run := newRunState(&out)
+	run.banner = opts.Banner
+	start := time.Now()
+	run.printStatus(message)
+	if elapsed := time.Since(start); elapsed >= opts.Banner.Delay {
+		t.Errorf("printStatus() took %v without a banner, want it printed at once", elapsed)
+	}
+	if out.String() != message+"\n" {
+		t.Errorf("printStatus() wrote %q, want %q", out.String(), message+"\n")
+	}
+
+	out.Reset()
+	run.banner = bannercli.Config{Style: bannercli.StyleTyping, Delay: time.Millisecond}
+	start = time.Now()
+	run.printStatus(message)
+	if elapsed := time.Since(start); elapsed < time.Duration(len(message))*time.Millisecond {
+		t.Errorf("printStatus() took %v with the typing banner, want at least %d delays", elapsed, len(message))
+	}
+	if out.String() != message+"\n" {
+		t.Errorf("printStatus() wrote %q, want %q", out.String(), message+"\n")
+	}
+}
+
+// TestParseFlagsExclude verifies that the exclusion flags are added to the filter of -filter.
+func TestParseFlagsExclude(t *testing.T) {
+	opts, err := parseFlags([]string{
+		"-filter", "tag:work", "-exclude-model", "gpt-4, gpt-3.5-turbo,", "-exclude-session-id", "s1", "-exclude-search", "secret",
+	}, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("parseFlags() returned an error: %v", err)
+	}
+	want := exporter.SessionFilter{
+		Tags:               []string{"work"},
+		ExcludeModels:      []string{"gpt-4", "gpt-3.5-turbo"},
+		ExcludeSessionIDs:  []string{"s1"},
+		ExcludeSearchQuery: "secret",
+	}
+	if !reflect.DeepEqual(opts.Filter, want) {
+		t.Errorf("Filter = %+v, want %+v", opts.Filter, want)
+	}
+
+	if opts, err := parseFlags(nil, func(string) string { return "" }); err != nil || !opts.Filter.IsZero() {
+		t.Errorf("parseFlags() without filter flags = %+v, %v, want the zero filter", opts.Filter, err)
+	}
+}
+
+// flakyFileSystem wraps the mock file system and fails the first reads with the configured error.
+type flakyFileSystem struct {
+	*filesystem.MockFileSystem
+	failures int   // failures is the number of reads that fail before reads succeed.
+	err      error // err is the error returned by failing reads.
+	reads    int   // reads counts the calls to ReadFile.
+}
+
+// ReadFile fails with the configured error until the configured number of failures is reached.
+func (f *flakyFileSystem) ReadFile(name string) ([]byte, error) {
+	f.reads++
+	if f.reads <= f.failures {
+		return nil, f.err
+	}
+	return f.MockFileSystem.ReadFile(name)
+}
+
+// TestLoadStoreRetry verifies that transient input read errors are retried while genuine
+// not-found errors, and the default policy, are not.
+func TestLoadStoreRetry(t *testing.T) {
+	transient := &os.PathError{Op: "read", Path: "input.json", Err: syscall.EIO}
+	tests := []struct {
+		name          string
+		failures      int
+		err           error
+		policy        filesystem.RetryPolicy
+		expectError   bool
+		expectedReads int
+	}{
+		{"DefaultNoRetry", 1, transient, filesystem.RetryPolicy{}, true, 1},
+		{"TransientRecovered", 2, transient, filesystem.RetryPolicy{Attempts: 3}, false, 3},
+		{"TransientExhausted", 3, transient, filesystem.RetryPolicy{Attempts: 2}, true, 2},
+		{"NotFoundNotRetried", 1, os.ErrNotExist, filesystem.RetryPolicy{Attempts: 3}, true, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockFS := filesystem.NewMockFileSystem()
+			mockFS.Files["input.json"] = []byte(`{"chat-next-web-store":{"sessions":[]}}`)
+			flakyFS := &flakyFileSystem{MockFileSystem: mockFS, failures: tc.failures, err: tc.err}
+
+			_, err := loadStore(context.Background(), flakyFS, "input.json", exporter.InputFormatAuto, tc.policy)
+			if (err != nil) != tc.expectError {
+				t.Errorf("loadStore() error = %v, wantErr %v", err, tc.expectError)
+			}
+			if flakyFS.reads != tc.expectedReads {
+				t.Errorf("ReadFile called %d times, want %d", flakyFS.reads, tc.expectedReads)
+			}
+		})
+	}
+}
+
+// TestListSessions verifies the session table listing, both in full for piped output
+// and paged with truncated topics for an interactive terminal.
+func TestListSessions(t *testing.T) {
+	sessions := testsupport.SmallStore().ChatNextWebStore.Sessions
+
+	var buf bytes.Buffer
+	listSessions(&buf, nil, sessions, exporter.DateFieldUpdated, false)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header, separator, and 2 rows, got:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "#  Date") || !strings.Contains(lines[2], "Travel Guide") || !strings.Contains(lines[2], "gpt-4-1106-preview") {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+
+	// Paging one row at a time, quitting after the first page, with a width that forces the topic to be truncated.
+	table := sessionsTable(sessions, exporter.DateFieldUpdated)
+	buf.Reset()
+	if err := table.Page(&buf, bufio.NewReader(strings.NewReader("q\n")), 60, 1); err != nil {
+		t.Fatalf("Page() returned an error: %v", err)
+	}
+	output := buf.String()
+	if strings.Contains(output, "Go Concur") || !strings.Contains(output, "Travel G…") || !strings.Contains(output, "q to quit") {
+		t.Errorf("expected only the first page to be shown, got:\n%s", output)
+	}
+	for _, line := range table.Lines(60) {
+		if n := len([]rune(line)); n > 60 {
+			t.Errorf("line exceeds the width of 60 (%d): %q", n, line)
+		}
+	}
+
+	noEnv := func(string) string { return "" }
+	if opts, err := parseFlags([]string{"-list"}, noEnv); err != nil || opts.Format != "list" {
+		t.Errorf("parseFlags(-list) = %q, %v, want the list format", opts.Format, err)
+	}
+	if _, err := parseFlags([]string{"-list", "-format", "csv"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted -list with -format csv")
+	}
+}
+
+// TestRunSummary verifies that files written through the summary file system are recorded
+// with their sizes and CSV row counts, and that errors mark the run as failed.
+func TestRunSummary(t *testing.T) {
+	var out bytes.Buffer
+	runSum := newRunSummary(&out)
+	rfs := summaryFileSystem{FileSystem: filesystem.NewMockFileSystem(), summary: runSum}
+
+	csvData := []byte("id,topic\n1,\"multi\nline\"\n2,plain\n")
+	if err := rfs.WriteFile("output.csv", csvData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rfs.WriteFile("dataset.json", []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runSum.recordError("something failed\n")
+	if err := runSum.write(0); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Files []struct {
+			Path  string `json:"path"`
+			Bytes int    `json:"bytes"`
+			Rows  *int   `json:"rows"`
+		} `json:"files"`
+		Errors  []string `json:"errors"`
+		Success bool     `json:"success"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("summary is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(decoded.Files) != 2 || decoded.Files[0].Rows == nil || *decoded.Files[0].Rows != 2 || decoded.Files[0].Bytes != len(csvData) {
+		t.Errorf("unexpected CSV file summary: %s", out.String())
+	}
+	if decoded.Files[1].Rows != nil {
+		t.Errorf("rows should be omitted for non-CSV files: %s", out.String())
+	}
+	if decoded.Success || len(decoded.Errors) != 1 || decoded.Errors[0] != "something failed" {
+		t.Errorf("expected a failed run with one error: %s", out.String())
+	}
+}
+
+// TestIncrementalExport verifies that a missing state file exports everything, that the saved state
+// skips unchanged sessions on the next run, and that -full still selects every session.
+func TestIncrementalExport(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state", "state.json")
+	sessions := testsupport.SmallStore().ChatNextWebStore.Sessions
+	realFS := &filesystem.RealFileSystem{}
+	selected, err := selectIncrementalSessions(newRunState(io.Discard), realFS, statePath, false, sessions)
+	if err != nil || len(selected) != len(sessions) {
+		t.Fatalf("first run selected %d sessions (err %v), want %d", len(selected), err, len(sessions))
+	}
+
+	tracker := &writeTrackingFileSystem{FileSystem: filesystem.NewMockFileSystem()}
+	if err := finishIncrementalExport(newRunState(io.Discard), realFS, statePath, tracker, false, sessions); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Fatalf("state file was saved although nothing was exported: %v", err)
+	}
+	if err := tracker.WriteFile("out.csv", []byte("id\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := finishIncrementalExport(newRunState(io.Discard), realFS, statePath, tracker, true, sessions); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Fatalf("state file was saved although the export failed: %v", err)
+	}
+	if err := finishIncrementalExport(newRunState(io.Discard), realFS, statePath, tracker, false, sessions); err != nil {
+		t.Fatal(err)
+	}
+
+	edited := append([]exporter.Session(nil), sessions...)
+	edited[1].Messages = append(edited[1].Messages, testsupport.NewMessage("s2-m3", "user", "Thanks!"))
+	selected, err = selectIncrementalSessions(newRunState(io.Discard), realFS, statePath, false, edited)
+	if err != nil || len(selected) != 1 || selected[0].ID != edited[1].ID {
+		t.Errorf("second run selected %v (err %v), want only %s", selected, err, edited[1].ID)
+	}
+	if selected, _ = selectIncrementalSessions(newRunState(io.Discard), realFS, statePath, true, edited); len(selected) != len(edited) {
+		t.Errorf("full run selected %d sessions, want %d", len(selected), len(edited))
+	}
+
+	if err := os.WriteFile(statePath, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := selectIncrementalSessions(newRunState(io.Discard), realFS, statePath, false, sessions); err == nil {
+		t.Error("a corrupt state file should be reported")
+	}
+}
+
+// TestSessionTagsFile verifies the default tags file path, that a missing tags file tags nothing,
+// and that the tags entered interactively are saved and read back.
+func TestSessionTagsFile(t *testing.T) {
+	home := t.TempDir()
+	getenv := func(key string) string {
+		if key == appdirs.EnvHome {
+			return home
+		}
+		return ""
+	}
+	if got, err := defaultTagsPath(getenv); err != nil || got != filepath.Join(home, "state", "tags.json") {
+		t.Errorf("defaultTagsPath() = %s, %v, want tags.json in the state directory", got, err)
+	}
+	first, err := resolveAutoPath(getenv, autoPath, "incremental", filepath.Join("a", "store.json"), ".json")
+	if err != nil || filepath.Dir(first) != filepath.Join(home, "state", "incremental") || !strings.HasPrefix(filepath.Base(first), "store-") {
+		t.Errorf("resolveAutoPath(auto) = %s, %v, want a store-<hash>.json file in the incremental state directory", first, err)
+	}
+	if second, _ := resolveAutoPath(getenv, autoPath, "incremental", filepath.Join("b", "store.json"), ".json"); second == first {
+		t.Errorf("inputs in different directories share the state file %s", first)
+	}
+	if got, _ := resolveAutoPath(getenv, "state.json", "incremental", "store.json", ".json"); got != "state.json" {
+		t.Errorf("resolveAutoPath() = %s, want an explicit path kept as is", got)
+	}
+
+	mockFS := filesystem.NewMockFileSystem()
+	tags, err := loadSessionTags(mockFS, "store.tags.json")
+	if err != nil || len(tags) != 0 {
+		t.Fatalf("loadSessionTags() of a missing file = %v, %v, want no tags", tags, err)
+	}
+	sessions := testsupport.LargeStore().ChatNextWebStore.Sessions[:4]
+	tags[sessions[1].ID] = []string{"old"}
+	tags[sessions[2].ID] = []string{"kept"}
+	reader := bufio.NewReader(strings.NewReader("Work, personal\n-\n\nq\n"))
+	if err := tagSessions(context.Background(), io.Discard, reader, sessions, tags); err != nil {
+		t.Fatalf("tagSessions() returned an error: %v", err)
+	}
+	if err := saveSessionTags(mockFS, "store.tags.json", tags); err != nil {
+		t.Fatalf("saveSessionTags() returned an error: %v", err)
+	}
+
+	saved, err := loadSessionTags(mockFS, "store.tags.json")
+	if err != nil {
+		t.Fatalf("loadSessionTags() returned an error: %v", err)
+	}
+	want := exporter.SessionTags{sessions[0].ID: {"personal", "work"}, sessions[2].ID: {"kept"}}
+	if !reflect.DeepEqual(saved, want) {
+		t.Errorf("saved tags = %v, want %v", saved, want)
+	}
+
+	mockFS.Files["store.tags.json"] = []byte("not json")
+	if _, err := loadSessionTags(mockFS, "store.tags.json"); err == nil {
+		t.Error("a corrupt tags file should be reported")
+	}
+}
+
+// TestPromptRepairedPath verifies the default repaired file name, a custom path from the prompt or
+// the -repair-out flag, and that an existing file is only replaced after confirmation.
+func TestPromptRepairedPath(t *testing.T) {
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["out/existing.json"] = []byte("{}")
+
+	tests := []struct {
+		name      string
+		input     string
+		repairOut string
+		expected  string
+	}{
+		{"Default", "\n", "", filepath.Join("data", "repaired_store.json")},
+		{"DefaultAtEndOfInput", "", "", filepath.Join("data", "repaired_store.json")},
+		{"Prompted", "out/fixed.json\n", "", "out/fixed.json"},
+		{"Flag", "", "out/flag.json", "out/flag.json"},
+		{"OverwriteConfirmed", "yes\n", "out/existing.json", "out/existing.json"},
+		{"OverwriteDeclined", "no\n", "out/existing.json", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(tc.input))
+			got, err := promptRepairedPath(mockFS, context.Background(), io.Discard, reader, filepath.Join("data", "store.json"), tc.repairOut)
+			if err != nil {
+				t.Fatalf("promptRepairedPath() returned an error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("promptRepairedPath() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+// flakyWriteFileSystem wraps the mock file system and fails the first writes with the configured error.
+type flakyWriteFileSystem struct {
+	*filesystem.MockFileSystem
+	failures int   // failures is the number of writes that fail before writes succeed.
+	err      error // err is the error returned by failing writes.
+	writes   int   // writes counts the calls to WriteFile.
+}
+
+// WriteFile fails with the configured error until the configured number of failures is reached.
+func (f *flakyWriteFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f.writes++
+	if f.writes <= f.failures {
+		return f.err
+	}
+	return f.MockFileSystem.WriteFile(name, data, perm)
+}
+
+// fakeDiskSpace reports a fixed amount of free space, or an error.
+type fakeDiskSpace struct {
+	free uint64
+	err  error
+}
+
+// Available returns the configured free space.
+func (f fakeDiskSpace) Available(path string) (uint64, error) {
+	return f.free, f.err
+}
+
+// TestConfirmDiskSpace verifies the size estimate and that a confirmation is only required
+// when the estimate exceeds 90% of the free space.
+func TestConfirmDiskSpace(t *testing.T) {
+	sessions := testsupport.LargeStore().ChatNextWebStore.Sessions
+	csvSize := estimateOutputSize(sessions, "csv")
+	if csvSize <= 0 || estimateOutputSize(sessions, "dataset") <= csvSize || estimateOutputSize(sessions, "list") != 0 {
+		t.Fatalf("unexpected estimates: csv %d, dataset %d", csvSize, estimateOutputSize(sessions, "dataset"))
+	}
+
+	tests := []struct {
+		name     string
+		space    fakeDiskSpace
+		input    string
+		expected bool
+		warned   bool
+	}{
+		{"PlentyOfSpace", fakeDiskSpace{free: uint64(csvSize) * 10}, "", true, false},
+		{"UnknownSpace", fakeDiskSpace{err: filesystem.ErrDiskSpaceUnsupported}, "", true, false},
+		{"LowSpaceConfirmed", fakeDiskSpace{free: uint64(csvSize)}, "yes\n", true, true},
+		{"LowSpaceDeclined", fakeDiskSpace{free: uint64(csvSize)}, "no\n", false, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			reader := bufio.NewReader(strings.NewReader(tc.input))
+			ok, err := confirmDiskSpace(context.Background(), newRunState(&out), reader, tc.space, ".", csvSize)
+			if err != nil {
+				t.Fatalf("confirmDiskSpace() returned an error: %v", err)
+			}
+			if ok != tc.expected || strings.Contains(out.String(), "Warning") != tc.warned {
+				t.Errorf("confirmDiskSpace() = %v with output %q", ok, out.String())
+			}
+		})
+	}
+
+	if got := formatSize(1536); got != "1.5 KiB" {
+		t.Errorf("formatSize(1536) = %q", got)
+	}
+}
+
+// TestPreflightOutput verifies that an output directory the user cannot write to is reported with an
+// actionable message.
+func TestPreflightOutput(t *testing.T) {
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Dirs["/data"] = true
+
+	readOnly := &flakyWriteFileSystem{MockFileSystem: mockFS, failures: 1, err: fs.ErrPermission}
+	err := preflightOutput(readOnly, "/data", cliOptions{})
+	if err == nil || err.Error() != "directory /data is not writable by the current user" {
+		t.Errorf("preflightOutput() on a read-only directory returned %v", err)
+	}
+}
+
+// TestBenchmarkFlag verifies that -benchmark is validated and hidden from the usage text, and that
+// the benchmark reports the throughput of every conversion of the format without writing to disk.
+func TestBenchmarkFlag(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	if opts, err := parseFlags([]string{"-benchmark", "3"}, noEnv); err != nil || opts.Benchmark != 3 {
+		t.Errorf("parseFlags(-benchmark 3) = %d, %v", opts.Benchmark, err)
+	}
+	if _, err := parseFlags([]string{"-benchmark", "-1"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted a negative -benchmark")
+	}
+	if _, err := parseFlags([]string{"-benchmark", "3", "-format", "list"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted -benchmark with the list format")
+	}
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.Int("benchmark", 0, "hidden")
+	flagSet.String("format", "", "visible")
+	var usage bytes.Buffer
+	printVisibleDefaults(&usage, flagSet)
+	if strings.Contains(usage.String(), "-benchmark") || !strings.Contains(usage.String(), "-format") {
+		t.Errorf("usage text does not hide -benchmark:\n%s", usage.String())
+	}
+
+	sessions := testsupport.SmallStore().ChatNextWebStore.Sessions
+	var output bytes.Buffer
+	if err := runBenchmarks(context.Background(), &output, "csv", sessions, 2, cliOptions{}); err != nil {
+		t.Fatalf("runBenchmarks() returned an error: %v", err)
+	}
+	for _, name := range []string{"csv/inline", "csv/perline", "csv/json", "csv/separate", "sessions/sec", "MB/sec"} {
+		if !strings.Contains(output.String(), name) {
+			t.Errorf("benchmark output is missing %q:\n%s", name, output.String())
+		}
+	}
+	if err := runBenchmarks(context.Background(), io.Discard, "list", sessions, 2, cliOptions{}); err == nil {
+		t.Error("runBenchmarks() accepted the list format")
+	}
+}
+
+// TestStructuredLogging verifies that -log-format is validated and that diagnostics and errors
+// become JSON lines with level, message, and detail fields in JSON mode while text mode is unchanged.
+func TestStructuredLogging(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	if opts, err := parseFlags(nil, noEnv); err != nil || opts.LogFormat != LogFormatText {
+		t.Errorf("default LogFormat = %q, %v; want %q", opts.LogFormat, err, LogFormatText)
+	}
+	if _, err := parseFlags([]string{"-log-format", "xml"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted -log-format xml")
+	}
+
+	var text bytes.Buffer
+	newRunState(&text).logDiagnostic(slog.LevelInfo, "2 new session(s).", "new", 2)
+	if text.String() != "[GopherHelper] 2 new session(s).\n" {
+		t.Errorf("text diagnostic = %q", text.String())
+	}
+
+	var logs bytes.Buffer
+	var unused bytes.Buffer
+	run := newRunState(&unused)
+	run.logger = newStructuredLogger(&logs)
+	run.logDiagnostic(slog.LevelWarn, "Low disk space.", "free_bytes", 42)
+	run.printError("\n[GopherHelper] Error reading input: boom\n")
+	if run.errors != 1 {
+		t.Errorf("printError() counted %d error(s), want 1", run.errors)
+	}
+	if unused.Len() != 0 {
+		t.Errorf("JSON mode wrote text diagnostics: %q", unused.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2:\n%s", len(lines), logs.String())
+	}
+	var warning, failure map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &warning); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &failure); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if warning["level"] != "WARN" || warning["msg"] != "Low disk space." || warning["free_bytes"] != float64(42) {
+		t.Errorf("unexpected warning record: %v", warning)
+	}
+	if failure["level"] != "ERROR" || failure["msg"] != "Error reading input: boom" {
+		t.Errorf("unexpected error record: %v", failure)
+	}
+}
+
+// TestDiffMode verifies that -diff compares two exports and prints a summary line and one line per change.
+func TestDiffMode(t *testing.T) {
+	if opts, err := parseFlags([]string{"-diff", "old.json"}, func(string) string { return "" }); err != nil || opts.Diff != "old.json" {
+		t.Errorf("parseFlags(-diff old.json) = %q, %v", opts.Diff, err)
+	}
+
+	mockFS := filesystem.NewMockFileSystem()
+	older, _ := json.Marshal(testsupport.NewStore(
+		testsupport.NewSession("kept", testsupport.WithConversation(2)),
+		testsupport.NewSession("gone", testsupport.WithTopic("Old\nnotes"), testsupport.WithConversation(1)),
+	))
+	newer, _ := json.Marshal(testsupport.NewStore(
+		testsupport.NewSession("kept", testsupport.WithConversation(4)),
+		testsupport.NewSession("fresh", testsupport.WithTopic(""), testsupport.WithConversation(1)),
+	))
+	mockFS.Files["old.json"] = older
+	mockFS.Files["new.json"] = newer
+
+	var output bytes.Buffer
+	if err := runDiff(context.Background(), mockFS, &output, "old.json", "new.json", exporter.InputFormatAuto, filesystem.RetryPolicy{}); err != nil {
+		t.Fatalf("runDiff() returned an error: %v", err)
+	}
+	for _, want := range []string{
+		"Compared with old.json: 1 added, 1 removed, 1 modified, 0 unchanged.",
+		"+ fresh  (untitled) (1 messages)",
+		"- gone  Old notes (1 messages)",
+		"~ kept  Test Session (2 -> 4 messages)",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("diff output is missing %q:\n%s", want, output.String())
+		}
+	}
+
+	if err := runDiff(context.Background(), mockFS, io.Discard, "missing.json", "new.json", exporter.InputFormatAuto, filesystem.RetryPolicy{}); err == nil {
+		t.Error("runDiff() accepted a missing file")
+	}
+}
+
+// TestAtomicWriteFileDecorators verifies that the decorators of main see the final name of files
+// written with AtomicWriteFile rather than the temporary one.
+func TestAtomicWriteFileDecorators(t *testing.T) {
+	run := newRunSummary(io.Discard)
+	tracker := &writeTrackingFileSystem{FileSystem: summaryFileSystem{FileSystem: filesystem.NewMockFileSystem(), summary: run}}
+	if err := filesystem.AtomicWriteFile(filesystem.Atomic(tracker), "export.csv", []byte("id\n"), 0644); err != nil {
+		t.Fatalf("AtomicWriteFile() through decorators returned an error: %v", err)
+	}
+	if tracker.writes != 1 || len(run.Files) != 1 || run.Files[0].Path != "export.csv" {
+		t.Errorf("decorators recorded %d write(s) and files %+v, want one write of export.csv", tracker.writes, run.Files)
+	}
+}
+
+// TestSheetsUpload verifies the validation of the -sheets-id flags and that a missing service account key
+// is reported before anything is sent.
+func TestSheetsUpload(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	for _, args := range [][]string{
+		{"-sheets-id", "sheet-1"},
+		{"-sheets-id", "sheet-1", "-sheets-key", "key.json", "-format", "csv"},
+	} {
+		if _, err := parseFlags(args, noEnv); err == nil {
+			t.Errorf("parseFlags(%v) did not return an error", args)
+		}
+	}
+	env := func(name string) string {
+		if name == uploader.EnvGoogleCredentials {
+			return "key.json"
+		}
+		return ""
+	}
+	opts, err := parseFlags([]string{"-sheets-id", "sheet-1"}, env)
+	if err != nil || opts.SheetsKey != "key.json" {
+		t.Fatalf("parseFlags(-sheets-id) with %s = %+v, %v", uploader.EnvGoogleCredentials, opts, err)
+	}
+
+	sessions := testsupport.SmallStore().ChatNextWebStore.Sessions
+	err = uploadToSheets(context.Background(), io.Discard, filesystem.NewMockFileSystem(), opts, sessions)
+	if err == nil || !strings.Contains(err.Error(), "service account key") {
+		t.Errorf("uploadToSheets() without a key file returned %v", err)
+	}
+}
+
+// TestHubUpload verifies the validation of the -hf-repo flags and that the dataset export is committed
+// under the name it was saved with, or only planned in a dry run.
+func TestHubUpload(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	for _, args := range [][]string{
+		{"-hf-dry-run"},
+		{"-hf-repo", "no-owner"},
+		{"-hf-repo", "a/b/c"},
+		{"-hf-repo", "gopher/chats", "-format", "csv"},
+	} {
+		if _, err := parseFlags(args, noEnv); err == nil {
+			t.Errorf("parseFlags(%v) did not return an error", args)
+		}
+	}
+	if opts, err := parseFlags([]string{"-hf-repo", "gopher/chats", "-hf-dry-run", "-format", "dataset"}, noEnv); err != nil || opts.HubRepo != "gopher/chats" || !opts.HubDryRun {
+		t.Errorf("parseFlags(-hf-repo, -hf-dry-run) = %+v, %v", opts, err)
+	}
+
+	var committed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/commit/main") {
+			body, _ := io.ReadAll(r.Body)
+			committed = append(committed, string(body))
+		}
+	}))
+	defer server.Close()
+
+	hub := &uploader.HuggingFaceUploader{Token: "secret", BaseURL: server.URL, Client: server.Client()}
+	var output bytes.Buffer
+	if err := pushDatasetToHub(context.Background(), &output, hub, "gopher/chats", "exports/chats.json", `{"sessions":[]}`); err != nil {
+		t.Fatalf("pushDatasetToHub() returned an error: %v", err)
+	}
+	if len(committed) != 1 || !strings.Contains(committed[0], `"path":"chats.json"`) {
+		t.Errorf("commits = %v, want one commit of chats.json", committed)
+	}
+	if !strings.Contains(output.String(), "huggingface.co/datasets/gopher/chats") {
+		t.Errorf("output does not link to the repository:\n%s", output.String())
+	}
+
+	output.Reset()
+	hub = &uploader.HuggingFaceUploader{BaseURL: server.URL, Client: server.Client(), DryRun: true}
+	if err := pushDatasetToHub(context.Background(), &output, hub, "gopher/chats", "", "{}"); err != nil {
+		t.Fatalf("pushDatasetToHub() dry run returned an error: %v", err)
+	}
+	if len(committed) != 1 || !strings.Contains(output.String(), hubDatasetFileName) {
+		t.Errorf("dry run committed %d time(s) and printed:\n%s", len(committed)-1, output.String())
+	}
+
+	hub = &uploader.HuggingFaceUploader{BaseURL: server.URL, Client: server.Client()}
+	if err := pushDatasetToHub(context.Background(), io.Discard, hub, "gopher/chats", "", "{}"); err == nil {
+		t.Error("pushDatasetToHub() uploaded without a token")
+	}
+}
+
+// TestAppendDedup verifies that -append-dedup extends an existing CSV file with new sessions only.
+func TestAppendDedup(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	if _, err := parseFlags([]string{"-append-dedup", "-format", "dataset"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted -append-dedup with the dataset format")
+	}
+	if _, err := parseFlags([]string{"-append-dedup", "-output-zip", "out.zip"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted -append-dedup with -output-zip")
+	}
+
+	opts := cliOptions{AppendDedup: true}
+	daily := func(ids ...string) []exporter.Session {
+		var sessions []exporter.Session
+		for _, id := range ids {
+			sessions = append(sessions, testsupport.NewSession(id, testsupport.WithConversation(2)))
+		}
+		return sessions
+	}
+	var monday bytes.Buffer
+	if err := exporter.WriteSessionsCSV(context.Background(), &monday, daily("mon-1", "mon-2"), exporter.FormatOptionPerLine, opts.csvOptions()); err != nil {
+		t.Fatalf("WriteSessionsCSV() returned an error: %v", err)
+	}
+	mockFS := filesystem.NewMockFileSystem()
+	// The last row lacks its line break, as in files edited by hand; the new rows still start on a line of their own.
+	mockFS.Files["master.csv"] = bytes.TrimSuffix(monday.Bytes(), []byte("\n"))
+
+	reader := bufio.NewReader(strings.NewReader(""))
+	convertToSingleCSV(mockFS, context.Background(), newRunState(io.Discard), reader, daily("mon-2", "tue-1"), exporter.FormatOptionPerLine, "master.csv", opts)
+
+	content := string(mockFS.Files["master.csv"])
+	if !strings.HasPrefix(content, monday.String()) {
+		t.Errorf("appending changed the existing rows:\n%s", content)
+	}
+	if headers := strings.Count(content, "session_id,"); headers != 1 {
+		t.Errorf("master.csv has %d header rows, want 1:\n%s", headers, content)
+	}
+	for _, id := range []string{"mon-1", "mon-2", "tue-1"} {
+		if got := strings.Count(content, "\n"+id+","); got != 2 {
+			t.Errorf("master.csv has %d row(s) of %s, want 2:\n%s", got, id, content)
+		}
+	}
+}
+
+// TestAppendDedupSummary verifies that the JSON summary of -append-dedup reports the rows appended, without
+// a header or the line break separating them from the existing rows, and the size of the whole file.
+func TestAppendDedupSummary(t *testing.T) {
+	opts, err := parseFlags([]string{"-json-output", "-append-dedup"}, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("parseFlags() returned an error: %v", err)
+	}
+	var existing bytes.Buffer
+	sessions := []exporter.Session{
+		testsupport.NewSession("mon-1", testsupport.WithConversation(2)),
+		testsupport.NewSession("tue-1", testsupport.WithConversation(2)),
+	}
+	if err := exporter.WriteSessionsCSV(context.Background(), &existing, sessions[:1], exporter.FormatOptionPerLine, opts.csvOptions()); err != nil {
+		t.Fatalf("WriteSessionsCSV() returned an error: %v", err)
+	}
+	csvFileName := filepath.Join(t.TempDir(), "master.csv")
+	if err := os.WriteFile(csvFileName, bytes.TrimSuffix(existing.Bytes(), []byte("\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := newRunState(io.Discard)
+	run.summary = newRunSummary(io.Discard)
+	reader := bufio.NewReader(strings.NewReader(""))
+	convertToSingleCSV(run.withSummary(filesystem.RealFileSystem{}), context.Background(), run, reader, sessions, exporter.FormatOptionPerLine, csvFileName, opts)
+
+	info, err := os.Stat(csvFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.summary.Files) != 1 {
+		t.Fatalf("the summary lists %d file(s), want 1: %+v", len(run.summary.Files), run.summary.Files)
+	}
+	file := run.summary.Files[0]
+	if file.Rows == nil || *file.Rows != 2 {
+		t.Errorf("the summary reports %v appended row(s), want 2", file.Rows)
+	}
+	if file.Bytes != int(info.Size()) {
+		t.Errorf("the summary reports %d bytes, want the file size %d", file.Bytes, info.Size())
+	}
+}
+
+// cancelAfterChecks is a context that is canceled once it has been checked for cancellation a number
+// of times, to cancel an export at a known point.
+type cancelAfterChecks struct {
+	context.Context
+	checks int // checks is the number of checks left before the context is canceled.
+}
+
+func (c *cancelAfterChecks) Done() <-chan struct{} {
+	if c.checks--; c.checks >= 0 {
+		return nil
+	}
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+func (c *cancelAfterChecks) Err() error {
+	if c.checks >= 0 {
+		return nil
+	}
+	return context.Canceled
+}
+
+// TestPartialCSV verifies that a CSV export canceled midway keeps the rows of the completed sessions in a
+// .partial.csv file instead of the requested one, and that nothing is saved when no session was completed.
+func TestPartialCSV(t *testing.T) {
+	if got := partialCSVFileName("out/sessions.csv"); got != "out/sessions.partial.csv" {
+		t.Errorf("partialCSVFileName() = %q, want out/sessions.partial.csv", got)
+	}
+
+	sessions := []exporter.Session{
+		testsupport.NewSession("first", testsupport.WithConversation(2)),
+		testsupport.NewSession("second", testsupport.WithConversation(2)),
+		testsupport.NewSession("third", testsupport.WithConversation(2)),
+	}
+	reader := bufio.NewReader(strings.NewReader(""))
+	mockFS := filesystem.NewMockFileSystem()
+	// One check before the export starts and one before the first session, then the export is canceled.
+	convertToSingleCSV(mockFS, &cancelAfterChecks{Context: context.Background(), checks: 2}, newRunState(io.Discard), reader, sessions, exporter.FormatOptionPerLine, "big.csv", cliOptions{})
+
+	if _, ok := mockFS.Files["big.csv"]; ok {
+		t.Error("the canceled export was saved under the requested name")
+	}
+	partial := string(mockFS.Files["big.partial.csv"])
+	if !strings.HasPrefix(partial, "session_id,") || strings.Count(partial, "\nfirst,") != 2 || strings.Contains(partial, "third") {
+		t.Errorf("big.partial.csv does not hold exactly the rows of the first session:\n%s", partial)
+	}
+
+	mockFS = filesystem.NewMockFileSystem()
+	convertToSingleCSV(mockFS, &cancelAfterChecks{Context: context.Background(), checks: 1}, newRunState(io.Discard), reader, sessions, exporter.FormatOptionPerLine, "big.csv", cliOptions{})
+	if len(mockFS.Files) != 0 {
+		t.Errorf("an export canceled before the first session saved %d file(s)", len(mockFS.Files))
+	}
+}
+
+// TestInspectMode verifies the validation of the -inspect flags and that the pretty-printed store is saved to a file.
+func TestInspectMode(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	for _, args := range [][]string{{"-inspect-limit", "2"}, {"-inspect-session", "abc"}, {"-inspect", "-inspect-limit", "-1"}} {
+		if _, err := parseFlags(args, noEnv); err == nil {
+			t.Errorf("parseFlags(%v) did not return an error", args)
+		}
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "store.json")
+	data, _ := json.Marshal(testsupport.SmallStore())
+	os.WriteFile(input, data, 0644)
+
+	output := filepath.Join(dir, "pretty.json")
+	var progress bytes.Buffer
+	if err := inspectToFile(context.Background(), lockedRealFileSystem(), output, input, exporter.InspectOptions{MaxSessions: 1}, inspectProgress(&progress)); err != nil {
+		t.Fatalf("inspectToFile() returned an error: %v", err)
+	}
+	pretty, _ := os.ReadFile(output)
+	store, err := exporter.ReadJSONFromReader(bytes.NewReader(pretty))
+	if err != nil || len(store.ChatNextWebStore.Sessions) != 1 || !strings.Contains(string(pretty), "\n    \"sessions\": [") {
+		t.Errorf("inspectToFile() wrote %d session(s), %v:\n%s", len(store.ChatNextWebStore.Sessions), err, pretty)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("inspectToFile() left files behind: %v", entries)
+	}
+	if !strings.HasSuffix(progress.String(), ": 100%") {
+		t.Errorf("inspectToFile() reported the progress %q, want it to end at 100%%", progress.String())
+	}
+}
+
+// TestTempOut verifies the validation of -tempout, that each format is written to its own temporary file,
+// and that the path of the file is the only output besides the messages of the export.
+func TestTempOut(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	for _, args := range [][]string{{"-tempout"}, {"-tempout", "-list"}, {"-tempout", "-format", "csv", "-json-output"}, {"-tempout", "-format", "csv", "-output-zip", "out.zip"}} {
+		if _, err := parseFlags(args, noEnv); err == nil {
+			t.Errorf("parseFlags(%v) did not return an error", args)
+		}
+	}
+	if opts, err := parseFlags([]string{"-tempout", "-format", "org"}, noEnv); err != nil || !opts.TempOut {
+		t.Errorf("parseFlags() = %+v, %v, want -tempout enabled", opts, err)
+	}
+
+	dir := t.TempDir()
+	sessions := testsupport.SmallStore().ChatNextWebStore.Sessions
+	paths := make(map[string]bool)
+	for option, extension := range map[string]string{`1`: ".csv", `2`: ".json", `3`: ".org", `5`: ".jsonl", `6`: ".csv"} {
+		path, err := writeTempOutput(context.Background(), dir, option, sessions, exporter.Options{})
+		if err != nil {
+			t.Fatalf("writeTempOutput(%s) returned an error: %v", option, err)
+		}
+		content, _ := os.ReadFile(path)
+		if filepath.Dir(path) != dir || filepath.Ext(path) != extension || len(content) == 0 || paths[path] {
+			t.Errorf("writeTempOutput(%s) wrote %d bytes to %s", option, len(content), path)
+		}
+		paths[path] = true
+	}
+
+	if _, err := writeTempOutput(context.Background(), dir, `4`, sessions, exporter.Options{}); err == nil {
+		t.Error("writeTempOutput() accepted the list format")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 5 {
+		t.Errorf("writeTempOutput() left %d files, want 5", len(entries))
+	}
+
+	// The messages of the export go to their own writer, so that the path is the only output on pathOut.
+	exportDir := t.TempDir()
+	t.Setenv("TMPDIR", exportDir)
+	input := filepath.Join(t.TempDir(), "store.json")
+	data, _ := json.Marshal(testsupport.SmallStore())
+	os.WriteFile(input, data, 0644)
+	opts, err := parseFlags([]string{"-tempout", "-format", "csv", "-sample", "1", "-tags-file", filepath.Join(exportDir, "tags.json")}, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages, pathOut bytes.Buffer
+	if err := exportInput(context.Background(), newRunState(&messages), bufio.NewReader(strings.NewReader("")), input, opts, &pathOut); err != nil {
+		t.Fatalf("exportInput() with -tempout returned an error: %v", err)
+	}
+	path := strings.TrimSuffix(pathOut.String(), "\n")
+	if content, err := os.ReadFile(path); err != nil || len(content) == 0 || strings.Contains(path, "\n") {
+		t.Errorf("exportInput() with -tempout printed %q, want only the path of the export", pathOut.String())
+	}
+	if !strings.Contains(messages.String(), "Sampled 1 session(s)") {
+		t.Errorf("exportInput() with -tempout wrote the messages %q, want the sampling report", messages.String())
+	}
+}
+
+// TestPrintFormats verifies that the formats command describes every registered format with an example.
+func TestPrintFormats(t *testing.T) {
+	var out bytes.Buffer
+	if err := printFormats(context.Background(), &out); err != nil {
+		t.Fatalf("printFormats() returned an error: %v", err)
+	}
+	for _, format := range exporter.Formats() {
+		doc := format.Describe()
+		if !strings.Contains(out.String(), doc.Name+" ("+doc.Extension+")\n"+doc.Description) {
+			t.Errorf("output does not describe %s", doc.Name)
+		}
+	}
+	if got, want := strings.Count(out.String(), "\nExample:\n"), len(exporter.Formats()); got != want {
+		t.Errorf("output has %d examples, want %d", got, want)
+	}
+	if !strings.Contains(out.String(), "  sessions.csv:\n  id,topic,memoryPrompt\n") || !strings.Contains(out.String(), "  messages.csv:\n") {
+		t.Error("output does not show the files of csv-separate apart")
+	}
+}
+
+// TestPrintPaths verifies that the paths command prints the resolved directories, and fails without
+// a home directory.
+func TestPrintPaths(t *testing.T) {
+	env := map[string]string{appdirs.EnvHome: filepath.Join("data", "exporter")}
+	var out bytes.Buffer
+	if err := printPaths(&out, func(key string) string { return env[key] }); err != nil {
+		t.Fatalf("printPaths() returned an error: %v", err)
+	}
+	for _, want := range []string{filepath.Join("data", "exporter", "config"), filepath.Join("data", "exporter", "state"), appdirs.EnvHome} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not mention %s:\n%s", want, out.String())
+		}
+	}
+	if err := printPaths(io.Discard, func(string) string { return "" }); err == nil {
+		t.Error("printPaths() without a home directory returned no error")
+	}
+}
+
+// TestRerunOnHangup verifies that SIGHUP requests a re-run instead of cancelling, and that the re-run
+// replays the recorded answers and confirms everything else.
+func TestRerunOnHangup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP cannot be sent on Windows")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hangups := make(chan struct{}, 1)
+	setupSignalHandling(io.Discard, cancel, hangups)
+	defer signal.Reset(syscall.SIGHUP)
+
+	self, _ := os.FindProcess(os.Getpid())
+	if err := self.Signal(syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-hangups:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGHUP did not request a re-run")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("SIGHUP cancelled the context")
+	}
+
+	recording := &exportRecording{}
+	recordingCtx := withRecording(ctx, recording)
+	for _, want := range []string{"1", "out.csv"} {
+		if got, _ := promptForInput(recordingCtx, io.Discard, bufio.NewReader(strings.NewReader(want+"\n")), ""); got != want {
+			t.Fatalf("promptForInput() = %q, want %q", got, want)
+		}
+	}
+	recording.recordFiles([]string{"out.csv"})
+
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["out.csv"] = []byte("old")
+	mockFS.Files["other.csv"] = []byte("old")
+	var replayed []string
+	var log bytes.Buffer
+	replays := 0
+	hangups <- struct{}{}
+	rerunOnHangup(ctx, newRunState(&log), hangups, recording, func(ctx context.Context, reader *bufio.Reader) error {
+		replays++
+		if replays == 1 {
+			hangups <- struct{}{}
+			for i := 0; i < 3; i++ {
+				answer, err := promptForInput(ctx, io.Discard, reader, "")
+				if err != nil {
+					return err
+				}
+				replayed = append(replayed, answer)
+			}
+			return nil
+		}
+		defer cancel()
+		if overwrite, err := confirmOverwrite(mockFS, ctx, io.Discard, reader, "out.csv"); err != nil || !overwrite {
+			t.Errorf("confirmOverwrite() of a recorded file = %v, %v, want it overwritten without asking", overwrite, err)
+		}
+		if _, err := confirmOverwrite(mockFS, ctx, io.Discard, reader, "other.csv"); err == nil {
+			t.Error("confirmOverwrite() answered for a file the recorded export did not write")
+		}
+		return nil
+	})
+	if want := []string{"1", "out.csv"}; fmt.Sprint(replayed) != fmt.Sprint(want) {
+		t.Errorf("replayed answers = %v, want %v", replayed, want)
+	}
+	if !strings.Contains(log.String(), "The re-run failed: "+errReplayExhausted.Error()) || replays != 2 {
+		t.Errorf("a replay without answers left was not logged and followed by the next one:\n%s", log.String())
+	}
+}
+
+// TestDuplicateIDReport verifies the -duplicate-ids flag and the report of resolved duplicates.
+func TestDuplicateIDReport(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	if opts, err := parseFlags([]string{"-duplicate-ids", "newest"}, noEnv); err != nil || opts.DuplicateIDs != exporter.DuplicateIDKeepNewest {
+		t.Errorf("parseFlags() = %v, %v, want the newest policy", opts.DuplicateIDs, err)
+	}
+	if _, err := parseFlags([]string{"-duplicate-ids", "merge"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted an unknown duplicate ID policy")
+	}
+
+	sessions := []exporter.Session{testsupport.NewSession("x"), testsupport.NewSession("x")}
+	_, report, _ := exporter.ResolveDuplicateIDs(sessions, exporter.DuplicateIDKeepBoth)
+	var out bytes.Buffer
+	printDuplicateReport(newRunState(&out), report)
+	for _, want := range []string{`"x" (2)`, "1 session(s) kept under a new ID"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report is missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+// TestInputFormatFlag verifies that -input-format forces the layout loadStore decodes the input with.
+func TestInputFormatFlag(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	opts, err := parseFlags([]string{"-input-format", "nextweb"}, noEnv)
+	if err != nil || opts.InputFormat != exporter.InputFormatNextWeb {
+		t.Errorf("parseFlags() = %v, %v, want nextweb", opts.InputFormat, err)
+	}
+	if _, err := parseFlags([]string{"-input-format", "openai"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted an unknown input format")
+	}
+
+	mockFS := filesystem.NewMockFileSystem()
+	object := `{"chat-next-web-store": {"sessions": {"s1": {"topic": "Keyed", "messages": []}}}}`
+	if err := mockFS.WriteFile("object.json", []byte(object), 0644); err != nil {
+		t.Fatal(err)
+	}
+	retry := filesystem.RetryPolicy{Attempts: 1}
+
+	store, err := loadStore(context.Background(), mockFS, "object.json", exporter.InputFormatNextWebObject, retry)
+	if err != nil || len(store.ChatNextWebStore.Sessions) != 1 || store.ChatNextWebStore.Sessions[0].ID != "s1" {
+		t.Errorf("loadStore() as nextweb-object = %+v, %v, want session s1", store.ChatNextWebStore.Sessions, err)
+	}
+	if _, err := loadStore(context.Background(), mockFS, "object.json", exporter.InputFormatNextWeb, retry); err == nil || !strings.Contains(err.Error(), "as nextweb format") {
+		t.Errorf("loadStore() as nextweb returned %v, want an error naming the format", err)
+	}
+}
+
+// TestSelfChecks verifies that -healthcheck and -selftest pass within their time limit, that -verbose
+// lists every check, and that a failed check is reported.
+func TestSelfChecks(t *testing.T) {
+	if _, err := parseFlags([]string{"-healthcheck", "-selftest"}, func(string) string { return "" }); err == nil {
+		t.Error("parseFlags() accepted -healthcheck with -selftest")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), selfCheckTimeout)
+	defer cancel()
+	var output bytes.Buffer
+	if !runSelfChecks(ctx, &output, healthChecks(), false) || output.String() != "ok (2 checks passed)\n" {
+		t.Errorf("runSelfChecks(healthChecks()) failed:\n%s", output.String())
+	}
+
+	output.Reset()
+	if !runSelfChecks(ctx, &output, selfTestChecks(), true) {
+		t.Errorf("runSelfChecks(selfTestChecks()) failed:\n%s", output.String())
+	}
+	for _, format := range exporter.Formats() {
+		if !strings.Contains(output.String(), "format "+format.Describe().Name) {
+			t.Errorf("-selftest -verbose does not list the %s format:\n%s", format.Describe().Name, output.String())
+		}
+	}
+
+	output.Reset()
+	broken := []selfCheck{{"broken", func(context.Context) error { return errors.New("boom") }}}
+	if runSelfChecks(ctx, &output, broken, false) || !strings.HasPrefix(output.String(), "FAIL broken: boom\n") {
+		t.Errorf("runSelfChecks() with a failing check wrote:\n%s", output.String())
+	}
+}
+
+// TestReportSkippedSessions verifies the -include-empty flag and that the skipped sessions are
+// reported by reason and recorded in the run summary.
+func TestReportSkippedSessions(t *testing.T) {
+	if opts, err := parseFlags([]string{"-include-empty"}, func(string) string { return "" }); err != nil || !opts.IncludeEmpty {
+		t.Errorf("parseFlags(-include-empty) = %v, %v, want IncludeEmpty", opts.IncludeEmpty, err)
+	}
+
+	var out bytes.Buffer
+	run := newRunState(&out)
+	run.summary = newRunSummary(io.Discard)
+	report := exporter.SkipReport{Input: 6, Exported: 2, Empty: 3, Deduplicated: 1}
+	reportSkippedSessions(run, report)
+	if want := "2 of 6 session(s) exported; skipped 3 empty, 0 filtered, 1 deduplicated, 0 unchanged, 0 not sampled."; !strings.Contains(out.String(), want) {
+		t.Errorf("report = %q, want %q", out.String(), want)
+	}
+	if run.summary.Sessions == nil || *run.summary.Sessions != report {
+		t.Errorf("summary.Sessions = %v, want %v", run.summary.Sessions, report)
+	}
+
+	out.Reset()
+	reportSkippedSessions(newRunState(&out), exporter.SkipReport{Input: 2, Exported: 2})
+	if out.Len() != 0 {
+		t.Errorf("nothing skipped, but the report printed %q", out.String())
+	}
+}
+
+// TestMergeSessionsMode verifies the session number parsing of -merge and that the merged session
+// replaces the picked ones in the saved backup at the position of the earliest of them.
+func TestMergeSessionsMode(t *testing.T) {
+	if indices, err := parseSessionNumbers("3, 1", 3); err != nil || !reflect.DeepEqual(indices, []int{2, 0}) {
+		t.Errorf("parseSessionNumbers() = %v, %v, want [2 0]", indices, err)
+	}
+	for _, input := range []string{"1", "1,1", "0,2", "2,x"} {
+		if _, err := parseSessionNumbers(input, 3); err == nil {
+			t.Errorf("parseSessionNumbers(%q) accepted an invalid selection", input)
+		}
+	}
+
+	if opts, err := parseFlags([]string{"-merge"}, func(string) string { return "" }); err != nil || !opts.Merge {
+		t.Errorf("parseFlags(-merge) = %v, %v, want Merge", opts.Merge, err)
+	}
+
+	sessions := testsupport.LargeStore().ChatNextWebStore.Sessions[:3]
+	mockFS := filesystem.NewMockFileSystem()
+	reader := bufio.NewReader(strings.NewReader("3,2\n2\nCombined\nno\nyes\nmerged\n"))
+	path, err := mergeSessions(mockFS, context.Background(), newRunState(io.Discard), reader, sessions, nil, exporter.DateFieldUpdated)
+	if err != nil || path != "merged.json" {
+		t.Fatalf("mergeSessions() = %q, %v, want merged.json", path, err)
+	}
+	store, err := exporter.ReadJSONFromReader(bytes.NewReader(mockFS.Files[path]))
+	if err != nil {
+		t.Fatalf("the saved backup cannot be read: %v", err)
+	}
+	saved := store.ChatNextWebStore.Sessions
+	if len(saved) != 2 || saved[0].ID != sessions[0].ID || saved[1].Topic != "Combined" ||
+		len(saved[1].Messages) != len(sessions[1].Messages)+len(sessions[2].Messages) {
+		t.Errorf("saved sessions = %v", saved)
+	}
+}
+
+// TestSessionPreview verifies that the -verbose preview shows the first three sessions with the start
+// of their first message on a single line, and flags sessions without a topic or messages.
+func TestSessionPreview(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	sessions := []exporter.Session{
+		testsupport.NewSession("a", testsupport.WithTopic("Gophers"), testsupport.WithMessages(
+			testsupport.NewMessage("1", "user", "Why\nare gophers   so cute?"),
+			testsupport.NewMessage("2", "assistant", "They just are."),
+		)),
+		testsupport.NewSession("b", testsupport.WithTopic(""), testsupport.WithMessages(testsupport.NewMessage("1", "user", long))),
+		testsupport.NewSession("c", testsupport.WithTopic("Empty")),
+		testsupport.NewSession("d", testsupport.WithTopic("Fourth")),
+	}
+	var out bytes.Buffer
+	printSessionPreview(newRunState(io.Discard), &out, sessions)
+	for _, want := range []string{
+		"Session 1: Gophers (2 message(s)) - user: Why are gophers so cute?",
+		"Session 2: (no topic) (1 message(s)) - user: " + strings.TrimSpace(long[:previewLength-1]) + "…",
+		"Session 3: Empty (0 message(s)) - (no messages)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("preview is missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Fourth") {
+		t.Errorf("preview shows more than %d sessions:\n%s", previewSessions, out.String())
+	}
+}
+
+// scriptedTerminal is a viewerTerminal replaying scripted key presses and recording what is drawn.
+type scriptedTerminal struct {
+	keys          *strings.Reader
+	output        bytes.Buffer
+	width, height int
+}
+
+func (t *scriptedTerminal) ReadByte() (byte, error)     { return t.keys.ReadByte() }
+func (t *scriptedTerminal) Write(p []byte) (int, error) { return t.output.Write(p) }
+func (t *scriptedTerminal) Size() (int, int)            { return t.width, t.height }
+
+// lastScreen returns the lines of the last screen drawn.
+func (t *scriptedTerminal) lastScreen() []string {
+	screens := strings.Split(t.output.String(), clearScreen)
+	return strings.Split(screens[len(screens)-1], "\r\n")
+}
+
+// TestViewer verifies scrolling, switching sessions, searching, and quitting in the viewer, and the
+// decoding of arrow keys.
+func TestViewer(t *testing.T) {
+	var lines []exporter.Message
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, testsupport.NewMessage(fmt.Sprint(i), "user", fmt.Sprintf("line %d", i)))
+	}
+	sessions := []exporter.Session{
+		testsupport.NewSession("a", testsupport.WithTopic("First"), testsupport.WithMessages(lines...)),
+		testsupport.NewSession("b", testsupport.WithTopic("Second"), testsupport.WithMessages(
+			testsupport.NewMessage("1", "assistant", "```go\nfmt.Println(\"needle\")\n```"))),
+	}
+	run := func(keys string) (*viewer, *scriptedTerminal) {
+		t.Helper()
+		term := &scriptedTerminal{keys: strings.NewReader(keys), width: 40, height: 6}
+		v := &viewer{sessions: sessions}
+		if err := v.run(term); err != nil {
+			t.Fatalf("run(%q) returned an error: %v", keys, err)
+		}
+		return v, term
+	}
+
+	v, term := run("jj\x1b[Bkq")
+	if v.top != 2 {
+		t.Errorf("after scrolling down 3 and up 1 lines, top = %d, want 2", v.top)
+	}
+	if screen := term.lastScreen(); len(screen) != 6 || !strings.Contains(screen[5], "[1/2] lines 3-7 of") {
+		t.Errorf("screen = %q, want 5 lines and the status line", screen)
+	}
+	if !strings.HasPrefix(term.output.String(), enterAlternateScreen) || !strings.HasSuffix(term.output.String(), leaveAlternateScreen) {
+		t.Error("viewer did not switch to the alternate screen and back")
+	}
+
+	if v, _ = run("G"); v.top != len(v.lines)-5 {
+		t.Errorf("after G, top = %d, want the last page at %d", v.top, len(v.lines)-5)
+	}
+	if v, _ = run("/line 17\r"); !strings.Contains(v.lines[v.top], "line 17") {
+		t.Errorf("after searching, top line = %q, want the match", v.lines[v.top])
+	}
+	if v, term = run("/nothing\rq"); !strings.Contains(term.lastScreen()[5], "Pattern not found: nothing") {
+		t.Errorf("status line = %q, want the failed search reported", term.lastScreen()[5])
+	}
+
+	if v, term = run("n/needle\rq"); v.index != 1 || !slices.Contains(term.lastScreen(), `    fmt.Println("needle")`) {
+		t.Errorf("after n and searching, session = %d, screen = %q, want the indented code of the second session", v.index, term.lastScreen())
+	}
+	v, term = run("np/needle\rq")
+	if v.index != 0 || v.top != 0 || !strings.Contains(term.lastScreen()[5], "Pattern not found: needle") {
+		t.Errorf("after n, p, and searching, session = %d, top = %d, want the first session without a match", v.index, v.top)
+	}
+	if v, term = run("pq"); !strings.Contains(term.lastScreen()[5], "This is the first session.") {
+		t.Errorf("status line = %q, want the first session reported", term.lastScreen()[5])
+	}
+
+	for keys, want := range map[string]string{"\x1b[A": "up", "\x1b[6~": "pgdown", "\x1bOB": "down", "\x1b[C": "esc", "x": "x"} {
+		if key, err := readKey(strings.NewReader(keys)); key != want || err != nil {
+			t.Errorf("readKey(%q) = %q, %v, want %q", keys, key, err, want)
+		}
+	}
+}
+
+// TestAnonymizeIDsFlags verifies that -id-map requires -anonymize-ids and that the mapping is written
+// as CSV readable by the owner only.
+func TestAnonymizeIDsFlags(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	if _, err := parseFlags([]string{"-id-map", "ids.csv"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted -id-map without -anonymize-ids")
+	}
+	opts, err := parseFlags([]string{"-anonymize-ids", "-id-map", "ids.csv"}, noEnv)
+	if err != nil || !opts.AnonymizeIDs || opts.IDMap != "ids.csv" {
+		t.Errorf("parseFlags() = %+v, %v, want anonymized IDs with a mapping file", opts, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ids.csv")
+	if err := writeIDMapping(filesystem.RealFileSystem{}, path, map[string]string{"real": "s0001"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "anonymized_id,original_id\ns0001,real\n" {
+		t.Errorf("mapping file = %q, %v", data, err)
+	}
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("mapping file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+// TestMergeStoresMode verifies the -merge-store flags and that a diverged session is shown with both
+// versions and resolved by the answer, re-asking after an invalid one.
+func TestMergeStoresMode(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	if _, err := parseFlags([]string{"-merge-conflicts", "prefer-newest"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted -merge-conflicts without -merge-store")
+	}
+	opts, err := parseFlags([]string{"-merge-store", "phone.json", "-merge-conflicts", "prefer-newest", "-tempout"}, noEnv)
+	if err != nil || opts.MergeStore != "phone.json" || opts.MergeConflicts != exporter.ConflictPreferNewest || !opts.TempOut {
+		t.Errorf("parseFlags() = %+v, %v, want a scripted store merge", opts, err)
+	}
+
+	mockFS := filesystem.NewMockFileSystem()
+	laptop, _ := json.Marshal(testsupport.NewStore(testsupport.NewSession("s", testsupport.WithTopic("Laptop"), testsupport.WithMessages(
+		testsupport.NewMessage("1", "user", "hello"), testsupport.NewMessage("2", "assistant", "from the laptop")))))
+	phone, _ := json.Marshal(testsupport.NewStore(testsupport.NewSession("s", testsupport.WithTopic("Phone"), testsupport.WithMessages(
+		testsupport.NewMessage("1", "user", "hello"), testsupport.NewMessage("2", "assistant", "from the phone")))))
+	mockFS.Files["laptop.json"] = laptop
+	mockFS.Files["phone.json"] = phone
+
+	var output bytes.Buffer
+	reader := bufio.NewReader(strings.NewReader("x\nr\nyes\nmerged\n"))
+	path, err := mergeStores(context.Background(), mockFS, newRunState(&output), reader, "laptop.json", cliOptions{MergeStore: "phone.json"})
+	if err != nil || path != "merged.json" {
+		t.Fatalf("mergeStores() = %q, %v, want merged.json", path, err)
+	}
+	for _, want := range []string{"Session s diverged in both files:", "Left (laptop.json): Laptop, 2 message(s)", "  assistant: from the phone",
+		"Please answer l, r, or b.", "kept 0 left, 1 right, and 0 both"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, output.String())
+		}
+	}
+	store, err := exporter.ReadJSONFromReader(bytes.NewReader(mockFS.Files[path]))
+	if err != nil || len(store.ChatNextWebStore.Sessions) != 1 || store.ChatNextWebStore.Sessions[0].Topic != "Phone" {
+		t.Errorf("saved store = %v, %v, want the phone version", store.ChatNextWebStore.Sessions, err)
+	}
+}
+
+// TestBatchMode verifies that directories and glob patterns expand to their JSON files, the batch flags,
+// and that a failed file stops the batch with -fail-fast while the others are still exported without it.
+func TestBatchMode(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.json", "a.json", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+	for _, path := range []string{dir, filepath.Join(dir, "*.json")} {
+		if paths, err := expandInputPaths(filesystem.RealFileSystem{}, path); err != nil || !slices.Equal(paths, want) {
+			t.Errorf("expandInputPaths(%q) = %v, %v, want %v", path, paths, err, want)
+		}
+	}
+	if paths, err := expandInputPaths(filesystem.RealFileSystem{}, "missing.json"); err != nil || !slices.Equal(paths, []string{"missing.json"}) {
+		t.Errorf("expandInputPaths() of a file = %v, %v, want the file itself", paths, err)
+	}
+	if _, err := expandInputPaths(filesystem.RealFileSystem{}, filepath.Join(dir, "*.csv")); err == nil {
+		t.Error("expandInputPaths() accepted a pattern without matches")
+	}
+
+	noEnv := func(string) string { return "" }
+	if _, err := parseFlags([]string{"-fail-fast", "-keep-going"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted -fail-fast with -keep-going")
+	}
+	if opts, err := parseFlags([]string{"-fail-fast"}, noEnv); err != nil || !opts.FailFast {
+		t.Errorf("parseFlags(-fail-fast) = %+v, %v", opts, err)
+	}
+
+	// Errors are logged as structured records, so that they are not typed out slowly.
+	run := newRunState(io.Discard)
+	run.logger = newStructuredLogger(io.Discard)
+	failing := errors.New("broken store")
+	paths := []string{"a.json", "b.json", "c.json"}
+	for _, test := range []struct {
+		failFast bool
+		exported []string
+	}{
+		{failFast: true, exported: []string{"a.json", "b.json"}},
+		{failFast: false, exported: paths},
+	} {
+		var exported []string
+		err := exportBatch(run, paths, test.failFast, func(path string) error {
+			exported = append(exported, path)
+			if path == "b.json" {
+				return failing
+			}
+			return nil
+		})
+		if !slices.Equal(exported, test.exported) {
+			t.Errorf("failFast %v exported %v, want %v", test.failFast, exported, test.exported)
+		}
+		if test.failFast && (!errors.Is(err, failing) || !strings.Contains(err.Error(), "b.json")) {
+			t.Errorf("failFast returned %v, want the error of b.json", err)
+		}
+		if !test.failFast && (err == nil || err.Error() != "1 of 3 input files failed") {
+			t.Errorf("keep-going returned %v, want a count of the failures", err)
+		}
+	}
+
+	var exported int
+	err := exportBatch(run, paths, false, func(string) error { exported++; return context.Canceled })
+	if !errors.Is(err, context.Canceled) || exported != 1 {
+		t.Errorf("a canceled batch exported %d file(s) and returned %v, want it to stop", exported, err)
+	}
+}
+
+// TestSampleFlags verifies that -sample-seed 0 can be given explicitly, and that sessions left out by
+// -sample are not recorded in the incremental state.
+func TestSampleFlags(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	if opts, err := parseFlags([]string{"-sample", "2", "-sample-seed", "0"}, noEnv); err != nil || opts.SampleSeed != 0 {
+		t.Errorf("parseFlags(-sample-seed 0) = %d, %v, want seed 0", opts.SampleSeed, err)
+	}
+	if _, err := parseFlags([]string{"-sample-seed", "0"}, noEnv); err == nil {
+		t.Error("parseFlags() accepted -sample-seed 0 without -sample")
+	}
+
+	sessions := testsupport.SmallStore().ChatNextWebStore.Sessions
+	selected := sessions[1:]
+	sampled := exporter.SampleSessions(selected, 1, 42)
+	state := withoutUnsampled(sessions, selected, sampled)
+	if len(state) != len(sessions)-len(selected)+1 {
+		t.Fatalf("withoutUnsampled() kept %d session(s), want the unselected ones and the sampled one", len(state))
+	}
+	for _, session := range state {
+		if session.ID != sessions[0].ID && session.ID != sampled[0].ID {
+			t.Errorf("withoutUnsampled() kept the unsampled session %s", session.ID)
+		}
+	}
+}
+
+// TestAttachmentsRelativeToOutput verifies that -attachments writes the attachments once the output file
+// is chosen, and references them relative to the directory of that file.
+func TestAttachmentsRelativeToOutput(t *testing.T) {
+	sessions := []exporter.Session{testsupport.NewSession("s1", testsupport.WithMessages(
+		testsupport.NewMessage("m1", "user", "![a](data:image/png;base64,aGVsbG8=)")))}
+	mockFS := filesystem.NewMockFileSystem()
+	reader := bufio.NewReader(strings.NewReader("yes\nout/export\n"))
+
+	opts := cliOptions{Attachments: filepath.Join("out", "files")}
+	if err := processOrgModeOption(mockFS, context.Background(), newRunState(io.Discard), reader, sessions, opts); err != nil {
+		t.Fatalf("processOrgModeOption() returned an error: %v", err)
+	}
+	output := string(mockFS.Files["out/export.org"])
+	if !strings.Contains(output, "files/") || strings.Contains(output, "out/files/") || strings.Contains(output, "base64") {
+		t.Errorf("the attachment is not referenced relative to out/export.org:\n%s", output)
+	}
+
+	// Declining to save the output writes no attachments either.
+	mockFS = filesystem.NewMockFileSystem()
+	reader = bufio.NewReader(strings.NewReader("no\n"))
+	if err := processOrgModeOption(mockFS, context.Background(), newRunState(io.Discard), reader, sessions, opts); err != nil {
+		t.Fatalf("processOrgModeOption() returned an error: %v", err)
+	}
+	if len(mockFS.Files) != 0 {
+		t.Errorf("declining the export wrote %d file(s)", len(mockFS.Files))
+	}
+}
+
+// TestRegisteredFormatOptions verifies that the formats of the exporter registry without a menu entry of
+// their own can be selected with -format and from the menu, and are saved with their documented extension.
+func TestRegisteredFormatOptions(t *testing.T) {
+	sessions := testsupport.SmallStore().ChatNextWebStore.Sessions
+	for _, tc := range []struct {
+		format, fileName string
+	}{
+		{"text", "out.txt"},
+		{"qa-jsonl", "out.jsonl"},
+		{"turns-json", "out.json"},
+		{"atom", "out.xml"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			opts, err := parseFlags([]string{"-format", tc.format}, func(string) string { return "" })
+			if err != nil {
+				t.Fatalf("parseFlags(-format %s) returned an error: %v", tc.format, err)
+			}
+			option, ok := outputOptionForFormat(opts.Format)
+			if !ok || outputFormatName(option) != tc.format {
+				t.Fatalf("-format %s selects menu option %q, named %q", tc.format, option, outputFormatName(option))
+			}
+
+			mockFS := filesystem.NewMockFileSystem()
+			reader := bufio.NewReader(strings.NewReader("yes\nout\n"))
+			if err := processOutputOption(mockFS, context.Background(), newRunState(io.Discard), reader, option, sessions, opts); err != nil {
+				t.Fatalf("processOutputOption(%s) returned an error: %v", option, err)
+			}
+			var want bytes.Buffer
+			if err := exporter.ConvertSessions(context.Background(), &want, sessions, tc.format, opts.exportOptions()); err != nil {
+				t.Fatal(err)
+			}
+			if got := string(mockFS.Files[tc.fileName]); got != want.String() {
+				t.Errorf("%s = %q, want the %s output %q", tc.fileName, got, tc.format, want.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// @merge.go:
+// This file implements the -merge mode, which combines two or more sessions picked from the session
+// list, such as a topic continued in a new chat, into one and writes the store back out as a backup
+// JSON file the web app can import, instead of exporting.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+const (
+	// FileTypeBackup is the file type of the backup JSON written by the -merge mode.
+	FileTypeBackup = "backup JSON"
+
+	PromptMergeSessions    = "Enter the numbers of the sessions to merge, such as 2,5,7: "
+	PromptMergeOrder       = "Order the messages:\n1) By timestamp, interleaving the sessions\n2) Manually, one session after the other in the order entered\n"
+	PromptMergeTopic       = "Enter the topic of the merged session (leave empty for %q): "
+	PromptMergeKeepSources = "Keep the original sessions in the output as well? (yes/no)\n"
+)
+
+// mergeSessions shows the sessions, asks which of them to merge, in which order, under which topic,
+// and whether to keep the originals, and offers to save the resulting store as a backup JSON file,
+// which keeps the fields of original, the JSON the sessions were read from, that the exporter does not model.
+// It returns the path of the saved file, or an empty string if nothing was saved.
+func mergeSessions(rfs filesystem.FileSystem, ctx context.Context, run *runState, reader *bufio.Reader, sessions []exporter.Session, original []byte, dateField exporter.DateField) (string, error) {
+	sessionsTable(sessions, dateField).Render(run, 0)
+
+	answer, err := promptForInput(ctx, run, reader, PromptMergeSessions)
+	if err != nil {
+		return "", err
+	}
+	indices, err := parseSessionNumbers(answer, len(sessions))
+	if err != nil {
+		fmt.Fprintf(run, "Invalid selection: %s\n", err)
+		return "", nil
+	}
+	selected := make([]exporter.Session, len(indices))
+	for i, index := range indices {
+		selected[i] = sessions[index]
+	}
+
+	var opts exporter.MergeOptions
+	answer, err = promptForInput(ctx, run, reader, PromptMergeOrder)
+	if err != nil {
+		return "", err
+	}
+	switch answer {
+	case "", "1":
+		opts.Order = exporter.MergeOrderTimestamp
+	case "2":
+		opts.Order = exporter.MergeOrderManual
+	default:
+		fmt.Fprintln(run, "Invalid order option.")
+		return "", nil
+	}
+	if opts.Topic, err = promptForInput(ctx, run, reader, fmt.Sprintf(PromptMergeTopic, selected[0].Topic)); err != nil {
+		return "", err
+	}
+	answer, err = promptForInput(ctx, run, reader, PromptMergeKeepSources)
+	if err != nil {
+		return "", err
+	}
+	keep := strings.EqualFold(answer, "yes")
+
+	merged, err := exporter.MergeSessions(selected, opts)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(run, "Merged %d session(s) into %s with %d message(s).\n", len(selected), merged, len(merged.Messages))
+
+	var store exporter.ChatNextWebStore
+	store.ChatNextWebStore.Sessions = replaceMergedSessions(sessions, indices, merged, keep)
+	var output bytes.Buffer
+	if err := exporter.WriteStoreJSON(&output, store, original); err != nil {
+		return "", err
+	}
+	return saveToFile(rfs, ctx, run, reader, output.String(), FileTypeBackup)
+}
+
+// parseSessionNumbers parses the 1-based session numbers entered by the user, separated by commas or
+// white space, into 0-based indices in the order entered. It returns an error unless at least two
+// distinct numbers between 1 and count are given.
+func parseSessionNumbers(input string, count int) ([]int, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	seen := make(map[int]bool, len(fields))
+	indices := make([]int, 0, len(fields))
+	for _, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 1 || number > count {
+			return nil, fmt.Errorf("%q is not a session number between 1 and %d", field, count)
+		}
+		if seen[number] {
+			return nil, fmt.Errorf("session %d is listed twice", number)
+		}
+		seen[number] = true
+		indices = append(indices, number-1)
+	}
+	if len(indices) < 2 {
+		return nil, fmt.Errorf("at least 2 sessions are needed")
+	}
+	return indices, nil
+}
+
+// replaceMergedSessions returns the sessions with the merged session at the position of the earliest
+// of the merged sessions. The merged sessions themselves are removed unless keep is set.
+func replaceMergedSessions(sessions []exporter.Session, indices []int, merged exporter.Session, keep bool) []exporter.Session {
+	mergedIndex := make(map[int]bool, len(indices))
+	first := indices[0]
+	for _, index := range indices {
+		mergedIndex[index] = true
+		first = min(first, index)
+	}
+	result := make([]exporter.Session, 0, len(sessions)+1)
+	for i, session := range sessions {
+		if i == first {
+			result = append(result, merged)
+		}
+		if keep || !mergedIndex[i] {
+			result = append(result, session)
+		}
+	}
+	return result
+}
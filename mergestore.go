@@ -0,0 +1,102 @@
+// @mergestore.go:
+// This file implements -merge-store, which combines the input file with a backup of another device.
+// Sessions found in one file only or continued on one side only are merged automatically, and the
+// sessions that diverged in both files are resolved by -merge-conflicts or by asking the user.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// PromptResolveConflict asks which version of a session that diverged in both files to keep.
+const PromptResolveConflict = "Keep which version? (l = left, r = right, b = both): "
+
+// conflictPreviewMessages is the number of last messages shown of each version of a conflict.
+const conflictPreviewMessages = 3
+
+// mergeStores merges the backup given by -merge-store into the input file at jsonFilePath and saves the
+// merged store as a backup JSON file: with -tempout to a new temporary file, otherwise to a file named
+// by the user. It returns the path of the saved file, or an empty string if nothing was saved.
+func mergeStores(ctx context.Context, rfs filesystem.FileSystem, run *runState, reader *bufio.Reader, jsonFilePath string, opts cliOptions) (string, error) {
+	left, leftData, err := loadStoreData(ctx, rfs, jsonFilePath, opts.InputFormat, opts.ReadRetry)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", jsonFilePath, err)
+	}
+	right, rightData, err := loadStoreData(ctx, rfs, opts.MergeStore, opts.InputFormat, opts.ReadRetry)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", opts.MergeStore, err)
+	}
+
+	mergeOptions := exporter.StoreMergeOptions{
+		Policy: opts.MergeConflicts,
+		Resolve: func(conflict exporter.StoreConflict) (exporter.ConflictChoice, error) {
+			return askConflictChoice(ctx, run, reader, conflict, jsonFilePath, opts.MergeStore)
+		},
+	}
+	merged, report, err := exporter.MergeStores(&left, &right, mergeOptions)
+	if err != nil {
+		return "", err
+	}
+	printStoreMergeReport(run, report, jsonFilePath, opts.MergeStore, len(merged.ChatNextWebStore.Sessions))
+
+	var output bytes.Buffer
+	if err := exporter.WriteStoreJSON(&output, merged, leftData, rightData); err != nil {
+		return "", err
+	}
+	if opts.TempOut {
+		return writeTempFile("", FileTypeBackup, output.Bytes())
+	}
+	return saveToFile(rfs, ctx, run, reader, output.String(), FileTypeBackup)
+}
+
+// askConflictChoice shows both versions of a conflict, with their topic, message count, update time,
+// and last messages, and asks the user which to keep until a valid answer is given.
+func askConflictChoice(ctx context.Context, w io.Writer, reader *bufio.Reader, conflict exporter.StoreConflict, leftPath, rightPath string) (exporter.ConflictChoice, error) {
+	fmt.Fprintf(w, "\nSession %s diverged in both files:\n", conflict.Left.ID)
+	for _, version := range []struct {
+		name, path string
+		session    exporter.Session
+	}{{"Left", leftPath, conflict.Left}, {"Right", rightPath, conflict.Right}} {
+		session := version.session
+		fmt.Fprintf(w, "%s (%s): %s, %d message(s), updated %s\n", version.name, version.path, diffTopic(session),
+			len(session.Messages), formatSessionDate(session.Timestamp(exporter.DateFieldUpdated)))
+		for _, message := range session.Messages[max(len(session.Messages)-conflictPreviewMessages, 0):] {
+			fmt.Fprintf(w, "  %s: %s\n", message.Role, previewSnippet(message.Content))
+		}
+	}
+
+	for {
+		answer, err := promptForInput(ctx, w, reader, PromptResolveConflict)
+		if err != nil {
+			return exporter.KeepLeft, err
+		}
+		switch strings.ToLower(answer) {
+		case "l", "left":
+			return exporter.KeepLeft, nil
+		case "r", "right":
+			return exporter.KeepRight, nil
+		case "b", "both":
+			return exporter.KeepBoth, nil
+		}
+		fmt.Fprintln(w, "Please answer l, r, or b.")
+	}
+}
+
+// printStoreMergeReport reports how the sessions of the two files were merged.
+func printStoreMergeReport(run *runState, report exporter.StoreMergeReport, leftPath, rightPath string, sessions int) {
+	text := fmt.Sprintf("Merged %s into %s: %d session(s) in total, %d unchanged, %d only in %s, %d only in %s, %d continued on one side.",
+		rightPath, leftPath, sessions, report.Unchanged, report.LeftOnly, leftPath, report.RightOnly, rightPath, report.FastForwarded)
+	if report.Conflicts() > 0 {
+		text += fmt.Sprintf(" Of %d diverged session(s), kept %d left, %d right, and %d both.", report.Conflicts(), report.KeptLeft, report.KeptRight, report.KeptBoth)
+	}
+	run.logDiagnostic(slog.LevelInfo, text, "report", report)
+}
@@ -0,0 +1,82 @@
+// @paths.go:
+// This file implements the paths command, which prints the directories the application resolves for
+// its configuration, cache, and state, to debug where persisted data is looked for, and the default
+// locations of the files kept in them.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/appdirs"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/tablecli"
+)
+
+// pathsCommand is the first argument that runs the paths command instead of the usual export flow.
+const pathsCommand = "paths"
+
+// autoPath is the value of -incremental and -id-map that keeps the file in the state directory, one
+// per input file.
+const autoPath = "auto"
+
+// defaultTagsPath returns the path of the tags file in the state directory resolved from the
+// environment read with getenv. A single tags file serves every input, as session IDs are unique
+// across stores.
+func defaultTagsPath(getenv func(string) string) (string, error) {
+	dirs, err := appdirs.Resolve(getenv)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dirs.State, "tags.json"), nil
+}
+
+// inputStatePath returns the path of the file with extension ext that is kept for the input file
+// inputPath in the subdirectory kind of the state directory. The file is named after the input file
+// and a hash of its absolute path, so that inputs with the same name in different directories do not
+// share it.
+func inputStatePath(getenv func(string) string, kind, inputPath, ext string) (string, error) {
+	dirs, err := appdirs.Resolve(getenv)
+	if err != nil {
+		return "", err
+	}
+	absolute, err := filepath.Abs(inputPath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(absolute))
+	base := filepath.Base(inputPath)
+	name := strings.TrimSuffix(base, filepath.Ext(base)) + "-" + hex.EncodeToString(sum[:4]) + ext
+	return filepath.Join(dirs.State, kind, name), nil
+}
+
+// resolveAutoPath returns path, or the path inputStatePath keeps for inputPath when path is autoPath.
+func resolveAutoPath(getenv func(string) string, path, kind, inputPath, ext string) (string, error) {
+	if path != autoPath {
+		return path, nil
+	}
+	return inputStatePath(getenv, kind, inputPath, ext)
+}
+
+// printPaths prints the directories resolved by appdirs.Resolve from the environment read with getenv.
+// The directories are not created.
+func printPaths(w io.Writer, getenv func(string) string) error {
+	dirs, err := appdirs.Resolve(getenv)
+	if err != nil {
+		return err
+	}
+	table := &tablecli.Table{Headers: []string{"Directory", "Path"}, FlexColumn: -1}
+	table.AddRow("config", dirs.Config)
+	table.AddRow("cache", dirs.Cache)
+	table.AddRow("state", dirs.State)
+	if err := table.Render(w, 0); err != nil {
+		return err
+	}
+	if home := getenv(appdirs.EnvHome); home != "" {
+		fmt.Fprintf(w, "\nAll directories are below %s, set by %s.\n", home, appdirs.EnvHome)
+	}
+	return nil
+}
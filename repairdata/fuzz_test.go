@@ -0,0 +1,39 @@
+package repairdata_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+)
+
+// maxSeedSize is the size of the largest corpus file that seeds the fuzzer. The fuzzer mutates small
+// inputs far more effectively, and the larger files are repaired by TestRepairCorpus instead.
+const maxSeedSize = 16 << 10
+
+// FuzzRepairSessionData verifies that RepairSessionData never panics and that whatever it returns
+// without an error is valid JSON. The files of testdata/corpus up to maxSeedSize seed the fuzzer; run
+// it with
+//
+//	go test ./repairdata -run '^$' -fuzz FuzzRepairSessionData
+func FuzzRepairSessionData(f *testing.F) {
+	for _, file := range loadCorpus(f) {
+		if len(file.data) <= maxSeedSize {
+			f.Add(file.data)
+		}
+	}
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`{"chat-next-web-store":{"sessions":[]}}`))
+	f.Add([]byte(`{"chat-next-web-store":{"sessions":[{"mask":{"id":1}}]}}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		repaired, err := repairdata.RepairSessionData(data)
+		if err != nil || repaired == nil {
+			return
+		}
+		var parsed map[string]interface{}
+		if err := json.Unmarshal(repaired, &parsed); err != nil {
+			t.Errorf("RepairSessionData(%q) returned unparsable JSON %q: %v", data, repaired, err)
+		}
+	})
+}
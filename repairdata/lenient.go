@@ -0,0 +1,82 @@
+package repairdata
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ArtifactReport counts the non-standard JSON artifacts removed by StripJSONArtifacts.
+type ArtifactReport struct {
+	TrailingCommas int `json:"trailingCommas"` // TrailingCommas is the number of commas removed before a closing bracket or brace.
+	LineComments   int `json:"lineComments"`   // LineComments is the number of // comments removed.
+	BlockComments  int `json:"blockComments"`  // BlockComments is the number of /* */ comments removed.
+}
+
+// Total returns the total number of artifacts removed.
+func (r ArtifactReport) Total() int {
+	return r.TrailingCommas + r.LineComments + r.BlockComments
+}
+
+// StripJSONArtifacts removes the JSON5-style artifacts that hand-edited files tend to contain,
+// namely trailing commas and line (//) or block (/* */) comments, so that strict JSON decoding succeeds.
+//
+// Text inside string literals is never touched. Comments are replaced by nothing, except that the
+// newline ending a line comment is kept, so line numbers of later errors stay meaningful.
+//
+// It returns an error if a block comment or a string literal is not terminated.
+func StripJSONArtifacts(data []byte) ([]byte, ArtifactReport, error) {
+	var report ArtifactReport
+	out := make([]byte, 0, len(data))
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"':
+			end, err := stringEnd(data, i)
+			if err != nil {
+				return nil, report, err
+			}
+			out = append(out, data[i:end]...)
+			i = end - 1
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			report.LineComments++
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, report, fmt.Errorf("unterminated block comment at offset %d", i)
+			}
+			report.BlockComments++
+			i += 2 + end + 1
+		case c == ']' || c == '}':
+			// Drop a comma that directly precedes the closing bracket, apart from whitespace.
+			trimmed := bytes.TrimRight(out, " \t\r\n")
+			if len(trimmed) > 0 && trimmed[len(trimmed)-1] == ',' {
+				report.TrailingCommas++
+				out = append(trimmed[:len(trimmed)-1], out[len(trimmed):]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, report, nil
+}
+
+// stringEnd returns the offset just past the JSON string literal starting at data[start].
+func stringEnd(data []byte, start int) (int, error) {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string starting at offset %d", start)
+}
@@ -0,0 +1,113 @@
+package repairdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// RepairChange describes a single value of the session data replaced by a semantic repair.
+type RepairChange struct {
+	Path string `json:"path"` // Path locates the value, such as "chat-next-web-store.sessions[2].messages[0].content".
+	From string `json:"from"` // From is the original JSON value.
+	To   string `json:"to"`   // To is the JSON value it was replaced with.
+}
+
+// nullSchema lists the fields of a JSON object that replaceNullStrings checks.
+type nullSchema struct {
+	strings  []string               // strings lists the fields that must hold a string.
+	children map[string]*nullSchema // children maps the fields holding an object, or an array of objects, to their schema.
+}
+
+// messageNullSchema describes a message of a session or of the context of a mask.
+var messageNullSchema = &nullSchema{strings: []string{"id", "date", "role", "content"}}
+
+// storeNullSchema describes the whole document, down to the string fields modeled by the structs of this package.
+var storeNullSchema = &nullSchema{children: map[string]*nullSchema{
+	"chat-next-web-store": {children: map[string]*nullSchema{
+		"sessions": {
+			strings: []string{"id", "topic", "memoryPrompt"},
+			children: map[string]*nullSchema{
+				"messages": messageNullSchema,
+				"mask": {
+					strings: []string{"avatar", "name", "lang"},
+					children: map[string]*nullSchema{
+						"context":     messageNullSchema,
+						"modelConfig": {strings: []string{"model", "quality", "size", "style", "system_fingerprint", "template"}},
+					},
+				},
+			},
+		},
+	}},
+}}
+
+// replaceNullStrings replaces null in the fields of the session data that must hold a string with an
+// empty string, such as {"content": null} in a message, and returns a change for each replacement.
+// Keys keep their order, and the changes are in document order. The data is returned as it is if nothing was replaced.
+func replaceNullStrings(data []byte) ([]byte, []RepairChange, error) {
+	var changes []RepairChange
+	replaced, err := replaceNullsIn(data, "", storeNullSchema, &changes)
+	if err != nil || len(changes) == 0 {
+		return data, nil, err
+	}
+	return replaced, changes, nil
+}
+
+// replaceNullsIn applies replaceNullStrings to the value at path, an object or an array of objects
+// described by schema. Other values are returned as they are.
+func replaceNullsIn(value json.RawMessage, path string, schema *nullSchema, changes *[]RepairChange) (json.RawMessage, error) {
+	switch jsonKind(value) {
+	case '[':
+		var elements []json.RawMessage
+		if err := json.Unmarshal(value, &elements); err != nil {
+			return nil, err
+		}
+		before := len(*changes)
+		for i, element := range elements {
+			replaced, err := replaceNullsIn(element, fmt.Sprintf("%s[%d]", path, i), schema, changes)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = replaced
+		}
+		if len(*changes) == before {
+			return value, nil
+		}
+		return json.Marshal(elements)
+	case '{':
+		var object orderedObject
+		if err := json.Unmarshal(value, &object); err != nil {
+			return nil, err
+		}
+		before := len(*changes)
+		for i, field := range object {
+			path := fieldPath(path, field.Key)
+			if slices.Contains(schema.strings, field.Key) && isNull(field.Value) {
+				object[i].Value = json.RawMessage(`""`)
+				*changes = append(*changes, RepairChange{Path: path, From: "null", To: `""`})
+				continue
+			}
+			if child, ok := schema.children[field.Key]; ok {
+				replaced, err := replaceNullsIn(field.Value, path, child, changes)
+				if err != nil {
+					return nil, err
+				}
+				object[i].Value = replaced
+			}
+		}
+		if len(*changes) == before {
+			return value, nil
+		}
+		return json.Marshal(object)
+	default:
+		return value, nil
+	}
+}
+
+// fieldPath returns the path of the field key of the object at path.
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
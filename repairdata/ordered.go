@@ -0,0 +1,206 @@
+package repairdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// orderedField is a single key/value pair of a JSON object whose value is kept undecoded.
+type orderedField struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// orderedObject is a JSON object that remembers the order of its keys, so that it can be
+// re-encoded without the reordering a round trip through a struct or map would cause.
+// Values are kept as raw JSON, which also preserves fields the repair does not know about.
+type orderedObject []orderedField
+
+// UnmarshalJSON decodes a JSON object, keeping its keys in their original order.
+func (o *orderedObject) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a JSON object, got %v", token)
+	}
+
+	fields := orderedObject{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("expected an object key, got %v", token)
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		fields = append(fields, orderedField{Key: key, Value: value})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	*o = fields
+	return nil
+}
+
+// MarshalJSON encodes the object with its keys in their stored order.
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(field.Value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Get returns the raw value stored under key, and whether the key is present.
+func (o orderedObject) Get(key string) (json.RawMessage, bool) {
+	for _, field := range o {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set replaces the value stored under key, or appends the key if it is not present yet.
+func (o *orderedObject) Set(key string, value json.RawMessage) {
+	for i, field := range *o {
+		if field.Key == key {
+			(*o)[i].Value = value
+			return
+		}
+	}
+	*o = append(*o, orderedField{Key: key, Value: value})
+}
+
+// isNull reports whether a raw JSON value is missing or the literal null.
+func isNull(value json.RawMessage) bool {
+	return len(value) == 0 || string(bytes.TrimSpace(value)) == "null"
+}
+
+// mergeUnknownFields adds every field of original that is missing from repaired back into repaired,
+// recursing into objects present in both and into arrays element by element. Fields that repaired
+// already holds keep their repaired value, and restored fields are appended after them.
+//
+// This keeps a repair through the typed structs of this package from discarding data they do not model.
+func mergeUnknownFields(repaired, original json.RawMessage) (json.RawMessage, error) {
+	switch {
+	case jsonKind(repaired) == '{' && jsonKind(original) == '{':
+		var repairedObject, originalObject orderedObject
+		if err := json.Unmarshal(repaired, &repairedObject); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(original, &originalObject); err != nil {
+			return nil, err
+		}
+		for _, field := range originalObject {
+			value, ok := repairedObject.Get(field.Key)
+			if !ok {
+				repairedObject.Set(field.Key, field.Value)
+				continue
+			}
+			merged, err := mergeUnknownFields(value, field.Value)
+			if err != nil {
+				return nil, err
+			}
+			repairedObject.Set(field.Key, merged)
+		}
+		return json.Marshal(repairedObject)
+	case jsonKind(repaired) == '[' && jsonKind(original) == '[':
+		var repairedArray, originalArray []json.RawMessage
+		if err := json.Unmarshal(repaired, &repairedArray); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(original, &originalArray); err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(repairedArray) && i < len(originalArray); i++ {
+			merged, err := mergeUnknownFields(repairedArray[i], originalArray[i])
+			if err != nil {
+				return nil, err
+			}
+			repairedArray[i] = merged
+		}
+		return json.Marshal(repairedArray)
+	default:
+		return repaired, nil
+	}
+}
+
+// sortKeys returns value with the keys of every object sorted, recursing into nested objects and
+// arrays. The sort is stable, so duplicate keys keep their relative order.
+func sortKeys(value json.RawMessage) (json.RawMessage, error) {
+	switch jsonKind(value) {
+	case '{':
+		var object orderedObject
+		if err := json.Unmarshal(value, &object); err != nil {
+			return nil, err
+		}
+		for i, field := range object {
+			sorted, err := sortKeys(field.Value)
+			if err != nil {
+				return nil, err
+			}
+			object[i].Value = sorted
+		}
+		sort.SliceStable(object, func(i, j int) bool { return object[i].Key < object[j].Key })
+		return json.Marshal(object)
+	case '[':
+		var array []json.RawMessage
+		if err := json.Unmarshal(value, &array); err != nil {
+			return nil, err
+		}
+		for i, element := range array {
+			sorted, err := sortKeys(element)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = sorted
+		}
+		return json.Marshal(array)
+	default:
+		return value, nil
+	}
+}
+
+// sortedIndented returns data with the keys of every object sorted, indented like the repaired data.
+func sortedIndented(data []byte) ([]byte, error) {
+	sorted, err := sortKeys(data)
+	if err != nil {
+		return nil, err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, sorted, "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+// jsonKind returns the first non-whitespace byte of a raw JSON value, which identifies its kind.
+func jsonKind(value json.RawMessage) byte {
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) == 0 {
+		return 0
+	}
+	return trimmed[0]
+}
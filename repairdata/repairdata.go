@@ -1,11 +1,13 @@
 // Package repairdata provides utilities for transforming JSON data from an old format to a new format.
 //
 // It specifically ensures that each session's modelConfig contains a 'systemprompt' field.
+// Optionally it also recovers "almost valid" hand-edited files by stripping trailing commas and comments.
 //
 // Copyright (c) 2023 H0llyW00dzZ
 package repairdata
 
 import (
+	"bytes"
 	"encoding/json"
 	"strconv"
 	"time"
@@ -130,10 +132,101 @@ type Session struct {
 	Mask               *Mask     `json:"mask"`
 }
 
+// defaultSystemPrompt is the system prompt added to a modelConfig that lacks one.
+const defaultSystemPrompt = "\nYou are ChatGPT, a large language model trained by OpenAI.\nKnowledge cutoff: {{cutoff}}\nCurrent model: {{model}}\nCurrent time: {{time}}\nLatex inline: $x^2$ \nLatex block: $$e=mc^2$$\n"
+
+// RepairOptions controls how RepairSessionDataWithOptions repairs session data.
+//
+// DefaultRepairOptions returns the options RepairSessionData uses.
+type RepairOptions struct {
+	// PreserveFieldOrder makes targeted fixes without re-serializing the data through typed structs,
+	// so the original ordering of object keys is kept. This matters for systems sensitive to key order.
+	PreserveFieldOrder bool
+
+	// StripArtifacts removes trailing commas and // or /* */ comments before decoding, which recovers
+	// hand-edited files that strict JSON decoding rejects; see StripJSONArtifacts.
+	StripArtifacts bool
+
+	// NullToEmpty replaces null in fields that must hold a string, such as {"content": null} in a message,
+	// with an empty string, and reports each replacement as a RepairChange. Without it, PreserveFieldOrder
+	// keeps such nulls, while the structured repair still cannot write them back as null.
+	NullToEmpty bool
+
+	// DeterministicOutput sorts the keys of every JSON object of the repaired data, so that the same
+	// input always yields byte-identical output and repaired files diff cleanly. Without it, known
+	// fields come out in the order of the typed structs, followed by the fields they do not model.
+	// It has no effect with PreserveFieldOrder, which keeps the original order instead.
+	DeterministicOutput bool
+}
+
+// DefaultRepairOptions returns the options RepairSessionData uses: null strings are replaced, the data
+// is re-serialized through the typed structs of this package, and object keys are sorted.
+func DefaultRepairOptions() RepairOptions {
+	return RepairOptions{NullToEmpty: true, DeterministicOutput: true}
+}
+
+// RepairReport describes what RepairSessionDataWithReport changed besides the format upgrade.
+type RepairReport struct {
+	Artifacts ArtifactReport // Artifacts counts the non-standard JSON artifacts removed when StripArtifacts is set.
+	Changes   []RepairChange // Changes lists the values replaced when NullToEmpty is set, in document order.
+}
+
 // RepairSessionData transforms JSON data from the old format to the new format.
 //
-// It adds a 'systemprompt' field to the 'modelConfig' within each session if it is missing.
+// It adds a 'systemprompt' field to the 'modelConfig' within each session if it is missing,
+// and replaces null in fields that must hold a string with an empty string.
+// The keys of every object are sorted, so the same input always yields the same output.
+// The repair is non-destructive: fields this package does not model are carried over unchanged.
 func RepairSessionData(oldDataBytes []byte) ([]byte, error) {
+	return RepairSessionDataWithOptions(oldDataBytes, DefaultRepairOptions())
+}
+
+// RepairSessionDataWithOptions transforms JSON data from the old format to the new format according to the options.
+//
+// It adds a 'systemprompt' field to the 'modelConfig' within each session if it is missing.
+func RepairSessionDataWithOptions(oldDataBytes []byte, opts RepairOptions) ([]byte, error) {
+	repaired, _, err := RepairSessionDataWithReport(oldDataBytes, opts)
+	return repaired, err
+}
+
+// RepairSessionDataWithReport behaves like RepairSessionDataWithOptions, and additionally reports
+// the artifacts that were removed from the input and the values that were replaced.
+func RepairSessionDataWithReport(oldDataBytes []byte, opts RepairOptions) ([]byte, RepairReport, error) {
+	var report RepairReport
+	if opts.StripArtifacts {
+		var err error
+		if oldDataBytes, report.Artifacts, err = StripJSONArtifacts(oldDataBytes); err != nil {
+			return nil, report, err
+		}
+	}
+	if opts.NullToEmpty {
+		var err error
+		if oldDataBytes, report.Changes, err = replaceNullStrings(oldDataBytes); err != nil {
+			return nil, report, err
+		}
+	}
+
+	var repaired []byte
+	var err error
+	switch {
+	case opts.PreserveFieldOrder:
+		repaired, err = repairPreservingOrder(oldDataBytes)
+	case opts.DeterministicOutput:
+		if repaired, err = repairStructured(oldDataBytes); err == nil {
+			repaired, err = sortedIndented(repaired)
+		}
+	default:
+		repaired, err = repairStructured(oldDataBytes)
+	}
+	if err != nil {
+		return nil, report, err
+	}
+	return repaired, report, nil
+}
+
+// repairStructured repairs the data by decoding it into the typed structs of this package and encoding it again.
+// Known fields come out in the order of the structs, followed by any fields the structs do not model.
+func repairStructured(oldDataBytes []byte) ([]byte, error) {
 	var oldData OldData
 	err := json.Unmarshal(oldDataBytes, &oldData)
 	if err != nil {
@@ -149,18 +242,116 @@ func RepairSessionData(oldDataBytes []byte) ([]byte, error) {
 		// Check if the systemprompt field is missing and add it if necessary.
 		if session.Mask != nil && session.Mask.ModelConfig != nil && session.Mask.ModelConfig.SystemPrompt == nil {
 			newData.ChatNextWebStore.Sessions[i].Mask.ModelConfig.SystemPrompt = &SystemPrompt{
-				Default: "\nYou are ChatGPT, a large language model trained by OpenAI.\nKnowledge cutoff: {{cutoff}}\nCurrent model: {{model}}\nCurrent time: {{time}}\nLatex inline: $x^2$ \nLatex block: $$e=mc^2$$\n",
+				Default: defaultSystemPrompt,
 			}
 		}
 	}
 
 	// Marshal the new data into JSON bytes.
-	newDataBytes, err := json.MarshalIndent(newData, "", "  ")
+	newDataBytes, err := json.Marshal(newData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore the fields the typed structs do not model, so that repairing never loses data.
+	merged, err := mergeUnknownFields(newDataBytes, oldDataBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, merged, "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+// repairPreservingOrder applies the same repairs as RepairSessionData, but walks the document as
+// ordered objects holding raw values, so that key order and every unmodeled field survive the repair.
+func repairPreservingOrder(data []byte) ([]byte, error) {
+	var root orderedObject
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	storeRaw, ok := root.Get("chat-next-web-store")
+	if !ok || isNull(storeRaw) {
+		return json.MarshalIndent(root, "", "  ")
+	}
+	var store orderedObject
+	if err := json.Unmarshal(storeRaw, &store); err != nil {
+		return nil, err
+	}
+
+	sessionsRaw, ok := store.Get("sessions")
+	if ok && !isNull(sessionsRaw) {
+		var sessions []json.RawMessage
+		if err := json.Unmarshal(sessionsRaw, &sessions); err != nil {
+			return nil, err
+		}
+		for i, sessionRaw := range sessions {
+			repaired, err := repairSessionPreservingOrder(sessionRaw)
+			if err != nil {
+				return nil, err
+			}
+			sessions[i] = repaired
+		}
+		repairedSessions, err := json.Marshal(sessions)
+		if err != nil {
+			return nil, err
+		}
+		store.Set("sessions", repairedSessions)
+	}
+
+	storeRaw, err := json.Marshal(store)
 	if err != nil {
 		return nil, err
 	}
+	root.Set("chat-next-web-store", storeRaw)
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// repairSessionPreservingOrder adds the default system prompt to the modelConfig of a single
+// session's mask if it is missing, leaving everything else untouched.
+func repairSessionPreservingOrder(sessionRaw json.RawMessage) (json.RawMessage, error) {
+	var session orderedObject
+	if err := json.Unmarshal(sessionRaw, &session); err != nil {
+		return nil, err
+	}
+	maskRaw, ok := session.Get("mask")
+	if !ok || isNull(maskRaw) {
+		return sessionRaw, nil
+	}
+	var mask orderedObject
+	if err := json.Unmarshal(maskRaw, &mask); err != nil {
+		return nil, err
+	}
+	modelConfigRaw, ok := mask.Get("modelConfig")
+	if !ok || isNull(modelConfigRaw) {
+		return sessionRaw, nil
+	}
+	var modelConfig orderedObject
+	if err := json.Unmarshal(modelConfigRaw, &modelConfig); err != nil {
+		return nil, err
+	}
+	if systemPrompt, ok := modelConfig.Get("systemprompt"); ok && !isNull(systemPrompt) {
+		return sessionRaw, nil
+	}
 
-	return newDataBytes, nil
+	systemPrompt, err := json.Marshal(SystemPrompt{Default: defaultSystemPrompt})
+	if err != nil {
+		return nil, err
+	}
+	modelConfig.Set("systemprompt", systemPrompt)
+	if modelConfigRaw, err = json.Marshal(modelConfig); err != nil {
+		return nil, err
+	}
+	mask.Set("modelConfig", modelConfigRaw)
+	if maskRaw, err = json.Marshal(mask); err != nil {
+		return nil, err
+	}
+	session.Set("mask", maskRaw)
+	return json.Marshal(session)
 }
 
 // Helper function millisToTime converts Unix milliseconds to a time.Time object.
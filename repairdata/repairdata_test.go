@@ -0,0 +1,273 @@
+package repairdata_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+)
+
+// corpusOptions are the options the corpus is repaired with. Several files hold trailing commas and
+// comments, so the artifacts are stripped, as with the -strip-json-artifacts flag.
+var corpusOptions = repairdata.RepairOptions{StripArtifacts: true}
+
+// corpusFile is a broken session file of testdata/corpus.
+type corpusFile struct {
+	name string
+	data []byte
+}
+
+// loadCorpus reads every file of testdata/corpus, in name order.
+func loadCorpus(tb testing.TB) []corpusFile {
+	tb.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.json"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if len(paths) == 0 {
+		tb.Fatal("testdata/corpus holds no files")
+	}
+	corpus := make([]corpusFile, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		corpus = append(corpus, corpusFile{name: strings.TrimSuffix(filepath.Base(path), ".json"), data: data})
+	}
+	return corpus
+}
+
+// TestRepairCorpus verifies that every corpus file is repaired into valid JSON in both repair modes,
+// so that the benchmark measures successful repairs.
+func TestRepairCorpus(t *testing.T) {
+	for _, file := range loadCorpus(t) {
+		t.Run(file.name, func(t *testing.T) {
+			for _, preserveOrder := range []bool{false, true} {
+				opts := corpusOptions
+				opts.PreserveFieldOrder = preserveOrder
+				repaired, err := repairdata.RepairSessionDataWithOptions(file.data, opts)
+				if err != nil {
+					t.Fatalf("RepairSessionDataWithOptions(PreserveFieldOrder: %v) returned an error: %v", preserveOrder, err)
+				}
+				if !json.Valid(repaired) {
+					t.Errorf("RepairSessionDataWithOptions(PreserveFieldOrder: %v) returned invalid JSON", preserveOrder)
+				}
+			}
+		})
+	}
+}
+
+// TestRepairNullToEmpty verifies that null in fields that must hold a string becomes an empty string in
+// both repair modes, that every replacement is reported in document order, and that other nulls are kept.
+func TestRepairNullToEmpty(t *testing.T) {
+	input := []byte(`{"chat-next-web-store":{"sessions":[{"id":"s1","topic":null,"messages":[{"id":"m1","role":"user","content":null,"extra":null}],` +
+		`"mask":{"name":null,"context":[{"id":null,"content":"hi"}],"modelConfig":{"model":null}}}]}}`)
+	wantPaths := []string{
+		"chat-next-web-store.sessions[0].topic",
+		"chat-next-web-store.sessions[0].messages[0].content",
+		"chat-next-web-store.sessions[0].mask.name",
+		"chat-next-web-store.sessions[0].mask.context[0].id",
+		"chat-next-web-store.sessions[0].mask.modelConfig.model",
+	}
+
+	for _, preserveOrder := range []bool{false, true} {
+		opts := repairdata.DefaultRepairOptions()
+		opts.PreserveFieldOrder = preserveOrder
+		repaired, report, err := repairdata.RepairSessionDataWithReport(input, opts)
+		if err != nil {
+			t.Fatalf("RepairSessionDataWithReport(PreserveFieldOrder: %v) returned an error: %v", preserveOrder, err)
+		}
+		var paths []string
+		for _, change := range report.Changes {
+			paths = append(paths, change.Path)
+			if change.From != "null" || change.To != `""` {
+				t.Errorf("change %+v, want null replaced with \"\"", change)
+			}
+		}
+		if strings.Join(paths, ",") != strings.Join(wantPaths, ",") {
+			t.Errorf("PreserveFieldOrder %v reported %v, want %v", preserveOrder, paths, wantPaths)
+		}
+
+		var decoded struct {
+			Store struct {
+				Sessions []struct {
+					Topic    *string                      `json:"topic"`
+					Messages []map[string]json.RawMessage `json:"messages"`
+				} `json:"sessions"`
+			} `json:"chat-next-web-store"`
+		}
+		if err := json.Unmarshal(repaired, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		session := decoded.Store.Sessions[0]
+		if session.Topic == nil || *session.Topic != "" || string(session.Messages[0]["content"]) != `""` || string(session.Messages[0]["extra"]) != "null" {
+			t.Errorf("PreserveFieldOrder %v repaired the data to %s", preserveOrder, repaired)
+		}
+	}
+
+	_, report, err := repairdata.RepairSessionDataWithReport(input, repairdata.RepairOptions{PreserveFieldOrder: true})
+	if err != nil || len(report.Changes) != 0 {
+		t.Errorf("without NullToEmpty, RepairSessionDataWithReport() = %v, %v, want no changes", report.Changes, err)
+	}
+}
+
+// TestRepairDeterministicOutput verifies that repairing the same input many times yields byte-identical
+// output with the keys of every object sorted, and that without DeterministicOutput the known fields
+// keep the order of the typed structs.
+func TestRepairDeterministicOutput(t *testing.T) {
+	input := []byte(`{"chat-next-web-store":{"sessions":[{"id":"x","topic":"t","zeta":1,"alpha":{"b":[{"y":1,"x":2}],"a":null},` +
+		`"messages":[{"id":"m","role":"user","content":"hi","streaming":false}],` +
+		`"mask":{"id":1,"modelConfig":{"model":"gpt-4","temperature":0.5}}}],"currentSessionIndex":0},"access-control":{"token":"","accessCode":""}}`)
+
+	first, err := repairdata.RepairSessionData(input)
+	if err != nil {
+		t.Fatalf("RepairSessionData() returned an error: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		repaired, err := repairdata.RepairSessionData(input)
+		if err != nil {
+			t.Fatalf("RepairSessionData() returned an error on run %d: %v", i, err)
+		}
+		if string(repaired) != string(first) {
+			t.Fatalf("run %d returned different output:\n%s\nwant:\n%s", i, repaired, first)
+		}
+	}
+
+	compact := compactJSON(t, first)
+	for _, ordered := range [][]string{
+		{`"access-control"`, `"chat-next-web-store"`},
+		{`"accessCode"`, `"token"`},
+		{`"alpha"`, `"id":"x"`, `"lastUpdate"`, `"mask"`, `"messages"`, `"topic"`, `"zeta"`},
+		{`"x":2`, `"y":1`},
+		{`"content"`, `"id":"m"`, `"role"`, `"streaming"`},
+	} {
+		for i := 1; i < len(ordered); i++ {
+			if strings.Index(compact, ordered[i-1]) > strings.Index(compact, ordered[i]) {
+				t.Errorf("%s comes after %s in the repaired data:\n%s", ordered[i-1], ordered[i], compact)
+			}
+		}
+	}
+
+	opts := repairdata.DefaultRepairOptions()
+	opts.DeterministicOutput = false
+	unsorted, err := repairdata.RepairSessionDataWithOptions(input, opts)
+	if err != nil {
+		t.Fatalf("RepairSessionDataWithOptions() returned an error: %v", err)
+	}
+	if compact := compactJSON(t, unsorted); !strings.Contains(compact, `{"id":"m","date":"","role":"user","content":"hi","streaming":false}`) {
+		t.Errorf("without DeterministicOutput, the message fields are not in struct order:\n%s", compact)
+	}
+}
+
+// compactJSON returns data without insignificant white space.
+func compactJSON(t *testing.T, data []byte) string {
+	t.Helper()
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		t.Fatalf("repaired data is not valid JSON: %v", err)
+	}
+	return compact.String()
+}
+
+// BenchmarkRepairSessionData measures the repair of each corpus file. Run it with
+// go test -bench=. -benchmem ./repairdata to compare against a previous baseline.
+func BenchmarkRepairSessionData(b *testing.B) {
+	for _, file := range loadCorpus(b) {
+		b.Run(file.name, func(b *testing.B) {
+			b.SetBytes(int64(len(file.data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := repairdata.RepairSessionDataWithOptions(file.data, corpusOptions); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestRepairPreserveFieldOrder verifies that repairing with PreserveFieldOrder keeps the original
+// key order, including keys the repair does not model, while still adding the system prompt.
+func TestRepairPreserveFieldOrder(t *testing.T) {
+	input := []byte(`{"zeta":1,"chat-next-web-store":{"sessions":[{"topic":"t","id":"x","mask":{"modelConfig":{"template":"{{input}}","model":"gpt-4"},"id":1}}],"lastUpdateTime":0},"alpha":true}`)
+
+	repaired, err := repairdata.RepairSessionDataWithOptions(input, repairdata.RepairOptions{PreserveFieldOrder: true})
+	if err != nil {
+		t.Fatalf("RepairSessionDataWithOptions() returned an error: %v", err)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, repaired); err != nil {
+		t.Fatalf("repaired data is not valid JSON: %v", err)
+	}
+	output := compact.String()
+	order := []string{`"zeta"`, `"chat-next-web-store"`, `"topic"`, `"id":"x"`, `"template"`, `"model"`, `"systemprompt"`, `"id":1`, `"lastUpdateTime"`, `"alpha"`}
+	last := -1
+	for _, key := range order {
+		index := strings.Index(output, key)
+		if index <= last {
+			t.Fatalf("key %s is missing or out of order in %s", key, output)
+		}
+		last = index
+	}
+}
+
+// TestRepairStripArtifacts verifies that trailing commas and comments are removed and counted,
+// that string contents which look like artifacts are left alone, and that strict repair still rejects them.
+func TestRepairStripArtifacts(t *testing.T) {
+	input := []byte(`{
+	// exported by hand
+	"chat-next-web-store": {
+		"sessions": [
+			{"id": "a", "topic": "URLs like https://x/* stay, and so does ,]", "messages": [],}, /* trailing */
+		],
+	},
+}`)
+
+	if _, err := repairdata.RepairSessionData(input); err == nil {
+		t.Fatal("strict repair should reject trailing commas and comments")
+	}
+
+	repaired, report, err := repairdata.RepairSessionDataWithReport(input, repairdata.RepairOptions{StripArtifacts: true, PreserveFieldOrder: true})
+	if err != nil {
+		t.Fatalf("RepairSessionDataWithReport() returned an error: %v", err)
+	}
+	expected := repairdata.ArtifactReport{TrailingCommas: 4, LineComments: 1, BlockComments: 1}
+	if report.Artifacts != expected {
+		t.Errorf("report = %+v, want %+v", report.Artifacts, expected)
+	}
+	if !strings.Contains(string(repaired), `"URLs like https://x/* stay, and so does ,]"`) {
+		t.Errorf("string contents were modified:\n%s", repaired)
+	}
+
+	if _, _, err := repairdata.StripJSONArtifacts([]byte(`{"a": 1 /* open`)); err == nil {
+		t.Error("an unterminated block comment should be reported")
+	}
+}
+
+// TestRepairKeepsUnknownFields verifies that the default repair carries over fields the typed
+// structs do not model, at every level of the document, instead of silently dropping them.
+func TestRepairKeepsUnknownFields(t *testing.T) {
+	input := []byte(`{"chat-next-web-store":{"sessions":[{"id":"x","topic":"t","customField":{"nested":[1,2]},` +
+		`"messages":[{"id":"m","role":"user","content":"hi","streaming":false}],` +
+		`"mask":{"id":1,"modelConfig":{"model":"gpt-4","temperature":0.5}}}],"currentSessionIndex":0},"access-control":{"accessCode":""}}`)
+
+	repaired, err := repairdata.RepairSessionData(input)
+	if err != nil {
+		t.Fatalf("RepairSessionData() returned an error: %v", err)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, repaired); err != nil {
+		t.Fatalf("repaired data is not valid JSON: %v", err)
+	}
+	for _, field := range []string{`"customField":{"nested":[1,2]}`, `"streaming":false`, `"temperature":0.5`, `"currentSessionIndex":0`, `"access-control":{"accessCode":""}`, `"systemprompt"`} {
+		if !strings.Contains(compact.String(), field) {
+			t.Errorf("repaired data is missing %s:\n%s", field, compact.String())
+		}
+	}
+}
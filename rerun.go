@@ -0,0 +1,113 @@
+// @rerun.go:
+// This file implements -rerun-on-hup for long-lived interactive sessions: the answers given to the
+// prompts of an export are recorded, and every SIGHUP replays the export with the same answers,
+// reading the input file again so that changes to it are picked up.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+)
+
+// waitingForHangupMessage is shown while -rerun-on-hup waits for the next SIGHUP.
+const waitingForHangupMessage = "Waiting for SIGHUP to re-run the export; press Ctrl+C to exit."
+
+// errReplayExhausted is returned by promptForInput when a replayed export asks a question its recording
+// holds no answer for, for example because the input file now holds different sessions.
+var errReplayExhausted = errors.New("the re-run asked a question the recorded export did not answer")
+
+// exportRecording holds what is needed to replay an export: the answers promptForInput returned, in
+// order, and the files the export wrote, which its replays overwrite without asking. It travels with
+// the context of the export, see withRecording, so that prompts deep down the export can reach it.
+type exportRecording struct {
+	answers []string        // answers holds the recorded answers.
+	files   map[string]bool // files holds the names of the files written by the recorded export.
+	replay  bool            // replay makes promptForInput return the recorded answers instead of reading input.
+	next    int             // next is the index of the answer a replay returns next.
+}
+
+// recordingKey is the context key of the exportRecording of an export.
+type recordingKey struct{}
+
+// withRecording returns a copy of ctx carrying recording, which promptForInput records into, or replays
+// from when recording.replay is set.
+func withRecording(ctx context.Context, recording *exportRecording) context.Context {
+	return context.WithValue(ctx, recordingKey{}, recording)
+}
+
+// recordingFrom returns the exportRecording carried by ctx, or nil if the export is not recorded.
+func recordingFrom(ctx context.Context) *exportRecording {
+	recording, _ := ctx.Value(recordingKey{}).(*exportRecording)
+	return recording
+}
+
+// record adds an answer read by promptForInput, unless the recording is being replayed.
+func (r *exportRecording) record(answer string) {
+	if !r.replay {
+		r.answers = append(r.answers, answer)
+	}
+}
+
+// recordFiles adds the names of files written by the export.
+func (r *exportRecording) recordFiles(names []string) {
+	if r.files == nil {
+		r.files = make(map[string]bool, len(names))
+	}
+	for _, name := range names {
+		r.files[name] = true
+	}
+}
+
+// nextAnswer returns the next recorded answer of a replay. It returns errReplayExhausted when all of
+// them were returned.
+func (r *exportRecording) nextAnswer() (string, error) {
+	if r.next == len(r.answers) {
+		return "", errReplayExhausted
+	}
+	answer := r.answers[r.next]
+	r.next++
+	return answer, nil
+}
+
+// replayed returns a new replay of the recording, starting with its first answer.
+func (r *exportRecording) replayed() *exportRecording {
+	return &exportRecording{answers: r.answers, files: r.files, replay: true}
+}
+
+// confirmOverwrite asks whether the existing file fileName may be overwritten, like
+// interactivity.ConfirmOverwrite. A replayed export overwrites the files its recording wrote without
+// asking, since replacing them is what the re-run is for; any other file is still confirmed.
+func confirmOverwrite(rfs filesystem.FileSystem, ctx context.Context, w io.Writer, reader *bufio.Reader, fileName string) (bool, error) {
+	if recording := recordingFrom(ctx); recording != nil && recording.replay && recording.files[fileName] {
+		return true, nil
+	}
+	return interactivity.ConfirmOverwrite(rfs, ctx, reader, interactivity.NewPrinter(w), fileName)
+}
+
+// rerunOnHangup replays the export of recording every time a hangup is received on hangups, until ctx is
+// cancelled. Each replay gets a context carrying a new replay of the recording, and a reader without
+// input, so that a question the recording does not answer fails the replay instead of being guessed.
+// The error of a failed replay is logged to run, and the next hangup replays the export again.
+func rerunOnHangup(ctx context.Context, run *runState, hangups <-chan struct{}, recording *exportRecording, export func(ctx context.Context, reader *bufio.Reader) error) {
+	for {
+		run.logDiagnostic(slog.LevelInfo, waitingForHangupMessage)
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+		}
+
+		run.logDiagnostic(slog.LevelInfo, "SIGHUP received; re-running the last export with the same answers.")
+		if err := export(withRecording(ctx, recording.replayed()), bufio.NewReader(strings.NewReader(""))); err != nil {
+			run.logDiagnostic(slog.LevelError, fmt.Sprintf("The re-run failed: %s", err), "error", err.Error())
+		}
+	}
+}
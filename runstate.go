@@ -0,0 +1,44 @@
+// @runstate.go:
+// This file holds the state a run of the CLI tool shares between its helpers besides the command-line
+// options: where the messages for the user go, the structured logger, and the run summary. It is
+// created once in main and passed down, so that no helper depends on package-level variables.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+)
+
+// runState is the state of a run that changes while it runs or depends on how the run was started.
+// It is an io.Writer writing to out, so that helpers can print their messages to it directly.
+type runState struct {
+	out     io.Writer        // out receives the messages for the user, including the prompts.
+	banner  bannercli.Config // banner is the -banner-style and -banner-delay setting the status messages follow.
+	logger  *slog.Logger     // logger receives the diagnostics when -log-format json is set. It is nil in text mode.
+	summary *runSummary      // summary collects what happened during the run. It is nil unless -json-output is set.
+	errors  int              // errors counts the errors shown to the user through printError.
+}
+
+// newRunState returns the state of a run writing its messages to out, without a logger or summary.
+func newRunState(out io.Writer) *runState {
+	return &runState{out: out}
+}
+
+// Write writes p to the output of the run.
+func (run *runState) Write(p []byte) (int, error) {
+	return run.out.Write(p)
+}
+
+// printStatus shows a status message, such as a saved file or a cancelled operation, to the user. It is
+// typed out like the startup banner, unless the banner is disabled, as in non-interactive runs, or has
+// no delay, in which case it is printed at once.
+func (run *runState) printStatus(message string) {
+	if run.banner.Style == bannercli.StyleNone || run.banner.Delay <= 0 {
+		fmt.Fprintln(run.out, message)
+		return
+	}
+	bannercli.PrintTypingBannerTo(run.out, message, run.banner.Delay)
+}
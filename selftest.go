@@ -0,0 +1,182 @@
+// @selftest.go:
+// This file implements -healthcheck and -selftest, which let the updater and container orchestrators
+// verify a binary quickly. Both work on a tiny embedded store, never touch the network, and report
+// their result through the exit status as well as a line per failed check.
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/tablecli"
+)
+
+// selfCheckTimeout bounds the run time of -healthcheck and -selftest.
+const selfCheckTimeout = time.Second
+
+// selfTestStore is the embedded store fixture the self checks export.
+const selfTestStore = `{"chat-next-web-store":{"sessions":[
+{"id":"h1","topic":"Health check","memoryPrompt":"A tiny session.","lastUpdate":1701166585000,
+ "mask":{"modelConfig":{"model":"gpt-4"}},
+ "messages":[{"id":"h1-m1","date":"11/28/2023, 10:16:25 AM","role":"user","content":"ping"},
+             {"id":"h1-m2","date":"11/28/2023, 10:16:31 AM","role":"assistant","content":"pong"}]},
+{"id":"h2","topic":"Second session","memoryPrompt":"","lastUpdate":1701252985000,
+ "messages":[{"id":"h2-m1","date":"11/29/2023, 10:16:25 AM","role":"user","content":"hello, \"world\""}]}
+]}}`
+
+// selfCheck is a single check of -healthcheck or -selftest.
+type selfCheck struct {
+	name string                          // name identifies the check in the output.
+	run  func(ctx context.Context) error // run performs the check and returns why it failed.
+}
+
+// healthChecks returns the checks of -healthcheck: the embedded store can be parsed, and an export
+// of it can be written to and read back from a temporary file on the real file system.
+func healthChecks() []selfCheck {
+	return []selfCheck{
+		{"parse embedded store", func(ctx context.Context) error {
+			_, err := selfTestSessions()
+			return err
+		}},
+		{"write temporary file", checkTempFileWrite},
+	}
+}
+
+// selfTestChecks returns the checks of -selftest: the health checks, followed by an export of the
+// embedded store in every registered format into an in-memory file system.
+func selfTestChecks() []selfCheck {
+	checks := healthChecks()
+	fsys := filesystem.NewMockFileSystem()
+	for _, format := range exporter.Formats() {
+		format := format
+		checks = append(checks, selfCheck{"format " + format.Describe().Name, func(ctx context.Context) error {
+			return checkFormatExport(ctx, fsys, format)
+		}})
+	}
+	return checks
+}
+
+// selfTestSessions parses the embedded store fixture.
+func selfTestSessions() ([]exporter.Session, error) {
+	store, err := exporter.ReadJSONFromReader(strings.NewReader(selfTestStore))
+	if err != nil {
+		return nil, err
+	}
+	if sessions := store.ChatNextWebStore.Sessions; len(sessions) != 2 {
+		return nil, fmt.Errorf("embedded store has %d sessions, want 2", len(sessions))
+	}
+	return store.ChatNextWebStore.Sessions, nil
+}
+
+// checkTempFileWrite exports the embedded store as CSV into a new temporary directory through
+// RealFileSystem, reads it back, and removes the directory again.
+func checkTempFileWrite(ctx context.Context) error {
+	sessions, err := selfTestSessions()
+	if err != nil {
+		return err
+	}
+	var csvOutput bytes.Buffer
+	if err := exporter.ConvertSessions(ctx, &csvOutput, sessions, "csv", exporter.Options{}); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "chatgpt-exporter-healthcheck-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	rfs := filesystem.RealFileSystem{}
+	path := filepath.Join(dir, "healthcheck.csv")
+	if err := filesystem.AtomicWriteFile(rfs, path, csvOutput.Bytes(), 0644); err != nil {
+		return err
+	}
+	written, err := rfs.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(written, csvOutput.Bytes()) {
+		return fmt.Errorf("%s does not hold the data written to it", path)
+	}
+	return nil
+}
+
+// checkFormatExport exports the embedded store in the format into fsys and reads every file back.
+func checkFormatExport(ctx context.Context, fsys filesystem.FileSystem, format exporter.Format) error {
+	sessions, err := selfTestSessions()
+	if err != nil {
+		return err
+	}
+	files, err := renderFormat(ctx, format, sessions)
+	if err != nil {
+		return err
+	}
+
+	doc := format.Describe()
+	for _, file := range files {
+		if file.data.Len() == 0 {
+			return fmt.Errorf("the export is empty")
+		}
+		name := "selftest-" + doc.Name + doc.Extension
+		if file.name != "" {
+			name = "selftest-" + doc.Name + "-" + file.name
+		}
+		if err := filesystem.AtomicWriteFile(fsys, name, file.data.Bytes(), 0644); err != nil {
+			return err
+		}
+		written, err := fsys.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(written, file.data.Bytes()) {
+			return fmt.Errorf("%s does not hold the data written to it", name)
+		}
+	}
+	return nil
+}
+
+// runSelfChecks runs the checks in order and reports whether all of them passed.
+//
+// Every failed check is printed to w as "FAIL <name>: <reason>", followed by a final line starting
+// with "ok" or "FAIL". With verbose, a table of every check, its result, and its duration is printed
+// instead of the failure lines.
+func runSelfChecks(ctx context.Context, w io.Writer, checks []selfCheck, verbose bool) bool {
+	table := &tablecli.Table{Headers: []string{"Check", "Result", "Time"}, FlexColumn: -1}
+	failed := 0
+	for _, check := range checks {
+		start := time.Now()
+		err := check.run(ctx)
+		if err == nil {
+			err = ctx.Err()
+		}
+		elapsed := time.Since(start).Round(time.Microsecond).String()
+
+		if err != nil {
+			failed++
+			table.AddRow(check.name, "FAIL: "+err.Error(), elapsed)
+			if !verbose {
+				fmt.Fprintf(w, "FAIL %s: %s\n", check.name, err)
+			}
+			continue
+		}
+		table.AddRow(check.name, "PASS", elapsed)
+	}
+
+	if verbose {
+		table.Render(w, 0)
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "FAIL (%d of %d checks failed)\n", failed, len(checks))
+		return false
+	}
+	fmt.Fprintf(w, "ok (%d checks passed)\n", len(checks))
+	return true
+}
@@ -0,0 +1,27 @@
+// @sheets.go:
+// This file implements -sheets-id, which writes the sessions into a new sheet of a Google spreadsheet
+// instead of exporting them to files.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// uploadToSheets writes the sessions into a new sheet of the spreadsheet opts.SheetsID, authenticated with
+// the service account key at opts.SheetsKey in rfs, and reports the spreadsheet to w.
+func uploadToSheets(ctx context.Context, w io.Writer, rfs filesystem.FileSystem, opts cliOptions, sessions []exporter.Session) error {
+	creds, err := rfs.ReadFile(opts.SheetsKey)
+	if err != nil {
+		return fmt.Errorf("reading the service account key: %w", err)
+	}
+	if err := exporter.ExtractToGoogleSheetsWithOptions(ctx, sessions, opts.SheetsID, creds, opts.csvOptions()); err != nil {
+		return fmt.Errorf("writing to Google Sheets: %w", err)
+	}
+	fmt.Fprintf(w, "%d session(s) written to https://docs.google.com/spreadsheets/d/%s\n", len(sessions), opts.SheetsID)
+	return nil
+}
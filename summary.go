@@ -0,0 +1,233 @@
+// @summary.go:
+// This file implements the machine-readable JSON summary printed at the end of a run when the
+// -json-output flag is set, so that the CLI can be wrapped by other programs without parsing
+// its free-form text.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// runSummary describes the outcome of a run as a single JSON object.
+type runSummary struct {
+	Format      string                       `json:"format,omitempty"`      // Format is the name of the chosen output format.
+	Files       []fileSummary                `json:"files"`                 // Files lists every file written, in order.
+	Incremental *exporter.IncrementalSummary `json:"incremental,omitempty"` // Incremental holds the session counts of an incremental export.
+	Tags        map[string]int               `json:"tags,omitempty"`        // Tags counts the exported sessions carrying each tag.
+	Sessions    *exporter.SkipReport         `json:"sessions,omitempty"`    // Sessions accounts for every input session as exported or skipped by reason.
+	Errors      []string                     `json:"errors"`                // Errors lists every error reported to the user.
+	DurationMS  int64                        `json:"duration_ms"`           // DurationMS is the wall-clock duration of the run in milliseconds.
+	Success     bool                         `json:"success"`               // Success reports whether the run finished without errors.
+
+	start time.Time // start is when the run began.
+	out   io.Writer // out is where the summary is written, the original standard output.
+}
+
+// fileSummary describes a single file written during the run.
+type fileSummary struct {
+	Path  string `json:"path"`           // Path is the name the file was written to.
+	Bytes int    `json:"bytes"`          // Bytes is the size of the file.
+	Rows  *int   `json:"rows,omitempty"` // Rows is the number of data rows, excluding the header, for CSV files; the rows appended for a file appended to.
+}
+
+// newRunSummary starts a summary that will be written to out.
+func newRunSummary(out io.Writer) *runSummary {
+	return &runSummary{Files: []fileSummary{}, Errors: []string{}, start: time.Now(), out: out}
+}
+
+// recordFile adds a file written in one piece to the summary, counting its rows if it is a CSV file.
+func (s *runSummary) recordFile(name string, data []byte) {
+	counter := csvRecordCounter{w: io.Discard}
+	counter.Write(data)
+	s.recordStreamedFile(name, &counter)
+}
+
+// recordStreamedFile adds a file streamed through counter to the summary, with its rows if it is a CSV file.
+func (s *runSummary) recordStreamedFile(name string, counter *csvRecordCounter) {
+	file := fileSummary{Path: name, Bytes: counter.bytes}
+	if strings.EqualFold(filepath.Ext(name), ".csv") && counter.records > 0 {
+		rows := counter.records - 1
+		file.Rows = &rows
+	}
+	s.Files = append(s.Files, file)
+}
+
+// recordAppendedFile adds a file that data was appended to, now size bytes long, to the summary, with the
+// appended rows if it is a CSV file. The appended rows carry no header, and a line break that only separates
+// them from the existing rows is not a row.
+func (s *runSummary) recordAppendedFile(name string, data []byte, size int) {
+	counter := csvRecordCounter{w: io.Discard}
+	counter.Write(bytes.TrimPrefix(data, []byte("\n")))
+	file := fileSummary{Path: name, Bytes: size}
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		file.Rows = &counter.records
+	}
+	s.Files = append(s.Files, file)
+}
+
+// csvRecordCounter is an io.Writer that passes the data through to w while counting the bytes and the
+// CSV records written. Line breaks inside quoted fields do not end a record; a final record without a
+// line break is not counted.
+type csvRecordCounter struct {
+	w        io.Writer
+	bytes    int
+	records  int
+	inQuotes bool
+}
+
+// Write writes p to w and counts the bytes and records written.
+func (c *csvRecordCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	for _, b := range p[:n] {
+		switch {
+		case b == '"':
+			c.inQuotes = !c.inQuotes
+		case b == '\n' && !c.inQuotes:
+			c.records++
+		}
+	}
+	c.bytes += n
+	return n, err
+}
+
+// recordError adds an error message to the summary.
+func (s *runSummary) recordError(message string) {
+	s.Errors = append(s.Errors, strings.TrimSpace(message))
+}
+
+// write finalizes the summary and writes it as a single line of JSON.
+func (s *runSummary) write(exitCode int) error {
+	s.DurationMS = time.Since(s.start).Milliseconds()
+	s.Success = exitCode == 0 && len(s.Errors) == 0
+	return json.NewEncoder(s.out).Encode(s)
+}
+
+// summaryFileSystem wraps a FileSystem and records every file written through it in the run summary.
+type summaryFileSystem struct {
+	filesystem.FileSystem
+	summary *runSummary
+}
+
+// WriteFile writes the file through the wrapped FileSystem and records it on success.
+func (s summaryFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := s.FileSystem.WriteFile(name, data, perm); err != nil {
+		return err
+	}
+	s.summary.recordFile(name, data)
+	return nil
+}
+
+// AtomicWriteFile replaces the file atomically through the wrapped FileSystem and records it under its final name on success.
+func (s summaryFileSystem) AtomicWriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := filesystem.AtomicWriteFile(s.FileSystem, name, data, perm); err != nil {
+		return err
+	}
+	s.summary.recordFile(name, data)
+	return nil
+}
+
+// AppendFile appends to the file through the wrapped FileSystem and records the appended rows on success,
+// with the size of the whole file, or the size of the data appended if the file cannot be stat'ed.
+func (s summaryFileSystem) AppendFile(name string, data []byte, perm fs.FileMode) error {
+	if err := filesystem.AppendFile(s.FileSystem, name, data, perm); err != nil {
+		return err
+	}
+	size := len(data)
+	if info, err := s.FileSystem.Stat(name); err == nil {
+		size = int(info.Size())
+	}
+	s.summary.recordAppendedFile(name, data, size)
+	return nil
+}
+
+// Remove removes the file through the wrapped FileSystem.
+func (s summaryFileSystem) Remove(name string) error {
+	return filesystem.Remove(s.FileSystem, name)
+}
+
+// MkdirAll creates the directory through the wrapped FileSystem.
+func (s summaryFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return filesystem.MkdirAll(s.FileSystem, path, perm)
+}
+
+// Open opens the file through the wrapped FileSystem.
+func (s summaryFileSystem) Open(name string) (io.ReadCloser, error) {
+	return filesystem.Open(s.FileSystem, name)
+}
+
+// CreateTempFile starts a file through the wrapped FileSystem that is recorded under its final name
+// once committed.
+func (s summaryFileSystem) CreateTempFile(dir string, perm fs.FileMode) (filesystem.TempFile, error) {
+	file, err := filesystem.CreateTempFile(s.FileSystem, dir, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &summaryTempFile{TempFile: file, counter: csvRecordCounter{w: file}, summary: s.summary}, nil
+}
+
+// summaryTempFile is the TempFile of summaryFileSystem.
+type summaryTempFile struct {
+	filesystem.TempFile
+	counter csvRecordCounter
+	summary *runSummary
+}
+
+// Write writes p to the file, counting its bytes and CSV records.
+func (s *summaryTempFile) Write(p []byte) (int, error) {
+	return s.counter.Write(p)
+}
+
+// Commit commits the file and records it on success.
+func (s *summaryTempFile) Commit(name string) error {
+	if err := s.TempFile.Commit(name); err != nil {
+		return err
+	}
+	s.summary.recordStreamedFile(name, &s.counter)
+	return nil
+}
+
+// withSummary wraps rfs so that written files are recorded when a summary is being collected.
+func (run *runState) withSummary(rfs filesystem.FileSystem) filesystem.FileSystem {
+	if run.summary == nil {
+		return rfs
+	}
+	return summaryFileSystem{FileSystem: rfs, summary: run.summary}
+}
+
+// setSummaryFormat records the name of the chosen output format when a summary is being collected.
+func (run *runState) setSummaryFormat(format string) {
+	if run.summary != nil {
+		run.summary.Format = format
+	}
+}
+
+// printError shows an error message to the user and records it in the summary, if one is being collected.
+func (run *runState) printError(message string) {
+	run.errors++
+	if run.summary != nil {
+		run.summary.recordError(message)
+	}
+	if run.logger != nil {
+		run.logErrorMessage(message)
+		return
+	}
+	run.printStatus(message)
+}
+
+// exit writes the summary, if one is being collected, and terminates the program with the given code.
+func (run *runState) exit(code int) {
+	if run.summary != nil {
+		run.summary.write(code)
+	}
+	os.Exit(code)
+}
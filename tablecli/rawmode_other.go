@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package tablecli
+
+import "os"
+
+// MakeRaw is not supported on this platform; it always returns ErrRawModeUnsupported.
+func MakeRaw(f *os.File) (restore func() error, err error) {
+	return nil, ErrRawModeUnsupported
+}
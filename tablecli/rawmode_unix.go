@@ -0,0 +1,36 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package tablecli
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// MakeRaw puts the terminal attached to f into raw mode, where every key press is read as soon as it
+// is typed, without echo and without line editing or signals, as needed by full-screen viewers. Output
+// processing is kept, so newlines still return the cursor to the start of the line.
+// It returns a function restoring the previous mode, which the caller must call before exiting.
+func MakeRaw(f *os.File) (restore func() error, err error) {
+	var previous syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&previous))); errno != 0 {
+		return nil, errno
+	}
+	raw := previous
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&raw))); errno != 0 {
+		return nil, errno
+	}
+	return func() error {
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&previous))); errno != 0 {
+			return errno
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,178 @@
+// Package tablecli renders aligned text tables for the terminal, with optional paging.
+//
+// Tables respect the terminal width by truncating a single flexible column (such as a topic),
+// and page their output only when writing to an interactive terminal, so that piped output
+// can be processed by tools like grep.
+//
+// # Example Usage
+//
+//	table := tablecli.Table{
+//		Headers:    []string{"#", "Topic", "Messages"},
+//		FlexColumn: 1,
+//	}
+//	table.AddRow("1", "Go Concurrency", "14")
+//	table.Render(os.Stdout, tablecli.TerminalWidth())
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package tablecli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// columnSeparator separates the columns of a rendered table.
+	columnSeparator = "  "
+
+	// ellipsis marks text that was truncated to fit the terminal width.
+	ellipsis = "…"
+
+	// minFlexWidth is the narrowest the flexible column is ever truncated to.
+	minFlexWidth = 8
+)
+
+// Table is a simple text table with a header row.
+type Table struct {
+	Headers    []string   // Headers holds the column titles.
+	Rows       [][]string // Rows holds the cells of each row; short rows are padded with empty cells.
+	FlexColumn int        // FlexColumn is the index of the column truncated to fit the width, or -1 for none.
+}
+
+// AddRow appends a row of cells to the table.
+func (t *Table) AddRow(cells ...string) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// Lines renders the table into lines that fit within width characters, header and separator first.
+// A width of zero or less disables truncation.
+func (t *Table) Lines(width int) []string {
+	widths := t.columnWidths(width)
+
+	lines := make([]string, 0, len(t.Rows)+2)
+	lines = append(lines, t.renderRow(t.Headers, widths))
+	separators := make([]string, len(widths))
+	for i, w := range widths {
+		separators[i] = strings.Repeat("-", w)
+	}
+	lines = append(lines, t.renderRow(separators, widths))
+	for _, row := range t.Rows {
+		lines = append(lines, t.renderRow(row, widths))
+	}
+	return lines
+}
+
+// Render writes the whole table to w without paging.
+func (t *Table) Render(w io.Writer, width int) error {
+	for _, line := range t.Lines(width) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Page writes the table to w one page of pageSize rows at a time, repeating the header on every page.
+// After each page it waits for a line from input: an empty line or a space shows the next page,
+// while "q" stops paging. A pageSize of zero or less, or a nil input, writes the whole table at once.
+func (t *Table) Page(w io.Writer, input *bufio.Reader, width int, pageSize int) error {
+	if pageSize <= 0 || input == nil || len(t.Rows) <= pageSize {
+		return t.Render(w, width)
+	}
+
+	lines := t.Lines(width)
+	header, rows := lines[:2], lines[2:]
+	for start := 0; start < len(rows); start += pageSize {
+		end := start + pageSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		for _, line := range append(header[:2:2], rows[start:end]...) {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		if end == len(rows) {
+			break
+		}
+
+		fmt.Fprintf(w, "-- %d/%d -- (Enter or space for next page, q to quit) ", end, len(rows))
+		answer, err := input.ReadString('\n')
+		if strings.TrimSpace(strings.ToLower(answer)) == "q" {
+			return nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// columnWidths computes the width of every column, truncating the flexible column
+// so that the rendered table fits within width characters.
+func (t *Table) columnWidths(width int) []int {
+	columns := len(t.Headers)
+	for _, row := range t.Rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	widths := make([]int, columns)
+	measure := func(cells []string) {
+		for i, cell := range cells {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	measure(t.Headers)
+	for _, row := range t.Rows {
+		measure(row)
+	}
+
+	if width <= 0 || t.FlexColumn < 0 || t.FlexColumn >= columns {
+		return widths
+	}
+	total := len(columnSeparator) * (columns - 1)
+	for _, w := range widths {
+		total += w
+	}
+	if excess := total - width; excess > 0 {
+		widths[t.FlexColumn] -= excess
+		if widths[t.FlexColumn] < minFlexWidth {
+			widths[t.FlexColumn] = minFlexWidth
+		}
+	}
+	return widths
+}
+
+// renderRow pads or truncates every cell to its column width and joins them.
+func (t *Table) renderRow(cells []string, widths []int) string {
+	parts := make([]string, len(widths))
+	for i, w := range widths {
+		cell := ""
+		if i < len(cells) {
+			cell = strings.Join(strings.Fields(cells[i]), " ")
+		}
+		parts[i] = fit(cell, w)
+	}
+	return strings.TrimRight(strings.Join(parts, columnSeparator), " ")
+}
+
+// fit truncates text to width characters, marking truncation with an ellipsis, and pads it to width.
+func fit(text string, width int) string {
+	n := utf8.RuneCountInString(text)
+	if n > width {
+		runes := []rune(text)
+		text = string(runes[:width-1]) + ellipsis
+		n = width
+	}
+	return text + strings.Repeat(" ", width-n)
+}
@@ -0,0 +1,105 @@
+package tablecli_test
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/tablecli"
+)
+
+// sessionsTable returns a table like the session list, with a topic too long for a narrow terminal.
+func sessionsTable() *tablecli.Table {
+	table := &tablecli.Table{Headers: []string{"#", "Topic", "Messages"}, FlexColumn: 1}
+	table.AddRow("1", "Go Concurrency", "14")
+	table.AddRow("2", "A very long topic about channels", "3")
+	return table
+}
+
+// TestRenderColumnWidths verifies that every column is as wide as its widest cell when the width is unlimited.
+func TestRenderColumnWidths(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sessionsTable().Render(&buf, 0); err != nil {
+		t.Fatalf("Render() returned an error: %v", err)
+	}
+	want := "#  Topic                             Messages\n" +
+		"-  --------------------------------  --------\n" +
+		"1  Go Concurrency                    14\n" +
+		"2  A very long topic about channels  3\n"
+	if buf.String() != want {
+		t.Errorf("Render() wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+// TestRenderTruncation verifies that the flexible column is truncated with an ellipsis to fit the width
+// of the terminal, but never below its minimum width.
+func TestRenderTruncation(t *testing.T) {
+	t.Setenv("COLUMNS", "30")
+	width := tablecli.TerminalWidth()
+	if width != 30 {
+		t.Fatalf("TerminalWidth() = %d, want 30 from COLUMNS", width)
+	}
+
+	var buf bytes.Buffer
+	if err := sessionsTable().Render(&buf, width); err != nil {
+		t.Fatalf("Render() returned an error: %v", err)
+	}
+	want := "#  Topic              Messages\n" +
+		"-  -----------------  --------\n" +
+		"1  Go Concurrency     14\n" +
+		"2  A very long topi…  3\n"
+	if buf.String() != want {
+		t.Errorf("Render() wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if n := utf8.RuneCountInString(line); n > width {
+			t.Errorf("line %q is %d characters wide, more than %d", line, n, width)
+		}
+	}
+
+	lines := sessionsTable().Lines(10)
+	if want := "2  A very …  3"; lines[3] != want {
+		t.Errorf("Lines(10)[3] = %q, want the topic truncated to its minimum width: %q", lines[3], want)
+	}
+}
+
+// TestPageBreaks verifies that Page repeats the header on every page of a terminal's height, prompts
+// between pages, and stops when the user quits.
+func TestPageBreaks(t *testing.T) {
+	t.Setenv("LINES", "6")
+	pageSize := tablecli.TerminalHeight() - 3
+	table := &tablecli.Table{Headers: []string{"#", "Topic"}, FlexColumn: 1}
+	for i := 1; i <= 5; i++ {
+		table.AddRow(strconv.Itoa(i), "Topic "+strconv.Itoa(i))
+	}
+	prompt := "-- 3/5 -- (Enter or space for next page, q to quit) "
+
+	var buf bytes.Buffer
+	if err := table.Page(&buf, bufio.NewReader(strings.NewReader("\n")), 0, pageSize); err != nil {
+		t.Fatalf("Page() returned an error: %v", err)
+	}
+	header := "#  Topic\n-  -------\n"
+	want := header + "1  Topic 1\n2  Topic 2\n3  Topic 3\n" + prompt + header + "4  Topic 4\n5  Topic 5\n"
+	if buf.String() != want {
+		t.Errorf("Page() wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := table.Page(&buf, bufio.NewReader(strings.NewReader("q\n")), 0, pageSize); err != nil {
+		t.Fatalf("Page() returned an error: %v", err)
+	}
+	if want := header + "1  Topic 1\n2  Topic 2\n3  Topic 3\n" + prompt; buf.String() != want {
+		t.Errorf("Page() after quitting wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := table.Page(&buf, nil, 0, pageSize); err != nil {
+		t.Fatalf("Page() returned an error: %v", err)
+	}
+	if strings.Contains(buf.String(), "q to quit") || strings.Count(buf.String(), "\n") != 7 {
+		t.Errorf("Page() without input should write the whole table without prompts, wrote\n%s", buf.String())
+	}
+}
@@ -0,0 +1,51 @@
+package tablecli
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+// ErrRawModeUnsupported is returned by MakeRaw on platforms where raw mode is not implemented.
+var ErrRawModeUnsupported = errors.New("raw terminal mode is not supported on this platform")
+
+const (
+	// defaultWidth is the width assumed when the terminal width cannot be determined.
+	defaultWidth = 80
+
+	// defaultHeight is the height assumed when the terminal height cannot be determined.
+	defaultHeight = 24
+)
+
+// IsTerminal reports whether the given file is an interactive terminal rather than a pipe or regular file.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// TerminalWidth returns the width of the terminal attached to standard output.
+// It consults the COLUMNS environment variable first and falls back to 80 columns.
+func TerminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	if width, _, ok := terminalSize(os.Stdout); ok && width > 0 {
+		return width
+	}
+	return defaultWidth
+}
+
+// TerminalHeight returns the height of the terminal attached to standard output.
+// It consults the LINES environment variable first and falls back to 24 lines.
+func TerminalHeight() int {
+	if lines, err := strconv.Atoi(os.Getenv("LINES")); err == nil && lines > 0 {
+		return lines
+	}
+	if _, height, ok := terminalSize(os.Stdout); ok && height > 0 {
+		return height
+	}
+	return defaultHeight
+}
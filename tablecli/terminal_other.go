@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package tablecli
+
+import (
+	"os"
+)
+
+// terminalSize is not supported on this platform, so the defaults are used instead.
+func terminalSize(f *os.File) (width, height int, ok bool) {
+	return 0, 0, false
+}
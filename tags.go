@@ -0,0 +1,125 @@
+// @tags.go:
+// This file implements session tags: a tags file in the state directory records tags such as "work" or
+// "delete-later" for sessions, -tag walks the sessions to edit them interactively, and the tags are
+// carried into the exports, where -filter tag:<name> selects the sessions carrying a tag.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/appdirs"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// PromptTagSession asks for the tags of the session shown by tagSessions.
+const PromptTagSession = "Tags (comma-separated; empty keeps them, - clears them, q stops tagging): "
+
+// tagPreviewLines is the number of lines of the first message tagSessions shows for each session.
+const tagPreviewLines = 3
+
+// loadSessionTags reads the tags file at path.
+// A missing tags file is not an error; it yields empty tags, so no session is tagged.
+func loadSessionTags(rfs filesystem.FileSystem, path string) (exporter.SessionTags, error) {
+	data, err := rfs.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return exporter.SessionTags{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tags, err := exporter.ParseSessionTags(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tags file %s: %w", path, err)
+	}
+	return tags, nil
+}
+
+// saveSessionTags writes the tags file at path, creating its directory if needed. The file is
+// replaced atomically, so a crash while saving leaves the previous tags intact.
+func saveSessionTags(rfs filesystem.FileSystem, path string, tags exporter.SessionTags) error {
+	data, err := json.MarshalIndent(tags, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := appdirs.Ensure(rfs, filepath.Dir(path)); err != nil {
+		return err
+	}
+	return filesystem.AtomicWriteFile(rfs, path, append(data, '\n'), 0644)
+}
+
+// tagSessions walks the sessions, showing the topic, the current tags, and the first lines of the
+// first message of each, and records the tags entered for it in tags. Tagging stops early when the
+// user enters "q"; the tags entered until then are kept.
+func tagSessions(ctx context.Context, w io.Writer, reader *bufio.Reader, sessions []exporter.Session, tags exporter.SessionTags) error {
+	for i, session := range sessions {
+		fmt.Fprintf(w, "\n[%d/%d] %s (%s)\n", i+1, len(sessions), session.Topic, session.ID)
+		if current := tags[session.ID]; len(current) > 0 {
+			fmt.Fprintf(w, "Tags: %s\n", strings.Join(current, ", "))
+		}
+		if len(session.Messages) > 0 {
+			for _, line := range previewLines(session.Messages[0].Content, tagPreviewLines) {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+
+		answer, err := promptForInput(ctx, w, reader, PromptTagSession)
+		if err != nil {
+			return err
+		}
+		switch answer {
+		case "":
+		case "q", "Q":
+			return nil
+		case "-":
+			tags.Set(session.ID, nil)
+		default:
+			tags.Set(session.ID, exporter.ParseTags(answer))
+		}
+	}
+	return nil
+}
+
+// previewLines returns the first non-empty lines of text, at most n of them.
+func previewLines(text string, n int) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" && len(lines) < n {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// tagCounts returns the number of sessions carrying each tag, or nil if no session is tagged.
+func tagCounts(sessions []exporter.Session) map[string]int {
+	var counts map[string]int
+	for _, session := range sessions {
+		for _, tag := range session.Tags {
+			if counts == nil {
+				counts = make(map[string]int)
+			}
+			counts[tag]++
+		}
+	}
+	return counts
+}
+
+// sortedTags returns the tags of counts in alphabetical order, for stable diagnostics.
+func sortedTags(counts map[string]int) []string {
+	tags := make([]string, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, fmt.Sprintf("%s (%d)", tag, count))
+	}
+	sort.Strings(tags)
+	return tags
+}
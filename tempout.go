@@ -0,0 +1,84 @@
+// @tempout.go:
+// This file implements the -tempout mode for scripts, which writes the export to a new temporary
+// file without prompting for file names and prints nothing but the path of that file to stdout.
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+// tempOutputPattern is the os.CreateTemp pattern of the files written by -tempout; the extension of the format is appended.
+const tempOutputPattern = "chatgpt-sessions-*"
+
+// tempOutputFormat is the format -tempout writes for an option of the output format menu.
+type tempOutputFormat struct {
+	name     string // name is the format name of exporter.ConvertSessions.
+	fileType string // fileType selects the extension of the temporary file.
+}
+
+// tempOutputFormats maps the options of the output format menu that write a file to the format written
+// by -tempout, using the defaults of the format where the interactive flow would prompt: the inline
+// layout for CSV, whole sessions for the dataset and fine-tuning formats, and CSV for summaries.
+var tempOutputFormats = map[string]tempOutputFormat{
+	`1`:  {"csv-inline", "csv"},
+	`2`:  {"dataset", FileTypeDataset},
+	`3`:  {"orgmode", FileTypeOrgMode},
+	`5`:  {"finetune", FileTypeFineTune},
+	`6`:  {"summaries-csv", FileTypeSummariesCSV},
+	`8`:  {"epub", FileTypeEPUB},
+	`9`:  {"text", "text"},
+	`10`: {"qa-jsonl", "qa-jsonl"},
+	`11`: {"turns-json", "turns-json"},
+	`12`: {"atom", "atom"},
+}
+
+// renderTempOutput converts the sessions into the content of the output format selected by the menu option.
+// It returns the content together with its file type.
+func renderTempOutput(ctx context.Context, outputOption string, sessions []exporter.Session, opts exporter.Options) ([]byte, string, error) {
+	format, ok := tempOutputFormats[outputOption]
+	if !ok {
+		return nil, "", fmt.Errorf("the %s format does not write a file", outputFormatName(outputOption))
+	}
+	if format.fileType == FileTypeFineTune && opts.Export.JSONArray {
+		format.fileType = FileTypeFineTuneArray
+	}
+	var output bytes.Buffer
+	if err := exporter.ConvertSessions(ctx, &output, sessions, format.name, opts); err != nil {
+		return nil, "", err
+	}
+	return output.Bytes(), format.fileType, nil
+}
+
+// writeTempOutput writes the export selected by the menu option to a new file in dir, or in the default
+// directory for temporary files if dir is empty, and returns its path. The file is named after
+// tempOutputPattern with the extension of the format, and is removed again if writing fails.
+func writeTempOutput(ctx context.Context, dir, outputOption string, sessions []exporter.Session, opts exporter.Options) (string, error) {
+	content, fileType, err := renderTempOutput(ctx, outputOption, sessions, opts)
+	if err != nil {
+		return "", err
+	}
+	return writeTempFile(dir, fileType, content)
+}
+
+// writeTempFile writes content to a new file in dir, named after tempOutputPattern with the extension
+// of fileType, and returns its path. The file is removed again if writing fails.
+func writeTempFile(dir, fileType string, content []byte) (string, error) {
+	file, err := os.CreateTemp(dir, tempOutputPattern+fileExtension(fileType))
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,101 @@
+package testsupport
+
+import (
+	"fmt"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+// SessionOption configures a synthetic session built by NewSession.
+type SessionOption func(*exporter.Session)
+
+// NewSession builds a synthetic session with the given ID and applies the options in order.
+// Without options the session has the topic "Test Session" and no messages.
+func NewSession(id string, opts ...SessionOption) exporter.Session {
+	session := exporter.Session{
+		ID:    id,
+		Topic: "Test Session",
+	}
+	for _, opt := range opts {
+		opt(&session)
+	}
+	return session
+}
+
+// NewMessage builds a synthetic message with the given ID, role, and content.
+// The date is a fixed timestamp so that outputs built from it are reproducible.
+func NewMessage(id, role, content string) exporter.Message {
+	return exporter.Message{
+		ID:      id,
+		Date:    "11/28/2023, 10:16:25 AM",
+		Role:    role,
+		Content: content,
+	}
+}
+
+// InBranch records the parent message and the branch of a message, as stores with branching support do.
+func InBranch(message exporter.Message, parentID, branchID string) exporter.Message {
+	message.ParentID = parentID
+	message.BranchID = branchID
+	return message
+}
+
+// WithMessages appends the given messages to the session.
+func WithMessages(messages ...exporter.Message) SessionOption {
+	return func(s *exporter.Session) {
+		s.Messages = append(s.Messages, messages...)
+	}
+}
+
+// WithTopic sets the topic of the session.
+func WithTopic(topic string) SessionOption {
+	return func(s *exporter.Session) {
+		s.Topic = topic
+	}
+}
+
+// WithMemoryPrompt sets the memory prompt (rolling summary) of the session.
+func WithMemoryPrompt(prompt string) SessionOption {
+	return func(s *exporter.Session) {
+		s.MemoryPrompt = prompt
+	}
+}
+
+// WithTimestamps sets the creation time (stored on the mask) and the last update time
+// of the session, both as Unix milliseconds.
+func WithTimestamps(createdAt, lastUpdate int64) SessionOption {
+	return func(s *exporter.Session) {
+		s.Mask.CreatedAt = createdAt
+		s.LastUpdate = lastUpdate
+	}
+}
+
+// WithModel sets the model configured on the session's mask.
+func WithModel(model string) SessionOption {
+	return func(s *exporter.Session) {
+		s.Mask.ModelConfig = &exporter.ModelConfig{Model: model}
+	}
+}
+
+// WithConversation appends n alternating user/assistant messages to the session.
+// Message IDs and contents are derived from the session ID so they are unique and reproducible.
+func WithConversation(n int) SessionOption {
+	return func(s *exporter.Session) {
+		for i := 0; i < n; i++ {
+			role := "user"
+			if i%2 == 1 {
+				role = "assistant"
+			}
+			id := fmt.Sprintf("%s-m%d", s.ID, i+1)
+			s.Messages = append(s.Messages, NewMessage(id, role, fmt.Sprintf("Message %d of %s", i+1, s.ID)))
+		}
+	}
+}
+
+// NewStore wraps the given sessions in a ChatNextWebStore.
+func NewStore(sessions ...exporter.Session) exporter.ChatNextWebStore {
+	if sessions == nil {
+		sessions = []exporter.Session{}
+	}
+	return exporter.ChatNextWebStore{ChatNextWebStore: exporter.Store{Sessions: sessions}}
+}
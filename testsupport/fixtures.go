@@ -0,0 +1,110 @@
+package testsupport
+
+import (
+	"fmt"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+// SmallStore returns a canonical store with two ordinary sessions.
+func SmallStore() exporter.ChatNextWebStore {
+	return NewStore(
+		NewSession("session-1",
+			WithTopic("Travel Guide"),
+			WithModel("gpt-4-1106-preview"),
+			WithTimestamps(1701141385000, 1701141400000),
+			WithMessages(
+				NewMessage("s1-m1", "user", "I am in Istanbul and I want to visit only museums."),
+				NewMessage("s1-m2", "assistant", "You could visit the Pera Museum and Istanbul Modern."),
+			),
+		),
+		NewSession("session-2",
+			WithTopic("Go Concurrency"),
+			WithMemoryPrompt("The user is learning about goroutines."),
+			WithTimestamps(1701227785000, 1701227800000),
+			WithMessages(
+				NewMessage("s2-m1", "user", "What is a goroutine?"),
+				NewMessage("s2-m2", "assistant", "A goroutine is a lightweight thread managed by the Go runtime."),
+			),
+		),
+	)
+}
+
+// LargeStore returns a canonical store with 50 sessions of 10 messages each,
+// suitable for exercising code paths that depend on volume.
+func LargeStore() exporter.ChatNextWebStore {
+	sessions := make([]exporter.Session, 0, 50)
+	for i := 0; i < 50; i++ {
+		sessions = append(sessions, NewSession(fmt.Sprintf("large-%03d", i+1),
+			WithTopic(fmt.Sprintf("Large Session %d", i+1)),
+			WithTimestamps(1701141385000+int64(i)*60000, 1701141385000+int64(i)*60000+30000),
+			WithConversation(10),
+		))
+	}
+	return NewStore(sessions...)
+}
+
+// EdgeCaseStore returns a canonical store full of content that tends to break exporters:
+// empty sessions, quotes, delimiters, newlines, unicode, and unusual roles.
+func EdgeCaseStore() exporter.ChatNextWebStore {
+	return NewStore(
+		NewSession("empty", WithTopic("")),
+		NewSession("quotes,commas",
+			WithTopic(`Topic with "quotes", commas; and semicolons`),
+			WithMessages(
+				NewMessage("e2-m1", "user", `She said "hello", then left; twice.`),
+				NewMessage("e2-m2", "assistant", "Line one\nLine two\r\nLine three"),
+			),
+		),
+		NewSession("unicode",
+			WithTopic("Unicode 🎩🪄 Beyoğlu 日本語"),
+			WithMemoryPrompt("Summary with emoji 🐹"),
+			WithMessages(
+				NewMessage("e3-m1", "user", "Çok teşekkürler! ありがとう"),
+				NewMessage("e3-m2", "assistant", "```go\nfmt.Println(\"🎩\")\n```"),
+			),
+		),
+		NewSession("roles",
+			WithTopic("Unusual Roles"),
+			WithMessages(
+				NewMessage("e4-m1", "system", "You are a helpful assistant."),
+				NewMessage("e4-m2", "assistant", "Leading assistant message."),
+				NewMessage("e4-m3", "assistant", "Consecutive assistant message."),
+				NewMessage("e4-m4", "user", ""),
+			),
+		),
+	)
+}
+
+// BranchedStore returns a canonical store of sessions with regenerated answers kept as branches:
+// one session records parents for every message, one only for the messages after the first branch,
+// and one has no branches at all.
+func BranchedStore() exporter.ChatNextWebStore {
+	return NewStore(
+		NewSession("branched",
+			WithTopic("Regenerated Answer"),
+			WithMessages(
+				NewMessage("b1-m1", "user", "Name a prime number."),
+				InBranch(NewMessage("b1-m2", "assistant", "Nine."), "b1-m1", "b1-a"),
+				InBranch(NewMessage("b1-m3", "user", "That is not prime."), "b1-m2", "b1-a"),
+				InBranch(NewMessage("b1-m4", "assistant", "Seven."), "b1-m1", "b1-b"),
+				InBranch(NewMessage("b1-m5", "user", "Thanks!"), "b1-m4", "b1-b"),
+			),
+		),
+		NewSession("partial",
+			WithTopic("Partially Recorded Parents"),
+			WithMessages(
+				NewMessage("b2-m1", "user", "Hello."),
+				NewMessage("b2-m2", "assistant", "Hi there."),
+				NewMessage("b2-m3", "user", "Tell me a joke."),
+				InBranch(NewMessage("b2-m4", "assistant", "A bad joke."), "b2-m3", "b2-a"),
+				InBranch(NewMessage("b2-m5", "assistant", "A better joke."), "b2-m3", "b2-b"),
+				NewMessage("b2-m6", "user", "Ha!"),
+			),
+		),
+		NewSession("linear",
+			WithTopic("No Branches"),
+			WithConversation(2),
+		),
+	)
+}
@@ -0,0 +1,32 @@
+package testsupport
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+// AssertDatasetRoundtrip exports sessions with exporter.ExtractToDataset, parses the result
+// back with exporter.ParseDatasetJSON, and fails the test unless the sessions come back unchanged.
+func AssertDatasetRoundtrip(t testing.TB, sessions []exporter.Session) {
+	t.Helper()
+
+	dataset, err := exporter.ExtractToDataset(sessions)
+	if err != nil {
+		t.Fatalf("ExtractToDataset() returned an error: %v", err)
+	}
+	got, err := exporter.ParseDatasetJSON([]byte(dataset))
+	if err != nil {
+		t.Fatalf("ParseDatasetJSON() returned an error: %v", err)
+	}
+
+	if len(got) != len(sessions) {
+		t.Fatalf("roundtrip returned %d sessions, want %d", len(got), len(sessions))
+	}
+	for i := range sessions {
+		if !reflect.DeepEqual(got[i], sessions[i]) {
+			t.Errorf("session %d did not survive the roundtrip\n got: %#v\nwant: %#v", i, got[i], sessions[i])
+		}
+	}
+}
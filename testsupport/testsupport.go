@@ -0,0 +1,88 @@
+// Package testsupport provides a golden-file test harness, canonical fixture stores,
+// and builders for synthetic sessions used by the exporter tests.
+//
+// It is exported so that downstream forks adding their own output formats can keep
+// their output byte-compatible by testing against the same fixtures and golden files.
+//
+// # Example Usage
+//
+//	func TestMyFormat(t *testing.T) {
+//		store := testsupport.EdgeCaseStore()
+//		got, err := myformat.Extract(store.ChatNextWebStore.Sessions)
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		// Compares against testdata/golden/myformat_edge.golden.
+//		testsupport.GoldenCompare(t, "myformat_edge", []byte(got))
+//	}
+//
+// Run `UPDATE_GOLDEN=1 go test ./...` once to (re)generate the golden files, review the diff,
+// and commit them alongside the format.
+//
+// Formats that are meant to be read back can additionally be checked with
+// AssertDatasetRoundtrip, which fails unless the sessions survive an export and reimport unchanged.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package testsupport
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// GoldenDir is the directory, relative to the package under test, where golden files are stored.
+const GoldenDir = "testdata/golden"
+
+// UpdateGoldenEnv is the environment variable that makes GoldenCompare rewrite golden files when it is set
+// to a true value such as "1". An environment variable is used instead of a flag, so that importing this
+// package never adds flags to the binary of the importer.
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// GoldenCompare compares got against the golden file named name in GoldenDir.
+// When UpdateGoldenEnv is set, the golden file is (re)written with got instead.
+// A missing golden file is reported as a test failure with a hint to set UpdateGoldenEnv.
+func GoldenCompare(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join(GoldenDir, name+".golden")
+
+	if update, _ := strconv.ParseBool(os.Getenv(UpdateGoldenEnv)); update {
+		if err := os.MkdirAll(GoldenDir, 0755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("failed to update golden file %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s (run with %s=1 to create it): %v", path, UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match golden file %s\n%s", path, diffLine(got, want))
+	}
+}
+
+// diffLine describes the first line at which got and want differ.
+func diffLine(got, want []byte) string {
+	gotLines := bytes.Split(got, []byte("\n"))
+	wantLines := bytes.Split(want, []byte("\n"))
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w []byte
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if !bytes.Equal(g, w) {
+			return fmt.Sprintf("first difference at line %d:\n got: %q\nwant: %q", i+1, g, w)
+		}
+	}
+	return "outputs differ only in length"
+}
@@ -9,6 +9,9 @@
 // the release asset that matches the running application's operating system and
 // architecture, replaces the current executable, and restarts the application.
 //
+// ReleasesSince lists the releases newer than a given version using the releases list endpoint,
+// so that the notes of every release an update jumps over can be shown with FormatChangelog.
+//
 // Usage:
 //
 // To use the updater, you should include it in your application's main package:
@@ -16,14 +19,16 @@
 //	import "github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/updater"
 //
 //	func main() {
-//	    if err := updater.UpdateApplication(); err != nil {
+//	    opts := updater.UpdateOptions{Interactive: true, In: os.Stdin}
+//	    if err := updater.UpdateApplication(ctx, filesystem.RealFileSystem{}, opts); err != nil {
 //	        // Handle error
 //	    }
 //	    // Continue with application logic
 //	}
 //
 // The updater assumes that the GitHub repository's release assets follow a
-// naming convention that includes the OS and architecture. It also assumes that
+// naming convention that includes the OS and architecture; the accepted names
+// can be configured through AssetNameTemplates. It also assumes that
 // the binary to be updated is named "myapp" and is located in the current working
 // directory of the running application.
 //
@@ -40,6 +45,8 @@
 // Security Considerations:
 //
 // The updater performs a direct binary replacement and restarts the application.
+// The replacement is confirmed by the user first, and the current binary is kept as a
+// timestamped backup next to it, which can be renamed back to undo the update.
 // Users should ensure that the GitHub repository and release assets are secure
 // and that the release process includes steps to verify the integrity and
 // authenticity of the binaries, such as signing the releases.
@@ -53,13 +60,16 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
@@ -68,73 +78,115 @@ import (
 const (
 	currentVersion = "1.3.3.7"
 	githubRepo     = "H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter"
+	binaryName     = "ChatGPT-Next-Web-Session-Exporter"
+
+	// binaryPerm is the permission of the binary installed by applyUpdate.
+	binaryPerm = 0755
+
+	// backupTimeLayout formats the time in the names of the backups made by applyUpdate.
+	backupTimeLayout = "20060102-150405"
 )
 
-// releaseInfo defines the structure for storing information about a GitHub release.
-// It captures the tag name of the release and a slice of assets that are part of the release.
-type releaseInfo struct {
-	TagName string `json:"tag_name"` // The name of the tag for the release.
-	Body    string `json:"body"`     // The release notes or description.
-	Assets  []struct {
-		Name               string `json:"name"`                 // The name of the asset.
-		BrowserDownloadURL string `json:"browser_download_url"` // The URL for downloading the asset.
-	} `json:"assets"` // A list of assets available for the release.
+// githubAPIURL is the address of the GitHub REST API. It is a variable so that tests can point it at a local server.
+var githubAPIURL = "https://api.github.com"
+
+// Release describes a GitHub release as returned by the GitHub Releases API.
+// It captures the tag name of the release, its notes, and the assets that are part of the release.
+type Release struct {
+	TagName     string         `json:"tag_name"`     // The name of the tag for the release.
+	Body        string         `json:"body"`         // The release notes or description.
+	Assets      []ReleaseAsset `json:"assets"`       // A list of assets available for the release.
+	Draft       bool           `json:"draft"`        // Whether the release is an unpublished draft.
+	Prerelease  bool           `json:"prerelease"`   // Whether the release is marked as a pre-release.
+	PublishedAt time.Time      `json:"published_at"` // When the release was published; zero for drafts.
+}
+
+// ReleaseAsset is a downloadable file attached to a Release.
+type ReleaseAsset struct {
+	Name               string `json:"name"`                 // The name of the asset.
+	BrowserDownloadURL string `json:"browser_download_url"` // The URL for downloading the asset.
 }
 
+// Patterns of the Markdown emphasis removed by formatReleaseNotes. The markers must enclose non-space text,
+// and underscores only count at word boundaries, so identifiers such as snake_case names are left alone.
+var (
+	boldStarPattern         = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`)
+	italicStarPattern       = regexp.MustCompile(`\*(\S(?:.*?\S)?)\*`)
+	boldUnderscorePattern   = regexp.MustCompile(`(^|\W)__(\S(?:.*?\S)?)__(\W|$)`)
+	italicUnderscorePattern = regexp.MustCompile(`(^|\W)_(\S(?:.*?\S)?)_(\W|$)`)
+)
+
 // printReleaseNotes takes a string containing the body of a GitHub release,
-// which is typically formatted using Markdown, and prints it to the console
-// with some basic formatting applied for improved readability.
+// which is typically formatted using Markdown, and prints it to w
+// with some basic formatting applied for improved readability, as described
+// by formatReleaseNotes.
+//
+// Parameters:
+// - w: Where the release notes are printed, usually the console.
+// - body: The Markdown-formatted release notes as a string.
+func printReleaseNotes(w io.Writer, body string) {
+	fmt.Fprint(w, formatReleaseNotes(body))
+}
+
+// formatReleaseNotes converts the Markdown body of a GitHub release into plain text for the terminal.
 //
 // The function performs the following transformations:
-//   - Converts Markdown headings (denoted by "## ") into all-uppercase text
-//     preceded by a newline, to visually separate sections when printed.
+//   - Converts Markdown headings (denoted by "## ") into a blank line followed by the heading text,
+//     to visually separate sections when printed.
 //   - Normalizes newline characters across different operating systems to ensure
 //     consistent line breaks.
-//   - Splits the body into individual lines and inspects each line to detect URLs.
-//
-// Lines that contain URLs are prefixed with "Link:" to highlight them as clickable
-// links, even though they are not actually clickable in the terminal output.
-// This is a simple heuristic and may not recognize all URLs, especially if they
-// do not start with "http" or are part of Markdown link syntax.
+//   - Replaces the "* " or "- " marker of list items with a bullet ("•"), keeping their indentation.
+//   - Removes the markers of bold and italic text ("**text**", "*text*", "__text__", and "_text_"),
+//     since rendering them in bold would require detecting the capabilities of the terminal.
+//   - Prefixes lines that contain URLs with "Link:" to highlight them as links,
+//     even though they are not actually clickable in the terminal output.
 //
 // This function does not fully render Markdown as seen in web browsers. It is
 // intended to provide a basic, text-only representation that is suitable for
-// terminal output. For more complex Markdown rendering, a dedicated Markdown
-// parser would be more appropriate.
-//
-// Parameters:
-// - body: The Markdown-formatted release notes as a string.
-//
-// Note: The current implementation is a simple approach and may not be suitable
-// for all Markdown features. It is recommended to use a proper Markdown parser
-// for a more accurate and complete transformation.
-func printReleaseNotes(body string) {
-	// Replace Markdown headings with all-uppercase plus newlines
+// terminal output. It does not recognize URLs that do not start with "https://",
+// and emphasis spanning several lines is left as it is.
+func formatReleaseNotes(body string) string {
+	// Replace Markdown headings with newlines
 	body = strings.ReplaceAll(body, "## ", "\n")
 	body = strings.ReplaceAll(body, "\r\n", "\n") // Normalize newlines for cross-platform compatibility
 
-	// Print each line, checking if it's a link to format it differently
-	lines := strings.Split(body, "\n")
-	for _, line := range lines {
+	var builder strings.Builder
+	for _, line := range strings.Split(body, "\n") {
+		line = formatReleaseNotesLine(line)
 		if strings.Contains(line, "https://") {
 			// This check assumes that any URLs will use HTTPS.
-			// In a real application, consider using a more robust method
-			// to detect both HTTP and HTTPS links.
-			fmt.Printf("Link: %s\n", line)
-		} else {
-			fmt.Println(line)
+			builder.WriteString("Link: ")
 		}
+		builder.WriteString(line + "\n")
 	}
+	return builder.String()
+}
+
+// formatReleaseNotesLine turns a list marker at the start of the line into a bullet and removes emphasis markers.
+func formatReleaseNotesLine(line string) string {
+	content := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(content)]
+	if strings.HasPrefix(content, "* ") || strings.HasPrefix(content, "- ") {
+		line = indent + "• " + content[2:]
+	}
+	line = boldStarPattern.ReplaceAllString(line, "$1")
+	line = italicStarPattern.ReplaceAllString(line, "$1")
+	line = boldUnderscorePattern.ReplaceAllString(line, "$1$2$3")
+	return italicUnderscorePattern.ReplaceAllString(line, "$1$2$3")
 }
 
 // getLatestRelease fetches the latest release information from the GitHub repository.
 // It constructs a request to the GitHub API to retrieve the latest release and parses
-// the response into a releaseInfo struct.
+// the response into a Release struct.
 //
-// Returns a pointer to a releaseInfo struct and nil error on success.
+// Returns a pointer to a Release struct and nil error on success.
 // On failure, it returns nil and an error indicating what went wrong.
-func getLatestRelease() (*releaseInfo, error) {
-	resp, err := http.Get(fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", githubRepo))
+func getLatestRelease(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/repos/%s/releases/latest", githubAPIURL, githubRepo), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -144,7 +196,7 @@ func getLatestRelease() (*releaseInfo, error) {
 		return nil, fmt.Errorf("GitHub API response status: %s", resp.Status)
 	}
 
-	var release releaseInfo
+	var release Release
 	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
 		return nil, err
 	}
@@ -152,129 +204,287 @@ func getLatestRelease() (*releaseInfo, error) {
 	return &release, nil
 }
 
+// UpdateOptions controls how UpdateApplication asks for confirmation before replacing the binary.
+type UpdateOptions struct {
+	AutoConfirm bool      // AutoConfirm applies the update without asking for confirmation.
+	Interactive bool      // Interactive reports whether a user reads the output and answers on In.
+	In          io.Reader // In is where the confirmation is read from; nil means os.Stdin.
+	Out         io.Writer // Out is where the release notes, progress, and prompts are written; nil means os.Stdout.
+	Changelog   bool      // Changelog shows the notes of every release since the current version instead of only the latest.
+
+	// Fetcher, Downloader, and Replacer perform the steps of the update that reach the network and the
+	// running process, so that tests can replace them with fakes. nil means the default implementation,
+	// which asks GitHub, downloads over HTTP, and restarts the binary as a new process.
+	Fetcher    ReleaseFetcher
+	Downloader AssetDownloader
+	Replacer   BinaryReplacer
+
+	// GOOS and GOARCH select the platform of the release asset; empty means runtime.GOOS and runtime.GOARCH.
+	GOOS, GOARCH string
+}
+
+// withDefaults returns the options with every unset dependency and platform replaced by its default.
+func (opts UpdateOptions) withDefaults() UpdateOptions {
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	if opts.Fetcher == nil {
+		opts.Fetcher = githubReleaseFetcher{}
+	}
+	if opts.Downloader == nil {
+		opts.Downloader = httpAssetDownloader{}
+	}
+	if opts.Replacer == nil {
+		opts.Replacer = executableReplacer{}
+	}
+	if opts.GOOS == "" {
+		opts.GOOS = runtime.GOOS
+	}
+	if opts.GOARCH == "" {
+		opts.GOARCH = runtime.GOARCH
+	}
+	return opts
+}
+
+// ErrConfirmationRequired is returned by UpdateApplication when an update is available but could
+// not be confirmed, because the run is not interactive and UpdateOptions.AutoConfirm is not set.
+var ErrConfirmationRequired = errors.New("update not applied: confirmation required, but the run is not interactive")
+
 // UpdateApplication checks the GitHub repository for a newer release of the application.
 // If a newer release is found, it downloads the corresponding binary for the current
 // platform and architecture, replaces the current executable with the downloaded binary,
 // and restarts the application.
 //
-// Returns nil if the application is up to date or the update is successfully applied.
+// The replacement is confirmed on opts.In unless opts.AutoConfirm is set. When it is not set and
+// the run is not interactive, the update is skipped before downloading anything and
+// ErrConfirmationRequired is returned, so that scripts never block on a prompt.
+//
+// Returns nil if the application is up to date, the update is successfully applied, or the user declines it.
 // If an error occurs during the update process, it returns a non-nil error.
-func UpdateApplication(rfs filesystem.FileSystem) error {
-	ctx := context.Background()
-	reader := bufio.NewReader(os.Stdin)
-	release, err := getLatestRelease()
+func UpdateApplication(ctx context.Context, rfs filesystem.FileSystem, opts UpdateOptions) error {
+	opts = opts.withDefaults()
+	release, err := opts.Fetcher.LatestRelease(ctx)
 	if err != nil {
 		return fmt.Errorf("error fetching latest release: %w", err)
 	}
 
-	if release.TagName == currentVersion {
-		fmt.Println("No update available.")
+	// Only a newer release is installed, so that an older or retagged "latest" release never
+	// downgrades the binary, even without a prompt.
+	if compareVersions(release.TagName, currentVersion) <= 0 {
+		fmt.Fprintln(opts.Out, "No update available.")
 		return nil
 	}
+	if !opts.Interactive && !opts.AutoConfirm {
+		fmt.Fprintf(opts.Out, "Update available: %s. Skipped because it cannot be confirmed without a terminal.\n", release.TagName)
+		return ErrConfirmationRequired
+	}
 
-	// Print release notes
-	fmt.Printf("Release notes for version %s:\n", release.TagName)
-	printReleaseNotes(release.Body)
+	// Print release notes, of every release the update jumps over if requested. The notes of the
+	// latest release are shown instead if the list of releases cannot be fetched.
+	var releases []Release
+	if opts.Changelog {
+		releases, _ = releasesSince(ctx, opts.Fetcher, currentVersion)
+	}
+	if len(releases) > 0 {
+		fmt.Fprint(opts.Out, FormatChangelog(releases))
+	} else {
+		fmt.Fprintf(opts.Out, "Release notes for version %s:\n", release.TagName)
+		printReleaseNotes(opts.Out, release.Body)
+	}
 
-	// Pass only the release to downloadAndUpdate
-	tempFileName, err := downloadAndUpdate(release)
+	tempFileName, err := downloadAndUpdate(ctx, release, opts)
 	if err != nil {
 		return err
 	}
+	// The download is removed unless it was installed, so that a declined or failed update does not
+	// leave an executable in the temporary directory.
+	installed := false
+	defer func() {
+		if !installed {
+			os.Remove(tempFileName) // ignore error; the download may already be gone
+		}
+	}()
 
-	// Pass the context, reader, and filesystem to applyUpdate
-	if err := applyUpdate(ctx, reader, rfs, tempFileName); err != nil {
+	in := opts.In
+	if in == nil {
+		in = os.Stdin
+	}
+	applied, err := applyUpdate(ctx, opts.Out, bufio.NewReader(in), rfs, opts.Replacer, tempFileName, release.TagName, opts.AutoConfirm)
+	if err != nil || !applied {
 		return err
 	}
+	installed = true
 
-	restartApplication()
-	return nil
+	fmt.Fprintln(opts.Out, "Update applied. Restarting application...")
+	return opts.Replacer.Restart()
 }
 
-// downloadAndUpdate handles the downloading and updating of the application.
+// downloadAndUpdate downloads the asset of the release for the platform of opts with opts.Downloader.
 // It returns the name of the downloaded file or an error.
-func downloadAndUpdate(release *releaseInfo) (string, error) {
-	fmt.Printf("Update available: %s\n", release.TagName)
-	fmt.Println("Downloading update...")
+func downloadAndUpdate(ctx context.Context, release *Release, opts UpdateOptions) (string, error) {
+	fmt.Fprintf(opts.Out, "Update available: %s\n", release.TagName)
+	fmt.Fprintln(opts.Out, "Downloading update...")
 
-	assetURL, err := findMatchingAsset(release)
+	assetURL, err := findMatchingAsset(release, opts.GOOS, opts.GOARCH)
 	if err != nil {
 		return "", err
 	}
 
-	tempFileName, err := downloadAsset(assetURL)
+	tempFileName, err := opts.Downloader.DownloadAsset(ctx, assetURL)
 	if err != nil {
 		return "", err
 	}
 
-	fmt.Println("Update downloaded.")
+	fmt.Fprintln(opts.Out, "Update downloaded.")
 	return tempFileName, nil
 }
 
-// findMatchingAsset finds and returns the URL of the asset that matches the current platform.
-func findMatchingAsset(release *releaseInfo) (string, error) {
-	for _, asset := range release.Assets {
-		if asset.Name == fmt.Sprintf("ChatGPT-Next-Web-Session-Exporter-%s-%s", runtime.GOOS, runtime.GOARCH) {
-			return asset.BrowserDownloadURL, nil
-		}
+// findMatchingAsset finds and returns the URL of the asset built for the given operating system and
+// architecture. The asset names tried are described by AssetNameTemplates.
+func findMatchingAsset(release *Release, goos, goarch string) (string, error) {
+	asset, err := matchAsset(release, AssetNameTemplates, goos, goarch)
+	if err != nil {
+		return "", err
 	}
-	return "", fmt.Errorf("no binary for the current platform")
+	return asset.BrowserDownloadURL, nil
 }
 
 // downloadAsset downloads the asset from the given URL and writes it to a temporary file.
 // It returns the name of the temporary file or an error.
-func downloadAsset(assetURL string) (string, error) {
-	resp, err := http.Get(assetURL)
-	if err != nil {
-		return "", fmt.Errorf("error downloading update: %w", err)
-	}
-	defer resp.Body.Close()
-
+//
+// When the server reports a Content-Length, the number of bytes received is compared against it.
+// A partial download, for example after the connection dropped, is treated as a failure and the
+// temporary file is removed so that a truncated binary can never be installed.
+func downloadAsset(ctx context.Context, assetURL string) (string, error) {
 	out, err := os.CreateTemp("", "ChatGPT-Next-Web-Session-Exporter-update-*")
 	if err != nil {
 		return "", fmt.Errorf("error creating temp file: %w", err)
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	_, err = fetchAsset(ctx, assetURL, out, nil)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return "", err
+		var incomplete *incompleteDownloadError
+		if errors.As(err, &incomplete) {
+			fmt.Fprintf(os.Stderr, "Warning: download incomplete, received %d of %d bytes.\n", incomplete.received, incomplete.expected)
+		}
+		os.Remove(out.Name()) // ignore error; we're already handling an error
+		return "", fmt.Errorf("error downloading update: %w", err)
 	}
 
 	return out.Name(), nil
 }
 
+// incompleteDownloadError reports a download that ended before the announced Content-Length was received.
+type incompleteDownloadError struct {
+	received, expected int64
+}
+
+// Error describes how much of the download was received.
+func (e *incompleteDownloadError) Error() string {
+	return fmt.Sprintf("incomplete download: received %d of %d bytes", e.received, e.expected)
+}
+
+// fetchAsset downloads the asset at assetURL into w and returns the number of bytes written.
+// If progress is not nil, it is called after every chunk with the bytes received so far and the
+// total size, which is -1 when the server does not report one. A download shorter than the
+// reported Content-Length fails with an *incompleteDownloadError.
+func fetchAsset(ctx context.Context, assetURL string, w io.Writer, progress func(downloaded, total int64)) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	if progress != nil {
+		body = &filesystem.ProgressReader{R: resp.Body, Total: resp.ContentLength, Progress: progress}
+	}
+	written, err := io.Copy(w, body)
+	if err != nil {
+		return written, err
+	}
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return written, &incompleteDownloadError{received: written, expected: resp.ContentLength}
+	}
+	return written, nil
+}
+
 // applyUpdate applies the update by replacing the current binary with the new one.
-// It takes the name of the temporary file containing the new binary as an argument.
-func applyUpdate(ctx context.Context, reader *bufio.Reader, rfs filesystem.FileSystem, tempFileName string) error {
-	// Confirm whether to overwrite the existing binary
-	shouldOverwrite, err := interactivity.ConfirmOverwrite(rfs, ctx, reader, "ChatGPT-Next-Web-Session-Exporter")
+// It takes the name of the temporary file containing the new binary and the version of the release.
+//
+// When a binary is already installed, the user confirms the replacement in a prompt naming both
+// versions and the path of the binary, unless autoConfirm is set. The current binary is copied to a
+// timestamped backup next to it before it is replaced, so that the update can be undone by renaming
+// the backup. The new binary is installed by replacer. The prompt and progress are written to w.
+// It reports whether the update was applied.
+func applyUpdate(ctx context.Context, w io.Writer, reader *bufio.Reader, rfs filesystem.FileSystem, replacer BinaryReplacer, tempFileName, newVersion string, autoConfirm bool) (bool, error) {
+	exists, err := rfs.FileExists(binaryName)
 	if err != nil {
-		return fmt.Errorf("error during overwrite confirmation: %w", err)
+		return false, fmt.Errorf("error during overwrite confirmation: %w", err)
 	}
-	if !shouldOverwrite {
-		fmt.Println("Update cancelled by the user.")
-		return nil
+	if exists {
+		path, err := filepath.Abs(binaryName)
+		if err != nil {
+			path = binaryName
+		}
+		if autoConfirm {
+			fmt.Fprintf(w, "Replacing %s with %s at %s.\n", displayVersion(currentVersion), displayVersion(newVersion), path)
+		} else {
+			prompt := fmt.Sprintf("Replace %s with %s at %s? (Y/n): ", displayVersion(currentVersion), displayVersion(newVersion), path)
+			confirmed, err := interactivity.Confirm(ctx, reader, interactivity.NewPrinter(w), prompt, true)
+			if err != nil {
+				return false, fmt.Errorf("error during overwrite confirmation: %w", err)
+			}
+			if !confirmed {
+				fmt.Fprintln(w, "Update cancelled by the user.")
+				return false, nil
+			}
+		}
+
+		backupName, err := backupBinary(rfs, time.Now())
+		if err != nil {
+			return false, fmt.Errorf("error backing up binary: %w", err)
+		}
+		fmt.Fprintf(w, "Current binary backed up to %s\n", backupName)
 	}
 
 	// Replace the current binary with the new one
-	if err := os.Rename(tempFileName, "ChatGPT-Next-Web-Session-Exporter"); err != nil {
-		return fmt.Errorf("error replacing binary: %w", err)
+	if err := replacer.ReplaceBinary(rfs, tempFileName); err != nil {
+		return false, err
 	}
-	return nil
+	return true, nil
 }
 
-// restartApplication restarts the application.
-func restartApplication() {
-	fmt.Println("Update applied. Restarting application...")
-	cmd := exec.Command(os.Args[0], os.Args[1:]...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Start(); err != nil {
-		fmt.Fprintf(os.Stderr, "error restarting application: %v", err)
-		return
+// backupBinary copies the current binary to "<binary>.<time>.bak", keeping its permissions,
+// and returns the name of the backup.
+func backupBinary(rfs filesystem.FileSystem, now time.Time) (string, error) {
+	data, err := rfs.ReadFile(binaryName)
+	if err != nil {
+		return "", err
+	}
+	perm := os.FileMode(binaryPerm)
+	if info, err := rfs.Stat(binaryName); err == nil && info.Mode().Perm() != 0 {
+		perm = info.Mode().Perm()
 	}
+	backupName := fmt.Sprintf("%s.%s.bak", binaryName, now.Format(backupTimeLayout))
+	if err := rfs.WriteFile(backupName, data, perm); err != nil {
+		return "", err
+	}
+	return backupName, nil
+}
 
-	// Exit the current process
-	os.Exit(0)
+// displayVersion returns the version with a single leading "v", as release tags may or may not have one.
+func displayVersion(version string) string {
+	return "v" + strings.TrimPrefix(version, "v")
 }
@@ -0,0 +1,65 @@
+package updater
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// DefaultAssetNameTemplate is the name of the release assets built by the release workflow.
+const DefaultAssetNameTemplate = "ChatGPT-Next-Web-Session-Exporter-{os}-{arch}"
+
+// AssetNameTemplates lists the names of the release asset built for a platform, tried in order until
+// one matches an asset of the release. The placeholders {os} and {arch} are replaced with runtime.GOOS
+// and runtime.GOARCH, and {version} with the tag name of the release without a leading "v", so that
+// "v{version}" reproduces the tag. Applications whose release workflow names assets differently
+// can replace or extend the list before updating.
+var AssetNameTemplates = []string{DefaultAssetNameTemplate}
+
+// expandAssetName fills the placeholders of an asset name template.
+func expandAssetName(template, tag, goos, goarch string) string {
+	return strings.NewReplacer(
+		"{version}", strings.TrimPrefix(tag, "v"),
+		"{os}", goos,
+		"{arch}", goarch,
+	).Replace(template)
+}
+
+// matchAsset returns the first asset of the release matching one of the templates for the given platform.
+//
+// When nothing matches, the error lists the names that were tried and the names of all assets of the
+// release, so that a change in the naming of the release workflow can be diagnosed.
+func matchAsset(release *Release, templates []string, goos, goarch string) (ReleaseAsset, error) {
+	tried := make([]string, 0, len(templates))
+	for _, template := range templates {
+		name := expandAssetName(template, release.TagName, goos, goarch)
+		if asset, ok := release.asset(name); ok {
+			return asset, nil
+		}
+		tried = append(tried, name)
+	}
+
+	seen := make([]string, 0, len(release.Assets))
+	for _, asset := range release.Assets {
+		seen = append(seen, asset.Name)
+	}
+	return ReleaseAsset{}, fmt.Errorf("no binary for the current platform in release %s: tried %s, release has %s",
+		release.TagName, quotedList(tried), quotedList(seen))
+}
+
+// platformAsset returns the asset of the release built for the current operating system and architecture.
+func (r *Release) platformAsset() (ReleaseAsset, error) {
+	return matchAsset(r, AssetNameTemplates, runtime.GOOS, runtime.GOARCH)
+}
+
+// quotedList formats names as a comma-separated list of quoted strings, or "no assets" when empty.
+func quotedList(names []string) string {
+	if len(names) == 0 {
+		return "no assets"
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,95 @@
+package updater
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ReleasesSince fetches the releases of the application from GitHub and returns the published stable
+// releases newer than version, newest first. An empty version means the version of the running
+// application, so the result lists every release an update would jump over.
+//
+// Only the most recent 100 releases are considered. It returns an error if the request fails or
+// the response cannot be decoded.
+func ReleasesSince(ctx context.Context, version string) ([]Release, error) {
+	return releasesSince(ctx, githubReleaseFetcher{}, version)
+}
+
+// releasesSince is ReleasesSince with the releases listed by fetcher.
+func releasesSince(ctx context.Context, fetcher ReleaseFetcher, version string) ([]Release, error) {
+	if version == "" {
+		version = currentVersion
+	}
+	releases, err := fetcher.ListReleases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var newer []Release
+	for _, release := range releases {
+		if !release.Draft && !release.Prerelease && compareVersions(release.TagName, version) > 0 {
+			newer = append(newer, release)
+		}
+	}
+	slices.SortStableFunc(newer, func(a, b Release) int {
+		return compareVersions(b.TagName, a.TagName)
+	})
+	return newer, nil
+}
+
+// FormatChangelog concatenates the notes of the releases in the given order, each under a heading
+// with its version, formatted for the terminal like the notes shown before an update.
+func FormatChangelog(releases []Release) string {
+	var builder strings.Builder
+	for i, release := range releases {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		fmt.Fprintf(&builder, "Release notes for version %s:\n", displayVersion(release.TagName))
+		builder.WriteString(formatReleaseNotes(release.Body))
+	}
+	return builder.String()
+}
+
+// compareVersions compares two semantic versions, returning -1, 0, or +1 like cmp.Compare.
+// A leading "v" and build metadata after "+" are ignored, the dot-separated numbers are compared in
+// order with missing ones counting as 0, so "1.2" equals "1.2.0", and a version with a pre-release
+// suffix after "-" is older than the same version without one. Parts that are not numbers count as 0.
+func compareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+	for i := 0; i < max(len(aCore), len(bCore)); i++ {
+		if c := cmp.Compare(versionNumber(aCore, i), versionNumber(bCore, i)); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+// splitVersion splits a version into its dot-separated numbers and its pre-release suffix.
+func splitVersion(version string) ([]string, string) {
+	version, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(version), "v"), "+")
+	core, pre, _ := strings.Cut(version, "-")
+	return strings.Split(core, "."), pre
+}
+
+// versionNumber returns the i-th number of a version split by splitVersion, or 0 if it is missing.
+func versionNumber(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	n, _ := strconv.Atoi(parts[i])
+	return n
+}
@@ -0,0 +1,185 @@
+package updater
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumAssetNames are the names of release assets listing the SHA-256 checksums of all assets,
+// in the "<hex digest>  <file name>" format of sha256sum. A "<asset name>.sha256" asset is preferred.
+var checksumAssetNames = []string{"checksums.txt", "SHA256SUMS"}
+
+// maxChecksumListingSize is the largest checksum asset that is read, in bytes. A listing of every asset
+// of a release is a few kilobytes, so a larger asset is not read into memory.
+const maxChecksumListingSize = 1 << 20
+
+// DownloadOptions controls how DownloadRelease downloads a release asset.
+type DownloadOptions struct {
+	// ProgressCallback, if not nil, is called as the download proceeds with the number of bytes
+	// received so far and the total size, which is -1 when the server does not report one.
+	ProgressCallback func(downloaded, total int64)
+
+	// OverwriteExisting replaces a file of the same name in the destination directory.
+	// Without it, DownloadRelease fails with an error wrapping fs.ErrExist.
+	OverwriteExisting bool
+
+	// VerifyChecksum compares the SHA-256 digest of the download with the checksum published in the
+	// release, either as a "<asset name>.sha256" asset or in a checksums.txt or SHA256SUMS asset.
+	// The download fails if the release publishes no checksum for the asset or the digests differ.
+	VerifyChecksum bool
+
+	// Downloader downloads the checksum assets of VerifyChecksum, so that tests can replace it with a fake.
+	// nil means the default implementation, which downloads over HTTP.
+	Downloader AssetDownloader
+}
+
+// LatestRelease fetches the latest release of the application from GitHub, for use with DownloadRelease.
+func LatestRelease(ctx context.Context) (*Release, error) {
+	return getLatestRelease(ctx)
+}
+
+// DownloadRelease downloads the asset of release built for the current operating system and architecture
+// into destDir without applying it, and returns the path of the downloaded file. This allows pre-downloading
+// an update, for example to install it later on a machine without network access.
+//
+// The file is first written to a temporary file in destDir and only renamed to the asset name once the
+// download is complete and, if requested, its checksum verified, so a failed download never leaves
+// a partial file behind under the final name.
+//
+// It returns an error if the release has no asset for the platform, the file already exists and
+// opts.OverwriteExisting is not set, the download fails or is incomplete, or the checksum does not match.
+func DownloadRelease(ctx context.Context, release *Release, destDir string, opts DownloadOptions) (string, error) {
+	asset, err := release.platformAsset()
+	if err != nil {
+		return "", err
+	}
+
+	destPath := filepath.Join(destDir, asset.Name)
+	if !opts.OverwriteExisting {
+		if _, err := os.Stat(destPath); err == nil {
+			return "", fmt.Errorf("%s: %w", destPath, fs.ErrExist)
+		}
+	}
+
+	var expected string
+	if opts.VerifyChecksum {
+		var err error
+		downloader := opts.Downloader
+		if downloader == nil {
+			downloader = httpAssetDownloader{}
+		}
+		if expected, err = releaseChecksum(ctx, downloader, release, asset.Name); err != nil {
+			return "", err
+		}
+	}
+
+	tmp, err := os.CreateTemp(destDir, "."+asset.Name+".download-*")
+	if err != nil {
+		return "", fmt.Errorf("error creating temp file: %w", err)
+	}
+	hash := sha256.New()
+	_, err = fetchAsset(ctx, asset.BrowserDownloadURL, io.MultiWriter(tmp, hash), opts.ProgressCallback)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && expected != "" {
+		if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+			err = fmt.Errorf("checksum mismatch for %s: got %s, want %s", asset.Name, actual, expected)
+		}
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0755)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), destPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name()) // ignore error; we're already handling an error
+		return "", fmt.Errorf("error downloading %s: %w", asset.Name, err)
+	}
+	return destPath, nil
+}
+
+// asset returns the asset of the release with the given name.
+func (r *Release) asset(name string) (ReleaseAsset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return ReleaseAsset{}, false
+}
+
+// releaseChecksum returns the lowercase hex SHA-256 digest the release publishes for the named asset,
+// downloading the checksum assets with downloader.
+func releaseChecksum(ctx context.Context, downloader AssetDownloader, release *Release, name string) (string, error) {
+	candidates := append([]string{name + ".sha256"}, checksumAssetNames...)
+	for _, candidate := range candidates {
+		asset, ok := release.asset(candidate)
+		if !ok {
+			continue
+		}
+		listing, err := fetchChecksumListing(ctx, downloader, asset.BrowserDownloadURL)
+		if err != nil {
+			return "", fmt.Errorf("error downloading %s: %w", candidate, err)
+		}
+		if digest, ok := findChecksum(listing, name); ok {
+			return digest, nil
+		}
+	}
+	return "", errors.New("release " + release.TagName + " publishes no checksum for " + name)
+}
+
+// fetchChecksumListing downloads the checksum asset at assetURL with downloader and reads at most
+// maxChecksumListingSize bytes of it. The downloaded file is removed again.
+func fetchChecksumListing(ctx context.Context, downloader AssetDownloader, assetURL string) (string, error) {
+	tempFileName, err := downloader.DownloadAsset(ctx, assetURL)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tempFileName) // ignore error; the listing has been read or failed to be read
+
+	file, err := os.Open(tempFileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	listing, err := io.ReadAll(io.LimitReader(file, maxChecksumListingSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(listing) > maxChecksumListingSize {
+		return "", fmt.Errorf("checksum listing exceeds %d bytes", maxChecksumListingSize)
+	}
+	return string(listing), nil
+}
+
+// findChecksum looks up the digest of the named file in sha256sum output. A listing made of a single
+// line holding only a digest, as a "<name>.sha256" asset, matches any name; in a listing of several
+// files, only a line naming the file does. The listing is split in memory, since fetchChecksumListing
+// caps its size, so that no line is too long to be read.
+func findChecksum(listing, name string) (string, bool) {
+	var lines [][]string
+	for _, line := range strings.Split(listing, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+	if len(lines) == 1 && len(lines[0]) == 1 {
+		return strings.ToLower(lines[0][0]), true
+	}
+	for _, fields := range lines {
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,708 @@
+package updater
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// platformAssetName returns the name the default template gives the asset for the current platform.
+func platformAssetName() string {
+	return expandAssetName(DefaultAssetNameTemplate, "", runtime.GOOS, runtime.GOARCH)
+}
+
+// TestDownloadRelease verifies that the platform asset is downloaded into the destination directory
+// with progress reports, that existing files are only replaced when requested, and that checksums are verified.
+func TestDownloadRelease(t *testing.T) {
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	checksums := hex.EncodeToString(sum[:]) + "  " + platformAssetName() + "\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/binary":
+			w.Write(binary)
+		case "/checksums.txt":
+			w.Write([]byte(checksums))
+		case "/bad-checksums.txt":
+			w.Write([]byte(strings.Repeat("0", 64) + "  " + platformAssetName() + "\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	release := &Release{TagName: "v9.9.9", Assets: []ReleaseAsset{
+		{Name: platformAssetName(), BrowserDownloadURL: server.URL + "/binary"},
+		{Name: "checksums.txt", BrowserDownloadURL: server.URL + "/checksums.txt"},
+	}}
+	dir := t.TempDir()
+
+	var lastDownloaded, lastTotal int64
+	opts := DownloadOptions{
+		VerifyChecksum:   true,
+		ProgressCallback: func(downloaded, total int64) { lastDownloaded, lastTotal = downloaded, total },
+	}
+	path, err := DownloadRelease(context.Background(), release, dir, opts)
+	if err != nil {
+		t.Fatalf("DownloadRelease() returned an error: %v", err)
+	}
+	if path != filepath.Join(dir, platformAssetName()) {
+		t.Errorf("DownloadRelease() = %q, want the asset name in the destination directory", path)
+	}
+	if got, _ := os.ReadFile(path); string(got) != string(binary) {
+		t.Errorf("downloaded content = %q, want %q", got, binary)
+	}
+	if lastDownloaded != int64(len(binary)) || lastTotal != int64(len(binary)) {
+		t.Errorf("last progress = %d/%d, want %d/%d", lastDownloaded, lastTotal, len(binary), len(binary))
+	}
+
+	if _, err := DownloadRelease(context.Background(), release, dir, DownloadOptions{}); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("DownloadRelease() over an existing file returned %v, want fs.ErrExist", err)
+	}
+	if _, err := DownloadRelease(context.Background(), release, dir, DownloadOptions{OverwriteExisting: true}); err != nil {
+		t.Errorf("DownloadRelease() with OverwriteExisting returned %v", err)
+	}
+
+	release.Assets[1].BrowserDownloadURL = server.URL + "/bad-checksums.txt"
+	if _, err := DownloadRelease(context.Background(), release, dir, DownloadOptions{OverwriteExisting: true, VerifyChecksum: true}); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("DownloadRelease() with a wrong checksum returned %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("a failed download left files behind: %v", entries)
+	}
+
+	release.Assets = release.Assets[:1]
+	if _, err := DownloadRelease(context.Background(), release, dir, DownloadOptions{OverwriteExisting: true, VerifyChecksum: true}); err == nil {
+		t.Error("DownloadRelease() verified a release without checksums")
+	}
+}
+
+// TestFindChecksum verifies that a bare digest only matches any name when it is the whole listing, that
+// a long line does not end the lookup, and that a checksum listing larger than maxChecksumListingSize is not read.
+func TestFindChecksum(t *testing.T) {
+	tests := []struct {
+		name    string
+		listing string
+		want    string
+		wantOK  bool
+	}{
+		{"SingleDigest", "ABC123\n", "abc123", true},
+		{"SingleDigestBlankLines", "\nabc123\n\n", "abc123", true},
+		{"NamedLine", "abc123  other\ndef456 *app\n", "def456", true},
+		{"DigestAmongOthers", "abc123\ndef456  other\n", "", false},
+		{"SeveralDigests", "abc123\ndef456\n", "", false},
+		{"NotListed", "abc123  other\n", "", false},
+		{"LongLine", strings.Repeat("x", 70000) + "\ndef456  app\n", "def456", true},
+		{"CRLF", "def456  app\r\n", "def456", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := findChecksum(tc.listing, "app")
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("findChecksum(%q) = %q, %v; want %q, %v", tc.listing, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("abc123  other\n", maxChecksumListingSize/14+1)))
+	}))
+	defer server.Close()
+	release := &Release{TagName: "v1.2.3", Assets: []ReleaseAsset{{Name: "checksums.txt", BrowserDownloadURL: server.URL}}}
+	if _, err := releaseChecksum(context.Background(), httpAssetDownloader{}, release, "app"); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("releaseChecksum() with an oversized listing returned %v", err)
+	}
+
+	// The checksum assets are downloaded with the Downloader of the options, and removed once read.
+	downloader := contentDownloader{dir: t.TempDir(), content: "DEF456  app\n"}
+	if digest, err := releaseChecksum(context.Background(), downloader, release, "app"); err != nil || digest != "def456" {
+		t.Errorf("releaseChecksum() with a fake downloader = %q, %v; want def456", digest, err)
+	}
+	if entries, _ := os.ReadDir(downloader.dir); len(entries) != 0 {
+		t.Errorf("the downloaded checksum listing was left behind: %v", entries)
+	}
+}
+
+// contentDownloader downloads every asset as a new file in dir holding content.
+type contentDownloader struct {
+	dir     string
+	content string
+}
+
+func (d contentDownloader) DownloadAsset(ctx context.Context, assetURL string) (string, error) {
+	out, err := os.CreateTemp(d.dir, "asset-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = out.WriteString(d.content)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return out.Name(), err
+}
+
+// TestListAvailableVersions verifies that drafts are skipped and that pre-releases and releases without
+// a binary for the current platform are filtered out according to the options.
+func TestListAvailableVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/"+githubRepo+"/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"tag_name": "v3.0.0-rc1", "prerelease": true, "published_at": "2024-03-01T00:00:00Z", "assets": [{"name": "` + platformAssetName() + `"}]},
+			{"tag_name": "v2.1.0", "draft": true, "assets": [{"name": "` + platformAssetName() + `"}]},
+			{"tag_name": "v2.0.0", "published_at": "2024-02-01T00:00:00Z", "body": "` + strings.Repeat("word ", 60) + `", "assets": [{"name": "other-platform"}]},
+			{"tag_name": "v1.0.0", "published_at": "2024-01-01T00:00:00Z", "body": "First\r\n\r\nrelease", "assets": [{"name": "` + platformAssetName() + `"}]}
+		]`))
+	}))
+	defer server.Close()
+	defer func(url string) { githubAPIURL = url }(githubAPIURL)
+	githubAPIURL = server.URL
+
+	tests := []struct {
+		name string
+		opts *VersionListOptions
+		want []string
+	}{
+		{"defaults", nil, []string{"v1.0.0"}},
+		{"with pre-releases", &VersionListOptions{FilterToCurrentPlatform: true, IncludePreReleases: true}, []string{"v3.0.0-rc1", "v1.0.0"}},
+		{"all platforms", &VersionListOptions{}, []string{"v2.0.0", "v1.0.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions, err := ListAvailableVersions(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("ListAvailableVersions() returned an error: %v", err)
+			}
+			var got []string
+			for _, version := range versions {
+				got = append(got, version.Version)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ListAvailableVersions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	fetcher := fakeFetcher{releases: []Release{{TagName: "v4.0.0"}, {TagName: "v3.9.0", Draft: true}}}
+	if versions, err := ListAvailableVersions(context.Background(), &VersionListOptions{Fetcher: fetcher}); err != nil || len(versions) != 1 || versions[0].Version != "v4.0.0" {
+		t.Errorf("ListAvailableVersions() with a fake fetcher = %+v, %v; want v4.0.0", versions, err)
+	}
+
+	versions, _ := ListAvailableVersions(context.Background(), &VersionListOptions{})
+	if snippet := versions[0].BodySnippet; len([]rune(snippet)) > releaseSnippetLength+1 || !strings.HasSuffix(snippet, "…") {
+		t.Errorf("BodySnippet of long notes = %q, want at most %d characters ending in an ellipsis", snippet, releaseSnippetLength)
+	}
+	if last := versions[1]; last.BodySnippet != "First release" || last.PublishedAt.Year() != 2024 || len(last.Assets) != 1 {
+		t.Errorf("ListAvailableVersions() returned %+v", last)
+	}
+}
+
+// TestMatchAsset verifies that asset name templates are tried in order against realistic asset lists
+// and that the error names the assets of the release when nothing matches.
+func TestMatchAsset(t *testing.T) {
+	goreleaser := "ChatGPT-Next-Web-Session-Exporter_{version}_{os}_{arch}"
+	tests := []struct {
+		name      string
+		tag       string
+		assets    []string
+		templates []string
+		goos      string
+		goarch    string
+		want      string
+	}{
+		{
+			name:      "current naming",
+			tag:       "v1.3.3.7",
+			assets:    []string{"ChatGPT-Next-Web-Session-Exporter-darwin-arm64", "ChatGPT-Next-Web-Session-Exporter-linux-amd64", "checksums.txt"},
+			templates: []string{DefaultAssetNameTemplate},
+			goos:      "linux",
+			goarch:    "amd64",
+			want:      "ChatGPT-Next-Web-Session-Exporter-linux-amd64",
+		},
+		{
+			name:      "version and underscores",
+			tag:       "v2.0.0",
+			assets:    []string{"ChatGPT-Next-Web-Session-Exporter_2.0.0_windows_amd64", "ChatGPT-Next-Web-Session-Exporter_2.0.0_linux_arm64"},
+			templates: []string{DefaultAssetNameTemplate, goreleaser},
+			goos:      "linux",
+			goarch:    "arm64",
+			want:      "ChatGPT-Next-Web-Session-Exporter_2.0.0_linux_arm64",
+		},
+		{
+			name:      "first matching template wins",
+			tag:       "v2.1.0",
+			assets:    []string{"ChatGPT-Next-Web-Session-Exporter_2.1.0_linux_amd64", "ChatGPT-Next-Web-Session-Exporter-linux-amd64"},
+			templates: []string{goreleaser, DefaultAssetNameTemplate},
+			goos:      "linux",
+			goarch:    "amd64",
+			want:      "ChatGPT-Next-Web-Session-Exporter_2.1.0_linux_amd64",
+		},
+		{
+			name:      "tag in file name",
+			tag:       "v3.0.0",
+			assets:    []string{"exporter-v3.0.0-darwin-amd64.bin"},
+			templates: []string{"exporter-v{version}-{os}-{arch}.bin"},
+			goos:      "darwin",
+			goarch:    "amd64",
+			want:      "exporter-v3.0.0-darwin-amd64.bin",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := &Release{TagName: tt.tag}
+			for _, name := range tt.assets {
+				release.Assets = append(release.Assets, ReleaseAsset{Name: name, BrowserDownloadURL: "https://example.com/" + name})
+			}
+			asset, err := matchAsset(release, tt.templates, tt.goos, tt.goarch)
+			if err != nil {
+				t.Fatalf("matchAsset() returned an error: %v", err)
+			}
+			if asset.Name != tt.want {
+				t.Errorf("matchAsset() = %q, want %q", asset.Name, tt.want)
+			}
+		})
+	}
+
+	release := &Release{TagName: "v4.0.0", Assets: []ReleaseAsset{{Name: "exporter_4.0.0_Linux_x86_64.tar.gz"}}}
+	_, err := matchAsset(release, []string{DefaultAssetNameTemplate}, "linux", "amd64")
+	if err == nil {
+		t.Fatal("matchAsset() matched a release without the platform binary")
+	}
+	for _, want := range []string{`"ChatGPT-Next-Web-Session-Exporter-linux-amd64"`, `"exporter_4.0.0_Linux_x86_64.tar.gz"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("matchAsset() error %q does not mention %s", err, want)
+		}
+	}
+}
+
+// TestFormatReleaseNotes verifies the plain-text rendering of Markdown release notes.
+func TestFormatReleaseNotes(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"plain text", "Bug fixes.", "Bug fixes.\n"},
+		{"heading", "## What's Changed\r\nFixes", "\nWhat's Changed\nFixes\n"},
+		{"star list", "* first\n* second", "• first\n• second\n"},
+		{"dash list", "- first\n  - nested", "• first\n  • nested\n"},
+		{"bold", "**Breaking:** flags renamed", "Breaking: flags renamed\n"},
+		{"italic", "now *much* faster", "now much faster\n"},
+		{"underscore bold and italic", "__Note__: _really_ works", "Note: really works\n"},
+		{"bold in list item", "* **exporter**: add diff", "• exporter: add diff\n"},
+		{"snake_case is kept", "rename read_json_file to ReadJSON", "rename read_json_file to ReadJSON\n"},
+		{"lone asterisk is kept", "2 * 3 = 6", "2 * 3 = 6\n"},
+		{"link", "**Full Changelog**: https://github.com/o/r/compare/v1...v2", "Link: Full Changelog: https://github.com/o/r/compare/v1...v2\n"},
+		{"link with underscores", "- see https://example.com/some_page_here", "Link: • see https://example.com/some_page_here\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatReleaseNotes(tt.body); got != tt.want {
+				t.Errorf("formatReleaseNotes(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyUpdate verifies that replacing an installed binary is confirmed and backed up first,
+// that declining leaves everything in place, and that a missing binary is installed without asking.
+func TestApplyUpdate(t *testing.T) {
+	newFS := func(installed bool) *filesystem.MockFileSystem {
+		mockFS := filesystem.NewMockFileSystem()
+		mockFS.Files["update.tmp"] = []byte("new")
+		if installed {
+			mockFS.Files[binaryName] = []byte("old")
+		}
+		return mockFS
+	}
+	backups := func(mockFS *filesystem.MockFileSystem) []string {
+		var names []string
+		for name := range mockFS.Files {
+			if strings.HasPrefix(name, binaryName+".") && strings.HasSuffix(name, ".bak") {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	mockFS := newFS(true)
+	applied, err := applyUpdate(context.Background(), io.Discard, bufio.NewReader(strings.NewReader("n\n")), mockFS, executableReplacer{}, "update.tmp", "v1.4.0", false)
+	if err != nil || applied {
+		t.Fatalf("declined applyUpdate() = %v, %v, want false, nil", applied, err)
+	}
+	if string(mockFS.Files[binaryName]) != "old" || len(backups(mockFS)) != 0 {
+		t.Errorf("declining changed the files: %v", mockFS.Files)
+	}
+
+	mockFS = newFS(true)
+	applied, err = applyUpdate(context.Background(), io.Discard, bufio.NewReader(strings.NewReader("\n")), mockFS, executableReplacer{}, "update.tmp", "v1.4.0", false)
+	if err != nil || !applied {
+		t.Fatalf("confirmed applyUpdate() = %v, %v, want true, nil", applied, err)
+	}
+	if string(mockFS.Files[binaryName]) != "new" {
+		t.Errorf("the binary holds %q, want the update", mockFS.Files[binaryName])
+	}
+	if names := backups(mockFS); len(names) != 1 || string(mockFS.Files[names[0]]) != "old" {
+		t.Errorf("backups = %v, want one holding the old binary", names)
+	}
+
+	mockFS = newFS(false)
+	applied, err = applyUpdate(context.Background(), io.Discard, bufio.NewReader(strings.NewReader("")), mockFS, executableReplacer{}, "update.tmp", "v1.4.0", false)
+	if err != nil || !applied || string(mockFS.Files[binaryName]) != "new" {
+		t.Errorf("installing applyUpdate() = %v, %v with %q, want the update installed", applied, err, mockFS.Files[binaryName])
+	}
+
+	if got, want := displayVersion(currentVersion)+" "+displayVersion("v1.4.0"), "v1.3.3.7 v1.4.0"; got != want {
+		t.Errorf("displayVersion() = %q, want %q", got, want)
+	}
+}
+
+// TestUpdateApplicationNonInteractive verifies that an update that cannot be confirmed is skipped
+// with ErrConfirmationRequired before anything is downloaded, and that AutoConfirm replaces the
+// binary without reading a confirmation.
+func TestUpdateApplicationNonInteractive(t *testing.T) {
+	downloads := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + githubRepo + "/releases/latest":
+			w.Write([]byte(`{"tag_name": "v9.9.9", "assets": [{"name": "` + platformAssetName() + `", "browser_download_url": "` + server.URL + `/asset"}]}`))
+		case "/asset":
+			downloads++
+			w.Write([]byte("new"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(url string) { githubAPIURL = url }(githubAPIURL)
+	githubAPIURL = server.URL
+
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files[binaryName] = []byte("old")
+	err := UpdateApplication(context.Background(), mockFS, UpdateOptions{In: strings.NewReader("yes\n")})
+	if !errors.Is(err, ErrConfirmationRequired) {
+		t.Errorf("UpdateApplication() without a terminal returned %v, want ErrConfirmationRequired", err)
+	}
+	if downloads != 0 || string(mockFS.Files[binaryName]) != "old" {
+		t.Errorf("a skipped update downloaded %d asset(s) and left the binary %q", downloads, mockFS.Files[binaryName])
+	}
+
+	mockFS.Files["update.tmp"] = []byte("new")
+	applied, err := applyUpdate(context.Background(), io.Discard, bufio.NewReader(strings.NewReader("")), mockFS, executableReplacer{}, "update.tmp", "v9.9.9", true)
+	if err != nil || !applied || string(mockFS.Files[binaryName]) != "new" {
+		t.Errorf("auto-confirmed applyUpdate() = %v, %v with %q, want the update applied", applied, err, mockFS.Files[binaryName])
+	}
+}
+
+// TestReleasesSince verifies the version comparison and that ReleasesSince returns the stable releases
+// newer than a version, newest first, with their notes concatenated by FormatChangelog.
+func TestReleasesSince(t *testing.T) {
+	comparisons := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.10.0", "1.9.9", 1},
+		{"1.2", "v1.2.0", 0},
+		{"v1.4.0", currentVersion, 1},
+		{"v1.3.3", currentVersion, -1},
+		{"v2.0.0-rc1", "v2.0.0", -1},
+		{"v2.0.0-rc2", "v2.0.0-rc1", 1},
+		{"v2.0.0+build5", "v2.0.0", 0},
+	}
+	for _, c := range comparisons {
+		if got := compareVersions(c.a, c.b); got != c.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/"+githubRepo+"/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[
+			{"tag_name": "v1.10.0", "body": "## Changes\n* Tenth"},
+			{"tag_name": "v2.0.0-rc1", "prerelease": true, "body": "Preview"},
+			{"tag_name": "v1.11.0", "draft": true, "body": "Draft"},
+			{"tag_name": "v1.9.0", "body": "Ninth"},
+			{"tag_name": "v1.4.0", "body": "Fourth"},
+			{"tag_name": "1.3.3.7", "body": "Current"}
+		]`))
+	}))
+	defer server.Close()
+	defer func(url string) { githubAPIURL = url }(githubAPIURL)
+	githubAPIURL = server.URL
+
+	releases, err := ReleasesSince(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ReleasesSince() returned an error: %v", err)
+	}
+	var got []string
+	for _, release := range releases {
+		got = append(got, release.TagName)
+	}
+	if want := "v1.10.0,v1.9.0,v1.4.0"; strings.Join(got, ",") != want {
+		t.Errorf("ReleasesSince(current) = %v, want %s", got, want)
+	}
+	want := "Release notes for version v1.10.0:\n\nChanges\n• Tenth\n\nRelease notes for version v1.9.0:\nNinth\n\nRelease notes for version v1.4.0:\nFourth\n"
+	if changelog := FormatChangelog(releases); changelog != want {
+		t.Errorf("FormatChangelog() = %q, want %q", changelog, want)
+	}
+
+	if releases, err := ReleasesSince(context.Background(), "v1.10.0"); err != nil || len(releases) != 0 {
+		t.Errorf("ReleasesSince(latest) = %v, %v, want no releases", releases, err)
+	}
+}
+
+// TestApplyUpdateExecutable verifies that the binary installed by applyUpdate on the real file system
+// is executable, although the download was not.
+func TestApplyUpdateExecutable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows does not use Unix permission bits")
+	}
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	download, err := os.CreateTemp(dir, "update-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	download.Close()
+	applied, err := applyUpdate(context.Background(), io.Discard, bufio.NewReader(strings.NewReader("")), filesystem.RealFileSystem{}, executableReplacer{}, download.Name(), "v1.4.0", true)
+	if err != nil || !applied {
+		t.Fatalf("applyUpdate() = %v, %v, want the update applied", applied, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, binaryName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != binaryPerm {
+		t.Errorf("the installed binary has mode %v, want %v", mode, os.FileMode(binaryPerm))
+	}
+}
+
+// fakeFetcher, fakeDownloader, and fakeReplacer stand in for the network and the running process in
+// tests of UpdateApplication.
+type fakeFetcher struct {
+	release  *Release
+	releases []Release
+}
+
+func (f fakeFetcher) LatestRelease(ctx context.Context) (*Release, error) { return f.release, nil }
+
+func (f fakeFetcher) ListReleases(ctx context.Context) ([]Release, error) { return f.releases, nil }
+
+type fakeDownloader struct {
+	mockFS *filesystem.MockFileSystem
+	urls   []string
+}
+
+func (d *fakeDownloader) DownloadAsset(ctx context.Context, assetURL string) (string, error) {
+	d.urls = append(d.urls, assetURL)
+	d.mockFS.Files["update.tmp"] = []byte("new")
+	return "update.tmp", nil
+}
+
+type fakeReplacer struct {
+	executableReplacer
+	restarts int
+}
+
+func (r *fakeReplacer) Restart() error {
+	r.restarts++
+	return nil
+}
+
+// TestFindMatchingAsset verifies that the asset built for each operating system and architecture is
+// picked from a release with the assets of every platform.
+func TestFindMatchingAsset(t *testing.T) {
+	platforms := [][2]string{
+		{"linux", "amd64"}, {"linux", "arm64"}, {"linux", "386"},
+		{"darwin", "amd64"}, {"darwin", "arm64"},
+		{"windows", "amd64"}, {"windows", "arm64"},
+	}
+	release := &Release{TagName: "v1.4.0"}
+	for _, p := range platforms {
+		name := expandAssetName(DefaultAssetNameTemplate, release.TagName, p[0], p[1])
+		release.Assets = append(release.Assets, ReleaseAsset{Name: name, BrowserDownloadURL: "https://example.com/" + name})
+	}
+	for _, p := range platforms {
+		t.Run(p[0]+"/"+p[1], func(t *testing.T) {
+			url, err := findMatchingAsset(release, p[0], p[1])
+			if err != nil {
+				t.Fatalf("findMatchingAsset() returned an error: %v", err)
+			}
+			if want := "https://example.com/ChatGPT-Next-Web-Session-Exporter-" + p[0] + "-" + p[1]; url != want {
+				t.Errorf("findMatchingAsset() = %q, want %q", url, want)
+			}
+		})
+	}
+
+	if _, err := findMatchingAsset(release, "freebsd", "amd64"); err == nil {
+		t.Error("findMatchingAsset() matched a platform without a binary")
+	}
+}
+
+// TestUpdateApplicationFakes verifies the version comparison, asset matching, confirmation, and restart
+// of UpdateApplication with fakes in place of GitHub, the download, and the restart.
+func TestUpdateApplicationFakes(t *testing.T) {
+	release := &Release{TagName: "v9.9.9", Assets: []ReleaseAsset{
+		{Name: "ChatGPT-Next-Web-Session-Exporter-linux-amd64", BrowserDownloadURL: "https://example.com/linux-amd64"},
+		{Name: "ChatGPT-Next-Web-Session-Exporter-darwin-arm64", BrowserDownloadURL: "https://example.com/darwin-arm64"},
+	}}
+	run := func(release *Release, answer string) (*filesystem.MockFileSystem, *fakeDownloader, *fakeReplacer, error) {
+		mockFS := filesystem.NewMockFileSystem()
+		mockFS.Files[binaryName] = []byte("old")
+		downloader := &fakeDownloader{mockFS: mockFS}
+		replacer := &fakeReplacer{}
+		err := UpdateApplication(context.Background(), mockFS, UpdateOptions{
+			Interactive: true,
+			In:          strings.NewReader(answer),
+			Fetcher:     fakeFetcher{release: release},
+			Downloader:  downloader,
+			Replacer:    replacer,
+			GOOS:        "darwin",
+			GOARCH:      "arm64",
+		})
+		return mockFS, downloader, replacer, err
+	}
+
+	_, downloader, replacer, err := run(&Release{TagName: currentVersion}, "y\n")
+	if err != nil || len(downloader.urls) != 0 || replacer.restarts != 0 {
+		t.Errorf("up-to-date UpdateApplication() = %v with downloads %v and %d restart(s), want nothing done", err, downloader.urls, replacer.restarts)
+	}
+
+	_, downloader, replacer, err = run(&Release{TagName: "v1.3.3", Assets: release.Assets}, "y\n")
+	if err != nil || len(downloader.urls) != 0 || replacer.restarts != 0 {
+		t.Errorf("older UpdateApplication() = %v with downloads %v and %d restart(s), want no downgrade", err, downloader.urls, replacer.restarts)
+	}
+
+	mockFS, downloader, replacer, err := run(release, "y\n")
+	if err != nil {
+		t.Fatalf("UpdateApplication() returned an error: %v", err)
+	}
+	if len(downloader.urls) != 1 || downloader.urls[0] != "https://example.com/darwin-arm64" {
+		t.Errorf("downloaded %v, want the darwin/arm64 asset", downloader.urls)
+	}
+	if string(mockFS.Files[binaryName]) != "new" || replacer.restarts != 1 {
+		t.Errorf("the binary holds %q after %d restart(s), want the update and one restart", mockFS.Files[binaryName], replacer.restarts)
+	}
+	if mode := mockFS.Modes[binaryName]; mode != binaryPerm {
+		t.Errorf("the binary has mode %v, want %v", mode, binaryPerm)
+	}
+
+	mockFS, _, replacer, err = run(release, "n\n")
+	if err != nil || string(mockFS.Files[binaryName]) != "old" || replacer.restarts != 0 {
+		t.Errorf("declined UpdateApplication() = %v with the binary %q and %d restart(s), want nothing replaced", err, mockFS.Files[binaryName], replacer.restarts)
+	}
+
+	_, _, _, err = run(&Release{TagName: "v9.9.9", Assets: release.Assets[:1]}, "y\n")
+	if err == nil {
+		t.Error("UpdateApplication() succeeded without a binary for the platform")
+	}
+}
+
+// TestUpdateApplicationChangelogFake verifies that the changelog shown before an update is listed by
+// the injected fetcher, without asking GitHub.
+func TestUpdateApplicationChangelogFake(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("UpdateApplication() requested %s from GitHub despite the fake fetcher", r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	defer func(url string) { githubAPIURL = url }(githubAPIURL)
+	githubAPIURL = server.URL
+
+	latest := Release{TagName: "v1.5.0", Body: "* Fifth", Assets: []ReleaseAsset{
+		{Name: "ChatGPT-Next-Web-Session-Exporter-linux-amd64", BrowserDownloadURL: "https://example.com/linux-amd64"},
+	}}
+	fetcher := fakeFetcher{release: &latest, releases: []Release{
+		{TagName: "v1.4.0", Body: "Fourth"},
+		latest,
+		{TagName: "v1.3.0", Body: "Third"},
+	}}
+	mockFS := filesystem.NewMockFileSystem()
+	var out strings.Builder
+	err := UpdateApplication(context.Background(), mockFS, UpdateOptions{
+		AutoConfirm: true,
+		Out:         &out,
+		Changelog:   true,
+		Fetcher:     fetcher,
+		Downloader:  &fakeDownloader{mockFS: mockFS},
+		Replacer:    &fakeReplacer{},
+		GOOS:        "linux",
+		GOARCH:      "amd64",
+	})
+	if err != nil {
+		t.Fatalf("UpdateApplication() returned an error: %v", err)
+	}
+	want := "Release notes for version v1.5.0:\n• Fifth\n\nRelease notes for version v1.4.0:\nFourth\n"
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("UpdateApplication() printed %q, want the changelog %q", out.String(), want)
+	}
+	if strings.Contains(out.String(), "Third") {
+		t.Errorf("UpdateApplication() printed the notes of an older release: %q", out.String())
+	}
+}
+
+// tempDownloader downloads into a new file in dir, like the default AssetDownloader.
+type tempDownloader struct{ dir string }
+
+func (d tempDownloader) DownloadAsset(ctx context.Context, assetURL string) (string, error) {
+	out, err := os.CreateTemp(d.dir, "update-*")
+	if err != nil {
+		return "", err
+	}
+	return out.Name(), out.Close()
+}
+
+// TestUpdateApplicationRemovesDownload verifies that the downloaded binary is removed when the update
+// is declined or cannot be installed.
+func TestUpdateApplicationRemovesDownload(t *testing.T) {
+	release := &Release{TagName: "v9.9.9", Assets: []ReleaseAsset{
+		{Name: "ChatGPT-Next-Web-Session-Exporter-linux-amd64", BrowserDownloadURL: "https://example.com/linux-amd64"},
+	}}
+	for _, answer := range []string{"n\n", "y\n"} {
+		dir := t.TempDir()
+		mockFS := filesystem.NewMockFileSystem()
+		mockFS.Files[binaryName] = []byte("old")
+		// The mock file system does not hold the download, so replacing the binary fails after a "y".
+		UpdateApplication(context.Background(), mockFS, UpdateOptions{
+			Interactive: true,
+			In:          strings.NewReader(answer),
+			Out:         io.Discard,
+			Fetcher:     fakeFetcher{release: release},
+			Downloader:  tempDownloader{dir: dir},
+			Replacer:    &fakeReplacer{},
+			GOOS:        "linux",
+			GOARCH:      "amd64",
+		})
+		if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+			t.Errorf("after answering %q the download directory holds %v, %v, want it empty", answer, entries, err)
+		}
+	}
+}
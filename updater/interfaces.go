@@ -0,0 +1,85 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// ReleaseFetcher fetches the releases of the application.
+type ReleaseFetcher interface {
+	// LatestRelease fetches the latest release.
+	LatestRelease(ctx context.Context) (*Release, error)
+
+	// ListReleases fetches the most recent releases, newest first, including drafts and pre-releases.
+	ListReleases(ctx context.Context) ([]Release, error)
+}
+
+// AssetDownloader downloads a release asset into a new temporary file and returns the name of the file.
+// The file must be removed again if the download fails.
+type AssetDownloader interface {
+	DownloadAsset(ctx context.Context, assetURL string) (string, error)
+}
+
+// BinaryReplacer installs a downloaded binary in place of the current one and restarts the application.
+type BinaryReplacer interface {
+	// ReplaceBinary moves the binary in tempFileName to binaryName through rfs and makes it executable.
+	ReplaceBinary(rfs filesystem.FileSystem, tempFileName string) error
+
+	// Restart starts the installed binary with the arguments of the running application and exits.
+	// It returns only if starting the binary fails.
+	Restart() error
+}
+
+// githubReleaseFetcher is the default ReleaseFetcher, which asks the GitHub Releases API.
+type githubReleaseFetcher struct{}
+
+func (githubReleaseFetcher) LatestRelease(ctx context.Context) (*Release, error) {
+	return getLatestRelease(ctx)
+}
+
+func (githubReleaseFetcher) ListReleases(ctx context.Context) ([]Release, error) {
+	return fetchReleases(ctx)
+}
+
+// httpAssetDownloader is the default AssetDownloader, which downloads over HTTP; see downloadAsset.
+type httpAssetDownloader struct{}
+
+func (httpAssetDownloader) DownloadAsset(ctx context.Context, assetURL string) (string, error) {
+	return downloadAsset(ctx, assetURL)
+}
+
+// executableReplacer is the default BinaryReplacer, which renames the download over the binary and
+// restarts it as a new process.
+type executableReplacer struct{}
+
+func (executableReplacer) ReplaceBinary(rfs filesystem.FileSystem, tempFileName string) error {
+	if err := filesystem.Rename(rfs, tempFileName, binaryName); err != nil {
+		return fmt.Errorf("error replacing binary: %w", err)
+	}
+	// The download is created by os.CreateTemp with mode 0600, so it is not executable yet.
+	// Windows does not use Unix permission bits.
+	if runtime.GOOS != "windows" {
+		if err := filesystem.Chmod(rfs, binaryName, binaryPerm); err != nil {
+			return fmt.Errorf("error making binary executable: %w", err)
+		}
+	}
+	return nil
+}
+
+func (executableReplacer) Restart() error {
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error restarting application: %w", err)
+	}
+
+	// Exit the current process
+	os.Exit(0)
+	return nil
+}
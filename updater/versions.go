@@ -0,0 +1,120 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// releaseSnippetLength is the maximum number of characters of the release notes kept in ReleaseInfo.BodySnippet.
+const releaseSnippetLength = 200
+
+// VersionListOptions controls which releases ListAvailableVersions returns.
+type VersionListOptions struct {
+	// FilterToCurrentPlatform leaves out releases without a binary for the current operating system
+	// and architecture, that is, versions this installation could not be updated to.
+	FilterToCurrentPlatform bool
+
+	// IncludePreReleases also returns releases marked as pre-releases. Drafts are never returned.
+	IncludePreReleases bool
+
+	// Fetcher fetches the releases, so that tests can replace it with a fake. nil means the default
+	// implementation, which asks GitHub.
+	Fetcher ReleaseFetcher
+}
+
+// DefaultVersionListOptions returns the options ListAvailableVersions uses when none are given:
+// only stable releases with a binary for the current platform.
+func DefaultVersionListOptions() VersionListOptions {
+	return VersionListOptions{FilterToCurrentPlatform: true}
+}
+
+// ReleaseInfo summarizes a release for display in a list of available versions.
+type ReleaseInfo struct {
+	Version     string         // The tag name of the release.
+	PublishedAt time.Time      // When the release was published.
+	Prerelease  bool           // Whether the release is marked as a pre-release.
+	BodySnippet string         // The beginning of the release notes, shortened to a few sentences.
+	Assets      []ReleaseAsset // The assets attached to the release.
+}
+
+// ListAvailableVersions fetches the releases of the application from GitHub, newest first, and returns
+// those selected by opts. A nil opts is the same as DefaultVersionListOptions.
+//
+// Only the most recent 100 releases are considered. It returns an error if the request fails or
+// the response cannot be decoded.
+func ListAvailableVersions(ctx context.Context, opts *VersionListOptions) ([]ReleaseInfo, error) {
+	if opts == nil {
+		defaults := DefaultVersionListOptions()
+		opts = &defaults
+	}
+
+	fetcher := opts.Fetcher
+	if fetcher == nil {
+		fetcher = githubReleaseFetcher{}
+	}
+	releases, err := fetcher.ListReleases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []ReleaseInfo
+	for _, release := range releases {
+		if release.Draft || (release.Prerelease && !opts.IncludePreReleases) {
+			continue
+		}
+		if _, err := release.platformAsset(); opts.FilterToCurrentPlatform && err != nil {
+			continue
+		}
+		versions = append(versions, ReleaseInfo{
+			Version:     release.TagName,
+			PublishedAt: release.PublishedAt,
+			Prerelease:  release.Prerelease,
+			BodySnippet: releaseSnippet(release.Body),
+			Assets:      release.Assets,
+		})
+	}
+	return versions, nil
+}
+
+// fetchReleases fetches the most recent 100 releases of the application from the GitHub releases list
+// endpoint, newest first, including drafts and pre-releases.
+func fetchReleases(ctx context.Context) ([]Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/repos/%s/releases?per_page=100", githubAPIURL, githubRepo), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API response status: %s", resp.Status)
+	}
+
+	var releases []Release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+// releaseSnippet collapses the whitespace of the release notes and shortens them to releaseSnippetLength
+// characters, cutting at a word boundary where possible and marking the cut with an ellipsis.
+func releaseSnippet(body string) string {
+	body = strings.Join(strings.Fields(body), " ")
+	runes := []rune(body)
+	if len(runes) <= releaseSnippetLength {
+		return body
+	}
+	cut := string(runes[:releaseSnippetLength])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
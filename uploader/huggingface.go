@@ -0,0 +1,244 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// EnvHuggingFaceToken is the environment variable that holds the Hugging Face access token.
+	EnvHuggingFaceToken = "HF_TOKEN"
+
+	// HuggingFaceURL is the base URL of the Hugging Face Hub.
+	HuggingFaceURL = "https://huggingface.co"
+
+	// huggingFaceRetries is the default number of extra attempts for requests failing with a 5xx status.
+	huggingFaceRetries = 3
+
+	// huggingFaceBackoff is the default delay before the first retry; it doubles after each retry.
+	huggingFaceBackoff = time.Second
+)
+
+// Doer sends HTTP requests. *http.Client implements it; tests can provide fakes.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// HubFile is a file to upload to a Hugging Face Hub repository.
+type HubFile struct {
+	Path    string // Path is the path of the file in the repository, such as "data/train.json".
+	Content []byte // Content is the content of the file.
+}
+
+// HuggingFaceUploader commits files, such as those produced by exporter.ExtractToDataset, to a dataset
+// repository on the Hugging Face Hub, creating the repository first if it does not exist.
+//
+// Files are sent inline with the commit endpoint of the Hub API, which suits files of up to a few
+// megabytes; the Hub may require Git LFS for larger files, which is not supported.
+type HuggingFaceUploader struct {
+	Token   string        // Token is the Hugging Face access token; it needs write access.
+	BaseURL string        // BaseURL is the base URL of the Hub; it defaults to HuggingFaceURL.
+	Client  Doer          // Client sends the requests; it defaults to http.DefaultClient.
+	Private bool          // Private creates missing repositories as private.
+	Retries int           // Retries is the number of extra attempts for requests failing with a 5xx status.
+	Backoff time.Duration // Backoff is the delay before the first retry, doubled after each retry.
+
+	// DryRun only prints the plan, the repository to create and the files to commit, to Out.
+	// Whether the repository exists is still checked, but nothing is changed on the Hub.
+	DryRun bool
+	Out    io.Writer // Out receives the plan of a dry run; it defaults to os.Stdout.
+}
+
+// NewHuggingFaceUploader creates a HuggingFaceUploader authenticated with the given access token.
+func NewHuggingFaceUploader(token string) *HuggingFaceUploader {
+	return &HuggingFaceUploader{
+		Token:   token,
+		BaseURL: HuggingFaceURL,
+		Client:  http.DefaultClient,
+		Retries: huggingFaceRetries,
+		Backoff: huggingFaceBackoff,
+	}
+}
+
+// NewHuggingFaceUploaderFromEnv creates a HuggingFaceUploader using the token stored in the HF_TOKEN
+// environment variable. It returns an error if the variable is not set.
+func NewHuggingFaceUploaderFromEnv() (*HuggingFaceUploader, error) {
+	token := os.Getenv(EnvHuggingFaceToken)
+	if token == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", EnvHuggingFaceToken)
+	}
+	return NewHuggingFaceUploader(token), nil
+}
+
+// Upload commits the files to the main branch of the dataset repository repoID, given as "owner/name",
+// with the given commit message. The repository is created first if it does not exist.
+//
+// Requests failing with a 5xx status are retried. It returns an error if repoID is malformed,
+// the context is cancelled, or the Hub rejects a request.
+func (u *HuggingFaceUploader) Upload(ctx context.Context, repoID string, files []HubFile, message string) error {
+	owner, name, ok := strings.Cut(repoID, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("repository id %q must have the form owner/name", repoID)
+	}
+
+	exists, err := u.repoExists(ctx, repoID)
+	if err != nil {
+		return err
+	}
+
+	if u.DryRun {
+		u.printPlan(repoID, exists, files, message)
+		return nil
+	}
+
+	if !exists {
+		if err := u.createRepo(ctx, owner, name); err != nil {
+			return err
+		}
+	}
+	return u.commit(ctx, repoID, files, message)
+}
+
+// printPlan describes what Upload would do to Out.
+func (u *HuggingFaceUploader) printPlan(repoID string, exists bool, files []HubFile, message string) {
+	out := u.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	if !exists {
+		visibility := "public"
+		if u.Private {
+			visibility = "private"
+		}
+		fmt.Fprintf(out, "Would create %s dataset repository %s.\n", visibility, repoID)
+	}
+	fmt.Fprintf(out, "Would commit %d file(s) to %s with message %q:\n", len(files), repoID, message)
+	for _, file := range files {
+		fmt.Fprintf(out, "  %s (%d bytes)\n", file.Path, len(file.Content))
+	}
+}
+
+// repoExists reports whether the dataset repository exists and is visible with the token.
+func (u *HuggingFaceUploader) repoExists(ctx context.Context, repoID string) (bool, error) {
+	resp, err := u.do(ctx, http.MethodGet, "/api/datasets/"+repoID, "", nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, huggingFaceError(resp)
+	}
+}
+
+// createRepo creates the dataset repository owner/name.
+func (u *HuggingFaceUploader) createRepo(ctx context.Context, owner, name string) error {
+	body, err := json.Marshal(map[string]any{"type": "dataset", "name": name, "organization": owner, "private": u.Private})
+	if err != nil {
+		return err
+	}
+	resp, err := u.do(ctx, http.MethodPost, "/api/repos/create", "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// A repository created concurrently, or one the existence check could not see, is not an error.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusConflict {
+		return huggingFaceError(resp)
+	}
+	return nil
+}
+
+// commit sends the files to the commit endpoint as newline-delimited JSON: a header line with the
+// commit message followed by one line per file with its base64-encoded content.
+func (u *HuggingFaceUploader) commit(ctx context.Context, repoID string, files []HubFile, message string) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	if err := encoder.Encode(map[string]any{"key": "header", "value": map[string]string{"summary": message}}); err != nil {
+		return err
+	}
+	for _, file := range files {
+		value := map[string]string{
+			"path":     file.Path,
+			"content":  base64.StdEncoding.EncodeToString(file.Content),
+			"encoding": "base64",
+		}
+		if err := encoder.Encode(map[string]any{"key": "file", "value": value}); err != nil {
+			return err
+		}
+	}
+
+	resp, err := u.do(ctx, http.MethodPost, "/api/datasets/"+repoID+"/commit/main", "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return huggingFaceError(resp)
+	}
+	return nil
+}
+
+// do sends an authenticated request to the Hub, retrying responses with a 5xx status.
+// The response of the last attempt is returned; the caller must close its body.
+func (u *HuggingFaceUploader) do(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	baseURL := u.BaseURL
+	if baseURL == "" {
+		baseURL = HuggingFaceURL
+	}
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	backoff := u.Backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+u.Token)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error contacting the Hugging Face Hub: %w", err)
+		}
+		if resp.StatusCode < 500 || attempt >= u.Retries {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// huggingFaceError describes a failed Hub response, including the start of its body.
+func huggingFaceError(resp *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("Hugging Face API response status: %s: %s", resp.Status, bytes.TrimSpace(message))
+}
@@ -0,0 +1,133 @@
+package uploader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeHub is an httptest handler that emulates the parts of the Hugging Face Hub API used by the uploader.
+type fakeHub struct {
+	t        *testing.T
+	repos    map[string]bool
+	failures int // failures is the number of commit requests answered with 503 before succeeding.
+	requests []string
+	commits  [][]map[string]any
+}
+
+func (h *fakeHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.requests = append(h.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/datasets/"):
+		if !h.repos[strings.TrimPrefix(r.URL.Path, "/api/datasets/")] {
+			http.NotFound(w, r)
+		}
+	case r.Method == http.MethodPost && r.URL.Path == "/api/repos/create":
+		var body struct{ Type, Name, Organization string }
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Type != "dataset" {
+			h.t.Errorf("created repository of type %q, want dataset", body.Type)
+		}
+		h.repos[body.Organization+"/"+body.Name] = true
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/commit/main"):
+		if h.failures > 0 {
+			h.failures--
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/x-ndjson" {
+			h.t.Errorf("commit content type = %q", r.Header.Get("Content-Type"))
+		}
+		var lines []map[string]any
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var line map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				h.t.Errorf("invalid commit line %q: %v", scanner.Text(), err)
+			}
+			lines = append(lines, line)
+		}
+		h.commits = append(h.commits, lines)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// TestHuggingFaceUploaderUpload verifies that a missing repository is created and the files are committed,
+// retrying server errors.
+func TestHuggingFaceUploaderUpload(t *testing.T) {
+	hub := &fakeHub{t: t, repos: map[string]bool{}, failures: 2}
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	uploader := &HuggingFaceUploader{Token: "secret", BaseURL: server.URL, Client: server.Client(), Retries: 2}
+	files := []HubFile{{Path: "data/dataset.json", Content: []byte(`{"sessions":[]}`)}}
+	if err := uploader.Upload(context.Background(), "gopher/chats", files, "Add dataset"); err != nil {
+		t.Fatalf("Upload() returned an error: %v", err)
+	}
+	if !hub.repos["gopher/chats"] {
+		t.Error("Upload() did not create the missing repository")
+	}
+	if len(hub.commits) != 1 || len(hub.commits[0]) != 2 {
+		t.Fatalf("commits = %v, want one commit with a header and a file", hub.commits)
+	}
+	header, file := hub.commits[0][0], hub.commits[0][1]["value"].(map[string]any)
+	if header["key"] != "header" || header["value"].(map[string]any)["summary"] != "Add dataset" {
+		t.Errorf("commit header = %v", header)
+	}
+	content, _ := base64.StdEncoding.DecodeString(file["content"].(string))
+	if file["path"] != "data/dataset.json" || string(content) != `{"sessions":[]}` {
+		t.Errorf("committed file = %v with content %q", file, content)
+	}
+
+	hub.requests = nil
+	if err := uploader.Upload(context.Background(), "gopher/chats", files, "Update dataset"); err != nil {
+		t.Fatalf("second Upload() returned an error: %v", err)
+	}
+	for _, request := range hub.requests {
+		if request == "POST /api/repos/create" {
+			t.Error("Upload() created an existing repository again")
+		}
+	}
+
+	hub.failures = 3
+	if err := uploader.Upload(context.Background(), "gopher/chats", files, "Update dataset"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Upload() after exhausting retries returned %v, want a 503 error", err)
+	}
+}
+
+// TestHuggingFaceUploaderDryRun verifies that a dry run prints the plan without changing anything.
+func TestHuggingFaceUploaderDryRun(t *testing.T) {
+	hub := &fakeHub{t: t, repos: map[string]bool{}}
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	var out bytes.Buffer
+	uploader := &HuggingFaceUploader{Token: "secret", BaseURL: server.URL, Client: server.Client(), DryRun: true, Out: &out}
+	files := []HubFile{{Path: "dataset.json", Content: []byte("12345")}}
+	if err := uploader.Upload(context.Background(), "gopher/chats", files, "Add dataset"); err != nil {
+		t.Fatalf("Upload() returned an error: %v", err)
+	}
+	for _, want := range []string{"Would create public dataset repository gopher/chats.", "dataset.json (5 bytes)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("plan is missing %q:\n%s", want, out.String())
+		}
+	}
+	if len(hub.repos) != 0 || len(hub.commits) != 0 {
+		t.Error("a dry run changed the Hub")
+	}
+
+	if err := uploader.Upload(context.Background(), "no-owner", files, "Add dataset"); err == nil {
+		t.Error("Upload() accepted a repository id without an owner")
+	}
+}
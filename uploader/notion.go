@@ -0,0 +1,114 @@
+// Package uploader provides clients that publish exported chat session data to third-party services.
+//
+// The uploaders are deliberately kept separate from the exporter package: the exporter only
+// produces data, while the uploaders deal with authentication and the network.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+const (
+	// EnvNotionToken is the environment variable that holds the Notion integration token.
+	EnvNotionToken = "NOTION_TOKEN"
+
+	// NotionAPIURL is the base URL of the Notion API.
+	NotionAPIURL = "https://api.notion.com/v1"
+
+	// NotionVersion is the Notion API version the uploader speaks.
+	NotionVersion = "2022-06-28"
+
+	// notionMaxChildren is the maximum number of blocks the Notion API accepts in a single request.
+	notionMaxChildren = 100
+)
+
+// NotionUploader appends Notion API blocks, such as those produced by exporter.ExtractToNotionBlocks,
+// to an existing Notion page.
+type NotionUploader struct {
+	Token   string       // Token is the bearer token of the Notion integration.
+	BaseURL string       // BaseURL is the base URL of the Notion API; it defaults to NotionAPIURL.
+	Client  *http.Client // Client is the HTTP client used for requests; it defaults to http.DefaultClient.
+}
+
+// NewNotionUploader creates a NotionUploader authenticated with the given integration token.
+func NewNotionUploader(token string) *NotionUploader {
+	return &NotionUploader{Token: token, BaseURL: NotionAPIURL, Client: http.DefaultClient}
+}
+
+// NewNotionUploaderFromEnv creates a NotionUploader using the token stored in the NOTION_TOKEN environment variable.
+// It returns an error if the variable is not set.
+func NewNotionUploaderFromEnv() (*NotionUploader, error) {
+	token := os.Getenv(EnvNotionToken)
+	if token == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", EnvNotionToken)
+	}
+	return NewNotionUploader(token), nil
+}
+
+// Upload appends the blocks, given as a JSON array of Notion block objects, to the page with the given ID.
+//
+// The blocks are sent with the "append block children" endpoint in batches of 100, the maximum the API accepts.
+// It returns an error if the blocks are not a JSON array, the context is cancelled, or the API rejects a request.
+func (u *NotionUploader) Upload(ctx context.Context, pageID string, blocks []byte) error {
+	var children []json.RawMessage
+	if err := json.Unmarshal(blocks, &children); err != nil {
+		return fmt.Errorf("blocks must be a JSON array: %w", err)
+	}
+
+	for start := 0; start < len(children); start += notionMaxChildren {
+		end := start + notionMaxChildren
+		if end > len(children) {
+			end = len(children)
+		}
+		if err := u.appendChildren(ctx, pageID, children[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendChildren sends a single "append block children" request.
+func (u *NotionUploader) appendChildren(ctx context.Context, pageID string, children []json.RawMessage) error {
+	body, err := json.Marshal(map[string][]json.RawMessage{"children": children})
+	if err != nil {
+		return err
+	}
+
+	baseURL := u.BaseURL
+	if baseURL == "" {
+		baseURL = NotionAPIURL
+	}
+	endpoint := fmt.Sprintf("%s/blocks/%s/children", baseURL, url.PathEscape(pageID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+u.Token)
+	req.Header.Set("Notion-Version", NotionVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error uploading blocks to Notion: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Notion API response status: %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
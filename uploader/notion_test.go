@@ -0,0 +1,59 @@
+package uploader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestNotionUploaderUpload verifies that blocks are sent in batches with the expected headers.
+func TestNotionUploaderUpload(t *testing.T) {
+	var batches []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/blocks/page-id/children" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Notion-Version") != NotionVersion {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		var body struct {
+			Children []json.RawMessage `json:"children"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		batches = append(batches, len(body.Children))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	blocks := make([]string, 150)
+	for i := range blocks {
+		blocks[i] = fmt.Sprintf(`{"object":"block","type":"paragraph","paragraph":{"rich_text":[]},"n":%d}`, i)
+	}
+
+	uploader := &NotionUploader{Token: "secret", BaseURL: server.URL, Client: server.Client()}
+	if err := uploader.Upload(context.Background(), "page-id", []byte("["+strings.Join(blocks, ",")+"]")); err != nil {
+		t.Fatalf("Upload() returned an error: %v", err)
+	}
+	if len(batches) != 2 || batches[0] != 100 || batches[1] != 50 {
+		t.Errorf("batches = %v, want [100 50]", batches)
+	}
+}
+
+// TestNotionUploaderError verifies that API errors are reported.
+func TestNotionUploaderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"unauthorized"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	uploader := &NotionUploader{Token: "wrong", BaseURL: server.URL, Client: server.Client()}
+	if err := uploader.Upload(context.Background(), "page-id", []byte(`[{}]`)); err == nil {
+		t.Errorf("expected an error for an unauthorized request")
+	}
+}
@@ -0,0 +1,141 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	// EnvGoogleCredentials is the environment variable that holds the path of the service account JSON key.
+	EnvGoogleCredentials = "GOOGLE_APPLICATION_CREDENTIALS"
+
+	// GoogleSheetsAPIURL is the base URL of the Google Sheets API v4.
+	GoogleSheetsAPIURL = "https://sheets.googleapis.com/v4"
+
+	// googleSheetsScope is the OAuth 2.0 scope granting read and write access to spreadsheets.
+	googleSheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+)
+
+// GoogleSheetsUploader writes rows of values, such as those produced by exporter.ExtractToSheetValues,
+// into a new sheet of an existing Google spreadsheet, authenticated as a service account.
+//
+// The spreadsheet must be shared with the service account's e-mail address with edit access.
+type GoogleSheetsUploader struct {
+	BaseURL string // BaseURL is the base URL of the Sheets API; it defaults to GoogleSheetsAPIURL.
+	Client  Doer   // Client sends the requests; it defaults to http.DefaultClient.
+
+	config *jwt.Config
+}
+
+// NewGoogleSheetsUploader creates a GoogleSheetsUploader from the JSON key of a service account.
+// It returns an error if the key is not a service account key or its private key cannot be parsed.
+func NewGoogleSheetsUploader(credentials []byte) (*GoogleSheetsUploader, error) {
+	config, err := google.JWTConfigFromJSON(credentials, googleSheetsScope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account key: %w", err)
+	}
+	return &GoogleSheetsUploader{BaseURL: GoogleSheetsAPIURL, Client: http.DefaultClient, config: config}, nil
+}
+
+// SessionsSheetTitle returns the title of the sheet the sessions exported at t are written to, "Sessions 2006-01-02".
+func SessionsSheetTitle(t time.Time) string {
+	return "Sessions " + t.Format("2006-01-02")
+}
+
+// Upload adds a sheet with the given title to the spreadsheet and writes the rows into it, starting at
+// cell A1, with a values batchUpdate. The values are written as they are, without parsing them as
+// numbers or formulas.
+//
+// It returns an error if authentication fails, a sheet with that title already exists, the context is
+// cancelled, or the API rejects a request.
+func (u *GoogleSheetsUploader) Upload(ctx context.Context, spreadsheetID, title string, rows [][]string) error {
+	token, err := u.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	spreadsheet := "/spreadsheets/" + url.PathEscape(spreadsheetID)
+	addSheet := map[string]any{
+		"requests": []any{map[string]any{
+			"addSheet": map[string]any{"properties": map[string]string{"title": title}},
+		}},
+	}
+	if err := u.post(ctx, token, spreadsheet+":batchUpdate", addSheet); err != nil {
+		return err
+	}
+
+	// A sheet title is quoted in A1 notation, with single quotes doubled.
+	valueRange := map[string]any{
+		"range":          "'" + strings.ReplaceAll(title, "'", "''") + "'!A1",
+		"majorDimension": "ROWS",
+		"values":         rows,
+	}
+	values := map[string]any{"valueInputOption": "RAW", "data": []any{valueRange}}
+	return u.post(ctx, token, spreadsheet+"/values:batchUpdate", values)
+}
+
+// accessToken exchanges a JWT signed with the service account's private key for an OAuth 2.0 access token.
+// The token endpoint is contacted with Client when it is an *http.Client.
+func (u *GoogleSheetsUploader) accessToken(ctx context.Context) (string, error) {
+	if client, ok := u.Client.(*http.Client); ok {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	}
+	token, err := u.config.TokenSource(ctx).Token()
+	if err != nil {
+		return "", fmt.Errorf("error requesting a Google access token: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+// post sends an authenticated JSON request to the Sheets API.
+func (u *GoogleSheetsUploader) post(ctx context.Context, token, path string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	baseURL := u.BaseURL
+	if baseURL == "" {
+		baseURL = GoogleSheetsAPIURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := u.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("error contacting the Google Sheets API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return googleError(resp)
+	}
+	return nil
+}
+
+// client returns the HTTP client used for requests.
+func (u *GoogleSheetsUploader) client() Doer {
+	if u.Client == nil {
+		return http.DefaultClient
+	}
+	return u.Client
+}
+
+// googleError describes a failed Google API response, including the start of its body.
+func googleError(resp *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("Google API response status: %s: %s", resp.Status, bytes.TrimSpace(message))
+}
@@ -0,0 +1,141 @@
+package uploader
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGoogle is an httptest handler that emulates the Google token endpoint and the parts of the
+// Sheets API used by the uploader.
+type fakeGoogle struct {
+	t      *testing.T
+	key    *rsa.PublicKey
+	sheets map[string][][]any
+}
+
+func (g *fakeGoogle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if len(parts) != 3 {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(g.key, crypto.SHA256, digest[:], signature); err != nil {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"token","token_type":"Bearer","expires_in":3600}`))
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, `{"error":"unauthenticated"}`, http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/spreadsheets/sheet-1:batchUpdate":
+		var body struct {
+			Requests []struct {
+				AddSheet struct {
+					Properties struct{ Title string }
+				}
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		title := body.Requests[0].AddSheet.Properties.Title
+		if _, ok := g.sheets[title]; ok {
+			http.Error(w, `{"error":"sheet already exists"}`, http.StatusBadRequest)
+			return
+		}
+		g.sheets[title] = nil
+	case "/spreadsheets/sheet-1/values:batchUpdate":
+		var body struct {
+			ValueInputOption string
+			Data             []struct {
+				Range  string
+				Values [][]any
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.ValueInputOption != "RAW" || len(body.Data) != 1 {
+			g.t.Errorf("values batchUpdate = %+v", body)
+		}
+		g.sheets[body.Data[0].Range] = body.Data[0].Values
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// testServiceAccountKey returns a service account JSON key with a fresh RSA key, whose token endpoint is tokenURL.
+func testServiceAccountKey(t *testing.T, tokenURL string) ([]byte, *rsa.PublicKey) {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "exporter@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURL,
+	})
+	return key, &privateKey.PublicKey
+}
+
+// TestGoogleSheetsUploaderUpload verifies that the uploader authenticates as the service account,
+// adds the sheet, and writes the rows into it.
+func TestGoogleSheetsUploaderUpload(t *testing.T) {
+	google := &fakeGoogle{t: t, sheets: map[string][][]any{}}
+	server := httptest.NewServer(google)
+	defer server.Close()
+
+	credentials, publicKey := testServiceAccountKey(t, server.URL+"/token")
+	google.key = publicKey
+	uploader, err := NewGoogleSheetsUploader(credentials)
+	if err != nil {
+		t.Fatalf("NewGoogleSheetsUploader() returned an error: %v", err)
+	}
+	uploader.BaseURL = server.URL
+	uploader.Client = server.Client()
+
+	title := SessionsSheetTitle(time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC))
+	if title != "Sessions 2024-01-02" {
+		t.Errorf("SessionsSheetTitle() = %q", title)
+	}
+	rows := [][]string{{"id", "topic"}, {"1", "=SUM(A1)"}}
+	if err := uploader.Upload(context.Background(), "sheet-1", title, rows); err != nil {
+		t.Fatalf("Upload() returned an error: %v", err)
+	}
+	want := [][]any{{"id", "topic"}, {"1", "=SUM(A1)"}}
+	if got := google.sheets["'Sessions 2024-01-02'!A1"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("written values = %v, want %v", got, want)
+	}
+
+	if err := uploader.Upload(context.Background(), "sheet-1", title, rows); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Upload() into an existing sheet returned %v, want the API error", err)
+	}
+
+	if _, err := NewGoogleSheetsUploader([]byte(`{"type":"authorized_user"}`)); err == nil {
+		t.Error("NewGoogleSheetsUploader() accepted credentials that are not a service account key")
+	}
+}
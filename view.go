@@ -0,0 +1,293 @@
+// @view.go:
+// This file implements -view, a pager for reading the conversations in the terminal before deciding
+// what to export. It scrolls the plain-text transcript of one session at a time in raw mode and falls
+// back to printing the transcripts when the input or output is not a terminal.
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/tablecli"
+)
+
+// PromptViewSession asks for the session the viewer starts with.
+const PromptViewSession = "Enter the number of the session to read (leave empty for 1): "
+
+// viewerHelp is shown in the status line of the viewer.
+const viewerHelp = "j/k scroll, space/b page, n/p session, / search, q quit"
+
+// Escape sequences used by the viewer: switching to and from the alternate screen, clearing it, and
+// showing the status line in reverse video.
+const (
+	enterAlternateScreen = "\x1b[?1049h"
+	leaveAlternateScreen = "\x1b[?1049l"
+	clearScreen          = "\x1b[H\x1b[2J"
+	clearLine            = "\r\x1b[K"
+	reverseVideo         = "\x1b[7m"
+	resetVideo           = "\x1b[0m"
+)
+
+// viewerTerminal is the terminal the viewer reads key presses from and draws on.
+//
+// It is an interface so that the viewer can be tested with scripted key presses instead of a real
+// terminal in raw mode.
+type viewerTerminal interface {
+	io.ByteReader
+	io.Writer
+	Size() (width, height int)
+}
+
+// stdioTerminal is the viewerTerminal of the standard input and output, which must be in raw mode.
+type stdioTerminal struct {
+	*bufio.Reader
+	io.Writer
+}
+
+func (stdioTerminal) Size() (width, height int) {
+	return tablecli.TerminalWidth(), tablecli.TerminalHeight()
+}
+
+// viewSessions lets the user pick a session from the list and read the sessions in a pager, one at a
+// time, starting with the picked one. Without a terminal on both standard input and output, or if the
+// terminal cannot be put into raw mode, the transcripts of all sessions are printed to w instead.
+func viewSessions(ctx context.Context, w io.Writer, reader *bufio.Reader, sessions []exporter.Session, dateField exporter.DateField) error {
+	if len(sessions) == 0 {
+		fmt.Fprintln(w, "No sessions to view.")
+		return nil
+	}
+	if !tablecli.IsTerminal(os.Stdin) || !tablecli.IsTerminal(os.Stdout) {
+		_, err := io.WriteString(w, exporter.ExtractToPlainText(sessions))
+		return err
+	}
+
+	sessionsTable(sessions, dateField).Render(w, tablecli.TerminalWidth())
+	answer, err := promptForInput(ctx, w, reader, PromptViewSession)
+	if err != nil {
+		return err
+	}
+	start := 0
+	if answer != "" {
+		number, err := strconv.Atoi(answer)
+		if err != nil || number < 1 || number > len(sessions) {
+			fmt.Fprintf(w, "Invalid selection: %q is not a session number between 1 and %d\n", answer, len(sessions))
+			return nil
+		}
+		start = number - 1
+	}
+
+	restore, err := tablecli.MakeRaw(os.Stdin)
+	if err != nil {
+		_, err := io.WriteString(w, exporter.ExtractToPlainText(sessions))
+		return err
+	}
+	defer restore()
+	return (&viewer{sessions: sessions, index: start}).run(stdioTerminal{reader, w})
+}
+
+// viewer is the state of the pager of viewSessions.
+type viewer struct {
+	sessions []exporter.Session
+	index    int      // index is the session shown.
+	lines    []string // lines holds the transcript of the session shown, wrapped to width.
+	width    int      // width is the width lines were wrapped to, or 0 if they must be wrapped again.
+	top      int      // top is the index of the first line shown.
+	query    string   // query is the last search, repeated by an empty search.
+	status   string   // status replaces the help in the status line until the next key.
+}
+
+// run draws the viewer on the terminal and handles key presses until the user quits or the input ends.
+func (v *viewer) run(term viewerTerminal) error {
+	fmt.Fprint(term, enterAlternateScreen)
+	defer fmt.Fprint(term, leaveAlternateScreen)
+	for {
+		width, height := term.Size()
+		v.draw(term, width, height)
+		key, err := readKey(term)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		page := max(height-1, 1)
+		v.status = ""
+		switch key {
+		case "j", "down", "\r", "\n":
+			v.top++
+		case "k", "up":
+			v.top--
+		case " ", "f", "pgdown":
+			v.top += page
+		case "b", "pgup":
+			v.top -= page
+		case "g":
+			v.top = 0
+		case "G":
+			v.top = len(v.lines)
+		case "n":
+			v.showSession(v.index + 1)
+		case "p":
+			v.showSession(v.index - 1)
+		case "/":
+			query, err := v.readQuery(term, height)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			v.search(query)
+		case "q", "Q", "\x03", "\x04":
+			return nil
+		}
+	}
+}
+
+// showSession switches to the session at index, staying at the first or last session.
+func (v *viewer) showSession(index int) {
+	switch {
+	case index < 0:
+		v.status = "This is the first session."
+	case index >= len(v.sessions):
+		v.status = "This is the last session."
+	default:
+		v.index, v.top, v.width = index, 0, 0
+	}
+}
+
+// draw clears the screen and draws the visible lines of the session followed by the status line.
+func (v *viewer) draw(w io.Writer, width, height int) {
+	if v.width != width {
+		v.lines = wrapLines(exporter.ExtractToPlainText(v.sessions[v.index:v.index+1]), width)
+		v.width = width
+	}
+	page := max(height-1, 1)
+	v.top = max(min(v.top, len(v.lines)-page), 0)
+	end := min(v.top+page, len(v.lines))
+
+	var screen strings.Builder
+	screen.WriteString(clearScreen)
+	for _, line := range v.lines[v.top:end] {
+		screen.WriteString(line + "\r\n")
+	}
+	for i := end - v.top; i < page; i++ {
+		screen.WriteString("~\r\n")
+	}
+	status := v.status
+	if status == "" {
+		status = fmt.Sprintf("[%d/%d] lines %d-%d of %d (%s)", v.index+1, len(v.sessions), v.top+1, end, len(v.lines), viewerHelp)
+	}
+	screen.WriteString(reverseVideo + truncateToWidth(status, width) + resetVideo)
+	io.WriteString(w, screen.String())
+}
+
+// readQuery reads a search query typed in the status line, ended by Enter. Backspace on an empty
+// query or Ctrl-C cancels the search and returns an empty query.
+func (v *viewer) readQuery(term viewerTerminal, height int) (string, error) {
+	var query []byte
+	for {
+		fmt.Fprintf(term, "%s/%s", clearLine, query)
+		b, err := term.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case b == '\r' || b == '\n':
+			if len(query) == 0 {
+				// An empty query repeats the last search.
+				return v.query, nil
+			}
+			return string(query), nil
+		case b == 0x7f || b == 0x08:
+			if len(query) == 0 {
+				return "", nil
+			}
+			_, size := utf8.DecodeLastRune(query)
+			query = query[:len(query)-size]
+		case b == 0x03:
+			return "", nil
+		case b >= 0x20:
+			query = append(query, b)
+		}
+	}
+}
+
+// search moves to the next line of the session containing query, ignoring case, wrapping around to
+// the start of the session if there is none below the first line shown.
+func (v *viewer) search(query string) {
+	if query == "" {
+		return
+	}
+	v.query = query
+	needle := strings.ToLower(query)
+	for i := 1; i <= len(v.lines); i++ {
+		line := (v.top + i) % len(v.lines)
+		if strings.Contains(strings.ToLower(v.lines[line]), needle) {
+			v.top = line
+			return
+		}
+	}
+	v.status = "Pattern not found: " + query
+}
+
+// readKey reads a key press: a single character, or "up", "down", "pgup", or "pgdown" for the
+// escape sequences of the arrow and page keys. Other escape sequences are returned as "esc".
+func readKey(r io.ByteReader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if b != 0x1b {
+		return string(b), nil
+	}
+	if b, err = r.ReadByte(); err != nil || (b != '[' && b != 'O') {
+		return "esc", err
+	}
+	if b, err = r.ReadByte(); err != nil {
+		return "esc", err
+	}
+	switch b {
+	case 'A':
+		return "up", nil
+	case 'B':
+		return "down", nil
+	case '5', '6':
+		if tilde, err := r.ReadByte(); err != nil || tilde != '~' {
+			return "esc", err
+		}
+		if b == '5' {
+			return "pgup", nil
+		}
+		return "pgdown", nil
+	}
+	return "esc", nil
+}
+
+// wrapLines splits text into lines no wider than width characters, expanding tabs to four spaces.
+func wrapLines(text string, width int) []string {
+	width = max(width, 1)
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		runes := []rune(strings.ReplaceAll(line, "\t", "    "))
+		for len(runes) > width {
+			lines = append(lines, string(runes[:width]))
+			runes = runes[width:]
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
+
+// truncateToWidth cuts text to at most width characters.
+func truncateToWidth(text string, width int) string {
+	if runes := []rune(text); len(runes) > width {
+		return string(runes[:max(width, 0)])
+	}
+	return text
+}